@@ -0,0 +1,21 @@
+package v1alpha1
+
+const (
+	//DelegatedDomainsAnnotation is set on a Namespace to delegate domains to it. The value is a comma-separated list of domains,
+	//which can be wildcards, such as `*.billing.example.com`. Hosts in a delegated domain can be exposed only by APIRules in the namespaces the domain is delegated to.
+	DelegatedDomainsAnnotation = "gateway.kyma-project.io/delegated-domains"
+	//DryRunAnnotation set to "true" on an APIRule makes the controller compute the changes of generated objects without applying them.
+	//The planned changes are reported in the status of the APIRule.
+	DryRunAnnotation = "gateway.kyma-project.io/dry-run"
+	//ForceDeleteAnnotation set to "true" on an APIRule being deleted makes the controller release it without waiting for the objects
+	//generated for it to be deleted
+	ForceDeleteAnnotation = "gateway.kyma-project.io/force-delete"
+	//PausedAnnotation set to "true" on an APIRule makes the controller validate it and report the changes of generated objects
+	//without applying them, so the generated objects can be frozen and patched by hand
+	PausedAnnotation = "gateway.kyma-project.io/paused"
+	//UserManagedAnnotation set to "true" on a generated Virtual Service or Oathkeeper Rule makes the controller leave the object unchanged
+	UserManagedAnnotation = "gateway.kyma-project.io/user-managed"
+)
+
+//Finalizer is set on APIRules to delete the objects generated for them before the APIRules are released
+const Finalizer = "gateway.kyma-project.io/generated-objects"
@@ -16,6 +16,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"regexp"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -30,6 +32,10 @@ const (
 	StatusSkipped StatusCode = "SKIPPED"
 	//StatusError .
 	StatusError StatusCode = "ERROR"
+	//StatusDeleting .
+	StatusDeleting StatusCode = "DELETING"
+	//StatusPending .
+	StatusPending StatusCode = "PENDING"
 )
 
 // APIRuleSpec defines the desired state of ApiRule
@@ -51,6 +57,30 @@ type APIRuleStatus struct {
 	APIRuleStatus        *APIRuleResourceStatus `json:"APIRuleStatus,omitempty"`
 	VirtualServiceStatus *APIRuleResourceStatus `json:"virtualServiceStatus,omitempty"`
 	AccessRuleStatus     *APIRuleResourceStatus `json:"accessRuleStatus,omitempty"`
+	//CertificateStatus tells if the certificate provisioned for the host is ready. Not set if no certificate is provisioned
+	CertificateStatus *APIRuleResourceStatus `json:"certificateStatus,omitempty"`
+	//RateLimitStatus tells if the rate limits of the rules are active. Not set if no rule is rate limited
+	RateLimitStatus *APIRuleResourceStatus `json:"rateLimitStatus,omitempty"`
+	//Plan lists the changes of generated objects computed, but not applied, in dry-run mode
+	Plan *APIRulePlan `json:"plan,omitempty"`
+	//PhaseTimes tells when the phases of processing were last completed
+	PhaseTimes *PhaseTimes `json:"phaseTimes,omitempty"`
+	//FailedAttempts counts the processing attempts which ended with the ERROR or SKIPPED status since the last success.
+	//Failed APIRules are processed again with a delay growing with the number of attempts.
+	FailedAttempts int32 `json:"failedAttempts,omitempty"`
+}
+
+//PhaseTimes tells when the phases of processing the APIRule were last completed
+type PhaseTimes struct {
+	// When the APIRule last passed validation
+	// +optional
+	Validated *metav1.Time `json:"validated,omitempty"`
+	// When the generated objects were last applied
+	// +optional
+	Applied *metav1.Time `json:"applied,omitempty"`
+	// When processing last ended with the ERROR or SKIPPED status
+	// +optional
+	Failed *metav1.Time `json:"failed,omitempty"`
 }
 
 //APIRule is the Schema for the apis ApiRule
@@ -78,6 +108,12 @@ type APIRuleList struct {
 type Service struct {
 	// Name of the service
 	Name *string `json:"name"`
+	// Namespace of the service. Defaults to the namespace of the APIRule. Services in other namespaces can be exposed
+	// only if a ReferenceGrant in their namespace allows it
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	Namespace *string `json:"namespace,omitempty"`
 	// Port of the service to expose
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:validation:Maximum=65535
@@ -90,13 +126,69 @@ type Service struct {
 	// Defines if the service is internal (in cluster) or external
 	// +optional
 	IsExternal *bool `json:"external,omitempty"`
+	// Protocol spoken by the service on the exposed port. Defaults to http
+	// +kubebuilder:validation:Enum=http;grpc;grpc-web;websocket
+	// +optional
+	Protocol *string `json:"protocol,omitempty"`
+	// TLS of the connections to the service. Connections use the mesh defaults if not set
+	// +optional
+	TLS *UpstreamTLS `json:"tls,omitempty"`
 }
 
+//UpstreamTLS configures TLS of the connections to the exposed service, applied with a Destination Rule
+type UpstreamTLS struct {
+	// TLS mode: DISABLE for plain text, SIMPLE to verify the certificate of the service, MUTUAL to also present a client certificate,
+	// or ISTIO_MUTUAL to use Istio mutual TLS
+	// +kubebuilder:validation:Enum=DISABLE;SIMPLE;MUTUAL;ISTIO_MUTUAL
+	Mode string `json:"mode"`
+	// Name of the Secret with the CA certificate verifying the service in the ca.crt key. Required in SIMPLE mode
+	// +optional
+	CASecretName *string `json:"caSecretName,omitempty"`
+	// Name of the Secret with the client certificate and key in the tls.crt and tls.key keys, and the CA certificate verifying the service
+	// in the ca.crt key. Required in MUTUAL mode
+	// +optional
+	ClientSecretName *string `json:"clientSecretName,omitempty"`
+}
+
+//Protocols of the exposed service
+const (
+	ProtocolHTTP      = "http"
+	ProtocolGRPC      = "grpc"
+	ProtocolGRPCWeb   = "grpc-web"
+	ProtocolWebSocket = "websocket"
+)
+
+//Units of time of rate limits
+const (
+	RateLimitUnitSecond = "second"
+	RateLimitUnitMinute = "minute"
+	RateLimitUnitHour   = "hour"
+)
+
+//Types of rate limit keys
+const (
+	RateLimitKeyClientIP   = "client_ip"
+	RateLimitKeyJWTSubject = "jwt_subject"
+	RateLimitKeyHeader     = "header"
+)
+
+//TLS modes of the connections to the exposed service
+const (
+	TLSModeDisable     = "DISABLE"
+	TLSModeSimple      = "SIMPLE"
+	TLSModeMutual      = "MUTUAL"
+	TLSModeIstioMutual = "ISTIO_MUTUAL"
+)
+
 //Rule .
 type Rule struct {
-	// Path to be exposed
+	// Path to be exposed. Either path or grpc has to be set
 	// +kubebuilder:validation:Pattern=^([0-9a-zA-Z./*()?!\\_-]+)
-	Path string `json:"path"`
+	// +optional
+	Path string `json:"path,omitempty"`
+	// gRPC service and method to be exposed, instead of a path. Can be used with the grpc and grpc-web protocols
+	// +optional
+	GRPC *GRPCMatch `json:"grpc,omitempty"`
 	// Set of allowed HTTP methods
 	// +kubebuilder:validation:MinItems=1
 	Methods []string `json:"methods"`
@@ -106,6 +198,56 @@ type Rule struct {
 	// Mutators to be used
 	// +optional
 	Mutators []*Mutator `json:"mutators,omitempty"`
+	// Limit of requests matching the rule. Requests are not limited if not set
+	// +optional
+	RateLimit *RateLimit `json:"rateLimit,omitempty"`
+	// Client addresses allowed or denied to send requests matching the rule. Requests are accepted from any address if not set
+	// +optional
+	SourceIPs *SourceIPs `json:"sourceIPs,omitempty"`
+}
+
+//SourceIPs restricts the client addresses of requests, applied with an AuthorizationPolicy on the ingress gateway
+type SourceIPs struct {
+	// IP addresses or CIDR blocks of the clients allowed to send requests. Requests from other addresses are denied
+	// +optional
+	Allow []string `json:"allow,omitempty"`
+	// IP addresses or CIDR blocks of the clients denied to send requests
+	// +optional
+	Deny []string `json:"deny,omitempty"`
+}
+
+//RateLimit limits the number of requests per unit of time, applied with an EnvoyFilter on the ingress gateway
+type RateLimit struct {
+	// Number of requests allowed per unit
+	// +kubebuilder:validation:Minimum=1
+	Requests uint32 `json:"requests"`
+	// Unit of time: second, minute or hour
+	// +kubebuilder:validation:Enum=second;minute;hour
+	Unit string `json:"unit"`
+	// Key the requests are counted by, so every client gets its own limit. All requests matching the rule share the limit if not set
+	// +optional
+	Key *RateLimitKey `json:"key,omitempty"`
+}
+
+//RateLimitKey identifies the client of a request for rate limiting
+type RateLimitKey struct {
+	// Type of the key: client_ip, jwt_subject or header
+	// +kubebuilder:validation:Enum=client_ip;jwt_subject;header
+	Type string `json:"type"`
+	// Name of the header identifying the client. Required for the header type
+	// +optional
+	Header *string `json:"header,omitempty"`
+}
+
+//GRPCMatch matches the requests calling a gRPC service
+type GRPCMatch struct {
+	// Fully qualified name of the gRPC service, e.g. helloworld.Greeter
+	// +kubebuilder:validation:Pattern=^[a-zA-Z_][a-zA-Z0-9_.]*$
+	Service string `json:"service"`
+	// Method of the gRPC service. All methods are matched if not set
+	// +kubebuilder:validation:Pattern=^[a-zA-Z_][a-zA-Z0-9_]*$
+	// +optional
+	Method string `json:"method,omitempty"`
 }
 
 //APIRuleResourceStatus .
@@ -114,6 +256,52 @@ type APIRuleResourceStatus struct {
 	Description string     `json:"desc,omitempty"`
 }
 
+//APIRulePlan lists the changes planned for the objects generated for the APIRule
+type APIRulePlan struct {
+	Changes []PlannedChange `json:"changes,omitempty"`
+}
+
+//PlannedChange describes the action planned for an object generated for the APIRule
+type PlannedChange struct {
+	// Kind of the object: VirtualService or AccessRule
+	Kind string `json:"kind"`
+	// Name of the object
+	Name string `json:"name"`
+	// Action planned: create, update, delete, skip or none
+	Action string `json:"action"`
+	// Differences between the spec of the existing object and the planned one. Removed lines start with "-", added lines with "+"
+	// +optional
+	Diff string `json:"diff,omitempty"`
+}
+
+//ServiceNamespace returns the namespace of the exposed Service, which defaults to the namespace of the APIRule
+func (in *APIRule) ServiceNamespace() string {
+	if in.Spec.Service != nil && in.Spec.Service.Namespace != nil && *in.Spec.Service.Namespace != "" {
+		return *in.Spec.Service.Namespace
+	}
+	return in.Namespace
+}
+
+//ServiceProtocol returns the protocol of the exposed service, http if it isn't set
+func (in *APIRule) ServiceProtocol() string {
+	if in.Spec.Service != nil && in.Spec.Service.Protocol != nil && *in.Spec.Service.Protocol != "" {
+		return *in.Spec.Service.Protocol
+	}
+	return ProtocolHTTP
+}
+
+//MatchPath returns the path regex matched by the rule. gRPC calls are matched by the path /<service>/<method>
+func (r *Rule) MatchPath() string {
+	if r.GRPC == nil {
+		return r.Path
+	}
+	method := "[^/]+"
+	if r.GRPC.Method != "" {
+		method = regexp.QuoteMeta(r.GRPC.Method)
+	}
+	return "/" + regexp.QuoteMeta(r.GRPC.Service) + "/" + method
+}
+
 func init() {
 	SchemeBuilder.Register(&APIRule{}, &APIRuleList{})
 }
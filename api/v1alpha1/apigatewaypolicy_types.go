@@ -0,0 +1,75 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// APIGatewayPolicySpec defines the restrictions applied to APIRules in the selected namespaces
+type APIGatewayPolicySpec struct {
+	// Names of the namespaces the policy applies to
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Selects the namespaces the policy applies to by labels. If neither namespaces nor namespaceSelector is set, the policy applies to all namespaces
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// Domains that can be exposed. The host must be equal to the domain or be its subdomain
+	// +optional
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+	// Gateways that can be used
+	// +optional
+	AllowedGateways []string `json:"allowedGateways,omitempty"`
+	// Access strategies that can be used. Empty list allows all access strategies
+	// +optional
+	AllowedAccessStrategies []string `json:"allowedAccessStrategies,omitempty"`
+	// Access strategies that can't be used
+	// +optional
+	DeniedAccessStrategies []string `json:"deniedAccessStrategies,omitempty"`
+	// Services that can't be exposed
+	// +optional
+	BlockedServices []string `json:"blockedServices,omitempty"`
+	// Mutators that must be configured on every secured rule. Rules using only the allow access strategy are not checked
+	// +optional
+	RequiredMutators []string `json:"requiredMutators,omitempty"`
+	// Maximum number of rules in a single APIRule
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxRules *int `json:"maxRules,omitempty"`
+}
+
+//APIGatewayPolicy is the Schema for the cluster-wide policies restricting APIRules
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+type APIGatewayPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec APIGatewayPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// APIGatewayPolicyList contains a list of APIGatewayPolicy
+type APIGatewayPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []APIGatewayPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&APIGatewayPolicy{}, &APIGatewayPolicyList{})
+}
@@ -5,3 +5,13 @@ type JWTAccStrConfig struct {
 	TrustedIssuers []string `json:"trusted_issuers,omitempty"`
 	RequiredScopes []string `json:"required_scopes,omitempty"`
 }
+
+//APIKeyAccStrConfig is used to deserialize api_key accessStrategy configuration
+type APIKeyAccStrConfig struct {
+	// Name of the Secret in the namespace of the APIRule holding the keys. Every value of the Secret is a valid key, identified by its name
+	SecretName string `json:"secretName,omitempty"`
+	// Header carrying the key. Only one of header or queryParameter can be set
+	Header string `json:"header,omitempty"`
+	// Query parameter carrying the key
+	QueryParameter string `json:"queryParameter,omitempty"`
+}
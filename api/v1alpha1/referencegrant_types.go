@@ -0,0 +1,88 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReferenceGrantSpec defines the APIRules allowed to expose Services of the namespace of the grant
+type ReferenceGrantSpec struct {
+	// Namespaces of the APIRules allowed to expose the Services
+	// +kubebuilder:validation:MinItems=1
+	From []ReferenceGrantFrom `json:"from"`
+	// Services that can be exposed. All Services of the namespace can be exposed if empty
+	// +optional
+	To []ReferenceGrantTo `json:"to,omitempty"`
+}
+
+// ReferenceGrantFrom selects the APIRules allowed to expose the Services
+type ReferenceGrantFrom struct {
+	// Namespace of the APIRules
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+}
+
+// ReferenceGrantTo selects a Service that can be exposed
+type ReferenceGrantTo struct {
+	// Name of the Service
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+//ReferenceGrant is the Schema for the consent to expose Services of its namespace with APIRules in other namespaces
+// +kubebuilder:object:root=true
+type ReferenceGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ReferenceGrantSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReferenceGrantList contains a list of ReferenceGrant
+type ReferenceGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReferenceGrant `json:"items"`
+}
+
+//Grants tells if the grant allows APIRules in the namespace to expose the Service
+func (g *ReferenceGrant) Grants(namespace, service string) bool {
+	from := false
+	for _, f := range g.Spec.From {
+		if f.Namespace == namespace {
+			from = true
+		}
+	}
+	if !from {
+		return false
+	}
+	if len(g.Spec.To) == 0 {
+		return true
+	}
+	for _, t := range g.Spec.To {
+		if t.Name == service {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	SchemeBuilder.Register(&ReferenceGrant{}, &ReferenceGrantList{})
+}
@@ -42,6 +42,20 @@ if [ $? != 0 ]; then
 else echo -e "${GREEN}√ go test${NC}"
 fi
 
+##
+# GO TEST WITH ENVTEST
+##
+if [ -n "${KUBEBUILDER_ASSETS}" ]; then
+	echo "? go test with envtest"
+	go test -short -tags envtest ./controllers/...
+	# Check if tests passed
+	if [ $? != 0 ]; then
+		echo -e "${RED}✗ go test with envtest\n${NC}"
+		exit 1
+	else echo -e "${GREEN}√ go test with envtest${NC}"
+	fi
+fi
+
 
 ##
 #  GO LINT
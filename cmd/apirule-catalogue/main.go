@@ -0,0 +1,66 @@
+//apirule-catalogue prints the catalogue of the routes exposed by APIRules, without a cluster.
+//
+//Usage:
+//
+//	kubectl get apirules --all-namespaces -o yaml | apirule-catalogue -
+//	apirule-catalogue --output openapi apirule.yaml...
+//
+//Every rule of the APIRules read from the given manifest files, or from the standard input if the path is "-", is listed with its host,
+//path, methods, access strategies with the required scopes, mutators, service and the status of the APIRule. The catalogue is printed
+//as JSON, or as OpenAPI 3 skeletons of the hosts with the security requirements filled in from the access strategies.
+//The controller manager serves the same catalogue of the APIRules in the cluster at the /catalogue path of the metrics endpoint.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kyma-incubator/api-gateway/internal/catalogue"
+	"github.com/kyma-incubator/api-gateway/internal/render"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("apirule-catalogue", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: apirule-catalogue [flags] apirule.yaml...\n\nPrints the routes exposed by the APIRules read from the files, or from the standard input if the path is \"-\".\n\n")
+		flags.PrintDefaults()
+	}
+	var domainName, output string
+	flags.StringVar(&domainName, "default-domain-name", "", "A default domain name for hostnames with no domain provided. Optional.")
+	flags.StringVar(&output, "output", "json", "Output format: json or openapi")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("no APIRule manifests given")
+	}
+	if output != "json" && output != "openapi" {
+		return fmt.Errorf("unsupported output format %s", output)
+	}
+
+	apis, err := render.ReadAPIRules(flags.Args()...)
+	if err != nil {
+		return err
+	}
+	c := catalogue.Build(apis, domainName)
+
+	if output == "openapi" {
+		return catalogue.WriteOpenAPI(stdout, c.OpenAPI())
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
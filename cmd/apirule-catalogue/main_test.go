@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCatalogue(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Catalogue Command Suite")
+}
+
+//apiRules is a list in the format printed by kubectl
+const apiRules = `apiVersion: v1
+kind: List
+items:
+- apiVersion: gateway.kyma-project.io/v1alpha1
+  kind: APIRule
+  metadata:
+    name: httpbin
+    namespace: default
+  spec:
+    gateway: kyma-gateway.kyma-system.svc.cluster.local
+    service:
+      name: httpbin
+      port: 8000
+      host: httpbin
+    rules:
+    - path: /headers
+      methods: ["GET"]
+      accessStrategies:
+      - handler: oauth2_introspection
+        config:
+          required_scope: ["read"]
+      mutators:
+      - handler: header
+  status:
+    APIRuleStatus:
+      code: OK
+`
+
+var _ = Describe("apirule-catalogue", func() {
+
+	var path string
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "apirule-catalogue")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "apirules.yaml")
+		Expect(ioutil.WriteFile(path, []byte(apiRules), 0600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filepath.Dir(path))).To(Succeed())
+	})
+
+	catalogue := func(args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		err := run(append([]string{"--default-domain-name", "kyma.local"}, append(args, path)...), &stdout, &stderr)
+		return stdout.String(), err
+	}
+
+	It("Should print the routes as JSON", func() {
+		stdout, err := catalogue()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(`"host": "httpbin.kyma.local"`))
+		Expect(stdout).To(ContainSubstring(`"scopes": [`))
+		Expect(stdout).To(ContainSubstring(`"mutators": [`))
+		Expect(stdout).To(ContainSubstring(`"service": "httpbin.default.svc.cluster.local:8000"`))
+		Expect(stdout).To(ContainSubstring(`"status": "OK"`))
+	})
+
+	It("Should print OpenAPI skeletons of the hosts", func() {
+		stdout, err := catalogue("--output", "openapi")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("openapi: 3.0.3\n"))
+		Expect(stdout).To(ContainSubstring("  /headers:\n    get:\n"))
+		Expect(stdout).To(ContainSubstring("      security:\n      - oauth2_introspection:\n        - read\n"))
+	})
+
+	It("Should reject unsupported output formats", func() {
+		_, err := catalogue("--output", "xml")
+
+		Expect(err).To(MatchError("unsupported output format xml"))
+	})
+})
@@ -0,0 +1,156 @@
+//apirule-openapi generates the rules of an APIRule from an OpenAPI 3 specification of the service.
+//
+//Usage:
+//
+//	apirule-openapi --name httpbin --service-name httpbin --service-port 8000 --host httpbin.kyma.local openapi.yaml
+//	apirule-openapi --apirule apirule.yaml openapi.yaml
+//
+//Every path of the specification becomes a rule allowing the methods of its operations. Path templates become regular expressions
+//matching a single path segment per parameter. The security of the operations is mapped to access strategies: OpenID Connect and
+//bearer tokens in the JWT format to jwt, OAuth2 and other bearer tokens to oauth2_introspection, with the required scopes. Public
+//operations are exposed with the allow or the noop access strategy.
+//
+//With the --apirule flag the rules of an existing APIRule are replaced, keeping the mutators of the rules with unchanged paths, so the
+//APIRule can be kept in sync with the specification. The APIRule is printed to the standard output. Paths, which can't be exposed, are
+//reported to the standard error and make the command exit with a non-zero code.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/manifests"
+	"github.com/kyma-incubator/api-gateway/internal/openapi"
+	"github.com/kyma-incubator/api-gateway/internal/render"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("apirule-openapi", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: apirule-openapi [flags] openapi.yaml\n\nPrints an APIRule with the rules generated from the OpenAPI 3 specification.\n\n")
+		flags.PrintDefaults()
+	}
+
+	var name, namespace, serviceName, host, gateway, apiRuleFile, basePath string
+	var servicePort uint
+	var opts openapi.Options
+	flags.StringVar(&name, "name", "", "Name of the generated APIRule")
+	flags.StringVar(&namespace, "namespace", "", "Namespace of the generated APIRule. Optional.")
+	flags.StringVar(&serviceName, "service-name", "", "Name of the exposed service. Defaults to the name of the APIRule.")
+	flags.UintVar(&servicePort, "service-port", 80, "Port of the exposed service")
+	flags.StringVar(&host, "host", "", "Host the service is exposed on")
+	flags.StringVar(&gateway, "gateway", "kyma-gateway.kyma-system.svc.cluster.local", "Gateway the service is exposed on")
+	flags.StringVar(&apiRuleFile, "apirule", "", "Path to the manifest of an existing APIRule, whose rules are replaced. Optional.")
+	flags.StringVar(&basePath, "base-path", "", "Path prefixed to the paths of the specification. Defaults to the path of the first server URL.")
+	flags.StringVar(&opts.PublicHandler, "public-handler", "allow", "Access strategy of public operations: allow or noop")
+	flags.StringVar(&opts.JWTIssuer, "jwt-issuer", "", "Issuer trusted for bearer tokens in the JWT format. Required if the specification accepts them.")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("exactly one OpenAPI specification is required")
+	}
+	if opts.PublicHandler != "allow" && opts.PublicHandler != "noop" {
+		return fmt.Errorf("unsupported public handler %s, expected allow or noop", opts.PublicHandler)
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "base-path" {
+			opts.BasePath = &basePath
+		}
+	})
+
+	var api *gatewayv1alpha1.APIRule
+	if apiRuleFile != "" {
+		apis, err := render.ReadAPIRules(apiRuleFile)
+		if err != nil {
+			return err
+		}
+		if len(apis) != 1 {
+			return fmt.Errorf("%s: expected one APIRule, found %d", apiRuleFile, len(apis))
+		}
+		api = synced(&apis[0])
+	} else {
+		if name == "" || host == "" {
+			flags.Usage()
+			return fmt.Errorf("the name and the host are required unless an existing APIRule is given")
+		}
+		if serviceName == "" {
+			serviceName = name
+		}
+		port := uint32(servicePort)
+		api = &gatewayv1alpha1.APIRule{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: gatewayv1alpha1.APIRuleSpec{
+				Service: &gatewayv1alpha1.Service{Name: &serviceName, Port: &port, Host: &host},
+				Gateway: &gateway,
+			},
+		}
+	}
+
+	data, err := ioutil.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	doc, err := openapi.Parse(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", flags.Arg(0), err)
+	}
+
+	rules, errs, warnings := openapi.Rules(doc, opts)
+	api.Spec.Rules = withMutators(rules, api.Spec.Rules)
+
+	if err := manifests.Write(stdout, []client.Object{api}); err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(stderr, "Warning: %s\n", warning)
+	}
+	for _, err := range errs {
+		fmt.Fprintf(stderr, "Skipped: %s\n", err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d path(s) skipped", len(errs))
+	}
+	return nil
+}
+
+//synced returns the APIRule stripped of the fields set by the cluster
+func synced(api *gatewayv1alpha1.APIRule) *gatewayv1alpha1.APIRule {
+	return &gatewayv1alpha1.APIRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        api.Name,
+			Namespace:   api.Namespace,
+			Labels:      api.Labels,
+			Annotations: api.Annotations,
+		},
+		Spec: api.Spec,
+	}
+}
+
+//withMutators copies the mutators of the existing rules to the generated rules with the same path, as the specification doesn't describe them
+func withMutators(rules, existing []gatewayv1alpha1.Rule) []gatewayv1alpha1.Rule {
+	mutators := map[string][]*gatewayv1alpha1.Mutator{}
+	for _, rule := range existing {
+		mutators[rule.Path] = rule.Mutators
+	}
+	for i := range rules {
+		rules[i].Mutators = mutators[rules[i].Path]
+	}
+	return rules
+}
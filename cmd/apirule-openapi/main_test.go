@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestOpenAPI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OpenAPI Command Suite")
+}
+
+const spec = `openapi: 3.0.0
+paths:
+  /headers:
+    get:
+      security:
+      - oauth2: []
+  /status/{code}:
+    get: {}
+    post: {}
+components:
+  securitySchemes:
+    oauth2:
+      type: oauth2
+`
+
+const apiRule = `apiVersion: gateway.kyma-project.io/v1alpha1
+kind: APIRule
+metadata:
+  name: httpbin
+  namespace: apps
+  resourceVersion: "42"
+spec:
+  gateway: kyma-gateway.kyma-system.svc.cluster.local
+  service:
+    name: httpbin
+    port: 8000
+    host: httpbin.kyma.local
+  rules:
+  - path: /headers
+    methods: ["GET"]
+    accessStrategies:
+    - handler: noop
+    mutators:
+    - handler: header
+      config:
+        headers:
+          X-Source: gateway
+  - path: /removed
+    methods: ["GET"]
+    accessStrategies:
+    - handler: noop
+status:
+  APIRuleStatus:
+    code: OK
+`
+
+var _ = Describe("apirule-openapi", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "apirule-openapi")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		return path
+	}
+
+	generate := func(args ...string) (string, string, error) {
+		var stdout, stderr bytes.Buffer
+		err := run(args, &stdout, &stderr)
+		return stdout.String(), stderr.String(), err
+	}
+
+	It("Should print a new APIRule with the rules generated from the specification", func() {
+		//when
+		stdout, stderr, err := generate("--name", "httpbin", "--service-port", "8000", "--host", "httpbin.kyma.local", writeFile("openapi.yaml", spec))
+
+		//then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(BeEmpty())
+		Expect(stdout).To(ContainSubstring("kind: APIRule\n"))
+		Expect(stdout).To(ContainSubstring("    name: httpbin\n    port: 8000\n"))
+		Expect(stdout).To(ContainSubstring("  - accessStrategies:\n    - handler: oauth2_introspection\n    methods:\n    - GET\n    path: /headers\n"))
+		Expect(stdout).To(ContainSubstring("    - handler: allow\n    methods:\n    - GET\n    - POST\n    path: /status/[^/]+\n"))
+	})
+
+	It("Should replace the rules of an existing APIRule keeping the mutators of unchanged paths", func() {
+		//when
+		stdout, _, err := generate("--apirule", writeFile("apirule.yaml", apiRule), writeFile("openapi.yaml", spec))
+
+		//then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("  namespace: apps\n"))
+		Expect(stdout).To(ContainSubstring("X-Source: gateway"))
+		Expect(stdout).NotTo(ContainSubstring("/removed"))
+		Expect(stdout).NotTo(ContainSubstring("resourceVersion"))
+		Expect(stdout).NotTo(ContainSubstring("code: OK"))
+	})
+
+	It("Should report the paths, which can't be exposed", func() {
+		//given
+		path := writeFile("openapi.yaml", `openapi: 3.0.0
+paths:
+  /login:
+    post:
+      security:
+      - basic: []
+components:
+  securitySchemes:
+    basic:
+      type: http
+      scheme: basic
+`)
+
+		//when
+		_, stderr, err := generate("--name", "httpbin", "--host", "httpbin.kyma.local", path)
+
+		//then
+		Expect(err).To(MatchError("1 path(s) skipped"))
+		Expect(stderr).To(Equal("Skipped: path /login: POST: security scheme basic of type http basic is not supported\n"))
+	})
+
+	It("Should require the name and the host of a new APIRule", func() {
+		_, _, err := generate(writeFile("openapi.yaml", spec))
+
+		Expect(err).To(MatchError("the name and the host are required unless an existing APIRule is given"))
+	})
+})
@@ -0,0 +1,65 @@
+//apirule-render prints the Virtual Services and Oathkeeper Rules generated for APIRules, without a cluster.
+//
+//Usage:
+//
+//	apirule-render [flags] apirule.yaml...
+//
+//APIRules are read from the given manifest files, or from the standard input if the path is "-". The generated objects are printed
+//to the standard output as YAML. Validation failures are printed to the standard error and make the command exit with a non-zero code.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kyma-incubator/api-gateway/internal/manifests"
+	"github.com/kyma-incubator/api-gateway/internal/render"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("apirule-render", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: apirule-render [flags] apirule.yaml...\n\nPrints the objects generated for the APIRules read from the files, or from the standard input if the path is \"-\".\n\n")
+		flags.PrintDefaults()
+	}
+	settings := render.AddFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("no APIRule manifests given")
+	}
+
+	r, err := settings.Renderer()
+	if err != nil {
+		return err
+	}
+	apis, err := render.ReadAPIRules(flags.Args()...)
+	if err != nil {
+		return err
+	}
+
+	objs, failures := r.Render(apis)
+	if err := manifests.Write(stdout, objs); err != nil {
+		return err
+	}
+	for _, failure := range failures {
+		fmt.Fprintln(stderr, failure)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d validation failure(s)", len(failures))
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRender(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Render Suite")
+}
+
+const apiRuleTemplate = `apiVersion: gateway.kyma-project.io/v1alpha1
+kind: APIRule
+metadata:
+  name: NAME
+spec:
+  gateway: kyma-gateway.kyma-system.svc.cluster.local
+  service:
+    name: NAME
+    port: 8000
+    host: HOST
+  rules:
+  - path: PATH
+    methods: ["GET"]
+    accessStrategies:
+    - handler: HANDLER
+`
+
+func apiRuleManifest(name, host, path, handler string) string {
+	return strings.NewReplacer("NAME", name, "HOST", host, "PATH", path, "HANDLER", handler).Replace(apiRuleTemplate)
+}
+
+var _ = Describe("apirule-render", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "apirule-render")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	writeFile := func(name string, docs ...string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(strings.Join(docs, "---\n")), 0600)).To(Succeed())
+		return path
+	}
+
+	render := func(args ...string) (string, string, error) {
+		var stdout, stderr bytes.Buffer
+		err := run(append([]string{"--domain-allowlist", "kyma.local"}, args...), &stdout, &stderr)
+		return stdout.String(), stderr.String(), err
+	}
+
+	It("Should print the Virtual Service and the access rules generated for the APIRule", func() {
+		//given
+		path := writeFile("apirule.yaml", apiRuleManifest("httpbin", "httpbin.kyma.local", "/.*", "noop"))
+
+		//when
+		stdout, stderr, err := render(path)
+
+		//then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(BeEmpty())
+		Expect(stdout).To(ContainSubstring("kind: VirtualService\n"))
+		Expect(stdout).To(ContainSubstring("kind: Rule\n"))
+		Expect(stdout).To(ContainSubstring("  - httpbin.kyma.local\n"))
+		Expect(stdout).To(ContainSubstring("url: http://httpbin.default.svc.cluster.local:8000\n"))
+	})
+
+	It("Should apply settings from the configuration file", func() {
+		//given
+		path := writeFile("apirule.yaml", apiRuleManifest("httpbin", "httpbin", "/.*", "allow"))
+		cfg := writeFile("config.yaml", "defaultDomainName: kyma.local\ngeneratedObjectsLabels:\n  managed-by: api-gateway\n")
+
+		//when
+		stdout, _, err := render("--config-file", cfg, path)
+
+		//then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("  - httpbin.kyma.local\n"))
+		Expect(stdout).To(ContainSubstring("managed-by: api-gateway\n"))
+		Expect(stdout).NotTo(ContainSubstring("kind: Rule\n"))
+	})
+
+	It("Should render the EnvoyFilter applying the rate limits of the rules", func() {
+		//given
+		manifest := apiRuleManifest("httpbin", "httpbin.kyma.local", "/.*", "noop") + "    rateLimit:\n      requests: 10\n      unit: second\n"
+		path := writeFile("apirule.yaml", manifest)
+		cfg := writeFile("config.yaml", "rateLimit:\n  namespace: istio-system\n")
+
+		//when
+		stdout, _, err := render("--config-file", cfg, path)
+
+		//then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("kind: EnvoyFilter\n"))
+		Expect(stdout).To(ContainSubstring("envoy.filters.http.local_ratelimit"))
+	})
+
+	It("Should render a single Virtual Service for APIRules sharing the host", func() {
+		//given
+		path := writeFile("apirules.yaml",
+			apiRuleManifest("first", "shop.kyma.local", "/orders", "allow"),
+			apiRuleManifest("second", "shop.kyma.local", "/carts", "allow"))
+
+		//when
+		stdout, _, err := render(path)
+
+		//then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Count(stdout, "kind: VirtualService\n")).To(Equal(1))
+		Expect(stdout).To(ContainSubstring("regex: /orders\n"))
+		Expect(stdout).To(ContainSubstring("regex: /carts\n"))
+	})
+
+	It("Should report validation failures and render the valid APIRules", func() {
+		//given
+		path := writeFile("apirules.yaml",
+			apiRuleManifest("httpbin", "httpbin.kyma.local", "/.*", "allow"),
+			apiRuleManifest("evil", "evil.example.com", "/.*", "allow"))
+
+		//when
+		stdout, stderr, err := render(path)
+
+		//then
+		Expect(err).To(MatchError("1 validation failure(s)"))
+		Expect(stderr).To(Equal("APIRule default/evil: Attribute \".spec.service.host\": Host is not allowlisted\n"))
+		Expect(stdout).To(ContainSubstring("  - httpbin.kyma.local\n"))
+		Expect(stdout).NotTo(ContainSubstring("evil"))
+	})
+
+	It("Should report hosts occupied by the given Virtual Services", func() {
+		//given
+		path := writeFile("apirule.yaml", apiRuleManifest("httpbin", "httpbin.kyma.local", "/.*", "allow"))
+		vs := writeFile("vs.yaml", `apiVersion: networking.istio.io/v1beta1
+kind: VirtualService
+metadata:
+  name: existing
+  namespace: default
+spec:
+  hosts: ["httpbin.kyma.local"]
+`)
+
+		//when
+		_, stderr, err := render("--virtual-services", vs, path)
+
+		//then
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("This host is occupied by another Virtual Service"))
+	})
+
+	It("Should report missing required fields", func() {
+		//given
+		path := writeFile("apirule.yaml", "apiVersion: gateway.kyma-project.io/v1alpha1\nkind: APIRule\nmetadata:\n  name: empty\n")
+
+		//when
+		_, stderr, err := render(path)
+
+		//then
+		Expect(err).To(HaveOccurred())
+		Expect(stderr).To(ContainSubstring("Attribute \".spec.gateway\": Required value"))
+		Expect(stderr).To(ContainSubstring("Attribute \".spec.service\": Required value"))
+	})
+})
@@ -0,0 +1,148 @@
+//apirule-simulate tells which route and which access rule handle a sample request sent to APIRules, without a cluster.
+//
+//Usage:
+//
+//	apirule-simulate [flags] --host httpbin.kyma.local --path /headers apirule.yaml...
+//
+//The objects generated for the APIRules read from the given manifest files are matched against the request the way Istio and Oathkeeper do.
+//APIRules failing validation are not exposed by the controller, so they are reported to the standard error and skipped.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kyma-incubator/api-gateway/internal/render"
+	"github.com/kyma-incubator/api-gateway/internal/simulation"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+//headerFlags collects the headers given in repeated flags in the "Name: value" format
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	var res []string
+	for k, v := range h {
+		res = append(res, k+": "+v)
+	}
+	return strings.Join(res, ", ")
+}
+
+func (h headerFlags) Set(value string) error {
+	nameValue := strings.SplitN(value, ":", 2)
+	if len(nameValue) != 2 || strings.TrimSpace(nameValue[0]) == "" {
+		return fmt.Errorf("header should be in the \"Name: value\" format")
+	}
+	h[strings.TrimSpace(nameValue[0])] = strings.TrimSpace(nameValue[1])
+	return nil
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("apirule-simulate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: apirule-simulate [flags] --host host --path path apirule.yaml...\n\nTells which route and access rule of the APIRules read from the files handle the request.\n\n")
+		flags.PrintDefaults()
+	}
+	settings := render.AddFlags(flags)
+
+	req := simulation.Request{Headers: headerFlags{}}
+	var output string
+	flags.StringVar(&req.Method, "method", "GET", "HTTP method of the request")
+	flags.StringVar(&req.Scheme, "scheme", "https", "Scheme of the request")
+	flags.StringVar(&req.Host, "host", "", "Host of the request")
+	flags.StringVar(&req.Path, "path", "/", "Path of the request, which can include a query")
+	flags.Var(headerFlags(req.Headers), "header", "Header of the request in the \"Name: value\" format. Can be repeated.")
+	flags.StringVar(&output, "output", "text", "Output format: text or json")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 || req.Host == "" {
+		flags.Usage()
+		return fmt.Errorf("the host and APIRule manifests are required")
+	}
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unsupported output format %s", output)
+	}
+
+	r, err := settings.Renderer()
+	if err != nil {
+		return err
+	}
+	apis, err := render.ReadAPIRules(flags.Args()...)
+	if err != nil {
+		return err
+	}
+
+	objs, failures := r.Render(apis)
+	for _, failure := range failures {
+		fmt.Fprintf(stderr, "Skipped: %s\n", failure)
+	}
+	//Virtual Services existing in the cluster route requests too
+	for i := range r.VirtualServices.Items {
+		objs = append(objs, &r.VirtualServices.Items[i])
+	}
+
+	res, err := simulation.New(objs, r.Config().OathkeeperSvcAddress).Simulate(req)
+	if err != nil {
+		return err
+	}
+	if output == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+	return printResult(stdout, res)
+}
+
+func printResult(w io.Writer, res *simulation.Result) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	line := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", name, value)
+		}
+	}
+
+	line("Virtual Service", res.VirtualService)
+	if res.Route >= 0 {
+		line("Route", fmt.Sprintf("%d (%s)", res.Route, res.Match))
+		destination := res.Destination
+		if res.ThroughOathkeeper {
+			destination += " (Oathkeeper)"
+		} else {
+			destination += " (service)"
+		}
+		line("Destination", destination)
+	}
+	if res.AccessRule != "" {
+		line("Access rule", fmt.Sprintf("%s (%s)", res.AccessRule, res.AccessRuleURL))
+		line("Authenticators", orNone(res.Authenticators))
+		line("Authorizer", orNone([]string{res.Authorizer}))
+		line("Mutators", orNone(res.Mutators))
+		line("Upstream", res.Upstream)
+	}
+	if res.StatusCode != 0 {
+		line("Response", fmt.Sprintf("%d %s", res.StatusCode, res.Reason))
+	}
+	return tw.Flush()
+}
+
+func orNone(values []string) string {
+	res := strings.Join(values, ", ")
+	if res == "" {
+		return "none"
+	}
+	return res
+}
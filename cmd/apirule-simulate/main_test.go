@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSimulate(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Simulate Suite")
+}
+
+const apiRules = `apiVersion: gateway.kyma-project.io/v1alpha1
+kind: APIRule
+metadata:
+  name: httpbin
+spec:
+  gateway: kyma-gateway.kyma-system.svc.cluster.local
+  service:
+    name: httpbin
+    port: 8000
+    host: httpbin.kyma.local
+  rules:
+  - path: /headers
+    methods: ["GET"]
+    accessStrategies:
+    - handler: noop
+    mutators:
+    - handler: header
+      config:
+        headers:
+          X-User: anonymous
+  - path: /public/.*
+    methods: ["GET"]
+    accessStrategies:
+    - handler: allow
+`
+
+var _ = Describe("apirule-simulate", func() {
+
+	var path string
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "apirule-simulate")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "apirules.yaml")
+		Expect(ioutil.WriteFile(path, []byte(apiRules), 0600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filepath.Dir(path))).To(Succeed())
+	})
+
+	simulate := func(args ...string) (string, string, error) {
+		var stdout, stderr bytes.Buffer
+		err := run(append([]string{"--domain-allowlist", "kyma.local"}, append(args, path)...), &stdout, &stderr)
+		return stdout.String(), stderr.String(), err
+	}
+
+	It("Should print the route, the access rule and the handlers applied", func() {
+		stdout, stderr, err := simulate("--host", "httpbin.kyma.local", "--path", "/headers", "--header", "Authorization: Bearer token")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stderr).To(BeEmpty())
+		Expect(stdout).To(ContainSubstring("Route:            0 (uri regex /headers)\n"))
+		Expect(stdout).To(ContainSubstring("(Oathkeeper)\n"))
+		Expect(stdout).To(ContainSubstring("Authenticators:   noop\n"))
+		Expect(stdout).To(ContainSubstring("Mutators:         header\n"))
+	})
+
+	It("Should print the response of requests rejected before reaching the service", func() {
+		stdout, _, err := simulate("--host", "httpbin.kyma.local", "--path", "/headers", "--method", "DELETE")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring("Response:         404 No access rule matches DELETE https://httpbin.kyma.local/headers\n"))
+	})
+
+	It("Should print the result as JSON", func() {
+		stdout, _, err := simulate("--host", "httpbin.kyma.local", "--path", "/public/index.html", "--output", "json")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stdout).To(ContainSubstring(`"destination": "httpbin.default.svc.cluster.local:8000"`))
+		Expect(stdout).To(ContainSubstring(`"throughOathkeeper": false`))
+	})
+
+	It("Should reject malformed headers", func() {
+		_, _, err := simulate("--host", "httpbin.kyma.local", "--header", "Authorization")
+
+		Expect(err).To(MatchError(ContainSubstring("Name: value")))
+	})
+})
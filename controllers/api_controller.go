@@ -18,20 +18,29 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
 
+	"github.com/kyma-incubator/api-gateway/internal/config"
 	"github.com/kyma-incubator/api-gateway/internal/processing"
 	"github.com/kyma-incubator/api-gateway/internal/validation"
 
 	"github.com/go-logr/logr"
 	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/apikeys"
+	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 //APIReconciler reconciles a Api object
@@ -42,10 +51,28 @@ type APIReconciler struct {
 	OathkeeperSvcPort      uint32
 	JWKSURI                string
 	CorsConfig             *processing.CorsConfig
+	GatewayProvisioning    *processing.GatewayProvisioning
+	RateLimit              *processing.RateLimit
+	SourceIPs              *processing.SourceIPs
+	APIKeys                *processing.APIKeys
 	GeneratedObjectsLabels map[string]string
 	ServiceBlockList       map[string][]string
 	DomainAllowList        []string
 	DefaultDomainName      string
+	DryRun                 bool
+	DeletionTimeout        time.Duration
+	ResyncInterval         time.Duration
+
+	//APIReader reads the Secrets referenced by APIRules from the API server, as the cache holds only their metadata. Client is used if nil
+	APIReader client.Reader
+
+	mu            sync.RWMutex
+	configHash    string
+	appliedConfig *config.Config
+	staleAPIRules map[types.NamespacedName]bool
+	resync        chan event.GenericEvent
+	//keys of the api_key access strategies checked by the APIKeysHandler
+	keyStore *apikeys.Store
 }
 
 //APIRuleValidator allows to validate APIRule instances created by the user.
@@ -56,8 +83,20 @@ type APIRuleValidator interface {
 //Reconcile .
 // +kubebuilder:rbac:groups=gateway.kyma-project.io,resources=apirules,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=gateway.kyma-project.io,resources=apirules/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=gateway.kyma-project.io,resources=apirules/finalizers,verbs=update
 // +kubebuilder:rbac:groups=networking.istio.io,resources=virtualservices,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.istio.io,resources=destinationrules,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.istio.io,resources=gateways,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.istio.io,resources=envoyfilters,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=security.istio.io,resources=authorizationpolicies;requestauthentications,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=oathkeeper.ory.sh,resources=rules,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.kyma-project.io,resources=apigatewaypolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.kyma-project.io,resources=referencegrants,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
 func (r *APIReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = r.Log.WithValues("Api", req.NamespacedName)
 
@@ -66,7 +105,7 @@ func (r *APIReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	err := r.Client.Get(ctx, req.NamespacedName, api)
 	if err != nil {
 		if apierrs.IsNotFound(err) {
-			//There is no APIRule. Nothing to process, dependent objects will be garbage-collected.
+			//There is no APIRule. Nothing to process, dependent objects are deleted before the finalizer is removed.
 			return doneReconcile()
 		}
 
@@ -74,34 +113,99 @@ func (r *APIReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		return r.setStatusForError(ctx, api, err, gatewayv1alpha1.StatusSkipped)
 	}
 
+	if !api.DeletionTimestamp.IsZero() {
+		return r.finalize(ctx, api)
+	}
+	if err := r.ensureFinalizer(ctx, api); err != nil {
+		return retryReconcile(err)
+	}
+
 	//Prevent reconciliation after status update. It should be solved by controller-runtime implementation but still isn't.
-	if api.Generation != api.Status.ObservedGeneration {
+	//APIRules affected by a configuration change are processed again even though their generation is observed,
+	//as are APIRules switched to or from dry-run mode or paused, and APIRules due for a retry or a periodic resync.
+	if api.Generation != api.Status.ObservedGeneration || r.takeStale(req.NamespacedName) || r.dryRunSwitched(api) || r.resyncDue(api) {
 
-		//1.1) Get the list of existing Virtual Services to validate host
-		var vsList networkingv1beta1.VirtualServiceList
-		if err := r.Client.List(ctx, &vsList); err != nil {
-			//Nothing is yet processed: StatusSkipped
+		dryRun := r.isDryRun(api)
+		paused := isPaused(api)
+		api.Status.Plan = nil
+		api.Status.CertificateStatus = nil
+		api.Status.RateLimitStatus = nil
+
+		validator, factory := r.newValidatorAndFactory()
+
+		//1.1) Get the policies applying to the namespace of the APIRule
+		validator.Policies, validator.NamespaceLabels, err = r.getPolicies(ctx, api.Namespace)
+		if err != nil {
+			return r.setStatusForError(ctx, api, err, gatewayv1alpha1.StatusSkipped)
+		}
+
+		//1.2) Get the domains delegated to namespaces
+		validator.DomainDelegations, err = r.getDomainDelegations(ctx)
+		if err != nil {
+			return r.setStatusForError(ctx, api, err, gatewayv1alpha1.StatusSkipped)
+		}
+
+		//1.3) Arbitrate the host between the APIRules exposing it
+		claim, err := r.getHostClaim(ctx, api, validator.DefaultDomainName)
+		if err != nil {
+			return r.setStatusForError(ctx, api, err, gatewayv1alpha1.StatusSkipped)
+		}
+		validator.HostClaim = claim
+
+		//1.4) Look up the Service exposed by the APIRule and the consent to expose it from another namespace
+		validator.ServiceLookup, err = r.getServiceLookup(ctx, api)
+		if err != nil {
+			return r.setStatusForError(ctx, api, err, gatewayv1alpha1.StatusSkipped)
+		}
+
+		//1.5) Look up the Secrets holding the keys of the api_key access strategies and the values of placeholders in handler configs, and the
+		//Secret of the upstream TLS mode in the namespace of Oathkeeper
+		validator.Secrets, err = r.getReferencedSecrets(ctx, api)
+		if err != nil {
+			return r.setStatusForError(ctx, api, err, gatewayv1alpha1.StatusSkipped)
+		}
+		factory.Secrets = validator.Secrets
+		r.apiKeyStore().Load(api.Namespace, apikeys.SecretNames(api), validator.Secrets)
+		validator.OathkeeperSecrets, err = r.getOathkeeperTLSSecrets(ctx, api, validator.OathkeeperNamespace)
+		if err != nil {
 			return r.setStatusForError(ctx, api, err, gatewayv1alpha1.StatusSkipped)
 		}
 
-		//1.2) Validate input including host
-		validator := validation.APIRule{
-			ServiceBlockList:  r.ServiceBlockList,
-			DomainAllowList:   r.DomainAllowList,
-			DefaultDomainName: r.DefaultDomainName,
+		//1.6) Get the list of existing Virtual Services exposing the host
+		vsList, err := r.getHostVirtualServices(ctx, claim.Host)
+		if err != nil {
+			//Nothing is yet processed: StatusSkipped
+			return r.setStatusForError(ctx, api, err, gatewayv1alpha1.StatusSkipped)
 		}
-		validationFailures := validator.Validate(api, vsList)
+
+		//1.7) Validate input including host, Service and Secrets
+		validationFailures := validator.Validate(api, processing.WithoutGeneratedVirtualServices(vsList))
 		if len(validationFailures) > 0 {
 			r.Log.Info(fmt.Sprintf(`Validation failure {"controller": "Api", "request": "%s/%s"}`, api.Namespace, api.Name))
+			if !claim.Granted && !dryRun && !paused {
+				//The host belongs to another APIRule, so Virtual Services exposing it for this one must be removed
+				if err := factory.ReleaseHost(ctx, api); err != nil {
+					return r.setStatusForError(ctx, api, err, gatewayv1alpha1.StatusError)
+				}
+			}
+			if validator.ServiceLookup != nil && !validator.ServiceLookup.Granted(api) && !dryRun && !paused {
+				//The consent to expose the Service from this namespace is missing or was revoked, so the Service must not stay exposed
+				if _, err := factory.DeleteGenerated(ctx, api); err != nil {
+					return r.setStatusForError(ctx, api, err, gatewayv1alpha1.StatusError)
+				}
+				//The APIRules sharing the host lose the Virtual Service if this one held it, so the host is arbitrated again without it
+				r.requeueHostClaimants(api)
+			}
 			return r.setStatus(ctx, api, generateValidationStatus(validationFailures), gatewayv1alpha1.StatusSkipped)
 		}
+		phaseTimes(api).Validated = &v1.Time{Time: time.Now()}
 
 		//2) Compute list of required objects (the set of objects required to satisfy our contract on apiRule.Spec, not yet applied)
-		factory := processing.NewFactory(r.Client, r.Log, r.OathkeeperSvc, r.OathkeeperSvcPort, r.JWKSURI, r.CorsConfig, r.GeneratedObjectsLabels, r.DefaultDomainName)
-		requiredObjects := factory.CalculateRequiredState(api)
+		//APIRules sharing the host are exposed by a single Virtual Service owned by the holder of the host
+		requiredObjects := factory.CalculateRequiredStateForHost(api, claim.Holder(), claim.Exposed(api))
 
 		//3.1 Fetch all existing objects related to _this_ apiRule from the cluster (VS, Rules)
-		actualObjects, err := factory.GetActualState(ctx, api)
+		actualObjects, err := factory.GetActualStateForHost(ctx, api, claim.Holder())
 		if err != nil {
 			return r.setStatusForError(ctx, api, err, gatewayv1alpha1.StatusSkipped)
 		}
@@ -109,23 +213,64 @@ func (r *APIReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		//3.2 Compute patch object
 		patch := factory.CalculateDiff(requiredObjects, actualObjects)
 
-		//3.3 Apply changes to the cluster
+		//3.3 In dry-run mode or while paused, report the planned changes instead of applying them
+		if dryRun || paused {
+			changes, err := patch.Plan()
+			if err != nil {
+				return r.setStatusForError(ctx, api, err, gatewayv1alpha1.StatusSkipped)
+			}
+			api.Status.Plan = &gatewayv1alpha1.APIRulePlan{Changes: changes}
+			if dryRun {
+				return r.setStatus(ctx, api, generatePlanStatus(changes), gatewayv1alpha1.StatusSkipped)
+			}
+			return r.setStatus(ctx, api, generatePausedStatus(changes), gatewayv1alpha1.StatusSkipped)
+		}
+
+		//3.4 Apply changes to the cluster
 		err = factory.ApplyDiff(ctx, patch)
 		if err != nil {
 			//We don't know exactly which object(s) are not updated properly.
 			//The safest approach is to assume nothing is correct and just use `StatusError`.
 			return r.setStatusForError(ctx, api, err, gatewayv1alpha1.StatusError)
 		}
+		phaseTimes(api).Applied = &v1.Time{Time: time.Now()}
 
-		//4) Update status of CR
+		//4) Update status of CR, reporting conflicts with other field managers, duplicated objects deleted and orphaned objects adopted
 		APIStatus := &gatewayv1alpha1.APIRuleResourceStatus{
 			Code: gatewayv1alpha1.StatusOK,
 		}
+		if patch.HasConflicts() {
+			APIStatus = toStatus(gatewayv1alpha1.StatusError, "Generated objects not applied because of fields managed by other field managers")
+		}
+		virtualServiceStatus := generateResourceStatus(patch.VirtualServiceConflicts(), patch.VirtualServiceRepairs())
+		accessRuleStatus := generateResourceStatus(patch.AccessRuleConflicts(), patch.AccessRuleRepairs())
+		api.Status.CertificateStatus = patch.CertificateStatus()
+		api.Status.RateLimitStatus = patch.RateLimitStatus()
 
-		return r.setStatus(ctx, api, APIStatus, gatewayv1alpha1.StatusOK)
+		return r.updateStatusOrRetry(ctx, api, APIStatus, virtualServiceStatus, accessRuleStatus)
 	}
 
-	return doneReconcile()
+	return r.requeueResync(api)
+}
+
+//newValidatorAndFactory creates the validator and the factory from a consistent snapshot of the settings, which can be changed by ApplyConfig at any time
+func (r *APIReconciler) newValidatorAndFactory() (*validation.APIRule, *processing.Factory) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	validator := &validation.APIRule{
+		ServiceBlockList:    r.ServiceBlockList,
+		DomainAllowList:     r.DomainAllowList,
+		DefaultDomainName:   r.DefaultDomainName,
+		APIKeys:             r.APIKeys != nil,
+		OathkeeperNamespace: oathkeeperNamespace(r.OathkeeperSvc),
+	}
+	factory := processing.NewFactory(r.Client, r.Log, r.OathkeeperSvc, r.OathkeeperSvcPort, r.JWKSURI, r.CorsConfig, r.GeneratedObjectsLabels, r.DefaultDomainName)
+	factory.GatewayProvisioning = r.GatewayProvisioning
+	factory.RateLimit = r.RateLimit
+	factory.SourceIPs = r.SourceIPs
+	factory.APIKeys = r.APIKeys
+	return validator, factory
 }
 
 //Sets status of APIRule. Accepts an auxilary status code that is used to report VirtualService and AccessRule status.
@@ -153,14 +298,15 @@ func (r *APIReconciler) setStatusForError(ctx context.Context, api *gatewayv1alp
 	return r.updateStatusOrRetry(ctx, api, generateErrorStatus(err), virtualServiceStatus, accessRuleStatus)
 }
 
-//Updates api status. If there was an error during update, returns the error so that entire reconcile loop is retried. If there is no error, returns a "reconcile success" value.
+//Updates api status. If there was an error during update, returns the error so that entire reconcile loop is retried.
+//If there is no error, the APIRule is scheduled to be processed again after the retry delay or the resync interval.
 func (r *APIReconciler) updateStatusOrRetry(ctx context.Context, api *gatewayv1alpha1.APIRule, apiStatus, virtualServiceStatus, accessRuleStatus *gatewayv1alpha1.APIRuleResourceStatus) (ctrl.Result, error) {
 	_, updateStatusErr := r.updateStatus(ctx, api, apiStatus, virtualServiceStatus, accessRuleStatus)
 	if updateStatusErr != nil {
 		return retryReconcile(updateStatusErr) //controller retries to set the correct status eventually.
 	}
-	//Fail fast: If status is updated, users are informed about the problem. We don't need to reconcile again.
-	return doneReconcile()
+	//If status is updated, users are informed about the problem. Failed APIRules are retried with backoff, so transient failures heal.
+	return r.requeueResync(api)
 }
 
 func doneReconcile() (ctrl.Result, error) {
@@ -173,8 +319,24 @@ func retryReconcile(err error) (ctrl.Result, error) {
 
 //SetupWithManager .
 func (r *APIReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := SetupIndexes(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.resync = make(chan event.GenericEvent)
+	r.mu.Unlock()
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&gatewayv1alpha1.APIRule{}).
+		Watches(&source.Kind{Type: &gatewayv1alpha1.APIRule{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForHostClaimants), builder.WithPredicates(claimChanged)).
+		Watches(&source.Channel{Source: r.resync}, &handler.EnqueueRequestForObject{}).
+		Watches(&source.Kind{Type: &gatewayv1alpha1.APIGatewayPolicy{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForAllAPIRules)).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForAllAPIRules), builder.WithPredicates(delegationChanged)).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForNamespaceAPIRules), builder.WithPredicates(namespaceLabelsChanged)).
+		Watches(&source.Kind{Type: &corev1.Service{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForServiceReferences), builder.WithPredicates(servicePortsChanged)).
+		Watches(&source.Kind{Type: &gatewayv1alpha1.ReferenceGrant{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForGrantedServices)).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForSecretReferences), builder.WithPredicates(secretChanged), builder.OnlyMetadata).
 		Complete(r)
 }
 
@@ -184,6 +346,11 @@ func (r *APIReconciler) updateStatus(ctx context.Context, api *gatewayv1alpha1.A
 	api.Status.APIRuleStatus = APIStatus
 	api.Status.VirtualServiceStatus = virtualServiceStatus
 	api.Status.AccessRuleStatus = accessRuleStatus
+	recordFailure(api, api.Status.LastProcessedTime.Time)
+	//The plan is set in dry-run mode or while paused even if it couldn't be computed, to tell the mode the APIRule was processed in
+	if r.isPlanOnly(api) && api.Status.Plan == nil {
+		api.Status.Plan = &gatewayv1alpha1.APIRulePlan{}
+	}
 
 	err := r.Client.Status().Update(ctx, api)
 	if err != nil {
@@ -200,6 +367,16 @@ func generateValidationStatus(failures []validation.Failure) *gatewayv1alpha1.AP
 	return toStatus(gatewayv1alpha1.StatusError, generateValidationDescription(failures))
 }
 
+func generateResourceStatus(conflicts, repairs string) *gatewayv1alpha1.APIRuleResourceStatus {
+	if conflicts == "" {
+		return toStatus(gatewayv1alpha1.StatusOK, repairs)
+	}
+	if repairs != "" {
+		conflicts += "\n" + repairs
+	}
+	return toStatus(gatewayv1alpha1.StatusError, conflicts)
+}
+
 func toStatus(c gatewayv1alpha1.StatusCode, desc string) *gatewayv1alpha1.APIRuleResourceStatus {
 	return &gatewayv1alpha1.APIRuleResourceStatus{
 		Code:        c,
@@ -212,12 +389,12 @@ func generateValidationDescription(failures []validation.Failure) string {
 
 	if len(failures) == 1 {
 		description = "Validation error: "
-		description += fmt.Sprintf("Attribute \"%s\": %s", failures[0].AttributePath, failures[0].Message)
+		description += failures[0].String()
 	} else {
 		const maxEntries = 3
 		description = "Multiple validation errors: "
 		for i := 0; i < len(failures) && i < maxEntries; i++ {
-			description += fmt.Sprintf("\nAttribute \"%s\": %s", failures[i].AttributePath, failures[i].Message)
+			description += "\n" + failures[i].String()
 		}
 		if len(failures) > maxEntries {
 			description += fmt.Sprintf("\n%d more error(s)...", len(failures)-maxEntries)
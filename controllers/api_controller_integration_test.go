@@ -1,3 +1,5 @@
+// +build envtest
+
 package controllers_test
 
 import (
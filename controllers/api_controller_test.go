@@ -1,3 +1,5 @@
+// +build envtest
+
 package controllers_test
 
 import (
@@ -14,7 +16,10 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -44,7 +49,7 @@ var _ = Describe("Controller", func() {
 			It("should update status", func() {
 				testAPI := fixAPI()
 
-				ts = getTestSuite(testAPI)
+				ts = getTestSuite(testAPI, fixService())
 				reconciler := getAPIReconciler(ts.mgr)
 				ctx := context.Background()
 
@@ -102,6 +107,17 @@ func fixAPI() *gatewayv1alpha1.APIRule {
 	}
 }
 
+func fixService() *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Name: "http", Port: 8000}},
+		},
+	}
+}
+
 func getAPIReconciler(mgr manager.Manager) reconcile.Reconciler {
 	return &controllers.APIReconciler{
 		Client:          mgr.GetClient(),
@@ -123,8 +139,12 @@ type testSuite struct {
 func getTestSuite(objects ...runtime.Object) *testSuite {
 	err := gatewayv1alpha1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
+	err = networkingv1alpha3.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
 	err = networkingv1beta1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
+	err = securityv1beta1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
 	err = rulev1alpha1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kyma-incubator/api-gateway/internal/apikeys"
+)
+
+//APIKeysPath is the path of the endpoint checking the keys of the api_key access strategies
+const APIKeysPath = "/api-keys/"
+
+//APIKeysHandler checks the keys of the api_key access strategies on behalf of Oathkeeper. APIRules are read from the cache, and the
+//keys from the store the reconciler loads the Secrets into while processing the APIRules using them
+func (r *APIReconciler) APIKeysHandler() http.Handler {
+	return http.StripPrefix(strings.TrimSuffix(APIKeysPath, "/"), apikeys.Handler(r.Client, r.apiKeyStore()))
+}
+
+//apiKeyStore returns the store of the keys of the api_key access strategies, which is created on first use
+func (r *APIReconciler) apiKeyStore() *apikeys.Store {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.keyStore == nil {
+		r.keyStore = apikeys.NewStore()
+	}
+	return r.keyStore
+}
+
+//APIKeysServer serves the check of API keys on a listener of its own, apart from the metrics endpoint which isn't authenticated,
+//so access to it can be restricted to Oathkeeper
+type APIKeysServer struct {
+	Addr    string
+	Handler http.Handler
+}
+
+//Start implements manager.Runnable. It serves the check until the context is cancelled
+func (s *APIKeysServer) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle(APIKeysPath, s.Handler)
+	server := &http.Server{Addr: s.Addr, Handler: mux}
+
+	errs := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errs <- err
+		}
+		close(errs)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+//NeedLeaderElection implements manager.LeaderElectionRunnable. Only the leader processes the APIRules and so holds the keys
+func (s *APIKeysServer) NeedLeaderElection() bool {
+	return true
+}
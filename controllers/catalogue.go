@@ -0,0 +1,16 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/kyma-incubator/api-gateway/internal/catalogue"
+)
+
+//CatalogueHandler serves the catalogue of the routes exposed by all APIRules, resolving hosts with the current configuration
+func (r *APIReconciler) CatalogueHandler() http.Handler {
+	return catalogue.Handler(r.Client, func() string {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return r.DefaultDomainName
+	})
+}
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/claims"
+	"github.com/kyma-incubator/api-gateway/internal/processing"
+	"github.com/kyma-incubator/api-gateway/internal/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+//getHostClaim arbitrates the host of the APIRule between all APIRules exposing it. The owner of the Virtual Service generated for the host keeps it.
+//APIRules exposing a Service of another namespace without a grant don't take part, as their generated objects are deleted
+func (r *APIReconciler) getHostClaim(ctx context.Context, api *gatewayv1alpha1.APIRule, defaultDomainName string) (*claims.Claim, error) {
+	host := claims.Host(api, defaultDomainName)
+	claimants, err := r.getHostClaimants(ctx, host, defaultDomainName)
+	if err != nil {
+		return nil, err
+	}
+	ungranted, err := r.getUngrantedClaimants(ctx, claimants, host, defaultDomainName)
+	if err != nil {
+		return nil, err
+	}
+	vsList, err := r.getHostVirtualServices(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return claims.Arbitrate(api, claimants, defaultDomainName, claims.Options{
+		Holder: processing.HostHolder(vsList, host),
+		Ignore: func(c *gatewayv1alpha1.APIRule) bool {
+			return ungranted[types.NamespacedName{Namespace: c.Namespace, Name: c.Name}]
+		},
+	}), nil
+}
+
+//getUngrantedClaimants returns the APIRules exposing the host and a Service of another namespace no ReferenceGrant allows them to expose.
+//APIRules only planning their changes are left out, as they keep their generated objects
+func (r *APIReconciler) getUngrantedClaimants(ctx context.Context, claimants []gatewayv1alpha1.APIRule, host, defaultDomainName string) (map[types.NamespacedName]bool, error) {
+	grants := map[string][]gatewayv1alpha1.ReferenceGrant{}
+	ungranted := map[types.NamespacedName]bool{}
+	for i := range claimants {
+		c := &claimants[i]
+		namespace := c.ServiceNamespace()
+		if namespace == c.Namespace || claims.Host(c, defaultDomainName) != host || r.isPlanOnly(c) {
+			continue
+		}
+		if _, ok := grants[namespace]; !ok {
+			grantList, err := r.getReferenceGrants(ctx, namespace)
+			if err != nil {
+				return nil, err
+			}
+			grants[namespace] = grantList
+		}
+		lookup := &validation.ServiceLookup{Grants: grants[namespace]}
+		if !lookup.Granted(c) {
+			ungranted[types.NamespacedName{Namespace: c.Namespace, Name: c.Name}] = true
+		}
+	}
+	return ungranted, nil
+}
+
+//requeueHostClaimants processes the other APIRules exposing the host of the APIRule again, so they take the host over
+//once the Virtual Service generated for it is deleted
+func (r *APIReconciler) requeueHostClaimants(api *gatewayv1alpha1.APIRule) {
+	requests := r.requestsForHostClaimants(api)
+
+	r.mu.RLock()
+	resync := r.resync
+	r.mu.RUnlock()
+	if resync == nil || len(requests) == 0 {
+		return
+	}
+
+	go func() {
+		for _, req := range requests {
+			resync <- event.GenericEvent{Object: &gatewayv1alpha1.APIRule{ObjectMeta: metav1.ObjectMeta{Namespace: req.Namespace, Name: req.Name}}}
+		}
+	}()
+}
+
+//requestsForHostClaimants maps a changed APIRule to the other APIRules exposing the same host, which are processed again
+//to rebuild the Virtual Service shared by them or to take over the host released by the changed APIRule
+func (r *APIReconciler) requestsForHostClaimants(obj client.Object) []reconcile.Request {
+	changed, ok := obj.(*gatewayv1alpha1.APIRule)
+	if !ok {
+		return nil
+	}
+
+	r.mu.RLock()
+	defaultDomainName := r.DefaultDomainName
+	r.mu.RUnlock()
+
+	host := claims.Host(changed, defaultDomainName)
+	if host == "" {
+		return nil
+	}
+
+	candidates, err := r.getHostClaimants(context.Background(), host, defaultDomainName)
+	if err != nil {
+		r.Log.Error(err, "Listing APIRules claiming the host failed", "host", host)
+		return nil
+	}
+
+	var claimants []gatewayv1alpha1.APIRule
+	for _, api := range candidates {
+		if api.Namespace == changed.Namespace && api.Name == changed.Name {
+			continue
+		}
+		if claims.Host(&api, defaultDomainName) == host {
+			claimants = append(claimants, api)
+		}
+	}
+
+	r.markStale(claimants)
+
+	requests := make([]reconcile.Request, 0, len(claimants))
+	for _, api := range claimants {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: api.Namespace, Name: api.Name}})
+	}
+	return requests
+}
+
+//claimChanged passes only the events that can change the outcome of host arbitration or the routes exposed for a shared host:
+//APIRules created, deleted or being deleted, moved to another host or processed in a new generation or with a different result
+var claimChanged = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		return true
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldAPI, okOld := e.ObjectOld.(*gatewayv1alpha1.APIRule)
+		newAPI, okNew := e.ObjectNew.(*gatewayv1alpha1.APIRule)
+		if !okOld || !okNew {
+			return false
+		}
+		return claims.Host(oldAPI, "") != claims.Host(newAPI, "") ||
+			(oldAPI.DeletionTimestamp == nil) != (newAPI.DeletionTimestamp == nil) ||
+			oldAPI.Status.ObservedGeneration != newAPI.Status.ObservedGeneration ||
+			statusCode(oldAPI) != statusCode(newAPI)
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		return true
+	},
+	GenericFunc: func(e event.GenericEvent) bool {
+		return false
+	},
+}
+
+func statusCode(api *gatewayv1alpha1.APIRule) gatewayv1alpha1.StatusCode {
+	if api.Status.APIRuleStatus == nil {
+		return ""
+	}
+	return api.Status.APIRuleStatus.Code
+}
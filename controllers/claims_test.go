@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("Controller", func() {
+	Describe("requestsForHostClaimants", func() {
+
+		newAPIRule := func(name, host string) *gatewayv1alpha1.APIRule {
+			return &gatewayv1alpha1.APIRule{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec:       gatewayv1alpha1.APIRuleSpec{Service: &gatewayv1alpha1.Service{Host: &host}},
+			}
+		}
+
+		It("should process again the other APIRules claiming the host", func() {
+			s := runtime.NewScheme()
+			Expect(gatewayv1alpha1.AddToScheme(s)).To(Succeed())
+			changed := newAPIRule("changed", "shop")
+			sharing := newAPIRule("sharing", "shop.kyma.local")
+			other := newAPIRule("other", "other.kyma.local")
+
+			r := &APIReconciler{
+				Client:            fake.NewFakeClientWithScheme(s, changed, sharing, other),
+				Log:               ctrl.Log.WithName("test"),
+				DefaultDomainName: "kyma.local",
+			}
+
+			requests := r.requestsForHostClaimants(changed)
+
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Name).To(Equal("sharing"))
+			Expect(r.takeStale(types.NamespacedName{Namespace: "default", Name: "sharing"})).To(BeTrue())
+		})
+
+		It("should pass only the changes affecting host claims", func() {
+			api := newAPIRule("api", "shop.kyma.local")
+			api.Status.ObservedGeneration = 1
+			api.Status.APIRuleStatus = &gatewayv1alpha1.APIRuleResourceStatus{Code: gatewayv1alpha1.StatusOK}
+
+			reprocessed := api.DeepCopy()
+			reprocessed.Status.LastProcessedTime = &metav1.Time{}
+			Expect(claimChanged.Update(event.UpdateEvent{ObjectOld: api, ObjectNew: reprocessed})).To(BeFalse())
+
+			failed := api.DeepCopy()
+			failed.Status.APIRuleStatus = &gatewayv1alpha1.APIRuleResourceStatus{Code: gatewayv1alpha1.StatusError}
+			Expect(claimChanged.Update(event.UpdateEvent{ObjectOld: api, ObjectNew: failed})).To(BeTrue())
+
+			moved := newAPIRule("api", "other.kyma.local")
+			moved.Status = api.Status
+			Expect(claimChanged.Update(event.UpdateEvent{ObjectOld: api, ObjectNew: moved})).To(BeTrue())
+		})
+	})
+})
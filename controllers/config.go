@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/apikeys"
+	"github.com/kyma-incubator/api-gateway/internal/config"
+	"github.com/kyma-incubator/api-gateway/internal/processing"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+//configRequeueInterval spaces the APIRules processed again after a configuration change, so a reload doesn't process all of them at once
+const configRequeueInterval = 20 * time.Millisecond
+
+//ApplyConfig replaces the settings of the reconciler with the given configuration.
+//If the reconciler is already running, the APIRules depending on the changed settings are processed again with the new settings.
+//Returns false if the configuration is the same as the one already applied.
+func (r *APIReconciler) ApplyConfig(cfg *config.Config) bool {
+	hash := cfg.Hash()
+
+	r.mu.Lock()
+	if hash == r.configHash {
+		r.mu.Unlock()
+		return false
+	}
+	affected := affectedAPIRules(r.appliedConfig, cfg)
+	r.configHash = hash
+	r.appliedConfig = cfg.DeepCopy()
+	r.OathkeeperSvc = cfg.OathkeeperSvcAddress
+	r.OathkeeperSvcPort = cfg.OathkeeperSvcPort
+	r.JWKSURI = cfg.JWKSURI
+	r.ServiceBlockList = cfg.ServiceBlockListMap()
+	r.DomainAllowList = cfg.DomainAllowList
+	r.DefaultDomainName = cfg.DefaultDomainName
+	r.CorsConfig = &processing.CorsConfig{
+		AllowOrigins: cfg.CorsAllowOrigins(),
+		AllowMethods: cfg.Cors.AllowMethods,
+		AllowHeaders: cfg.Cors.AllowHeaders,
+	}
+	r.GatewayProvisioning = nil
+	if cfg.GatewayProvisioning != nil {
+		provisioning := processing.GatewayProvisioning(*cfg.GatewayProvisioning)
+		r.GatewayProvisioning = &provisioning
+	}
+	r.RateLimit = nil
+	if cfg.RateLimit != nil {
+		r.RateLimit = &processing.RateLimit{Namespace: cfg.RateLimit.Namespace, Selector: cfg.RateLimit.Selector}
+		if cfg.RateLimit.Global != nil {
+			global := processing.GlobalRateLimit(*cfg.RateLimit.Global)
+			r.RateLimit.Global = &global
+		}
+	}
+	r.SourceIPs = nil
+	if cfg.SourceIPs != nil {
+		sourceIPs := processing.SourceIPs(*cfg.SourceIPs)
+		r.SourceIPs = &sourceIPs
+	}
+	r.APIKeys = nil
+	if cfg.APIKeys != nil {
+		r.APIKeys = &processing.APIKeys{CheckURL: cfg.APIKeys.CheckURL}
+	}
+	r.GeneratedObjectsLabels = cfg.GeneratedObjectsLabels
+	if r.GeneratedObjectsLabels == nil {
+		r.GeneratedObjectsLabels = map[string]string{}
+	}
+	r.DryRun = cfg.DryRun
+	r.DeletionTimeout = 0
+	if cfg.DeletionTimeout != nil {
+		r.DeletionTimeout = cfg.DeletionTimeout.Duration
+	}
+	r.ResyncInterval = 0
+	if cfg.ResyncInterval != nil {
+		r.ResyncInterval = cfg.ResyncInterval.Duration
+	}
+	resync := r.resync
+	r.mu.Unlock()
+
+	if resync != nil && affected != nil {
+		r.requeueAffected(resync, affected)
+	}
+	return true
+}
+
+//affectedAPIRules returns the filter of the APIRules whose validation or generated objects depend on the settings changed between the
+//configurations, or nil if no APIRule does. The settings of rate limits, source IPs and API keys affect only the APIRules using them,
+//the deletion timeout and the resync interval affect none, and any other setting affects all APIRules
+func affectedAPIRules(old, new *config.Config) func(api *gatewayv1alpha1.APIRule) bool {
+	if old == nil || commonSettings(old).Hash() != commonSettings(new).Hash() {
+		return func(*gatewayv1alpha1.APIRule) bool { return true }
+	}
+
+	var filters []func(api *gatewayv1alpha1.APIRule) bool
+	if !reflect.DeepEqual(old.RateLimit, new.RateLimit) {
+		filters = append(filters, usesRateLimits)
+	}
+	if !reflect.DeepEqual(old.SourceIPs, new.SourceIPs) {
+		filters = append(filters, usesSourceIPs)
+	}
+	if !reflect.DeepEqual(old.APIKeys, new.APIKeys) {
+		filters = append(filters, func(api *gatewayv1alpha1.APIRule) bool { return len(apikeys.SecretNames(api)) > 0 })
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return func(api *gatewayv1alpha1.APIRule) bool {
+		for _, filter := range filters {
+			if filter(api) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//commonSettings returns a copy of the configuration without the settings affecting only some APIRules or none of them
+func commonSettings(cfg *config.Config) *config.Config {
+	res := cfg.DeepCopy()
+	res.RateLimit, res.SourceIPs, res.APIKeys = nil, nil, nil
+	res.DeletionTimeout, res.ResyncInterval = nil, nil
+	return res
+}
+
+//usesRateLimits tells if the api depends on the rate limit settings, which also locate the EnvoyFilter upgrading WebSocket routes
+func usesRateLimits(api *gatewayv1alpha1.APIRule) bool {
+	if api.ServiceProtocol() == gatewayv1alpha1.ProtocolWebSocket {
+		return true
+	}
+	for _, rule := range api.Spec.Rules {
+		if rule.RateLimit != nil {
+			return true
+		}
+	}
+	return false
+}
+
+//usesSourceIPs tells if the api depends on the source IPs settings, which also locate the policies verifying JWTs on the gateway and
+//the namespace the Destination Rules of the SIMPLE and MUTUAL upstream TLS modes are exported to
+func usesSourceIPs(api *gatewayv1alpha1.APIRule) bool {
+	if processing.SecuredOnGateway(api) || usesGatewayOnlyTLS(api) {
+		return true
+	}
+	for _, rule := range api.Spec.Rules {
+		if rule.SourceIPs != nil || (rule.RateLimit != nil && rule.RateLimit.Key != nil && rule.RateLimit.Key.Type == gatewayv1alpha1.RateLimitKeyJWTSubject) {
+			return true
+		}
+	}
+	return false
+}
+
+func usesGatewayOnlyTLS(api *gatewayv1alpha1.APIRule) bool {
+	if api.Spec.Service == nil {
+		return false
+	}
+	tls := api.Spec.Service.TLS
+	return tls != nil && (tls.Mode == gatewayv1alpha1.TLSModeSimple || tls.Mode == gatewayv1alpha1.TLSModeMutual)
+}
+
+//requeueAffected marks the affected APIRules as stale and enqueues them one by one, so they are validated and processed with the current
+//settings without flooding the work queue
+func (r *APIReconciler) requeueAffected(resync chan<- event.GenericEvent, affected func(api *gatewayv1alpha1.APIRule) bool) {
+	var apiList gatewayv1alpha1.APIRuleList
+	if err := r.Client.List(context.Background(), &apiList); err != nil {
+		r.Log.Error(err, "Listing APIRules to apply new configuration failed")
+		return
+	}
+
+	var apis []gatewayv1alpha1.APIRule
+	for i := range apiList.Items {
+		if affected(&apiList.Items[i]) {
+			apis = append(apis, apiList.Items[i])
+		}
+	}
+	r.markStale(apis)
+
+	r.Log.Info("Configuration changed, processing APIRules again", "count", len(apis))
+	go func() {
+		for i := range apis {
+			if i > 0 {
+				time.Sleep(configRequeueInterval)
+			}
+			resync <- event.GenericEvent{Object: &apis[i]}
+		}
+	}()
+}
+
+//markStale marks the APIRules to be processed again even though their generation is observed
+func (r *APIReconciler) markStale(apis []gatewayv1alpha1.APIRule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.staleAPIRules == nil {
+		r.staleAPIRules = make(map[types.NamespacedName]bool)
+	}
+	for i := range apis {
+		r.staleAPIRules[types.NamespacedName{Namespace: apis[i].Namespace, Name: apis[i].Name}] = true
+	}
+}
+
+//takeStale tells if the APIRule has to be processed again because of a configuration change and clears the mark
+func (r *APIReconciler) takeStale(name types.NamespacedName) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.staleAPIRules[name] {
+		return false
+	}
+	delete(r.staleAPIRules, name)
+	return true
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/kyma-incubator/api-gateway/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+//ConfigReconciler watches the ConfigMap holding the controller configuration and applies it to the APIReconciler
+type ConfigReconciler struct {
+	Client    client.Client
+	Log       logr.Logger
+	Recorder  record.EventRecorder
+	ConfigMap types.NamespacedName
+	Key       string
+	// Sources merge the ConfigMap with the settings from flags and the configuration file
+	Sources *config.Sources
+	Target  *APIReconciler
+
+	//reader reads the ConfigMap from a cache limited to its namespace, so ConfigMaps of other namespaces are not cached
+	reader client.Reader
+}
+
+//Reconcile .
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+func (r *ConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	reader := r.reader
+	if reader == nil {
+		reader = r.Client
+	}
+	cfg, cm, err := r.Read(ctx, reader)
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			//Keep the current configuration
+			r.Log.Info("Configuration ConfigMap not found, keeping current configuration", "configMap", r.ConfigMap)
+			return doneReconcile()
+		}
+		if cm == nil {
+			return retryReconcile(err)
+		}
+		r.Log.Error(err, "Invalid configuration rejected", "configMap", r.ConfigMap)
+		r.Recorder.Event(cm, corev1.EventTypeWarning, "InvalidConfig", fmt.Sprintf("Configuration rejected: %s", err.Error()))
+		return doneReconcile()
+	}
+
+	if r.Target.ApplyConfig(cfg) {
+		r.Log.Info("Applied new configuration", "configMap", r.ConfigMap)
+		r.Recorder.Event(cm, corev1.EventTypeNormal, "ConfigApplied", "Configuration applied")
+	}
+	return doneReconcile()
+}
+
+//Read loads the ConfigMap and returns the configuration merged from all sources. The ConfigMap is kept in the sources only if it is valid.
+//The ConfigMap is returned whenever it could be fetched
+func (r *ConfigReconciler) Read(ctx context.Context, reader client.Reader) (*config.Config, *corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, r.ConfigMap, cm); err != nil {
+		return nil, nil, err
+	}
+
+	data, ok := cm.Data[r.Key]
+	if !ok {
+		return nil, cm, fmt.Errorf("key %s not found", r.Key)
+	}
+
+	cfg, err := r.Sources.SetConfigMap([]byte(data))
+	if err != nil {
+		return nil, cm, err
+	}
+	return cfg, cm, nil
+}
+
+//SetupWithManager watches the ConfigMap with a cache limited to its namespace, instead of the cache of the manager holding ConfigMaps of all namespaces
+func (r *ConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	cmCache, err := cache.New(mgr.GetConfig(), cache.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper(), Namespace: r.ConfigMap.Namespace})
+	if err != nil {
+		return err
+	}
+	if err := mgr.Add(cmCache); err != nil {
+		return err
+	}
+	r.reader = cmCache
+
+	c, err := controller.New("config", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	isConfigMap := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetNamespace() == r.ConfigMap.Namespace && obj.GetName() == r.ConfigMap.Name
+	})
+	return c.Watch(source.NewKindWithCache(&corev1.ConfigMap{}, cmCache), &handler.EnqueueRequestForObject{}, isConfigMap)
+}
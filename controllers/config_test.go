@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"time"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("Controller", func() {
+	Describe("ApplyConfig", func() {
+
+		cfg := &config.Config{
+			OathkeeperSvcAddress: "oathkeeper",
+			OathkeeperSvcPort:    4455,
+			JWKSURI:              "https://example.com/.well-known/jwks.json",
+			ServiceBlockList:     []string{"kubernetes.default"},
+			DomainAllowList:      []string{"kyma.local"},
+			Cors:                 config.Cors{AllowOrigins: []string{"regex:.*"}, AllowMethods: []string{"GET"}},
+		}
+
+		It("should apply settings only when they change", func() {
+			r := &APIReconciler{Log: ctrl.Log.WithName("test")}
+
+			Expect(r.ApplyConfig(cfg)).To(BeTrue())
+			Expect(r.OathkeeperSvcPort).To(Equal(uint32(4455)))
+			Expect(r.DomainAllowList).To(Equal([]string{"kyma.local"}))
+			Expect(r.ServiceBlockList).To(HaveKeyWithValue("default", []string{"kubernetes"}))
+			Expect(r.CorsConfig.AllowOrigins[0].GetRegex()).To(Equal(".*"))
+			Expect(r.GeneratedObjectsLabels).NotTo(BeNil())
+
+			Expect(r.ApplyConfig(cfg.DeepCopy())).To(BeFalse())
+		})
+
+		It("should process all APIRules again after the change", func() {
+			s := runtime.NewScheme()
+			Expect(gatewayv1alpha1.AddToScheme(s)).To(Succeed())
+			api := &gatewayv1alpha1.APIRule{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+
+			resync := make(chan event.GenericEvent, 1)
+			r := &APIReconciler{
+				Client: fake.NewFakeClientWithScheme(s, api),
+				Log:    ctrl.Log.WithName("test"),
+				resync: resync,
+			}
+
+			Expect(r.ApplyConfig(cfg)).To(BeTrue())
+
+			Eventually(resync).Should(Receive())
+			name := types.NamespacedName{Namespace: "default", Name: "test"}
+			Expect(r.takeStale(name)).To(BeTrue())
+			Expect(r.takeStale(name)).To(BeFalse())
+		})
+
+		It("should process again only the APIRules depending on the changed settings", func() {
+			//given
+			s := runtime.NewScheme()
+			Expect(gatewayv1alpha1.AddToScheme(s)).To(Succeed())
+			limited := &gatewayv1alpha1.APIRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "limited", Namespace: "default"},
+				Spec:       gatewayv1alpha1.APIRuleSpec{Rules: []gatewayv1alpha1.Rule{{Path: "/.*", RateLimit: &gatewayv1alpha1.RateLimit{}}}},
+			}
+			other := &gatewayv1alpha1.APIRule{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}}
+			resync := make(chan event.GenericEvent, 2)
+			r := &APIReconciler{Client: fake.NewFakeClientWithScheme(s, limited, other), Log: ctrl.Log.WithName("test")}
+			Expect(r.ApplyConfig(cfg)).To(BeTrue())
+			r.resync = resync
+
+			//when
+			changed := cfg.DeepCopy()
+			changed.RateLimit = &config.RateLimit{Namespace: "istio-system"}
+			Expect(r.ApplyConfig(changed)).To(BeTrue())
+
+			//then
+			var e event.GenericEvent
+			Eventually(resync).Should(Receive(&e))
+			Expect(e.Object.GetName()).To(Equal("limited"))
+			Consistently(resync).ShouldNot(Receive())
+			Expect(r.takeStale(types.NamespacedName{Namespace: "default", Name: "other"})).To(BeFalse())
+		})
+
+		It("should not process APIRules again when only the resync interval changes", func() {
+			//given
+			s := runtime.NewScheme()
+			Expect(gatewayv1alpha1.AddToScheme(s)).To(Succeed())
+			api := &gatewayv1alpha1.APIRule{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+			resync := make(chan event.GenericEvent, 1)
+			r := &APIReconciler{Client: fake.NewFakeClientWithScheme(s, api), Log: ctrl.Log.WithName("test")}
+			Expect(r.ApplyConfig(cfg)).To(BeTrue())
+			r.resync = resync
+
+			//when
+			changed := cfg.DeepCopy()
+			changed.ResyncInterval = &metav1.Duration{Duration: time.Minute}
+
+			//then
+			Expect(r.ApplyConfig(changed)).To(BeTrue())
+			Expect(r.ResyncInterval).To(Equal(time.Minute))
+			Consistently(resync).ShouldNot(Receive())
+		})
+	})
+})
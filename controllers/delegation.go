@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/validation"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+//getDomainDelegations returns the domains delegated to namespaces with the DelegatedDomainsAnnotation
+func (r *APIReconciler) getDomainDelegations(ctx context.Context) (map[string][]string, error) {
+	var nsList corev1.NamespaceList
+	if err := r.Client.List(ctx, &nsList); err != nil {
+		return nil, err
+	}
+
+	delegations := make(map[string][]string)
+	for _, ns := range nsList.Items {
+		for _, domain := range delegatedDomains(&ns) {
+			delegations[domain] = append(delegations[domain], ns.Name)
+		}
+	}
+	return delegations, nil
+}
+
+func delegatedDomains(ns *corev1.Namespace) []string {
+	var domains []string
+	for _, domain := range strings.Split(ns.Annotations[gatewayv1alpha1.DelegatedDomainsAnnotation], ",") {
+		domain = strings.TrimSpace(domain)
+		if domain != "" && validation.ValidateDomainPattern(domain) {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+//delegationChanged passes only the events changing domain delegations
+var delegationChanged = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		return e.Object.GetAnnotations()[gatewayv1alpha1.DelegatedDomainsAnnotation] != ""
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return e.ObjectOld.GetAnnotations()[gatewayv1alpha1.DelegatedDomainsAnnotation] != e.ObjectNew.GetAnnotations()[gatewayv1alpha1.DelegatedDomainsAnnotation]
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		return e.Object.GetAnnotations()[gatewayv1alpha1.DelegatedDomainsAnnotation] != ""
+	},
+	GenericFunc: func(e event.GenericEvent) bool {
+		return false
+	},
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"fmt"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+)
+
+//isDryRun tells if the changes of generated objects are only planned for the APIRule, either by the annotation or by the settings of the controller
+func (r *APIReconciler) isDryRun(api *gatewayv1alpha1.APIRule) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.DryRun || api.Annotations[gatewayv1alpha1.DryRunAnnotation] == "true"
+}
+
+//isPaused tells if the changes of generated objects are held back for the APIRule by the annotation
+func isPaused(api *gatewayv1alpha1.APIRule) bool {
+	return api.Annotations[gatewayv1alpha1.PausedAnnotation] == "true"
+}
+
+//isPlanOnly tells if the changes of generated objects are reported in the plan instead of being applied, in dry-run mode or while paused
+func (r *APIReconciler) isPlanOnly(api *gatewayv1alpha1.APIRule) bool {
+	return r.isDryRun(api) || isPaused(api)
+}
+
+//dryRunSwitched tells if the APIRule was last processed in another mode. Switching the mode doesn't change the generation of the APIRule.
+func (r *APIReconciler) dryRunSwitched(api *gatewayv1alpha1.APIRule) bool {
+	return r.isPlanOnly(api) != (api.Status.Plan != nil)
+}
+
+//generatePlanStatus reports the number of changes planned in dry-run mode
+func generatePlanStatus(changes []gatewayv1alpha1.PlannedChange) *gatewayv1alpha1.APIRuleResourceStatus {
+	return toStatus(gatewayv1alpha1.StatusSkipped, fmt.Sprintf("Dry run: %d change(s) of generated objects planned, see status.plan", countChanges(changes)))
+}
+
+//generatePausedStatus reports the number of changes held back while the APIRule is paused
+func generatePausedStatus(changes []gatewayv1alpha1.PlannedChange) *gatewayv1alpha1.APIRuleResourceStatus {
+	return toStatus(gatewayv1alpha1.StatusSkipped, fmt.Sprintf("Paused: %d change(s) of generated objects not applied, see status.plan", countChanges(changes)))
+}
+
+func countChanges(changes []gatewayv1alpha1.PlannedChange) int {
+	planned := 0
+	for _, change := range changes {
+		if change.Action != "none" && change.Action != "skip" {
+			planned++
+		}
+	}
+	return planned
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Controller", func() {
+	Describe("dry-run mode", func() {
+
+		name := types.NamespacedName{Namespace: "default", Name: "dry-run"}
+
+		newAPIRule := func(annotations map[string]string) *gatewayv1alpha1.APIRule {
+			api := newTestAPIRule(name)
+			api.Annotations = annotations
+			return api
+		}
+
+		countVirtualServices := func(c client.Client) int {
+			var vsList networkingv1beta1.VirtualServiceList
+			Expect(c.List(context.Background(), &vsList)).To(Succeed())
+			return len(vsList.Items)
+		}
+
+		It("should report the planned changes without applying them", func() {
+			r := newTestReconciler(nil, newAPIRule(map[string]string{gatewayv1alpha1.DryRunAnnotation: "true"}), newTestService(name.Namespace, 8000))
+
+			_, api := reconcileAndGet(r, name)
+
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusSkipped))
+			Expect(api.Status.APIRuleStatus.Description).To(ContainSubstring("2 change(s)"))
+			Expect(api.Status.Plan).NotTo(BeNil())
+			Expect(api.Status.Plan.Changes).To(HaveLen(2))
+			Expect(api.Status.Plan.Changes[0].Kind).To(Equal("VirtualService"))
+			Expect(api.Status.Plan.Changes[0].Action).To(Equal("create"))
+			Expect(api.Status.Plan.Changes[0].Diff).To(ContainSubstring("+ - httpbin.kyma.local\n"))
+			Expect(countVirtualServices(r.Client)).To(Equal(0))
+		})
+
+		It("should apply the changes once the annotation is removed", func() {
+			r := newTestReconciler(nil, newAPIRule(map[string]string{gatewayv1alpha1.DryRunAnnotation: "true"}), newTestService(name.Namespace, 8000))
+			_, api := reconcileAndGet(r, name)
+			Expect(r.dryRunSwitched(api)).To(BeFalse())
+
+			api.Annotations = nil
+			Expect(r.Client.Update(context.Background(), api)).To(Succeed())
+			Expect(r.dryRunSwitched(api)).To(BeTrue())
+
+			_, api = reconcileAndGet(r, name)
+
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			Expect(api.Status.Plan).To(BeNil())
+			Expect(countVirtualServices(r.Client)).To(Equal(1))
+		})
+
+		It("should validate and report the changes without applying them while paused", func() {
+			r := newTestReconciler(nil, newAPIRule(map[string]string{gatewayv1alpha1.PausedAnnotation: "true"}), newTestService(name.Namespace, 8000))
+
+			_, api := reconcileAndGet(r, name)
+
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusSkipped))
+			Expect(api.Status.APIRuleStatus.Description).To(Equal("Paused: 2 change(s) of generated objects not applied, see status.plan"))
+			Expect(api.Status.Plan).NotTo(BeNil())
+			Expect(api.Status.Plan.Changes).To(HaveLen(2))
+			Expect(countVirtualServices(r.Client)).To(Equal(0))
+		})
+
+		It("should apply the changes once resumed", func() {
+			r := newTestReconciler(nil, newAPIRule(map[string]string{gatewayv1alpha1.PausedAnnotation: "true"}), newTestService(name.Namespace, 8000))
+			_, api := reconcileAndGet(r, name)
+			Expect(r.dryRunSwitched(api)).To(BeFalse())
+
+			api.Annotations = nil
+			Expect(r.Client.Update(context.Background(), api)).To(Succeed())
+			Expect(r.dryRunSwitched(api)).To(BeTrue())
+
+			_, api = reconcileAndGet(r, name)
+
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			Expect(api.Status.Plan).To(BeNil())
+			Expect(countVirtualServices(r.Client)).To(Equal(1))
+		})
+
+		It("should plan changes for all APIRules if set in the configuration", func() {
+			r := newTestReconciler(nil, newAPIRule(nil), newTestService(name.Namespace, 8000))
+			r.DryRun = true
+
+			_, api := reconcileAndGet(r, name)
+
+			Expect(api.Status.Plan).NotTo(BeNil())
+			Expect(countVirtualServices(r.Client)).To(Equal(0))
+		})
+	})
+})
+
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+//deletionPollInterval is how often the deletion of generated objects is checked while an APIRule is being deleted
+const deletionPollInterval = 5 * time.Second
+
+//ensureFinalizer sets the finalizer on the APIRule, so the objects generated for it are deleted before it is released
+func (r *APIReconciler) ensureFinalizer(ctx context.Context, api *gatewayv1alpha1.APIRule) error {
+	if controllerutil.ContainsFinalizer(api, gatewayv1alpha1.Finalizer) {
+		return nil
+	}
+	controllerutil.AddFinalizer(api, gatewayv1alpha1.Finalizer)
+	return r.Client.Update(ctx, api)
+}
+
+//finalize deletes the objects generated for the APIRule being deleted and releases the APIRule once none is left, after which the host is arbitrated again.
+//The progress is reported in the status. The APIRule is released without waiting for the objects if the deletion times out or
+//if it is forced with the ForceDeleteAnnotation. Dry-run mode holds back only the changes of live objects, so the objects generated
+//before the APIRule was switched to it are deleted as well.
+func (r *APIReconciler) finalize(ctx context.Context, api *gatewayv1alpha1.APIRule) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(api, gatewayv1alpha1.Finalizer) {
+		return doneReconcile()
+	}
+	log := r.Log.WithValues("namespace", api.Namespace, "name", api.Name)
+
+	if api.Annotations[gatewayv1alpha1.ForceDeleteAnnotation] == "true" {
+		log.Info("Releasing APIRule without deleting generated objects, as forced by the annotation", "annotation", gatewayv1alpha1.ForceDeleteAnnotation)
+		return r.release(ctx, api)
+	}
+	timeout := r.deletionTimeout()
+	if timeout > 0 && time.Since(api.DeletionTimestamp.Time) > timeout {
+		log.Info("Deleting generated objects timed out, releasing APIRule. Generated objects may be left behind", "timeout", timeout)
+		return r.release(ctx, api)
+	}
+	_, factory := r.newValidatorAndFactory()
+	remaining, err := factory.DeleteGenerated(ctx, api)
+	if err != nil {
+		r.Log.Error(err, "Deleting generated objects failed", "namespace", api.Namespace, "name", api.Name)
+		status := toStatus(gatewayv1alpha1.StatusError, fmt.Sprintf("Deleting generated objects failed: %s", err.Error()))
+		_, _ = r.updateStatus(ctx, api, status, deletingStatus(), deletingStatus())
+		return retryReconcile(err)
+	}
+	if remaining == 0 {
+		//If the APIRule held a shared host, the other APIRules exposing it are left without routes until one of them takes the host over
+		r.requeueHostClaimants(api)
+		return r.release(ctx, api)
+	}
+
+	status := toStatus(gatewayv1alpha1.StatusDeleting, fmt.Sprintf("Deleting generated objects: %d remaining", remaining))
+	if _, err := r.updateStatus(ctx, api, status, deletingStatus(), deletingStatus()); err != nil {
+		return retryReconcile(err)
+	}
+	return ctrl.Result{RequeueAfter: deletionPollInterval}, nil
+}
+
+//release removes the finalizer, so the APIRule is deleted
+func (r *APIReconciler) release(ctx context.Context, api *gatewayv1alpha1.APIRule) (ctrl.Result, error) {
+	controllerutil.RemoveFinalizer(api, gatewayv1alpha1.Finalizer)
+	if err := r.Client.Update(ctx, api); client.IgnoreNotFound(err) != nil {
+		return retryReconcile(err)
+	}
+	return doneReconcile()
+}
+
+func (r *APIReconciler) deletionTimeout() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.DeletionTimeout
+}
+
+func deletingStatus() *gatewayv1alpha1.APIRuleResourceStatus {
+	return &gatewayv1alpha1.APIRuleResourceStatus{Code: gatewayv1alpha1.StatusDeleting}
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/config"
+	"github.com/kyma-incubator/api-gateway/internal/processing"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Controller", func() {
+	Describe("finalizer", func() {
+
+		name := types.NamespacedName{Namespace: "default", Name: "finalized"}
+
+		configure := func(cfg *config.Config) {
+			cfg.DeletionTimeout = &metav1.Duration{Duration: time.Minute}
+		}
+
+		countGenerated := func(c client.Client) int {
+			var vsList networkingv1beta1.VirtualServiceList
+			Expect(c.List(context.Background(), &vsList)).To(Succeed())
+			var arList rulev1alpha1.RuleList
+			Expect(c.List(context.Background(), &arList)).To(Succeed())
+			return len(vsList.Items) + len(arList.Items)
+		}
+
+		//markDeleted emulates the deletion of the APIRule, which the API server only marks with the timestamp while finalizers are set
+		markDeleted := func(r *APIReconciler, api *gatewayv1alpha1.APIRule, deletedAgo time.Duration) {
+			api.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-deletedAgo)}
+			Expect(r.Client.Update(context.Background(), api)).To(Succeed())
+		}
+
+		It("should set the finalizer and delete generated objects before releasing the APIRule", func() {
+			//given
+			r := newTestReconciler(configure, newTestAPIRule(name), newTestService(name.Namespace, 8000))
+			_, api := reconcileAndGet(r, name)
+			Expect(api.Finalizers).To(ConsistOf(gatewayv1alpha1.Finalizer))
+			Expect(countGenerated(r.Client)).To(Equal(2))
+
+			//when
+			markDeleted(r, api, 0)
+			res, api := reconcileAndGet(r, name)
+
+			//then
+			Expect(countGenerated(r.Client)).To(Equal(0))
+			Expect(res.RequeueAfter).To(Equal(deletionPollInterval))
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusDeleting))
+			Expect(api.Status.APIRuleStatus.Description).To(Equal("Deleting generated objects: 2 remaining"))
+			Expect(api.Finalizers).To(ConsistOf(gatewayv1alpha1.Finalizer))
+
+			//when
+			res, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(res.RequeueAfter).To(BeZero())
+			Expect(api.Finalizers).To(BeEmpty())
+		})
+
+		It("should hand a shared host over to another APIRule once the holder is deleted", func() {
+			//given
+			holder := newTestAPIRule(name)
+			holder.Spec.Rules[0].Path = "/orders"
+			holder.CreationTimestamp = metav1.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+			member := newTestAPIRule(types.NamespacedName{Namespace: name.Namespace, Name: "member"})
+			member.Spec.Rules[0].Path = "/status"
+			member.CreationTimestamp = metav1.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+			memberName := types.NamespacedName{Namespace: member.Namespace, Name: member.Name}
+			r := newTestReconciler(configure, holder, member, newTestService(name.Namespace, 8000))
+			_, holder = reconcileAndGet(r, name)
+			_, member = reconcileAndGet(r, memberName)
+			Expect(member.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+
+			//when
+			markDeleted(r, holder, 0)
+			reconcileAndGet(r, name)
+			_, holder = reconcileAndGet(r, name)
+			Expect(holder.Finalizers).To(BeEmpty())
+			_, member = reconcileAndGet(r, memberName)
+
+			//then
+			Expect(member.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			var vsList networkingv1beta1.VirtualServiceList
+			Expect(r.Client.List(context.Background(), &vsList)).To(Succeed())
+			Expect(vsList.Items).To(HaveLen(1))
+			Expect(vsList.Items[0].Labels).To(HaveKeyWithValue(processing.OwnerLabel, "member.default"))
+		})
+
+		It("should delete generated objects of an APIRule switched to dry-run mode", func() {
+			//given
+			r := newTestReconciler(configure, newTestAPIRule(name), newTestService(name.Namespace, 8000))
+			_, api := reconcileAndGet(r, name)
+			Expect(countGenerated(r.Client)).To(Equal(2))
+			api.Annotations = map[string]string{gatewayv1alpha1.DryRunAnnotation: "true"}
+
+			//when
+			markDeleted(r, api, 0)
+			reconcileAndGet(r, name)
+			_, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(countGenerated(r.Client)).To(Equal(0))
+			Expect(api.Finalizers).To(BeEmpty())
+		})
+
+		It("should release the APIRule without deleting generated objects if forced with the annotation", func() {
+			//given
+			r := newTestReconciler(configure, newTestAPIRule(name), newTestService(name.Namespace, 8000))
+			_, api := reconcileAndGet(r, name)
+			api.Annotations = map[string]string{gatewayv1alpha1.ForceDeleteAnnotation: "true"}
+
+			//when
+			markDeleted(r, api, 0)
+			_, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(api.Finalizers).To(BeEmpty())
+			Expect(countGenerated(r.Client)).To(Equal(2))
+		})
+
+		It("should release the APIRule once the deletion timed out", func() {
+			//given
+			r := newTestReconciler(configure, newTestAPIRule(name), newTestService(name.Namespace, 8000))
+			_, api := reconcileAndGet(r, name)
+
+			//when
+			markDeleted(r, api, 2*time.Minute)
+			_, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(api.Finalizers).To(BeEmpty())
+			Expect(countGenerated(r.Client)).To(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/config"
+	. "github.com/onsi/gomega"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+//newTestAPIRule returns an APIRule exposing the httpbin Service on httpbin.kyma.local to GET requests without access control
+func newTestAPIRule(name types.NamespacedName) *gatewayv1alpha1.APIRule {
+	serviceName, host, gateway, port := "httpbin", "httpbin.kyma.local", "kyma-gateway.kyma-system.svc.cluster.local", uint32(8000)
+	return &gatewayv1alpha1.APIRule{
+		ObjectMeta: metav1.ObjectMeta{Name: name.Name, Namespace: name.Namespace, Generation: 1},
+		Spec: gatewayv1alpha1.APIRuleSpec{
+			Service: &gatewayv1alpha1.Service{Name: &serviceName, Port: &port, Host: &host},
+			Gateway: &gateway,
+			Rules: []gatewayv1alpha1.Rule{{
+				Path:             "/.*",
+				Methods:          []string{"GET"},
+				AccessStrategies: []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "noop"}}},
+			}},
+		},
+	}
+}
+
+//newTestService returns the httpbin Service exposing the port
+func newTestService(namespace string, port int32) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "httpbin", Namespace: namespace},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: port}}},
+	}
+}
+
+//newTestReconciler returns a reconciler with a fake client holding the objects and the default namespace.
+//The configuration allows the kyma.local domain and can be adjusted with configure
+func newTestReconciler(configure func(cfg *config.Config), objs ...runtime.Object) *APIReconciler {
+	s := runtime.NewScheme()
+	Expect(gatewayv1alpha1.AddToScheme(s)).To(Succeed())
+	Expect(networkingv1beta1.AddToScheme(s)).To(Succeed())
+	Expect(networkingv1alpha3.AddToScheme(s)).To(Succeed())
+	Expect(securityv1beta1.AddToScheme(s)).To(Succeed())
+	Expect(rulev1alpha1.AddToScheme(s)).To(Succeed())
+	Expect(corev1.AddToScheme(s)).To(Succeed())
+	objs = append(objs, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+	r := &APIReconciler{Client: &applyClient{Client: fake.NewFakeClientWithScheme(s, objs...)}, Log: ctrl.Log.WithName("test")}
+	cfg := &config.Config{
+		OathkeeperSvcAddress: "oathkeeper",
+		OathkeeperSvcPort:    4455,
+		JWKSURI:              "https://example.com/.well-known/jwks.json",
+		DomainAllowList:      []string{"kyma.local"},
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	r.ApplyConfig(cfg)
+	return r
+}
+
+//reconcileAndGet processes the APIRule and returns the result and the APIRule read back
+func reconcileAndGet(r *APIReconciler, name types.NamespacedName) (ctrl.Result, *gatewayv1alpha1.APIRule) {
+	res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: name})
+	Expect(err).NotTo(HaveOccurred())
+	api := &gatewayv1alpha1.APIRule{}
+	Expect(r.Client.Get(context.Background(), name, api)).To(Succeed())
+	return res, api
+}
+
+//applyClient emulates server-side apply, which is not supported by the fake client, with creates and merge patches
+type applyClient struct {
+	client.Client
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		if apierrs.IsNotFound(err) {
+			return c.Create(ctx, obj)
+		}
+		return err
+	}
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	return c.Client.Patch(ctx, obj, client.RawPatch(types.MergePatchType, data))
+}
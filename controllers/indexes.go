@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/processing"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	//virtualServiceHostIndex is the name of the cache index of Virtual Services by their hosts
+	virtualServiceHostIndex = "spec.hosts"
+	//apiRuleHostIndex is the name of the cache index of APIRules by the host set in the spec, which can lack the default domain name
+	apiRuleHostIndex = "spec.service.host"
+	//apiRuleServiceIndex is the name of the cache index of APIRules by the namespace and name of the Service they expose
+	apiRuleServiceIndex = "spec.service.name"
+	//apiRuleServiceNamespaceIndex is the name of the cache index of APIRules by the namespace of the Service they expose
+	apiRuleServiceNamespaceIndex = "spec.service.namespace"
+	//apiRuleSecretIndex is the name of the cache index of APIRules by the namespace and name of the Secrets referenced by their handler configs
+	apiRuleSecretIndex = "spec.rules.secrets"
+	//apiRuleTLSSecretIndex is the name of the cache index of APIRules by the names of the Secrets of their upstream TLS mode
+	apiRuleTLSSecretIndex = "spec.service.tls.secrets"
+)
+
+//SetupIndexes registers the cache indexes used by the APIReconciler to find Virtual Services and APIRules exposing a host,
+//APIRules exposing a Service or referencing a Secret and objects generated for an APIRule without listing all objects in the cluster
+func SetupIndexes(ctx context.Context, indexer client.FieldIndexer) error {
+	if err := indexer.IndexField(ctx, &networkingv1beta1.VirtualService{}, virtualServiceHostIndex, indexVirtualServiceByHost); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &gatewayv1alpha1.APIRule{}, apiRuleHostIndex, indexAPIRuleByHost); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &gatewayv1alpha1.APIRule{}, apiRuleServiceIndex, indexAPIRuleByService); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &gatewayv1alpha1.APIRule{}, apiRuleServiceNamespaceIndex, indexAPIRuleByServiceNamespace); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &gatewayv1alpha1.APIRule{}, apiRuleSecretIndex, indexAPIRuleBySecret); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &gatewayv1alpha1.APIRule{}, apiRuleTLSSecretIndex, indexAPIRuleByTLSSecret); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &networkingv1beta1.VirtualService{}, processing.OwnerLabelIndex, processing.IndexByOwnerLabel); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &networkingv1beta1.DestinationRule{}, processing.OwnerLabelIndex, processing.IndexByOwnerLabel); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &networkingv1beta1.Gateway{}, processing.OwnerLabelIndex, processing.IndexByOwnerLabel); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &networkingv1alpha3.EnvoyFilter{}, processing.OwnerLabelIndex, processing.IndexByOwnerLabel); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &securityv1beta1.AuthorizationPolicy{}, processing.OwnerLabelIndex, processing.IndexByOwnerLabel); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(ctx, &securityv1beta1.RequestAuthentication{}, processing.OwnerLabelIndex, processing.IndexByOwnerLabel); err != nil {
+		return err
+	}
+	return indexer.IndexField(ctx, &rulev1alpha1.Rule{}, processing.OwnerLabelIndex, processing.IndexByOwnerLabel)
+}
+
+func indexVirtualServiceByHost(obj client.Object) []string {
+	vs, ok := obj.(*networkingv1beta1.VirtualService)
+	if !ok {
+		return nil
+	}
+	return vs.Spec.Hosts
+}
+
+func indexAPIRuleByHost(obj client.Object) []string {
+	api, ok := obj.(*gatewayv1alpha1.APIRule)
+	if !ok || api.Spec.Service == nil || api.Spec.Service.Host == nil {
+		return nil
+	}
+	return []string{*api.Spec.Service.Host}
+}
+
+func indexAPIRuleByService(obj client.Object) []string {
+	api, ok := obj.(*gatewayv1alpha1.APIRule)
+	if !ok || api.Spec.Service == nil || api.Spec.Service.Name == nil {
+		return nil
+	}
+	return []string{serviceKey(api.ServiceNamespace(), *api.Spec.Service.Name)}
+}
+
+func indexAPIRuleByServiceNamespace(obj client.Object) []string {
+	api, ok := obj.(*gatewayv1alpha1.APIRule)
+	if !ok || api.Spec.Service == nil {
+		return nil
+	}
+	return []string{api.ServiceNamespace()}
+}
+
+func indexAPIRuleBySecret(obj client.Object) []string {
+	api, ok := obj.(*gatewayv1alpha1.APIRule)
+	if !ok {
+		return nil
+	}
+	var keys []string
+	for _, name := range referencedSecretNames(api) {
+		keys = append(keys, types.NamespacedName{Namespace: api.Namespace, Name: name}.String())
+	}
+	return keys
+}
+
+func indexAPIRuleByTLSSecret(obj client.Object) []string {
+	api, ok := obj.(*gatewayv1alpha1.APIRule)
+	if !ok || api.Spec.Service == nil || api.Spec.Service.TLS == nil {
+		return nil
+	}
+	var names []string
+	for _, name := range []*string{api.Spec.Service.TLS.CASecretName, api.Spec.Service.TLS.ClientSecretName} {
+		if name != nil {
+			names = append(names, *name)
+		}
+	}
+	return names
+}
+
+func serviceKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+//getHostVirtualServices returns the Virtual Services exposing the host
+func (r *APIReconciler) getHostVirtualServices(ctx context.Context, host string) (networkingv1beta1.VirtualServiceList, error) {
+	var vsList networkingv1beta1.VirtualServiceList
+	err := r.Client.List(ctx, &vsList, client.MatchingFields{virtualServiceHostIndex: host})
+	return vsList, err
+}
+
+//getHostClaimants returns the APIRules, which can expose the host. The host of an APIRule can be set with or without the default domain name,
+//so both forms are looked up. The index is served by the cache of the manager only, the API server rejects the field selector.
+//The result has to be filtered by the host, as clients ignoring field selectors, like the fake client, return all APIRules.
+func (r *APIReconciler) getHostClaimants(ctx context.Context, host, defaultDomainName string) ([]gatewayv1alpha1.APIRule, error) {
+	keys := []string{host}
+	if defaultDomainName != "" {
+		short := strings.TrimSuffix(host, "."+defaultDomainName)
+		if short != host && !strings.Contains(short, ".") {
+			keys = append(keys, short)
+		}
+	}
+
+	var claimants []gatewayv1alpha1.APIRule
+	seen := make(map[types.NamespacedName]bool)
+	for _, key := range keys {
+		var apiList gatewayv1alpha1.APIRuleList
+		if err := r.Client.List(ctx, &apiList, client.MatchingFields{apiRuleHostIndex: key}); err != nil {
+			return nil, err
+		}
+		for _, api := range apiList.Items {
+			name := types.NamespacedName{Namespace: api.Namespace, Name: api.Name}
+			if !seen[name] {
+				seen[name] = true
+				claimants = append(claimants, api)
+			}
+		}
+	}
+	return claimants, nil
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/claims"
+	"github.com/kyma-incubator/api-gateway/internal/processing"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const benchmarkDomain = "kyma.local"
+
+var _ = Describe("Controller", func() {
+	Describe("indexed lookups", func() {
+
+		It("should find Virtual Services and APIRules exposing the host", func() {
+			c := newIndexedCache()
+			Expect(SetupIndexes(context.Background(), c)).To(Succeed())
+			Expect(c.add(
+				testVirtualService("shop", "shop.kyma.local"),
+				testVirtualService("other", "other.kyma.local"),
+				testIndexedAPIRule("short", "shop"),
+				testIndexedAPIRule("full", "shop.kyma.local"),
+				testIndexedAPIRule("other", "other.kyma.local"),
+			)).To(Succeed())
+			r := &APIReconciler{Client: c, Log: ctrl.Log.WithName("test")}
+
+			vsList, err := r.getHostVirtualServices(context.Background(), "shop.kyma.local")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vsList.Items).To(HaveLen(1))
+			Expect(vsList.Items[0].Name).To(Equal("shop"))
+
+			claimants, err := r.getHostClaimants(context.Background(), "shop.kyma.local", benchmarkDomain)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claimants).To(HaveLen(2))
+		})
+	})
+})
+
+//BenchmarkHostLookup compares the lookups done for every reconciled APIRule with the indexes and with listing all objects in the cluster
+func BenchmarkHostLookup(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		c := newIndexedCache()
+		if err := SetupIndexes(context.Background(), c); err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < n; i++ {
+			host := fmt.Sprintf("host-%d.%s", i, benchmarkDomain)
+			if err := c.add(testVirtualService(fmt.Sprintf("vs-%d", i), host), testIndexedAPIRule(fmt.Sprintf("api-%d", i), host)); err != nil {
+				b.Fatal(err)
+			}
+		}
+		r := &APIReconciler{Client: c, Log: ctrl.Log.WithName("benchmark")}
+		host := fmt.Sprintf("host-%d.%s", n/2, benchmarkDomain)
+		api := testIndexedAPIRule(fmt.Sprintf("api-%d", n/2), host)
+
+		b.Run(fmt.Sprintf("indexed/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := r.getHostVirtualServices(context.Background(), host); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := r.getHostClaim(context.Background(), api, benchmarkDomain); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("full-scan/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var vsList networkingv1beta1.VirtualServiceList
+				if err := c.List(context.Background(), &vsList); err != nil {
+					b.Fatal(err)
+				}
+				var apiList gatewayv1alpha1.APIRuleList
+				if err := c.List(context.Background(), &apiList); err != nil {
+					b.Fatal(err)
+				}
+				claims.Arbitrate(api, apiList.Items, benchmarkDomain, claims.Options{})
+			}
+		})
+	}
+}
+
+//indexedCache serves List requests from client-go indexers, the way the manager cache does
+type indexedCache struct {
+	client.Client
+	indexers map[string]cache.Indexer
+}
+
+func newIndexedCache() *indexedCache {
+	return &indexedCache{indexers: make(map[string]cache.Indexer)}
+}
+
+func (c *indexedCache) indexerFor(kind string) cache.Indexer {
+	indexer, ok := c.indexers[kind]
+	if !ok {
+		indexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		c.indexers[kind] = indexer
+	}
+	return indexer
+}
+
+func (c *indexedCache) IndexField(_ context.Context, obj client.Object, field string, extract client.IndexerFunc) error {
+	return c.indexerFor(fmt.Sprintf("%T", obj)).AddIndexers(cache.Indexers{
+		field: func(o interface{}) ([]string, error) {
+			return extract(o.(client.Object)), nil
+		},
+	})
+}
+
+func (c *indexedCache) add(objs ...client.Object) error {
+	for _, obj := range objs {
+		if err := c.indexerFor(fmt.Sprintf("%T", obj)).Add(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *indexedCache) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	var kind string
+	switch list.(type) {
+	case *networkingv1beta1.VirtualServiceList:
+		kind = fmt.Sprintf("%T", &networkingv1beta1.VirtualService{})
+	case *gatewayv1alpha1.APIRuleList:
+		kind = fmt.Sprintf("%T", &gatewayv1alpha1.APIRule{})
+	case *rulev1alpha1.RuleList:
+		kind = fmt.Sprintf("%T", &rulev1alpha1.Rule{})
+	default:
+		return fmt.Errorf("unsupported list %T", list)
+	}
+
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	indexer := c.indexerFor(kind)
+	var items []interface{}
+	if listOpts.FieldSelector != nil {
+		reqs := listOpts.FieldSelector.Requirements()
+		var err error
+		if items, err = indexer.ByIndex(reqs[0].Field, reqs[0].Value); err != nil {
+			return err
+		}
+	} else {
+		items = indexer.List()
+	}
+
+	objs := make([]runtime.Object, 0, len(items))
+	for _, item := range items {
+		obj := item.(client.Object)
+		if listOpts.LabelSelector != nil && !listOpts.LabelSelector.Matches(labels.Set(obj.GetLabels())) {
+			continue
+		}
+		objs = append(objs, obj.DeepCopyObject())
+	}
+	return apimeta.SetList(list, objs)
+}
+
+func testVirtualService(name, host string) *networkingv1beta1.VirtualService {
+	vs := &networkingv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	vs.Spec.Hosts = []string{host}
+	vs.Labels = map[string]string{processing.OwnerLabel: name + ".default"}
+	return vs
+}
+
+func testIndexedAPIRule(name, host string) *gatewayv1alpha1.APIRule {
+	return &gatewayv1alpha1.APIRule{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       gatewayv1alpha1.APIRuleSpec{Service: &gatewayv1alpha1.Service{Host: &host}},
+	}
+}
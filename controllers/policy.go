@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+//getPolicies returns all APIGatewayPolicies and the labels of the namespace, which are needed to find the policies applying to it.
+//If the APIGatewayPolicy CRD is not installed, no policies are returned.
+func (r *APIReconciler) getPolicies(ctx context.Context, namespace string) ([]gatewayv1alpha1.APIGatewayPolicy, map[string]string, error) {
+	var policyList gatewayv1alpha1.APIGatewayPolicyList
+	if err := r.Client.List(ctx, &policyList); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	needsLabels := false
+	for _, policy := range policyList.Items {
+		if policy.Spec.NamespaceSelector != nil {
+			needsLabels = true
+		}
+	}
+	if !needsLabels {
+		return policyList.Items, nil, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return nil, nil, err
+	}
+	return policyList.Items, ns.Labels, nil
+}
+
+//requestsForAllAPIRules maps a changed APIGatewayPolicy or domain delegation to all APIRules, which are processed again even though their generation is observed.
+//The APIRules affected by the previous version of the object are unknown, so all of them are processed.
+func (r *APIReconciler) requestsForAllAPIRules(_ client.Object) []reconcile.Request {
+	var apiList gatewayv1alpha1.APIRuleList
+	if err := r.Client.List(context.Background(), &apiList); err != nil {
+		r.Log.Error(err, "Listing APIRules affected by the change failed")
+		return nil
+	}
+
+	r.markStale(apiList.Items)
+
+	requests := make([]reconcile.Request, 0, len(apiList.Items))
+	for _, api := range apiList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: api.Namespace, Name: api.Name}})
+	}
+	return requests
+}
+
+//requestsForNamespaceAPIRules maps a Namespace with changed labels to the APIRules in it, which are validated again
+//against the APIGatewayPolicies selecting namespaces by labels
+func (r *APIReconciler) requestsForNamespaceAPIRules(obj client.Object) []reconcile.Request {
+	var apiList gatewayv1alpha1.APIRuleList
+	if err := r.Client.List(context.Background(), &apiList, client.InNamespace(obj.GetName())); err != nil {
+		r.Log.Error(err, "Listing APIRules of the namespace failed", "namespace", obj.GetName())
+		return nil
+	}
+
+	r.markStale(apiList.Items)
+
+	requests := make([]reconcile.Request, 0, len(apiList.Items))
+	for _, api := range apiList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: api.Namespace, Name: api.Name}})
+	}
+	return requests
+}
+
+//namespaceLabelsChanged passes only the updates of Namespaces changing their labels. Created Namespaces hold no APIRules yet
+var namespaceLabelsChanged = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		return false
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return !equality.Semantic.DeepEqual(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels())
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		return false
+	},
+	GenericFunc: func(e event.GenericEvent) bool {
+		return false
+	},
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("Controller", func() {
+	Describe("requestsForNamespaceAPIRules", func() {
+
+		It("should process again the APIRules of the namespace", func() {
+			s := runtime.NewScheme()
+			Expect(gatewayv1alpha1.AddToScheme(s)).To(Succeed())
+			inNamespace := &gatewayv1alpha1.APIRule{ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "team-a"}}
+			other := &gatewayv1alpha1.APIRule{ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "team-b"}}
+
+			r := &APIReconciler{
+				Client: fake.NewFakeClientWithScheme(s, inNamespace, other),
+				Log:    ctrl.Log.WithName("test"),
+			}
+
+			requests := r.requestsForNamespaceAPIRules(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}})
+
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].NamespacedName).To(Equal(types.NamespacedName{Namespace: "team-a", Name: "orders"}))
+			Expect(r.takeStale(types.NamespacedName{Namespace: "team-a", Name: "orders"})).To(BeTrue())
+		})
+
+		It("should pass only the changes of namespace labels", func() {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"env": "dev"}}}
+
+			annotated := ns.DeepCopy()
+			annotated.Annotations = map[string]string{"owner": "team-a"}
+			Expect(namespaceLabelsChanged.Update(event.UpdateEvent{ObjectOld: ns, ObjectNew: annotated})).To(BeFalse())
+
+			relabeled := ns.DeepCopy()
+			relabeled.Labels["env"] = "prod"
+			Expect(namespaceLabelsChanged.Update(event.UpdateEvent{ObjectOld: ns, ObjectNew: relabeled})).To(BeTrue())
+		})
+	})
+})
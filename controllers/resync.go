@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"time"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	//retryBaseDelay is the delay before processing a failed APIRule again for the first time. It doubles with every failed attempt.
+	retryBaseDelay = 5 * time.Second
+	//retryMaxDelay limits the delay between attempts to process a failed APIRule
+	retryMaxDelay = 5 * time.Minute
+	//certificatePollInterval is the delay before processing an APIRule again to check if its pending certificate is ready
+	certificatePollInterval = 30 * time.Second
+)
+
+//isFailed tells if the last processing of the APIRule ended with a status that may be fixed by processing it again
+func isFailed(api *gatewayv1alpha1.APIRule) bool {
+	code := statusCode(api)
+	return api.Status.FailedAttempts > 0 && (code == gatewayv1alpha1.StatusError || code == gatewayv1alpha1.StatusSkipped)
+}
+
+//retryDelay returns the delay after which the APIRule is processed again even if it didn't change, or zero if it isn't.
+//Failed APIRules are retried with exponential backoff, all others are processed with the resync interval.
+func (r *APIReconciler) retryDelay(api *gatewayv1alpha1.APIRule) time.Duration {
+	r.mu.RLock()
+	interval := r.ResyncInterval
+	r.mu.RUnlock()
+
+	if !isFailed(api) {
+		if isCertificatePending(api) && (interval == 0 || certificatePollInterval < interval) {
+			return certificatePollInterval
+		}
+		return interval
+	}
+	delay := retryMaxDelay
+	if attempts := api.Status.FailedAttempts; attempts <= 16 {
+		if backoff := retryBaseDelay << uint(attempts-1); backoff < delay {
+			delay = backoff
+		}
+	}
+	if interval > 0 && interval < delay {
+		return interval
+	}
+	return delay
+}
+
+//isCertificatePending tells if the certificate provisioned for the host of the APIRule isn't ready yet.
+//Certificates aren't watched, so the APIRule is processed again until it is
+func isCertificatePending(api *gatewayv1alpha1.APIRule) bool {
+	return api.Status.CertificateStatus != nil && api.Status.CertificateStatus.Code == gatewayv1alpha1.StatusPending
+}
+
+//resyncDue tells if the APIRule should be processed again because the retry delay passed since it was last processed
+func (r *APIReconciler) resyncDue(api *gatewayv1alpha1.APIRule) bool {
+	delay := r.retryDelay(api)
+	return delay > 0 && api.Status.LastProcessedTime != nil && time.Since(api.Status.LastProcessedTime.Time) >= delay
+}
+
+//requeueResync schedules processing the APIRule again once the retry delay passes
+func (r *APIReconciler) requeueResync(api *gatewayv1alpha1.APIRule) (ctrl.Result, error) {
+	delay := r.retryDelay(api)
+	if delay == 0 || api.Status.LastProcessedTime == nil {
+		return doneReconcile()
+	}
+	remaining := delay - time.Since(api.Status.LastProcessedTime.Time)
+	if remaining <= 0 {
+		remaining = time.Millisecond
+	}
+	return ctrl.Result{RequeueAfter: remaining}, nil
+}
+
+//recordFailure updates the failed attempts and the time of the failure in the status of the processed APIRule.
+//Changes planned in dry-run mode or while paused are reported with the SKIPPED status, but are not failures.
+func recordFailure(api *gatewayv1alpha1.APIRule, now time.Time) {
+	code := statusCode(api)
+	planned := code == gatewayv1alpha1.StatusSkipped && api.Status.Plan != nil
+	if (code == gatewayv1alpha1.StatusError || code == gatewayv1alpha1.StatusSkipped) && !planned {
+		api.Status.FailedAttempts++
+		phaseTimes(api).Failed = &metav1.Time{Time: now}
+		return
+	}
+	api.Status.FailedAttempts = 0
+}
+
+//phaseTimes returns the phase times in the status of the APIRule, initializing them if needed
+func phaseTimes(api *gatewayv1alpha1.APIRule) *gatewayv1alpha1.PhaseTimes {
+	if api.Status.PhaseTimes == nil {
+		api.Status.PhaseTimes = &gatewayv1alpha1.PhaseTimes{}
+	}
+	return api.Status.PhaseTimes
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Controller", func() {
+	Describe("resync", func() {
+
+		name := types.NamespacedName{Namespace: "default", Name: "resynced"}
+
+		newReconciler := func(domainAllowList []string, objs ...runtime.Object) *APIReconciler {
+			objs = append(objs, newTestService(name.Namespace, 8000))
+			return newTestReconciler(func(cfg *config.Config) {
+				cfg.DomainAllowList = domainAllowList
+				cfg.ResyncInterval = &metav1.Duration{Duration: time.Hour}
+			}, objs...)
+		}
+
+		//processedAgo emulates the passing of time since the APIRule was last processed
+		processedAgo := func(r *APIReconciler, api *gatewayv1alpha1.APIRule, ago time.Duration) {
+			api.Status.LastProcessedTime = &metav1.Time{Time: time.Now().Add(-ago)}
+			Expect(r.Client.Status().Update(context.Background(), api)).To(Succeed())
+		}
+
+		It("should retry failed APIRules with backoff until they are processed successfully", func() {
+			//given
+			r := newReconciler([]string{"other.local"}, newTestAPIRule(name))
+
+			//when
+			res, api := reconcileAndGet(r, name)
+
+			//then
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusError))
+			Expect(api.Status.FailedAttempts).To(BeEquivalentTo(1))
+			Expect(api.Status.PhaseTimes.Failed).NotTo(BeNil())
+			Expect(res.RequeueAfter).To(BeNumerically("~", retryBaseDelay, time.Second))
+
+			//when
+			processedAgo(r, api, retryBaseDelay)
+			res, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(api.Status.FailedAttempts).To(BeEquivalentTo(2))
+			Expect(res.RequeueAfter).To(BeNumerically("~", 2*retryBaseDelay, time.Second))
+
+			//when
+			r.DomainAllowList = []string{"kyma.local"}
+			res, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusError))
+			Expect(res.RequeueAfter).To(BeNumerically("<=", 2*retryBaseDelay))
+
+			//when
+			processedAgo(r, api, 2*retryBaseDelay)
+			res, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			Expect(api.Status.FailedAttempts).To(BeZero())
+			Expect(api.Status.PhaseTimes.Validated).NotTo(BeNil())
+			Expect(api.Status.PhaseTimes.Applied).NotTo(BeNil())
+			Expect(res.RequeueAfter).To(BeNumerically("~", time.Hour, time.Second))
+		})
+
+		It("should process APIRules again once the resync interval passed", func() {
+			//given
+			r := newReconciler([]string{"kyma.local"}, newTestAPIRule(name))
+			_, api := reconcileAndGet(r, name)
+			applied := api.Status.PhaseTimes.Applied
+			processedAgo(r, api, 2*time.Hour)
+
+			//when
+			_, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			Expect(api.Status.LastProcessedTime.Time).To(BeTemporally("~", time.Now(), time.Second))
+			Expect(api.Status.PhaseTimes.Applied.Time).NotTo(BeTemporally("<", applied.Time))
+		})
+
+		It("should limit the retry delay", func() {
+			r := newReconciler(nil)
+			api := newTestAPIRule(name)
+			api.Status.APIRuleStatus = toStatus(gatewayv1alpha1.StatusError, "failed")
+
+			api.Status.FailedAttempts = 3
+			Expect(r.retryDelay(api)).To(Equal(4 * retryBaseDelay))
+
+			api.Status.FailedAttempts = 100
+			Expect(r.retryDelay(api)).To(Equal(retryMaxDelay))
+
+			r.ResyncInterval = time.Minute
+			Expect(r.retryDelay(api)).To(Equal(time.Minute))
+		})
+
+		It("should poll APIRules until their certificate is ready", func() {
+			r := newReconciler(nil)
+			api := newTestAPIRule(name)
+			api.Status.APIRuleStatus = toStatus(gatewayv1alpha1.StatusOK, "")
+
+			api.Status.CertificateStatus = toStatus(gatewayv1alpha1.StatusPending, "Certificate is not ready yet")
+			Expect(r.retryDelay(api)).To(Equal(certificatePollInterval))
+
+			r.ResyncInterval = 10 * time.Second
+			Expect(r.retryDelay(api)).To(Equal(10 * time.Second))
+
+			api.Status.CertificateStatus = toStatus(gatewayv1alpha1.StatusOK, "Certificate is ready")
+			Expect(r.retryDelay(api)).To(Equal(10 * time.Second))
+		})
+	})
+})
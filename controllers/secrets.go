@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/apikeys"
+	"github.com/kyma-incubator/api-gateway/internal/secretrefs"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+//getReferencedSecrets looks up the Secrets holding the keys of the api_key access strategies of the APIRule and the Secrets referenced by
+//the placeholders in its handler configs, so the validator can check that they exist and the factory can resolve the placeholders.
+//Secrets that don't exist are missing in the result
+func (r *APIReconciler) getReferencedSecrets(ctx context.Context, api *gatewayv1alpha1.APIRule) (map[string]*corev1.Secret, error) {
+	secrets := make(map[string]*corev1.Secret)
+	for _, name := range referencedSecretNames(api) {
+		secret := &corev1.Secret{}
+		if err := r.secretReader().Get(ctx, types.NamespacedName{Namespace: api.Namespace, Name: name}, secret); err != nil {
+			if apierrs.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		secrets[name] = secret
+	}
+	return secrets, nil
+}
+
+//getOathkeeperTLSSecrets looks up the Secret of the SIMPLE or MUTUAL upstream TLS mode of the APIRule in the namespace of Oathkeeper,
+//which connects to the Service over TLS on its own in these modes. Returns nil if the namespace of Oathkeeper isn't known
+func (r *APIReconciler) getOathkeeperTLSSecrets(ctx context.Context, api *gatewayv1alpha1.APIRule, oathkeeperNamespace string) (map[string]*corev1.Secret, error) {
+	if oathkeeperNamespace == "" {
+		return nil, nil
+	}
+	secrets := make(map[string]*corev1.Secret)
+	tls := api.Spec.Service.TLS
+	if tls == nil {
+		return secrets, nil
+	}
+	for _, name := range []*string{tls.CASecretName, tls.ClientSecretName} {
+		if name == nil {
+			continue
+		}
+		secret := &corev1.Secret{}
+		if err := r.secretReader().Get(ctx, types.NamespacedName{Namespace: oathkeeperNamespace, Name: *name}, secret); err != nil {
+			if apierrs.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		secrets[*name] = secret
+	}
+	return secrets, nil
+}
+
+//oathkeeperNamespace returns the namespace of the Oathkeeper Service from its name.namespace.svc address, or empty if it has no namespace
+func oathkeeperNamespace(address string) string {
+	parts := strings.Split(address, ".")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+//secretReader returns the reader of the Secret contents, which aren't cached
+func (r *APIReconciler) secretReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+//referencedSecretNames returns the sorted names of the Secrets referenced by the APIRule in its namespace
+func referencedSecretNames(api *gatewayv1alpha1.APIRule) []string {
+	names := apikeys.SecretNames(api)
+	for _, name := range secretrefs.SecretNames(api) {
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+//requestsForSecretReferences maps a changed Secret to the APIRules referencing it, which are validated again and whose access rules are
+//generated again with the new values. Secrets in the namespace of Oathkeeper are also mapped to the APIRules using them for upstream TLS.
+func (r *APIReconciler) requestsForSecretReferences(obj client.Object) []reconcile.Request {
+	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}.String()
+	var apiList gatewayv1alpha1.APIRuleList
+	if err := r.Client.List(context.Background(), &apiList, client.MatchingFields{apiRuleSecretIndex: key}); err != nil {
+		r.Log.Error(err, "Listing APIRules referencing the Secret failed", "namespace", obj.GetNamespace(), "name", obj.GetName())
+		return nil
+	}
+
+	var referencing []gatewayv1alpha1.APIRule
+	for _, api := range apiList.Items {
+		for _, k := range indexAPIRuleBySecret(&api) {
+			if k == key {
+				referencing = append(referencing, api)
+				break
+			}
+		}
+	}
+
+	r.mu.RLock()
+	namespace := oathkeeperNamespace(r.OathkeeperSvc)
+	r.mu.RUnlock()
+	if namespace != "" && obj.GetNamespace() == namespace {
+		var tlsList gatewayv1alpha1.APIRuleList
+		if err := r.Client.List(context.Background(), &tlsList, client.MatchingFields{apiRuleTLSSecretIndex: obj.GetName()}); err != nil {
+			r.Log.Error(err, "Listing APIRules using the Secret for upstream TLS failed", "namespace", obj.GetNamespace(), "name", obj.GetName())
+			return nil
+		}
+		for _, api := range tlsList.Items {
+			if containsString(indexAPIRuleByTLSSecret(&api), obj.GetName()) {
+				referencing = append(referencing, api)
+			}
+		}
+	}
+	return r.requestsForStale(referencing)
+}
+
+//secretChanged passes only the events that can change the outcome of validating and resolving the Secret references:
+//Secrets created, deleted or updated. Secrets are watched by their metadata only, so any update changing the resource version is passed
+var secretChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		if e.ObjectOld == nil || e.ObjectNew == nil {
+			return false
+		}
+		return e.ObjectOld.GetResourceVersion() != e.ObjectNew.GetResourceVersion()
+	},
+	GenericFunc: func(e event.GenericEvent) bool {
+		return false
+	},
+}
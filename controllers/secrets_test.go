@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Controller", func() {
+	Describe("Secret reference", func() {
+
+		name := types.NamespacedName{Namespace: "default", Name: "partners"}
+
+		newAPIRule := func() *gatewayv1alpha1.APIRule {
+			api := newTestAPIRule(name)
+			api.Spec.Rules[0].AccessStrategies = []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{
+				Name:   "api_key",
+				Config: &runtime.RawExtension{Raw: []byte(`{"secretName":"partner-keys","header":"X-API-Key"}`)},
+			}}}
+			return api
+		}
+
+		newSecret := func(name string) *corev1.Secret {
+			return &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Data:       map[string][]byte{"acme": []byte("acme-key")},
+			}
+		}
+
+		newReconciler := func(objs ...runtime.Object) *APIReconciler {
+			return newTestReconciler(func(cfg *config.Config) {
+				cfg.APIKeys = &config.APIKeys{CheckURL: "http://api-gateway-metrics.kyma-system.svc.cluster.local:8080/api-keys"}
+			}, objs...)
+		}
+
+		It("should report a missing Secret of API keys and process the APIRule again once it is created", func() {
+			//given
+			r := newReconciler(newAPIRule(), newTestService(name.Namespace, 8000))
+			_, api := reconcileAndGet(r, name)
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusError))
+			Expect(api.Status.APIRuleStatus.Description).To(ContainSubstring("Secret partner-keys does not exist"))
+
+			//when
+			secret := newSecret("partner-keys")
+			Expect(r.Client.Create(context.Background(), secret)).To(Succeed())
+			requests := r.requestsForSecretReferences(secret)
+
+			//then
+			Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: name}))
+			_, api = reconcileAndGet(r, name)
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			var arList rulev1alpha1.RuleList
+			Expect(r.Client.List(context.Background(), &arList)).To(Succeed())
+			Expect(arList.Items).To(HaveLen(1))
+			Expect(arList.Items[0].Spec.Authenticators[0].Name).To(Equal("bearer_token"))
+			Expect(string(arList.Items[0].Spec.Authenticators[0].Config.Raw)).NotTo(ContainSubstring("acme-key"))
+		})
+
+		It("should write the values of referenced Secrets into the access rule and update them when the Secret changes", func() {
+			//given
+			api := newAPIRule()
+			api.Spec.Rules[0].AccessStrategies[0].Handler = &gatewayv1alpha1.Handler{
+				Name:   "oauth2_introspection",
+				Config: &runtime.RawExtension{Raw: []byte(`{"introspection_request_headers":{"authorization":{"valueFrom":{"secretKeyRef":{"name":"introspection","key":"authorization"}}}}}`)},
+			}
+			r := newReconciler(api, newTestService(name.Namespace, 8000))
+			_, api = reconcileAndGet(r, name)
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusError))
+			Expect(api.Status.APIRuleStatus.Description).To(ContainSubstring("Secret introspection does not exist"))
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "introspection", Namespace: name.Namespace},
+				Data:       map[string][]byte{"authorization": []byte("Basic b2xk")},
+			}
+			Expect(r.Client.Create(context.Background(), secret)).To(Succeed())
+			Expect(r.requestsForSecretReferences(secret)).To(HaveLen(1))
+			_, api = reconcileAndGet(r, name)
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+
+			//when
+			secret.Data["authorization"] = []byte("Basic bmV3")
+			Expect(r.Client.Update(context.Background(), secret)).To(Succeed())
+			requests := r.requestsForSecretReferences(secret)
+			_, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: name}))
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			Expect(api.Status.APIRuleStatus.Description).NotTo(ContainSubstring("Basic"))
+			var arList rulev1alpha1.RuleList
+			Expect(r.Client.List(context.Background(), &arList)).To(Succeed())
+			Expect(arList.Items).To(HaveLen(1))
+			Expect(string(arList.Items[0].Spec.Authenticators[0].Config.Raw)).To(Equal(`{"introspection_request_headers":{"authorization":"Basic bmV3"}}`))
+		})
+
+		It("should check API keys with the keys loaded while processing the APIRule, without reading the Secret", func() {
+			//given
+			secret := newSecret("partner-keys")
+			r := newReconciler(newAPIRule(), newTestService(name.Namespace, 8000), secret)
+			reconcileAndGet(r, name)
+			Expect(r.Client.Delete(context.Background(), secret)).To(Succeed())
+			check := func() int {
+				req := httptest.NewRequest(http.MethodGet, APIKeysPath+"default/partner-keys/header/X-API-Key", nil)
+				req.Header.Set("X-API-Key", "acme-key")
+				rec := httptest.NewRecorder()
+				r.APIKeysHandler().ServeHTTP(rec, req)
+				return rec.Code
+			}
+
+			//when
+			beforeProcessing := check()
+			r.requestsForSecretReferences(secret)
+			reconcileAndGet(r, name)
+
+			//then
+			Expect(beforeProcessing).To(Equal(http.StatusOK))
+			Expect(check()).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("should map a Secret only to the APIRules referencing it", func() {
+			//given
+			r := newReconciler(newAPIRule())
+
+			//when
+			requests := r.requestsForSecretReferences(newSecret("other"))
+
+			//then
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("should require the Secret of the upstream TLS mode in the namespace of Oathkeeper for secured rules", func() {
+			//given
+			api := newTestAPIRule(name)
+			caSecret := "httpbin-ca"
+			api.Spec.Service.TLS = &gatewayv1alpha1.UpstreamTLS{Mode: gatewayv1alpha1.TLSModeSimple, CASecretName: &caSecret}
+			r := newTestReconciler(func(cfg *config.Config) {
+				cfg.OathkeeperSvcAddress = "ory-oathkeeper-proxy.kyma-system.svc.cluster.local"
+			}, api, newTestService(name.Namespace, 8000))
+			_, api = reconcileAndGet(r, name)
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusError))
+			Expect(api.Status.APIRuleStatus.Description).To(ContainSubstring("Secret httpbin-ca has to exist in the kyma-system namespace too"))
+
+			//when
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: caSecret, Namespace: "kyma-system"}}
+			Expect(r.Client.Create(context.Background(), secret)).To(Succeed())
+			requests := r.requestsForSecretReferences(secret)
+
+			//then
+			Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: name}))
+			_, api = reconcileAndGet(r, name)
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			var arList rulev1alpha1.RuleList
+			Expect(r.Client.List(context.Background(), &arList)).To(Succeed())
+			Expect(arList.Items).To(HaveLen(1))
+			Expect(arList.Items[0].Spec.Upstream.URL).To(Equal("https://httpbin.default.svc.cluster.local:8000"))
+		})
+
+		It("should pass only Secret updates and drop generic events", func() {
+			oldSecret, changedSecret := newSecret("partner-keys"), newSecret("partner-keys")
+			oldSecret.ResourceVersion, changedSecret.ResourceVersion = "1", "1"
+			Expect(secretChanged.Update(event.UpdateEvent{ObjectOld: oldSecret, ObjectNew: changedSecret})).To(BeFalse())
+
+			changedSecret.ResourceVersion = "2"
+			Expect(secretChanged.Update(event.UpdateEvent{ObjectOld: oldSecret, ObjectNew: changedSecret})).To(BeTrue())
+			Expect(secretChanged.Generic(event.GenericEvent{Object: changedSecret})).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/validation"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+//getServiceLookup looks up the Service referenced by the APIRule, so the validator can check that it exists and exposes the port.
+//For a Service in another namespace, the ReferenceGrants of that namespace are looked up as well. The Service is not looked up
+//if no grant allows exposing it.
+func (r *APIReconciler) getServiceLookup(ctx context.Context, api *gatewayv1alpha1.APIRule) (*validation.ServiceLookup, error) {
+	if api.Spec.Service == nil || api.Spec.Service.Name == nil {
+		return nil, nil
+	}
+	lookup := &validation.ServiceLookup{}
+	namespace := api.ServiceNamespace()
+	if namespace != api.Namespace {
+		grants, err := r.getReferenceGrants(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		lookup.Grants = grants
+		if !lookup.Granted(api) {
+			return lookup, nil
+		}
+	}
+
+	svc := &corev1.Service{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: *api.Spec.Service.Name}, svc); err != nil {
+		if apierrs.IsNotFound(err) {
+			return lookup, nil
+		}
+		return nil, err
+	}
+	lookup.Service = svc
+	return lookup, nil
+}
+
+//getReferenceGrants returns the ReferenceGrants of the namespace. If the ReferenceGrant CRD is not installed, no grants are returned
+func (r *APIReconciler) getReferenceGrants(ctx context.Context, namespace string) ([]gatewayv1alpha1.ReferenceGrant, error) {
+	var grantList gatewayv1alpha1.ReferenceGrantList
+	if err := r.Client.List(ctx, &grantList, client.InNamespace(namespace)); err != nil && !meta.IsNoMatchError(err) {
+		return nil, err
+	}
+	return grantList.Items, nil
+}
+
+//requestsForServiceReferences maps a changed Service to the APIRules referencing it, which are validated again.
+//The result is filtered by the Service, as clients ignoring field selectors, like the fake client, return all APIRules.
+func (r *APIReconciler) requestsForServiceReferences(obj client.Object) []reconcile.Request {
+	key := serviceKey(obj.GetNamespace(), obj.GetName())
+	var apiList gatewayv1alpha1.APIRuleList
+	if err := r.Client.List(context.Background(), &apiList, client.MatchingFields{apiRuleServiceIndex: key}); err != nil {
+		r.Log.Error(err, "Listing APIRules referencing the Service failed", "namespace", obj.GetNamespace(), "name", obj.GetName())
+		return nil
+	}
+
+	var referencing []gatewayv1alpha1.APIRule
+	for _, api := range apiList.Items {
+		if keys := indexAPIRuleByService(&api); len(keys) == 1 && keys[0] == key {
+			referencing = append(referencing, api)
+		}
+	}
+	return r.requestsForStale(referencing)
+}
+
+//requestsForGrantedServices maps a changed ReferenceGrant to the APIRules exposing Services of its namespace from other namespaces,
+//which are validated again, so exposure is revoked when the grant is removed.
+//The result is filtered by the namespace, as clients ignoring field selectors, like the fake client, return all APIRules.
+func (r *APIReconciler) requestsForGrantedServices(obj client.Object) []reconcile.Request {
+	var apiList gatewayv1alpha1.APIRuleList
+	if err := r.Client.List(context.Background(), &apiList, client.MatchingFields{apiRuleServiceNamespaceIndex: obj.GetNamespace()}); err != nil {
+		r.Log.Error(err, "Listing APIRules exposing Services of the namespace failed", "namespace", obj.GetNamespace())
+		return nil
+	}
+
+	var referencing []gatewayv1alpha1.APIRule
+	for _, api := range apiList.Items {
+		if api.ServiceNamespace() == obj.GetNamespace() && api.Namespace != obj.GetNamespace() {
+			referencing = append(referencing, api)
+		}
+	}
+	return r.requestsForStale(referencing)
+}
+
+//requestsForStale marks the APIRules as stale and returns the requests to process them again
+func (r *APIReconciler) requestsForStale(apis []gatewayv1alpha1.APIRule) []reconcile.Request {
+	r.markStale(apis)
+
+	requests := make([]reconcile.Request, 0, len(apis))
+	for _, api := range apis {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: api.Namespace, Name: api.Name}})
+	}
+	return requests
+}
+
+//servicePortsChanged passes only the events that can change the outcome of validating the Service reference:
+//Services created, deleted or with changed ports or type
+var servicePortsChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldSvc, okOld := e.ObjectOld.(*corev1.Service)
+		newSvc, okNew := e.ObjectNew.(*corev1.Service)
+		if !okOld || !okNew {
+			return false
+		}
+		return oldSvc.Spec.Type != newSvc.Spec.Type || !reflect.DeepEqual(oldSvc.Spec.Ports, newSvc.Spec.Ports)
+	},
+	GenericFunc: func(e event.GenericEvent) bool {
+		return false
+	},
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/processing"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Controller", func() {
+	Describe("Service reference", func() {
+
+		name := types.NamespacedName{Namespace: "default", Name: "referencing"}
+
+		newAPIRule := func(serviceName string) *gatewayv1alpha1.APIRule {
+			api := newTestAPIRule(name)
+			api.Spec.Service.Name = &serviceName
+			return api
+		}
+
+		It("should report a missing Service and process the APIRule again once it is created", func() {
+			//given
+			r := newTestReconciler(nil, newAPIRule("httpbin"))
+			_, api := reconcileAndGet(r, name)
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusError))
+			Expect(api.Status.APIRuleStatus.Description).To(ContainSubstring("Service httpbin does not exist in namespace default"))
+
+			//when
+			svc := newTestService(name.Namespace, 8000)
+			Expect(r.Client.Create(context.Background(), svc)).To(Succeed())
+			requests := r.requestsForServiceReferences(svc)
+
+			//then
+			Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: name}))
+			_, api = reconcileAndGet(r, name)
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+		})
+
+		It("should report a port not exposed by the Service", func() {
+			//given
+			r := newTestReconciler(nil, newAPIRule("httpbin"), newTestService(name.Namespace, 80))
+
+			//when
+			_, api := reconcileAndGet(r, name)
+
+			//then
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusError))
+			Expect(api.Status.APIRuleStatus.Description).To(ContainSubstring("Port 8000 is not exposed by Service httpbin"))
+		})
+
+		It("should expose a Service in another namespace only while a ReferenceGrant allows it", func() {
+			//given
+			api := newAPIRule("httpbin")
+			backend := "backend"
+			api.Spec.Service.Namespace = &backend
+			svc := newTestService(name.Namespace, 8000)
+			svc.Namespace = backend
+			grant := &gatewayv1alpha1.ReferenceGrant{
+				ObjectMeta: metav1.ObjectMeta{Namespace: backend, Name: "default"},
+				Spec:       gatewayv1alpha1.ReferenceGrantSpec{From: []gatewayv1alpha1.ReferenceGrantFrom{{Namespace: name.Namespace}}},
+			}
+			r := newTestReconciler(nil, api, svc, grant)
+
+			//when
+			_, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			var arList rulev1alpha1.RuleList
+			Expect(r.Client.List(context.Background(), &arList)).To(Succeed())
+			Expect(arList.Items).To(HaveLen(1))
+			Expect(arList.Items[0].Spec.Upstream.URL).To(Equal("http://httpbin.backend.svc.cluster.local:8000"))
+
+			//when
+			Expect(r.Client.Delete(context.Background(), grant)).To(Succeed())
+			requests := r.requestsForGrantedServices(grant)
+			_, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: name}))
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusError))
+			Expect(api.Status.APIRuleStatus.Description).To(ContainSubstring("not allowed by any ReferenceGrant"))
+			var vsList networkingv1beta1.VirtualServiceList
+			Expect(r.Client.List(context.Background(), &vsList)).To(Succeed())
+			Expect(vsList.Items).To(BeEmpty())
+			Expect(r.Client.List(context.Background(), &arList)).To(Succeed())
+			Expect(arList.Items).To(BeEmpty())
+		})
+
+		It("should hand a shared host over to another APIRule once the holder loses the grant", func() {
+			//given
+			api := newAPIRule("httpbin")
+			backend := "backend"
+			api.Spec.Service.Namespace = &backend
+			api.Spec.Rules[0].Path = "/orders"
+			api.CreationTimestamp = metav1.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+			member := newTestAPIRule(types.NamespacedName{Namespace: name.Namespace, Name: "member"})
+			member.Spec.Rules[0].Path = "/status"
+			member.CreationTimestamp = metav1.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+			memberName := types.NamespacedName{Namespace: member.Namespace, Name: member.Name}
+			svc := newTestService(backend, 8000)
+			grant := &gatewayv1alpha1.ReferenceGrant{
+				ObjectMeta: metav1.ObjectMeta{Namespace: backend, Name: "default"},
+				Spec:       gatewayv1alpha1.ReferenceGrantSpec{From: []gatewayv1alpha1.ReferenceGrantFrom{{Namespace: name.Namespace}}},
+			}
+			r := newTestReconciler(nil, api, member, svc, newTestService(name.Namespace, 8000), grant)
+			_, api = reconcileAndGet(r, name)
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			_, member = reconcileAndGet(r, memberName)
+			Expect(member.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+
+			//when
+			Expect(r.Client.Delete(context.Background(), grant)).To(Succeed())
+			r.requestsForGrantedServices(grant)
+			_, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusError))
+
+			//when
+			_, member = reconcileAndGet(r, memberName)
+
+			//then
+			Expect(member.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			var vsList networkingv1beta1.VirtualServiceList
+			Expect(r.Client.List(context.Background(), &vsList)).To(Succeed())
+			Expect(vsList.Items).To(HaveLen(1))
+			Expect(vsList.Items[0].Labels).To(HaveKeyWithValue(processing.OwnerLabel, "member.default"))
+		})
+
+		It("should generate a Destination Rule for upstream TLS and delete it once TLS is removed", func() {
+			//given
+			api := newAPIRule("httpbin")
+			api.Spec.Service.TLS = &gatewayv1alpha1.UpstreamTLS{Mode: gatewayv1alpha1.TLSModeIstioMutual}
+			r := newTestReconciler(nil, api, newTestService(name.Namespace, 8000))
+
+			//when
+			_, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			var drList networkingv1beta1.DestinationRuleList
+			Expect(r.Client.List(context.Background(), &drList)).To(Succeed())
+			Expect(drList.Items).To(HaveLen(1))
+			Expect(drList.Items[0].Spec.Host).To(Equal("httpbin.default.svc.cluster.local"))
+
+			//when
+			api.Spec.Service.TLS = nil
+			api.Generation++
+			Expect(r.Client.Update(context.Background(), api)).To(Succeed())
+			_, api = reconcileAndGet(r, name)
+
+			//then
+			Expect(api.Status.APIRuleStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			Expect(r.Client.List(context.Background(), &drList)).To(Succeed())
+			Expect(drList.Items).To(BeEmpty())
+		})
+
+		It("should map a Service only to the APIRules referencing it", func() {
+			//given
+			r := newTestReconciler(nil, newAPIRule("other"))
+
+			//when
+			requests := r.requestsForServiceReferences(newTestService(name.Namespace, 8000))
+
+			//then
+			Expect(requests).To(BeEmpty())
+		})
+
+		It("should pass only Service changes affecting validation", func() {
+			oldSvc, newSvc := newTestService(name.Namespace, 8000), newTestService(name.Namespace, 8000)
+			newSvc.Labels = map[string]string{"app": "httpbin"}
+			Expect(servicePortsChanged.Update(event.UpdateEvent{ObjectOld: oldSvc, ObjectNew: newSvc})).To(BeFalse())
+
+			newSvc.Spec.Ports[0].Port = 8080
+			Expect(servicePortsChanged.Update(event.UpdateEvent{ObjectOld: oldSvc, ObjectNew: newSvc})).To(BeTrue())
+		})
+	})
+})
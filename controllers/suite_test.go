@@ -1,3 +1,5 @@
+// +build envtest
+
 package controllers_test
 
 import (
@@ -8,7 +10,9 @@ import (
 	"time"
 
 	"istio.io/api/networking/v1beta1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/envtest/printer"
 
@@ -82,8 +86,12 @@ var _ = BeforeSuite(func(done Done) {
 	err = gatewayv1alpha1.AddToScheme(s)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = networkingv1alpha3.AddToScheme(s)
+	Expect(err).NotTo(HaveOccurred())
 	err = networkingv1beta1.AddToScheme(s)
 	Expect(err).NotTo(HaveOccurred())
+	err = securityv1beta1.AddToScheme(s)
+	Expect(err).NotTo(HaveOccurred())
 
 	err = corev1.AddToScheme(s)
 	Expect(err).NotTo(HaveOccurred())
@@ -101,6 +109,16 @@ var _ = BeforeSuite(func(done Done) {
 	err = c.Create(context.TODO(), ns)
 	Expect(err).NotTo(HaveOccurred())
 
+	//Services exposed by the APIRules created in the tests
+	for name, port := range map[string]int32{"httpbin": 443, "httpbinnew": 446} {
+		svc := &corev1.Service{
+			ObjectMeta: v1.ObjectMeta{Name: name, Namespace: testNamespace},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "https", Port: port}}},
+		}
+		err = c.Create(context.TODO(), svc)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
 	reconciler := &controllers.APIReconciler{
 		Client:            mgr.GetClient(),
 		Log:               ctrl.Log.WithName("controllers").WithName("Api"),
@@ -115,6 +133,8 @@ var _ = BeforeSuite(func(done Done) {
 		GeneratedObjectsLabels: map[string]string{},
 	}
 
+	Expect(controllers.SetupIndexes(context.Background(), mgr.GetFieldIndexer())).To(Succeed())
+
 	var recFn reconcile.Reconciler
 	recFn, requests = SetupTestReconcile(reconciler)
 
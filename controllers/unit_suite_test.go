@@ -0,0 +1,17 @@
+// +build !envtest
+
+package controllers
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+//TestUnit runs the specs using the fake client without the test environment of the Controller Suite, which needs the etcd and
+//kube-apiserver binaries. The Controller Suite, built with the envtest tag, runs these specs as well.
+func TestUnit(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Controller Unit Suite")
+}
@@ -58,5 +58,27 @@ var _ = Describe("Controller", func() {
 			Expect(failureLines[3]).To(Equal("Attribute \"service.name\": is too short"))
 			Expect(failureLines[4]).To(Equal("2 more error(s)..."))
 		})
+
+		It("should cite violated policy", func() {
+			failures := []validation.Failure{{AttributePath: ".spec.gateway", Message: "is not allowed", Policy: "prod"}}
+			st := generateValidationStatus(failures)
+
+			Expect(st).NotTo(BeNil())
+			Expect(st.Description).To(Equal("Validation error: Attribute \".spec.gateway\": is not allowed (policy: prod)"))
+		})
+	})
+
+	Describe("generateResourceStatus", func() {
+		It("should report repairs with OK status", func() {
+			st := generateResourceStatus("", "Repaired: deleted duplicate a")
+			Expect(st.Code).To(Equal(gatewayv1alpha1.StatusOK))
+			Expect(st.Description).To(Equal("Repaired: deleted duplicate a"))
+		})
+
+		It("should report conflicts with ERROR status", func() {
+			st := generateResourceStatus("Conflicts: a: conflict", "Repaired: adopted orphan b")
+			Expect(st.Code).To(Equal(gatewayv1alpha1.StatusError))
+			Expect(st.Description).To(Equal("Conflicts: a: conflict\nRepaired: adopted orphan b"))
+		})
 	})
 })
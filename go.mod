@@ -4,6 +4,7 @@ go 1.14
 
 require (
 	github.com/go-logr/logr v0.4.0
+	github.com/gogo/protobuf v1.3.2
 	github.com/onsi/ginkgo v1.14.1
 	github.com/onsi/gomega v1.11.0
 	github.com/ory/oathkeeper-maester v0.1.0
@@ -15,4 +16,5 @@ require (
 	k8s.io/client-go v0.20.2
 	sigs.k8s.io/controller-runtime v0.8.3
 	sigs.k8s.io/controller-tools v0.5.0 // indirect
+	sigs.k8s.io/yaml v1.2.0
 )
@@ -0,0 +1,174 @@
+package apikeys
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//Session is the response to a valid key, read by the bearer_token authenticator of Oathkeeper
+type Session struct {
+	//Subject is the name of the key in the Secret
+	Subject string            `json:"subject"`
+	Extra   map[string]string `json:"extra,omitempty"`
+}
+
+//Configs returns the configurations of the api_key access strategies of the APIRule. Invalid configurations are skipped
+func Configs(api *gatewayv1alpha1.APIRule) []gatewayv1alpha1.APIKeyAccStrConfig {
+	var res []gatewayv1alpha1.APIKeyAccStrConfig
+	for _, rule := range api.Spec.Rules {
+		for _, strategy := range rule.AccessStrategies {
+			if strategy == nil || strategy.Handler == nil || strategy.Name != "api_key" || strategy.Config == nil {
+				continue
+			}
+			var config gatewayv1alpha1.APIKeyAccStrConfig
+			if err := json.Unmarshal(strategy.Config.Raw, &config); err == nil && config.SecretName != "" {
+				res = append(res, config)
+			}
+		}
+	}
+	return res
+}
+
+//SecretNames returns the sorted names of the Secrets holding the keys of the api_key access strategies of the APIRule
+func SecretNames(api *gatewayv1alpha1.APIRule) []string {
+	seen := make(map[string]bool)
+	var res []string
+	for _, config := range Configs(api) {
+		if !seen[config.SecretName] {
+			seen[config.SecretName] = true
+			res = append(res, config.SecretName)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+//Store holds the keys of the Secrets used by api_key access strategies, hashed, so the keys are checked without reading the Secrets.
+//It is filled while processing the APIRules, which happens again whenever a Secret they use changes
+type Store struct {
+	mu sync.RWMutex
+	//hashes of the keys by their names, by the namespace and name of their Secret
+	secrets map[types.NamespacedName]map[string][]byte
+}
+
+//NewStore creates an empty Store
+func NewStore() *Store {
+	return &Store{secrets: make(map[types.NamespacedName]map[string][]byte)}
+}
+
+//Load replaces the keys of the Secrets with the given names in the namespace by the ones of secrets, holding the Secrets found by name.
+//The keys of Secrets that don't exist are removed
+func (s *Store) Load(namespace string, names []string, secrets map[string]*corev1.Secret) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, name := range names {
+		key := types.NamespacedName{Namespace: namespace, Name: name}
+		secret, ok := secrets[name]
+		if !ok || secret == nil {
+			delete(s.secrets, key)
+			continue
+		}
+		hashes := make(map[string][]byte, len(secret.Data))
+		for keyName, value := range secret.Data {
+			hashes[keyName] = hash(bytes.TrimSpace(value))
+		}
+		s.secrets[key] = hashes
+	}
+}
+
+//Match returns the name of the key of the Secret equal to the given key. All keys of the Secret are compared in constant time
+func (s *Store) Match(namespace, secretName, key string) (string, bool) {
+	s.mu.RLock()
+	hashes := s.secrets[types.NamespacedName{Namespace: namespace, Name: secretName}]
+	s.mu.RUnlock()
+
+	names := make([]string, 0, len(hashes))
+	for name := range hashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	keyHash := hash([]byte(key))
+	var subject string
+	for _, name := range names {
+		if subtle.ConstantTimeCompare(hashes[name], keyHash) == 1 && subject == "" {
+			subject = name
+		}
+	}
+	return subject, subject != ""
+}
+
+func hash(value []byte) []byte {
+	sum := sha256.Sum256(value)
+	return sum[:]
+}
+
+//Handler checks the keys of the api_key access strategies on behalf of Oathkeeper. The path of the request, relative to the handler, is
+//namespace/secret/location/name, where location is header or query and name is the header or the query parameter carrying the key.
+//It responds with the Session of a valid key, and with 401 Unauthorized otherwise. Only the Secrets and the locations used by api_key
+//access strategies of APIRules in the namespace are checked, so other Secrets can't be probed. APIRules are listed with apis and the
+//keys are looked up in the store, so no request reaches the API server.
+func Handler(apis client.Reader, store *Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/"), "/")
+		if len(parts) != 4 || (parts[2] != "header" && parts[2] != "query") {
+			http.Error(w, "expected the namespace/secret/location/name path", http.StatusNotFound)
+			return
+		}
+		namespace, secretName, location, name := parts[0], parts[1], parts[2], parts[3]
+
+		var apiList gatewayv1alpha1.APIRuleList
+		if err := apis.List(req.Context(), &apiList, client.InNamespace(namespace)); err != nil {
+			http.Error(w, "listing APIRules failed", http.StatusInternalServerError)
+			return
+		}
+		if !referenced(apiList.Items, secretName, location, name) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		key := req.Header.Get(name)
+		if location == "query" {
+			key = req.URL.Query().Get(name)
+		}
+		if key == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		subject, ok := store.Match(namespace, secretName, key)
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Session{Subject: subject, Extra: map[string]string{"secret": namespace + "/" + secretName}})
+	})
+}
+
+//referenced tells if an api_key access strategy of the APIRules takes the key from the location and checks it with the Secret
+func referenced(apis []gatewayv1alpha1.APIRule, secretName, location, name string) bool {
+	for i := range apis {
+		for _, config := range Configs(&apis[i]) {
+			if config.SecretName != secretName {
+				continue
+			}
+			if (location == "header" && strings.EqualFold(config.Header, name)) || (location == "query" && config.QueryParameter == name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAPIKeys(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "API Keys Suite")
+}
+
+func apiRule(configs ...string) *gatewayv1alpha1.APIRule {
+	rule := gatewayv1alpha1.Rule{Path: "/.*", Methods: []string{"GET"}}
+	for _, config := range configs {
+		rule.AccessStrategies = append(rule.AccessStrategies, &gatewayv1alpha1.Authenticator{
+			Handler: &gatewayv1alpha1.Handler{Name: "api_key", Config: &runtime.RawExtension{Raw: []byte(config)}},
+		})
+	}
+	return &gatewayv1alpha1.APIRule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "partners", Name: "orders"},
+		Spec:       gatewayv1alpha1.APIRuleSpec{Rules: []gatewayv1alpha1.Rule{rule}},
+	}
+}
+
+var _ = Describe("API keys", func() {
+
+	It("should list the Secrets holding the keys", func() {
+		api := apiRule(`{"secretName":"b-keys","header":"X-API-Key"}`, `{"secretName":"a-keys","queryParameter":"api_key"}`, `{"secretName":"b-keys","queryParameter":"key"}`)
+
+		Expect(SecretNames(api)).To(Equal([]string{"a-keys", "b-keys"}))
+	})
+
+	It("should replace the keys of loaded Secrets and remove the ones of missing Secrets", func() {
+		//given
+		store := NewStore()
+		keys := &corev1.Secret{Data: map[string][]byte{"acme": []byte("acme-key")}}
+		store.Load("partners", []string{"partner-keys", "other-keys"}, map[string]*corev1.Secret{"partner-keys": keys, "other-keys": keys})
+
+		//when
+		rotated := &corev1.Secret{Data: map[string][]byte{"acme": []byte("rotated-key")}}
+		store.Load("partners", []string{"partner-keys", "other-keys"}, map[string]*corev1.Secret{"partner-keys": rotated})
+
+		//then
+		_, ok := store.Match("partners", "partner-keys", "acme-key")
+		Expect(ok).To(BeFalse())
+		subject, ok := store.Match("partners", "partner-keys", "rotated-key")
+		Expect(ok).To(BeTrue())
+		Expect(subject).To(Equal("acme"))
+		_, ok = store.Match("partners", "other-keys", "acme-key")
+		Expect(ok).To(BeFalse())
+		_, ok = store.Match("default", "partner-keys", "rotated-key")
+		Expect(ok).To(BeFalse())
+	})
+
+	table.DescribeTable("Handler",
+		func(path, header string, expectedCode int, expectedSubject string) {
+			//given
+			s := runtime.NewScheme()
+			Expect(gatewayv1alpha1.AddToScheme(s)).To(Succeed())
+			Expect(corev1.AddToScheme(s)).To(Succeed())
+			keys := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "partners", Name: "partner-keys"},
+				Data:       map[string][]byte{"acme": []byte("acme-key\n"), "globex": []byte("globex-key")},
+			}
+			other := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "partners", Name: "database"},
+				Data:       map[string][]byte{"password": []byte("secret")},
+			}
+			api := apiRule(`{"secretName":"partner-keys","header":"X-API-Key"}`, `{"secretName":"partner-keys","queryParameter":"api_key"}`)
+			c := fake.NewFakeClientWithScheme(s, api)
+			store := NewStore()
+			store.Load("partners", []string{"partner-keys", "database"}, map[string]*corev1.Secret{"partner-keys": keys, "database": other})
+			h := Handler(c, store)
+			req := httptest.NewRequest(http.MethodPost, path, nil)
+			if header != "" {
+				req.Header.Set("X-API-Key", header)
+			}
+			rec := httptest.NewRecorder()
+
+			//when
+			h.ServeHTTP(rec, req)
+
+			//then
+			Expect(rec.Code).To(Equal(expectedCode))
+			if expectedSubject != "" {
+				var session Session
+				Expect(json.Unmarshal(rec.Body.Bytes(), &session)).To(Succeed())
+				Expect(session.Subject).To(Equal(expectedSubject))
+				Expect(session.Extra).To(HaveKeyWithValue("secret", "partners/partner-keys"))
+			}
+		},
+		table.Entry("valid key in header", "/partners/partner-keys/header/X-API-Key", "globex-key", http.StatusOK, "globex"),
+		table.Entry("valid key in query parameter", "/partners/partner-keys/query/api_key?api_key=acme-key", "", http.StatusOK, "acme"),
+		table.Entry("invalid key", "/partners/partner-keys/header/X-API-Key", "unknown", http.StatusUnauthorized, ""),
+		table.Entry("no key", "/partners/partner-keys/header/X-API-Key", "", http.StatusUnauthorized, ""),
+		table.Entry("key in a location not used by APIRules", "/partners/partner-keys/header/X-Other-Key", "globex-key", http.StatusUnauthorized, ""),
+		table.Entry("Secret not used by APIRules", "/partners/database/header/X-API-Key", "secret", http.StatusUnauthorized, ""),
+		table.Entry("Secret in another namespace", "/default/partner-keys/header/X-API-Key", "globex-key", http.StatusUnauthorized, ""),
+		table.Entry("invalid path", "/partners/partner-keys", "globex-key", http.StatusNotFound, ""),
+	)
+})
@@ -0,0 +1,108 @@
+package builders
+
+import (
+	"istio.io/api/security/v1beta1"
+	typev1beta1 "istio.io/api/type/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+)
+
+// AuthorizationPolicy returns builder for istio.io/client-go/pkg/apis/security/v1beta1/AuthorizationPolicy type
+func AuthorizationPolicy() *authorizationPolicy {
+	return &authorizationPolicy{
+		value: &securityv1beta1.AuthorizationPolicy{},
+	}
+}
+
+type authorizationPolicy struct {
+	value *securityv1beta1.AuthorizationPolicy
+}
+
+func (ap *authorizationPolicy) Get() *securityv1beta1.AuthorizationPolicy {
+	return ap.value
+}
+
+func (ap *authorizationPolicy) Name(val string) *authorizationPolicy {
+	ap.value.Name = val
+	return ap
+}
+
+func (ap *authorizationPolicy) Namespace(val string) *authorizationPolicy {
+	ap.value.Namespace = val
+	return ap
+}
+
+func (ap *authorizationPolicy) Label(key, val string) *authorizationPolicy {
+	if ap.value.Labels == nil {
+		ap.value.Labels = make(map[string]string)
+	}
+	ap.value.Labels[key] = val
+	return ap
+}
+
+func (ap *authorizationPolicy) Spec(val *authorizationPolicySpec) *authorizationPolicy {
+	ap.value.Spec = *val.Get()
+	return ap
+}
+
+// AuthorizationPolicySpec returns builder for istio.io/api/security/v1beta1/AuthorizationPolicy type
+func AuthorizationPolicySpec() *authorizationPolicySpec {
+	return &authorizationPolicySpec{
+		value: &v1beta1.AuthorizationPolicy{},
+	}
+}
+
+type authorizationPolicySpec struct {
+	value *v1beta1.AuthorizationPolicy
+}
+
+func (aps *authorizationPolicySpec) Get() *v1beta1.AuthorizationPolicy {
+	return aps.value
+}
+
+func (aps *authorizationPolicySpec) Selector(val map[string]string) *authorizationPolicySpec {
+	aps.value.Selector = &typev1beta1.WorkloadSelector{MatchLabels: val}
+	return aps
+}
+
+func (aps *authorizationPolicySpec) Deny() *authorizationPolicySpec {
+	aps.value.Action = v1beta1.AuthorizationPolicy_DENY
+	return aps
+}
+
+// DenyRemoteIPs adds the rule matching the requests with the methods to the paths of the hosts sent by clients with the remote addresses
+// in the blocks. Requests with any method are matched if no methods are given
+func (aps *authorizationPolicySpec) DenyRemoteIPs(hosts, paths, methods, ipBlocks []string) *authorizationPolicySpec {
+	return aps.rule(hosts, paths, methods, &v1beta1.Source{RemoteIpBlocks: ipBlocks})
+}
+
+// DenyRemoteIPsExcept adds the rule matching the requests with the methods to the paths of the hosts sent by clients with remote addresses
+// outside the blocks. Requests with any method are matched if no methods are given
+func (aps *authorizationPolicySpec) DenyRemoteIPsExcept(hosts, paths, methods, ipBlocks []string) *authorizationPolicySpec {
+	return aps.rule(hosts, paths, methods, &v1beta1.Source{NotRemoteIpBlocks: ipBlocks})
+}
+
+// DenyUnauthenticated adds the rule matching the requests with the methods to the paths of the hosts which carry no valid JWT.
+// Requests with any method are matched if no methods are given
+func (aps *authorizationPolicySpec) DenyUnauthenticated(hosts, paths, methods []string) *authorizationPolicySpec {
+	return aps.rule(hosts, paths, methods, &v1beta1.Source{NotRequestPrincipals: []string{"*"}})
+}
+
+// DenyWithoutClaimValue adds the rule matching the requests with the methods to the paths of the hosts whose JWT claim doesn't hold the value.
+// Requests with any method are matched if no methods are given
+func (aps *authorizationPolicySpec) DenyWithoutClaimValue(hosts, paths, methods []string, claim, value string) *authorizationPolicySpec {
+	aps.rule(hosts, paths, methods, nil)
+	rule := aps.value.Rules[len(aps.value.Rules)-1]
+	rule.When = []*v1beta1.Condition{{Key: "request.auth.claims[" + claim + "]", NotValues: []string{value}}}
+	return aps
+}
+
+func (aps *authorizationPolicySpec) rule(hosts, paths, methods []string, source *v1beta1.Source) *authorizationPolicySpec {
+	rule := &v1beta1.Rule{
+		To: []*v1beta1.Rule_To{{Operation: &v1beta1.Operation{Hosts: hosts, Paths: paths, Methods: methods}}},
+	}
+	if source != nil {
+		rule.From = []*v1beta1.Rule_From{{Source: source}}
+	}
+	aps.value.Rules = append(aps.value.Rules, rule)
+	return aps
+}
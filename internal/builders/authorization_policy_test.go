@@ -0,0 +1,58 @@
+package builders
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"istio.io/api/security/v1beta1"
+)
+
+var _ = Describe("Builder for", func() {
+
+	Describe("AuthorizationPolicy", func() {
+		It("should build the object", func() {
+			name := "testName"
+			namespace := "testNs"
+			hosts := []string{"foo.bar", "foo.bar:*"}
+
+			ap := AuthorizationPolicy().Name(name).Namespace(namespace).
+				Label("key", "value").
+				Spec(AuthorizationPolicySpec().
+					Selector(map[string]string{"istio": "ingressgateway"}).
+					Deny().
+					DenyRemoteIPsExcept(hosts, []string{"/admin/*"}, []string{"POST"}, []string{"10.0.0.0/8"}).
+					DenyRemoteIPs(hosts, []string{"/admin/*"}, nil, []string{"10.1.0.0/16"})).
+				Get()
+
+			Expect(ap.Name).To(Equal(name))
+			Expect(ap.Namespace).To(Equal(namespace))
+			Expect(ap.Labels).To(HaveKeyWithValue("key", "value"))
+			Expect(ap.Spec.Selector.MatchLabels).To(HaveKeyWithValue("istio", "ingressgateway"))
+			Expect(ap.Spec.Action).To(Equal(v1beta1.AuthorizationPolicy_DENY))
+			Expect(ap.Spec.Rules).To(HaveLen(2))
+			Expect(ap.Spec.Rules[0].From[0].Source.NotRemoteIpBlocks).To(ConsistOf("10.0.0.0/8"))
+			Expect(ap.Spec.Rules[0].To[0].Operation.Hosts).To(Equal(hosts))
+			Expect(ap.Spec.Rules[0].To[0].Operation.Paths).To(ConsistOf("/admin/*"))
+			Expect(ap.Spec.Rules[0].To[0].Operation.Methods).To(ConsistOf("POST"))
+			Expect(ap.Spec.Rules[1].From[0].Source.RemoteIpBlocks).To(ConsistOf("10.1.0.0/16"))
+			Expect(ap.Spec.Rules[1].To[0].Operation.Methods).To(BeEmpty())
+		})
+
+		It("should build the rules requiring a JWT", func() {
+			hosts := []string{"foo.bar", "foo.bar:*"}
+
+			spec := AuthorizationPolicySpec().
+				Deny().
+				DenyUnauthenticated(hosts, []string{"/helloworld.Greeter/*"}, []string{"POST"}).
+				DenyWithoutClaimValue(hosts, []string{"/helloworld.Greeter/*"}, []string{"POST"}, "scp", "read").
+				Get()
+
+			Expect(spec.Rules).To(HaveLen(2))
+			Expect(spec.Rules[0].From[0].Source.NotRequestPrincipals).To(ConsistOf("*"))
+			Expect(spec.Rules[0].To[0].Operation.Paths).To(ConsistOf("/helloworld.Greeter/*"))
+			Expect(spec.Rules[1].From).To(BeEmpty())
+			Expect(spec.Rules[1].When).To(HaveLen(1))
+			Expect(spec.Rules[1].When[0].Key).To(Equal("request.auth.claims[scp]"))
+			Expect(spec.Rules[1].When[0].NotValues).To(ConsistOf("read"))
+		})
+	})
+})
@@ -0,0 +1,129 @@
+package builders
+
+import (
+	"istio.io/api/networking/v1beta1"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+)
+
+// DestinationRule returns builder for istio.io/client-go/pkg/apis/networking/v1beta1/DestinationRule type
+func DestinationRule() *destinationRule {
+	return &destinationRule{
+		value: &networkingv1beta1.DestinationRule{},
+	}
+}
+
+type destinationRule struct {
+	value *networkingv1beta1.DestinationRule
+}
+
+func (dr *destinationRule) Get() *networkingv1beta1.DestinationRule {
+	return dr.value
+}
+
+func (dr *destinationRule) Name(val string) *destinationRule {
+	dr.value.Name = val
+	return dr
+}
+
+func (dr *destinationRule) Namespace(val string) *destinationRule {
+	dr.value.Namespace = val
+	return dr
+}
+
+func (dr *destinationRule) Owner(val *ownerReference) *destinationRule {
+	dr.value.OwnerReferences = append(dr.value.OwnerReferences, *val.Get())
+	return dr
+}
+
+func (dr *destinationRule) Label(key, val string) *destinationRule {
+	if dr.value.Labels == nil {
+		dr.value.Labels = make(map[string]string)
+	}
+	dr.value.Labels[key] = val
+	return dr
+}
+
+func (dr *destinationRule) Spec(val *destinationRuleSpec) *destinationRule {
+	dr.value.Spec = *val.Get()
+	return dr
+}
+
+// DestinationRuleSpec returns builder for istio.io/api/networking/v1beta1/DestinationRule type
+func DestinationRuleSpec() *destinationRuleSpec {
+	return &destinationRuleSpec{
+		value: &v1beta1.DestinationRule{},
+	}
+}
+
+type destinationRuleSpec struct {
+	value *v1beta1.DestinationRule
+}
+
+func (drs *destinationRuleSpec) Get() *v1beta1.DestinationRule {
+	return drs.value
+}
+
+func (drs *destinationRuleSpec) Host(val string) *destinationRuleSpec {
+	drs.value.Host = val
+	return drs
+}
+
+// ExportTo sets the namespaces whose proxies apply the Destination Rule, "." being its own namespace
+func (drs *destinationRuleSpec) ExportTo(namespaces ...string) *destinationRuleSpec {
+	drs.value.ExportTo = namespaces
+	return drs
+}
+
+// PortTLS sets the TLS settings of the connections to the given port
+func (drs *destinationRuleSpec) PortTLS(port uint32, tls *clientTLSSettings) *destinationRuleSpec {
+	drs.portSettings(port).Tls = tls.Get()
+	return drs
+}
+
+// PortHTTP2Upgrade makes the connections to the given port use HTTP/2, whatever the protocol of the requests
+func (drs *destinationRuleSpec) PortHTTP2Upgrade(port uint32) *destinationRuleSpec {
+	drs.portSettings(port).ConnectionPool = &v1beta1.ConnectionPoolSettings{
+		Http: &v1beta1.ConnectionPoolSettings_HTTPSettings{H2UpgradePolicy: v1beta1.ConnectionPoolSettings_HTTPSettings_UPGRADE},
+	}
+	return drs
+}
+
+// portSettings returns the traffic policy of the given port, which is added if it isn't set yet
+func (drs *destinationRuleSpec) portSettings(port uint32) *v1beta1.TrafficPolicy_PortTrafficPolicy {
+	if drs.value.TrafficPolicy == nil {
+		drs.value.TrafficPolicy = &v1beta1.TrafficPolicy{}
+	}
+	for _, settings := range drs.value.TrafficPolicy.PortLevelSettings {
+		if settings.Port.GetNumber() == port {
+			return settings
+		}
+	}
+	settings := &v1beta1.TrafficPolicy_PortTrafficPolicy{Port: &v1beta1.PortSelector{Number: port}}
+	drs.value.TrafficPolicy.PortLevelSettings = append(drs.value.TrafficPolicy.PortLevelSettings, settings)
+	return settings
+}
+
+// ClientTLSSettings returns builder for istio.io/api/networking/v1beta1/ClientTLSSettings type
+func ClientTLSSettings() *clientTLSSettings {
+	return &clientTLSSettings{
+		value: &v1beta1.ClientTLSSettings{},
+	}
+}
+
+type clientTLSSettings struct {
+	value *v1beta1.ClientTLSSettings
+}
+
+func (ts *clientTLSSettings) Get() *v1beta1.ClientTLSSettings {
+	return ts.value
+}
+
+func (ts *clientTLSSettings) Mode(val v1beta1.ClientTLSSettings_TLSmode) *clientTLSSettings {
+	ts.value.Mode = val
+	return ts
+}
+
+func (ts *clientTLSSettings) CredentialName(val string) *clientTLSSettings {
+	ts.value.CredentialName = val
+	return ts
+}
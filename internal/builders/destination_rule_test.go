@@ -0,0 +1,53 @@
+package builders
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"istio.io/api/networking/v1beta1"
+)
+
+var _ = Describe("Builder for", func() {
+
+	Describe("DestinationRule", func() {
+		It("should build the object", func() {
+			name := "testName"
+			namespace := "testNs"
+			host := "somehost.somenamespace.svc.cluster.local"
+			var port uint32 = 8443
+			credentialName := "somehost-client"
+
+			dr := DestinationRule().Name(name).Namespace(namespace).
+				Owner(OwnerReference().Name("refName").APIVersion("v1alpha1").Kind("APIRule").UID("123").Controller(true)).
+				Label("key", "value").
+				Spec(DestinationRuleSpec().
+					Host(host).
+					ExportTo(".", "istio-system").
+					PortTLS(port, ClientTLSSettings().Mode(v1beta1.ClientTLSSettings_MUTUAL).CredentialName(credentialName))).
+				Get()
+
+			Expect(dr.Name).To(Equal(name))
+			Expect(dr.Namespace).To(Equal(namespace))
+			Expect(dr.OwnerReferences).To(HaveLen(1))
+			Expect(dr.Labels).To(HaveKeyWithValue("key", "value"))
+			Expect(dr.Spec.Host).To(Equal(host))
+			Expect(dr.Spec.ExportTo).To(Equal([]string{".", "istio-system"}))
+			Expect(dr.Spec.TrafficPolicy.PortLevelSettings).To(HaveLen(1))
+			Expect(dr.Spec.TrafficPolicy.PortLevelSettings[0].Port.Number).To(Equal(port))
+			Expect(dr.Spec.TrafficPolicy.PortLevelSettings[0].Tls.Mode).To(Equal(v1beta1.ClientTLSSettings_MUTUAL))
+			Expect(dr.Spec.TrafficPolicy.PortLevelSettings[0].Tls.CredentialName).To(Equal(credentialName))
+		})
+
+		It("should merge the settings of the same port", func() {
+			var port uint32 = 8443
+
+			spec := DestinationRuleSpec().
+				PortTLS(port, ClientTLSSettings().Mode(v1beta1.ClientTLSSettings_ISTIO_MUTUAL)).
+				PortHTTP2Upgrade(port).
+				Get()
+
+			Expect(spec.TrafficPolicy.PortLevelSettings).To(HaveLen(1))
+			Expect(spec.TrafficPolicy.PortLevelSettings[0].Tls.Mode).To(Equal(v1beta1.ClientTLSSettings_ISTIO_MUTUAL))
+			Expect(spec.TrafficPolicy.PortLevelSettings[0].ConnectionPool.Http.H2UpgradePolicy).To(Equal(v1beta1.ConnectionPoolSettings_HTTPSettings_UPGRADE))
+		})
+	})
+})
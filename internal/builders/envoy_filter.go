@@ -0,0 +1,115 @@
+package builders
+
+import (
+	"encoding/json"
+
+	"github.com/gogo/protobuf/types"
+	"istio.io/api/networking/v1alpha3"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+)
+
+// EnvoyFilter returns builder for istio.io/client-go/pkg/apis/networking/v1alpha3/EnvoyFilter type
+func EnvoyFilter() *envoyFilter {
+	return &envoyFilter{
+		value: &networkingv1alpha3.EnvoyFilter{},
+	}
+}
+
+type envoyFilter struct {
+	value *networkingv1alpha3.EnvoyFilter
+}
+
+func (ef *envoyFilter) Get() *networkingv1alpha3.EnvoyFilter {
+	return ef.value
+}
+
+func (ef *envoyFilter) Name(val string) *envoyFilter {
+	ef.value.Name = val
+	return ef
+}
+
+func (ef *envoyFilter) Namespace(val string) *envoyFilter {
+	ef.value.Namespace = val
+	return ef
+}
+
+func (ef *envoyFilter) Label(key, val string) *envoyFilter {
+	if ef.value.Labels == nil {
+		ef.value.Labels = make(map[string]string)
+	}
+	ef.value.Labels[key] = val
+	return ef
+}
+
+func (ef *envoyFilter) Spec(val *envoyFilterSpec) *envoyFilter {
+	ef.value.Spec = *val.Get()
+	return ef
+}
+
+// EnvoyFilterSpec returns builder for istio.io/api/networking/v1alpha3/EnvoyFilter type
+func EnvoyFilterSpec() *envoyFilterSpec {
+	return &envoyFilterSpec{
+		value: &v1alpha3.EnvoyFilter{},
+	}
+}
+
+type envoyFilterSpec struct {
+	value *v1alpha3.EnvoyFilter
+}
+
+func (efs *envoyFilterSpec) Get() *v1alpha3.EnvoyFilter {
+	return efs.value
+}
+
+func (efs *envoyFilterSpec) WorkloadSelector(val map[string]string) *envoyFilterSpec {
+	efs.value.WorkloadSelector = &v1alpha3.WorkloadSelector{Labels: val}
+	return efs
+}
+
+// GatewayRoutePatch merges the value into the configuration of the route with the given name on the gateway.
+// Returns an error if the value can't be converted to the patch, in which case no patch is added
+func (efs *envoyFilterSpec) GatewayRoutePatch(routeName string, value map[string]interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{"operation": "MERGE", "value": value})
+	if err != nil {
+		return err
+	}
+	patch := &v1alpha3.EnvoyFilter_Patch{}
+	if err := patch.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	efs.gatewayRoutePatch(routeName, patch)
+	return nil
+}
+
+// GatewayRouteWebSocketUpgrade enables the upgrade of requests to WebSocket connections on the route with the given name on the gateway
+func (efs *envoyFilterSpec) GatewayRouteWebSocketUpgrade(routeName string) *envoyFilterSpec {
+	upgrade := &types.Struct{Fields: map[string]*types.Value{
+		"upgrade_type": {Kind: &types.Value_StringValue{StringValue: "websocket"}},
+		"enabled":      {Kind: &types.Value_BoolValue{BoolValue: true}},
+	}}
+	route := &types.Struct{Fields: map[string]*types.Value{
+		"upgrade_configs": {Kind: &types.Value_ListValue{ListValue: &types.ListValue{Values: []*types.Value{{Kind: &types.Value_StructValue{StructValue: upgrade}}}}}},
+	}}
+	efs.gatewayRoutePatch(routeName, &v1alpha3.EnvoyFilter_Patch{
+		Operation: v1alpha3.EnvoyFilter_Patch_MERGE,
+		Value:     &types.Struct{Fields: map[string]*types.Value{"route": {Kind: &types.Value_StructValue{StructValue: route}}}},
+	})
+	return efs
+}
+
+func (efs *envoyFilterSpec) gatewayRoutePatch(routeName string, patch *v1alpha3.EnvoyFilter_Patch) {
+	efs.value.ConfigPatches = append(efs.value.ConfigPatches, &v1alpha3.EnvoyFilter_EnvoyConfigObjectPatch{
+		ApplyTo: v1alpha3.EnvoyFilter_HTTP_ROUTE,
+		Match: &v1alpha3.EnvoyFilter_EnvoyConfigObjectMatch{
+			Context: v1alpha3.EnvoyFilter_GATEWAY,
+			ObjectTypes: &v1alpha3.EnvoyFilter_EnvoyConfigObjectMatch_RouteConfiguration{
+				RouteConfiguration: &v1alpha3.EnvoyFilter_RouteConfigurationMatch{
+					Vhost: &v1alpha3.EnvoyFilter_RouteConfigurationMatch_VirtualHostMatch{
+						Route: &v1alpha3.EnvoyFilter_RouteConfigurationMatch_RouteMatch{Name: routeName},
+					},
+				},
+			},
+		},
+		Patch: patch,
+	})
+}
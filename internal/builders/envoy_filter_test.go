@@ -0,0 +1,58 @@
+package builders
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"istio.io/api/networking/v1alpha3"
+)
+
+var _ = Describe("Builder for", func() {
+
+	Describe("EnvoyFilter", func() {
+		It("should build the object", func() {
+			name := "testName"
+			namespace := "testNs"
+			routeName := "testRoute"
+
+			spec := EnvoyFilterSpec().WorkloadSelector(map[string]string{"istio": "ingressgateway"})
+			Expect(spec.GatewayRoutePatch(routeName, map[string]interface{}{"route": map[string]interface{}{"timeout": "5s"}})).To(Succeed())
+
+			ef := EnvoyFilter().Name(name).Namespace(namespace).
+				Label("key", "value").
+				Spec(spec).
+				Get()
+
+			Expect(ef.Name).To(Equal(name))
+			Expect(ef.Namespace).To(Equal(namespace))
+			Expect(ef.Labels).To(HaveKeyWithValue("key", "value"))
+			Expect(ef.Spec.WorkloadSelector.Labels).To(HaveKeyWithValue("istio", "ingressgateway"))
+			Expect(ef.Spec.ConfigPatches).To(HaveLen(1))
+			Expect(ef.Spec.ConfigPatches[0].ApplyTo).To(Equal(v1alpha3.EnvoyFilter_HTTP_ROUTE))
+			Expect(ef.Spec.ConfigPatches[0].Match.Context).To(Equal(v1alpha3.EnvoyFilter_GATEWAY))
+			Expect(ef.Spec.ConfigPatches[0].Match.GetRouteConfiguration().Vhost.Route.Name).To(Equal(routeName))
+			Expect(ef.Spec.ConfigPatches[0].Patch.Operation).To(Equal(v1alpha3.EnvoyFilter_Patch_MERGE))
+			Expect(ef.Spec.ConfigPatches[0].Patch.Value.Fields["route"].GetStructValue().Fields["timeout"].GetStringValue()).To(Equal("5s"))
+		})
+
+		It("should return the error of a value that can't be converted to the patch", func() {
+			spec := EnvoyFilterSpec()
+
+			err := spec.GatewayRoutePatch("testRoute", map[string]interface{}{"route": make(chan int)})
+
+			Expect(err).To(HaveOccurred())
+			Expect(spec.Get().ConfigPatches).To(BeEmpty())
+		})
+
+		It("should enable the WebSocket upgrade on the route", func() {
+			spec := EnvoyFilterSpec().GatewayRouteWebSocketUpgrade("testRoute").Get()
+
+			Expect(spec.ConfigPatches).To(HaveLen(1))
+			Expect(spec.ConfigPatches[0].Match.GetRouteConfiguration().Vhost.Route.Name).To(Equal("testRoute"))
+			Expect(spec.ConfigPatches[0].Patch.Operation).To(Equal(v1alpha3.EnvoyFilter_Patch_MERGE))
+			upgrades := spec.ConfigPatches[0].Patch.Value.Fields["route"].GetStructValue().Fields["upgrade_configs"].GetListValue().Values
+			Expect(upgrades).To(HaveLen(1))
+			Expect(upgrades[0].GetStructValue().Fields["upgrade_type"].GetStringValue()).To(Equal("websocket"))
+			Expect(upgrades[0].GetStructValue().Fields["enabled"].GetBoolValue()).To(BeTrue())
+		})
+	})
+})
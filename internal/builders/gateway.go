@@ -0,0 +1,92 @@
+package builders
+
+import (
+	"istio.io/api/networking/v1beta1"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+)
+
+// Gateway returns builder for istio.io/client-go/pkg/apis/networking/v1beta1/Gateway type
+func Gateway() *gateway {
+	return &gateway{
+		value: &networkingv1beta1.Gateway{},
+	}
+}
+
+type gateway struct {
+	value *networkingv1beta1.Gateway
+}
+
+func (gw *gateway) Get() *networkingv1beta1.Gateway {
+	return gw.value
+}
+
+func (gw *gateway) Name(val string) *gateway {
+	gw.value.Name = val
+	return gw
+}
+
+func (gw *gateway) Namespace(val string) *gateway {
+	gw.value.Namespace = val
+	return gw
+}
+
+func (gw *gateway) Owner(val *ownerReference) *gateway {
+	gw.value.OwnerReferences = append(gw.value.OwnerReferences, *val.Get())
+	return gw
+}
+
+func (gw *gateway) Label(key, val string) *gateway {
+	if gw.value.Labels == nil {
+		gw.value.Labels = make(map[string]string)
+	}
+	gw.value.Labels[key] = val
+	return gw
+}
+
+func (gw *gateway) Spec(val *gatewaySpec) *gateway {
+	gw.value.Spec = *val.Get()
+	return gw
+}
+
+// GatewaySpec returns builder for istio.io/api/networking/v1beta1/Gateway type
+func GatewaySpec() *gatewaySpec {
+	return &gatewaySpec{
+		value: &v1beta1.Gateway{},
+	}
+}
+
+type gatewaySpec struct {
+	value *v1beta1.Gateway
+}
+
+func (gs *gatewaySpec) Get() *v1beta1.Gateway {
+	return gs.value
+}
+
+func (gs *gatewaySpec) Selector(val map[string]string) *gatewaySpec {
+	gs.value.Selector = val
+	return gs
+}
+
+// HTTPSServer adds the server terminating TLS for the host with the certificate in the Secret named credentialName
+func (gs *gatewaySpec) HTTPSServer(host, credentialName string) *gatewaySpec {
+	gs.value.Servers = append(gs.value.Servers, &v1beta1.Server{
+		Port:  &v1beta1.Port{Number: 443, Protocol: "HTTPS", Name: "https"},
+		Hosts: []string{host},
+		Tls: &v1beta1.ServerTLSSettings{
+			Mode:           v1beta1.ServerTLSSettings_SIMPLE,
+			CredentialName: credentialName,
+		},
+	})
+	return gs
+}
+
+// HTTPRedirectServer adds the server redirecting plain HTTP requests for the host to HTTPS
+func (gs *gatewaySpec) HTTPRedirectServer(host string) *gatewaySpec {
+	gs.value.Servers = append(gs.value.Servers, &v1beta1.Server{
+		Port:  &v1beta1.Port{Number: 80, Protocol: "HTTP", Name: "http"},
+		Hosts: []string{host},
+		Tls:   &v1beta1.ServerTLSSettings{HttpsRedirect: true},
+	})
+	return gs
+}
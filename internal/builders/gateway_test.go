@@ -0,0 +1,42 @@
+package builders
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"istio.io/api/networking/v1beta1"
+)
+
+var _ = Describe("Builder for", func() {
+
+	Describe("Gateway", func() {
+		It("should build the object", func() {
+			host := "shop.example.com"
+			selector := map[string]string{"istio": "ingressgateway"}
+
+			gw := Gateway().Name("testName").Namespace("testNs").
+				Owner(OwnerReference().Name("refName").APIVersion("v1alpha1").Kind("APIRule").UID("123").Controller(true)).
+				Label("key", "value").
+				Spec(GatewaySpec().
+					Selector(selector).
+					HTTPSServer(host, "shop-tls").
+					HTTPRedirectServer(host)).
+				Get()
+
+			Expect(gw.Name).To(Equal("testName"))
+			Expect(gw.Namespace).To(Equal("testNs"))
+			Expect(gw.OwnerReferences).To(HaveLen(1))
+			Expect(gw.Labels).To(HaveKeyWithValue("key", "value"))
+			Expect(gw.Spec.Selector).To(Equal(selector))
+			Expect(gw.Spec.Servers).To(HaveLen(2))
+
+			Expect(gw.Spec.Servers[0].Port.Number).To(Equal(uint32(443)))
+			Expect(gw.Spec.Servers[0].Hosts).To(Equal([]string{host}))
+			Expect(gw.Spec.Servers[0].Tls.Mode).To(Equal(v1beta1.ServerTLSSettings_SIMPLE))
+			Expect(gw.Spec.Servers[0].Tls.CredentialName).To(Equal("shop-tls"))
+
+			Expect(gw.Spec.Servers[1].Port.Number).To(Equal(uint32(80)))
+			Expect(gw.Spec.Servers[1].Hosts).To(Equal([]string{host}))
+			Expect(gw.Spec.Servers[1].Tls.HttpsRedirect).To(BeTrue())
+		})
+	})
+})
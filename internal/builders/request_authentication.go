@@ -0,0 +1,77 @@
+package builders
+
+import (
+	"istio.io/api/security/v1beta1"
+	typev1beta1 "istio.io/api/type/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+)
+
+// RequestAuthentication returns builder for istio.io/client-go/pkg/apis/security/v1beta1/RequestAuthentication type
+func RequestAuthentication() *requestAuthentication {
+	return &requestAuthentication{
+		value: &securityv1beta1.RequestAuthentication{},
+	}
+}
+
+type requestAuthentication struct {
+	value *securityv1beta1.RequestAuthentication
+}
+
+func (ra *requestAuthentication) Get() *securityv1beta1.RequestAuthentication {
+	return ra.value
+}
+
+func (ra *requestAuthentication) Name(val string) *requestAuthentication {
+	ra.value.Name = val
+	return ra
+}
+
+func (ra *requestAuthentication) Namespace(val string) *requestAuthentication {
+	ra.value.Namespace = val
+	return ra
+}
+
+func (ra *requestAuthentication) Label(key, val string) *requestAuthentication {
+	if ra.value.Labels == nil {
+		ra.value.Labels = make(map[string]string)
+	}
+	ra.value.Labels[key] = val
+	return ra
+}
+
+func (ra *requestAuthentication) Spec(val *requestAuthenticationSpec) *requestAuthentication {
+	ra.value.Spec = *val.Get()
+	return ra
+}
+
+// RequestAuthenticationSpec returns builder for istio.io/api/security/v1beta1/RequestAuthentication type
+func RequestAuthenticationSpec() *requestAuthenticationSpec {
+	return &requestAuthenticationSpec{
+		value: &v1beta1.RequestAuthentication{},
+	}
+}
+
+type requestAuthenticationSpec struct {
+	value *v1beta1.RequestAuthentication
+}
+
+func (ras *requestAuthenticationSpec) Get() *v1beta1.RequestAuthentication {
+	return ras.value
+}
+
+func (ras *requestAuthenticationSpec) Selector(val map[string]string) *requestAuthenticationSpec {
+	ras.value.Selector = &typev1beta1.WorkloadSelector{MatchLabels: val}
+	return ras
+}
+
+// JWTRule adds the rule validating the JWTs of the issuer with the keys served at the URI. A rule of the same issuer is added only once.
+// The token is kept in the request, so Oathkeeper and the services behind the gateway can read it too
+func (ras *requestAuthenticationSpec) JWTRule(issuer, jwksURI string) *requestAuthenticationSpec {
+	for _, rule := range ras.value.JwtRules {
+		if rule.Issuer == issuer {
+			return ras
+		}
+	}
+	ras.value.JwtRules = append(ras.value.JwtRules, &v1beta1.JWTRule{Issuer: issuer, JwksUri: jwksURI, ForwardOriginalToken: true})
+	return ras
+}
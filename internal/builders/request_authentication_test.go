@@ -0,0 +1,35 @@
+package builders
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Builder for", func() {
+
+	Describe("RequestAuthentication", func() {
+		It("should build the object", func() {
+			name := "testName"
+			namespace := "testNs"
+
+			ra := RequestAuthentication().Name(name).Namespace(namespace).
+				Label("key", "value").
+				Spec(RequestAuthenticationSpec().
+					Selector(map[string]string{"istio": "ingressgateway"}).
+					JWTRule("https://issuer.example.com", "https://issuer.example.com/jwks").
+					JWTRule("https://issuer.example.com", "https://other.example.com/jwks").
+					JWTRule("https://dex.example.com", "https://dex.example.com/keys")).
+				Get()
+
+			Expect(ra.Name).To(Equal(name))
+			Expect(ra.Namespace).To(Equal(namespace))
+			Expect(ra.Labels).To(HaveKeyWithValue("key", "value"))
+			Expect(ra.Spec.Selector.MatchLabels).To(HaveKeyWithValue("istio", "ingressgateway"))
+			Expect(ra.Spec.JwtRules).To(HaveLen(2))
+			Expect(ra.Spec.JwtRules[0].Issuer).To(Equal("https://issuer.example.com"))
+			Expect(ra.Spec.JwtRules[0].JwksUri).To(Equal("https://issuer.example.com/jwks"))
+			Expect(ra.Spec.JwtRules[0].ForwardOriginalToken).To(BeTrue())
+			Expect(ra.Spec.JwtRules[1].Issuer).To(Equal("https://dex.example.com"))
+		})
+	})
+})
@@ -109,6 +109,11 @@ func (hr *httpRoute) Get() *v1beta1.HTTPRoute {
 	return hr.value
 }
 
+func (hr *httpRoute) Name(val string) *httpRoute {
+	hr.value.Name = val
+	return hr
+}
+
 func (hr *httpRoute) Match(mr *matchRequest) *httpRoute {
 	hr.value.Match = append(hr.value.Match, mr.Get())
 	return hr
@@ -215,6 +220,15 @@ func (cp *corsPolicy) AllowMethods(val ...string) *corsPolicy {
 	return cp
 }
 
+func (cp *corsPolicy) ExposeHeaders(val ...string) *corsPolicy {
+	if len(val) == 0 {
+		cp.value.ExposeHeaders = nil
+	} else {
+		cp.value.ExposeHeaders = append(cp.value.ExposeHeaders, val...)
+	}
+	return cp
+}
+
 func (cp *corsPolicy) AllowOrigins(val ...*v1beta1.StringMatch) *corsPolicy {
 	if len(val) == 0 {
 		cp.value.AllowOrigins = nil
@@ -0,0 +1,267 @@
+package catalogue
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/claims"
+	"github.com/kyma-incubator/api-gateway/internal/handlers"
+	"github.com/kyma-incubator/api-gateway/internal/openapi"
+	"github.com/kyma-incubator/api-gateway/internal/types/ory"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+//Catalogue lists the routes exposed by APIRules
+type Catalogue struct {
+	Routes []Route `json:"routes"`
+}
+
+//Route is a rule of an APIRule exposed on a host
+type Route struct {
+	//APIRule defining the route, in the namespace/name format
+	APIRule string `json:"apiRule"`
+	Host    string `json:"host"`
+	Gateway string `json:"gateway,omitempty"`
+	//Path is the regular expression matching the paths of requests
+	Path             string           `json:"path"`
+	Methods          []string         `json:"methods"`
+	AccessStrategies []AccessStrategy `json:"accessStrategies"`
+	Mutators         []string         `json:"mutators,omitempty"`
+	//Service the requests are forwarded to, in the host:port format
+	Service string `json:"service,omitempty"`
+	//Status of the APIRule
+	Status            gatewayv1alpha1.StatusCode `json:"status,omitempty"`
+	StatusDescription string                     `json:"statusDescription,omitempty"`
+}
+
+//AccessStrategy is an access strategy of a route with the scopes and the issuers it requires
+type AccessStrategy struct {
+	Handler        string   `json:"handler"`
+	Scopes         []string `json:"scopes,omitempty"`
+	TrustedIssuers []string `json:"trustedIssuers,omitempty"`
+}
+
+//Build lists the routes of the APIRules ordered by host, APIRule and the order of the rules
+func Build(apis []gatewayv1alpha1.APIRule, defaultDomainName string) *Catalogue {
+	c := &Catalogue{Routes: []Route{}}
+	for i := range apis {
+		api := &apis[i]
+		for _, rule := range api.Spec.Rules {
+			c.Routes = append(c.Routes, route(api, rule, defaultDomainName))
+		}
+	}
+	sort.SliceStable(c.Routes, func(i, j int) bool {
+		if c.Routes[i].Host != c.Routes[j].Host {
+			return c.Routes[i].Host < c.Routes[j].Host
+		}
+		return c.Routes[i].APIRule < c.Routes[j].APIRule
+	})
+	return c
+}
+
+func route(api *gatewayv1alpha1.APIRule, rule gatewayv1alpha1.Rule, defaultDomainName string) Route {
+	r := Route{
+		APIRule: types.NamespacedName{Namespace: api.Namespace, Name: api.Name}.String(),
+		Host:    claims.Host(api, defaultDomainName),
+		Path:    rule.MatchPath(),
+		Methods: rule.Methods,
+	}
+	if api.Spec.Gateway != nil {
+		r.Gateway = *api.Spec.Gateway
+	}
+	if service := api.Spec.Service; service != nil && service.Name != nil && service.Port != nil {
+		r.Service = fmt.Sprintf("%s.%s.svc.cluster.local:%d", *service.Name, api.ServiceNamespace(), *service.Port)
+	}
+	if status := api.Status.APIRuleStatus; status != nil {
+		r.Status = status.Code
+		r.StatusDescription = status.Description
+	}
+	for _, strategy := range rule.AccessStrategies {
+		if strategy == nil || strategy.Handler == nil {
+			continue
+		}
+		s := AccessStrategy{Handler: strategy.Name}
+		if strategy.Config != nil {
+			//Scopes and issuers are read from the configuration of the jwt and oauth2_introspection handlers
+			var config ory.JwtConfig
+			if err := json.Unmarshal(strategy.Config.Raw, &config); err == nil {
+				s.Scopes = config.RequiredScope
+				s.TrustedIssuers = config.TrustedIssuer
+			}
+		}
+		r.AccessStrategies = append(r.AccessStrategies, s)
+	}
+	for _, mutator := range rule.Mutators {
+		if mutator != nil && mutator.Handler != nil {
+			r.Mutators = append(r.Mutators, mutator.Name)
+		}
+	}
+	return r
+}
+
+//OpenAPI returns OpenAPI 3 skeletons of the hosts ordered by host. The security requirements of the operations are filled in from the
+//access strategies: jwt maps to openIdConnect schemes of the trusted issuers, or to JWT bearer tokens if no issuer is configured,
+//oauth2_introspection maps to bearer tokens, and public access strategies to an optional security requirement. Access strategies with no
+//counterpart in OpenAPI are listed in the x-kyma-access-strategies extension of the operations only.
+func (c *Catalogue) OpenAPI() []*openapi.Document {
+	var docs []*openapi.Document
+	var doc *openapi.Document
+	var schemes *securitySchemes
+	for _, r := range c.Routes {
+		if doc == nil || doc.Info.Title != r.Host {
+			doc = &openapi.Document{
+				OpenAPI: "3.0.3",
+				Info:    &openapi.Info{Title: r.Host, Version: "1.0.0"},
+				Servers: []openapi.Server{{URL: "https://" + r.Host}},
+				Paths:   map[string]openapi.PathItem{},
+			}
+			schemes = &securitySchemes{doc: doc}
+			docs = append(docs, doc)
+		}
+
+		key := pathTemplate(r.Path)
+		if _, exists := doc.Paths[key]; exists {
+			key = r.Path
+		}
+		item := openapi.PathItem{XPathRegex: r.Path}
+		for _, method := range r.Methods {
+			op := &openapi.Operation{
+				Security:  schemes.requirements(r.AccessStrategies),
+				Responses: map[string]openapi.Response{"default": {Description: "Response of the service"}},
+			}
+			for _, strategy := range r.AccessStrategies {
+				op.XAccessStrategies = append(op.XAccessStrategies, strategy.Handler)
+			}
+			item.SetOperation(method, op)
+		}
+		doc.Paths[key] = item
+	}
+	return docs
+}
+
+//WriteOpenAPI writes the OpenAPI documents as YAML separated with the document separator
+func WriteOpenAPI(w io.Writer, docs []*openapi.Document) error {
+	for i, doc := range docs {
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		data, err := yaml.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//securitySchemes registers the security schemes of a document under unique names
+type securitySchemes struct {
+	doc *openapi.Document
+}
+
+func (s *securitySchemes) requirements(strategies []AccessStrategy) *[]openapi.SecurityRequirement {
+	var res []openapi.SecurityRequirement
+	public := false
+	for _, strategy := range strategies {
+		scopes := strategy.Scopes
+		if scopes == nil {
+			scopes = []string{}
+		}
+		switch {
+		case handlers.IsPublic(strategy.Handler):
+			public = true
+		case strategy.Handler == "jwt" && len(strategy.TrustedIssuers) == 0:
+			name := s.register("jwt", openapi.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"})
+			res = append(res, openapi.SecurityRequirement{name: scopes})
+		case strategy.Handler == "jwt":
+			for _, issuer := range strategy.TrustedIssuers {
+				name := s.register("oidc_"+issuerName(issuer), openapi.SecurityScheme{
+					Type:             "openIdConnect",
+					OpenIDConnectURL: strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration",
+				})
+				res = append(res, openapi.SecurityRequirement{name: scopes})
+			}
+		case strategy.Handler == "oauth2_introspection":
+			name := s.register("oauth2_introspection", openapi.SecurityScheme{Type: "http", Scheme: "bearer"})
+			res = append(res, openapi.SecurityRequirement{name: scopes})
+		}
+	}
+	if public {
+		if len(res) == 0 {
+			return &[]openapi.SecurityRequirement{}
+		}
+		res = append(res, openapi.SecurityRequirement{})
+	}
+	if res == nil {
+		return nil
+	}
+	return &res
+}
+
+//register adds the scheme to the document under the name, or under the name with a number if another scheme has the name already
+func (s *securitySchemes) register(name string, scheme openapi.SecurityScheme) string {
+	if s.doc.Components.SecuritySchemes == nil {
+		s.doc.Components.SecuritySchemes = map[string]openapi.SecurityScheme{}
+	}
+	candidate := name
+	for i := 2; ; i++ {
+		existing, ok := s.doc.Components.SecuritySchemes[candidate]
+		if !ok {
+			s.doc.Components.SecuritySchemes[candidate] = scheme
+			return candidate
+		}
+		if existing == scheme {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+}
+
+var invalidSchemeNameCharacters = regexp.MustCompile(`[^a-zA-Z0-9.\-_]+`)
+
+func issuerName(issuer string) string {
+	if u, err := url.Parse(issuer); err == nil && u.Host != "" {
+		issuer = u.Host
+	}
+	return invalidSchemeNameCharacters.ReplaceAllString(issuer, "_")
+}
+
+const segmentPattern = "[^/]+"
+
+//pathTemplate converts the path regular expression to an OpenAPI path template. Patterns matching a single path segment become parameters.
+//Paths with other patterns can't be expressed as templates and are returned as they are.
+func pathTemplate(path string) string {
+	var sb strings.Builder
+	param := 0
+	for i := 0; i < len(path); i++ {
+		switch {
+		case strings.HasPrefix(path[i:], segmentPattern):
+			param++
+			fmt.Fprintf(&sb, "{param%d}", param)
+			i += len(segmentPattern) - 1
+		case path[i] == '\\' && i+1 < len(path) && !isAlphanumeric(path[i+1]):
+			i++
+			sb.WriteByte(path[i])
+		case strings.IndexByte(`.*+?()[]{}|^$`, path[i]) >= 0:
+			return path
+		default:
+			sb.WriteByte(path[i])
+		}
+	}
+	return sb.String()
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
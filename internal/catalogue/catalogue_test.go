@@ -0,0 +1,171 @@
+package catalogue
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/openapi"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCatalogue(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Catalogue Suite")
+}
+
+func handler(name, config string) *gatewayv1alpha1.Handler {
+	h := &gatewayv1alpha1.Handler{Name: name}
+	if config != "" {
+		h.Config = &runtime.RawExtension{Raw: []byte(config)}
+	}
+	return h
+}
+
+func apiRule(namespace, name, host string, rules ...gatewayv1alpha1.Rule) gatewayv1alpha1.APIRule {
+	serviceName, gateway, port := name, "kyma-gateway.kyma-system.svc.cluster.local", uint32(8000)
+	return gatewayv1alpha1.APIRule{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: gatewayv1alpha1.APIRuleSpec{
+			Service: &gatewayv1alpha1.Service{Name: &serviceName, Port: &port, Host: &host},
+			Gateway: &gateway,
+			Rules:   rules,
+		},
+		Status: gatewayv1alpha1.APIRuleStatus{
+			APIRuleStatus: &gatewayv1alpha1.APIRuleResourceStatus{Code: gatewayv1alpha1.StatusOK},
+		},
+	}
+}
+
+func rule(path string, methods []string, handlers ...*gatewayv1alpha1.Handler) gatewayv1alpha1.Rule {
+	r := gatewayv1alpha1.Rule{Path: path, Methods: methods}
+	for _, h := range handlers {
+		r.AccessStrategies = append(r.AccessStrategies, &gatewayv1alpha1.Authenticator{Handler: h})
+	}
+	return r
+}
+
+var _ = Describe("Catalogue", func() {
+
+	apis := []gatewayv1alpha1.APIRule{
+		apiRule("shop", "orders", "orders",
+			rule("/orders/[^/]+", []string{"GET", "DELETE"}, handler("jwt", `{"trusted_issuers":["https://dex.kyma.local"],"required_scope":["orders"]}`)),
+			rule("/health", []string{"GET"}, handler("noop", "")),
+		),
+		apiRule("default", "httpbin", "httpbin.kyma.local",
+			rule("/.*", []string{"GET"}, handler("oauth2_introspection", `{"required_scope":["read"]}`), handler("allow", "")),
+		),
+	}
+
+	It("Should list the routes of all APIRules ordered by host", func() {
+		//when
+		c := Build(apis, "kyma.local")
+
+		//then
+		Expect(c.Routes).To(HaveLen(3))
+		Expect(c.Routes[0].Host).To(Equal("httpbin.kyma.local"))
+		Expect(c.Routes[1]).To(Equal(Route{
+			APIRule:          "shop/orders",
+			Host:             "orders.kyma.local",
+			Gateway:          "kyma-gateway.kyma-system.svc.cluster.local",
+			Path:             "/orders/[^/]+",
+			Methods:          []string{"GET", "DELETE"},
+			AccessStrategies: []AccessStrategy{{Handler: "jwt", Scopes: []string{"orders"}, TrustedIssuers: []string{"https://dex.kyma.local"}}},
+			Service:          "orders.shop.svc.cluster.local:8000",
+			Status:           gatewayv1alpha1.StatusOK,
+		}))
+		Expect(c.Routes[2].Path).To(Equal("/health"))
+	})
+
+	It("Should build OpenAPI skeletons of the hosts with the security requirements of the access strategies", func() {
+		//when
+		docs := Build(apis, "kyma.local").OpenAPI()
+
+		//then
+		Expect(docs).To(HaveLen(2))
+
+		httpbin := docs[0]
+		Expect(httpbin.Servers).To(Equal([]openapi.Server{{URL: "https://httpbin.kyma.local"}}))
+		Expect(httpbin.Paths).To(HaveKey("/.*"))
+		Expect(*httpbin.Paths["/.*"].Get.Security).To(Equal([]openapi.SecurityRequirement{{"oauth2_introspection": {"read"}}, {}}))
+		Expect(httpbin.Components.SecuritySchemes["oauth2_introspection"]).To(Equal(openapi.SecurityScheme{Type: "http", Scheme: "bearer"}))
+
+		orders := docs[1]
+		Expect(orders.Paths).To(HaveKey("/orders/{param1}"))
+		item := orders.Paths["/orders/{param1}"]
+		Expect(item.XPathRegex).To(Equal("/orders/[^/]+"))
+		Expect(item.Get).NotTo(BeNil())
+		Expect(item.Delete).NotTo(BeNil())
+		Expect(*item.Get.Security).To(Equal([]openapi.SecurityRequirement{{"oidc_dex.kyma.local": {"orders"}}}))
+		Expect(item.Get.XAccessStrategies).To(Equal([]string{"jwt"}))
+		Expect(orders.Components.SecuritySchemes["oidc_dex.kyma.local"].OpenIDConnectURL).To(Equal("https://dex.kyma.local/.well-known/openid-configuration"))
+		Expect(*orders.Paths["/health"].Get.Security).To(BeEmpty())
+	})
+
+	It("Should produce documents the OpenAPI import maps back to the same access strategies", func() {
+		//given
+		docs := Build(apis, "kyma.local").OpenAPI()
+		basePath := ""
+
+		//when
+		rules, errs, _ := openapi.Rules(docs[1], openapi.Options{BasePath: &basePath, PublicHandler: "noop"})
+
+		//then
+		Expect(errs).To(BeEmpty())
+		Expect(rules).To(HaveLen(2))
+		Expect(rules[1].Path).To(Equal("/orders/[^/]+"))
+		Expect(rules[1].AccessStrategies[0].Name).To(Equal("jwt"))
+		Expect(string(rules[1].AccessStrategies[0].Config.Raw)).To(Equal(`{"required_scope":["orders"],"trusted_issuers":["https://dex.kyma.local"]}`))
+	})
+
+	table.DescribeTable("Should convert path regular expressions to templates",
+		func(path, expected string) {
+			Expect(pathTemplate(path)).To(Equal(expected))
+		},
+		table.Entry("literal path", "/headers", "/headers"),
+		table.Entry("escaped literal", `/files/[^/]+\.json`, "/files/{param1}.json"),
+		table.Entry("several segments", "/users/[^/]+/orders/[^/]+", "/users/{param1}/orders/{param2}"),
+		table.Entry("wildcard", "/.*", "/.*"),
+		table.Entry("character class escape", `/items/\d+`, `/items/\d+`),
+	)
+
+	It("Should serve the catalogue of the APIRules in the cluster", func() {
+		//given
+		scheme := runtime.NewScheme()
+		Expect(gatewayv1alpha1.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&apis[0], &apis[1]).Build()
+		h := Handler(c, func() string { return "kyma.local" })
+
+		//when
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalogue", nil))
+
+		//then
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var served Catalogue
+		Expect(json.Unmarshal(rec.Body.Bytes(), &served)).To(Succeed())
+		Expect(served.Routes).To(HaveLen(3))
+
+		//when
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalogue?format=openapi", nil))
+
+		//then
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring("title: orders.kyma.local\n"))
+
+		//when
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/catalogue?format=xml", nil))
+
+		//then
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+})
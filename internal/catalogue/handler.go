@@ -0,0 +1,42 @@
+package catalogue
+
+import (
+	"encoding/json"
+	"net/http"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//Handler serves the catalogue of all APIRules in the cluster as JSON, or as OpenAPI skeletons in the YAML format if the format query
+//parameter is set to openapi. The defaultDomainName function returns the domain of hosts with no domain in the current configuration
+func Handler(reader client.Reader, defaultDomainName func() string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "only GET is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		format := req.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "openapi" {
+			http.Error(w, "unsupported format "+format+", expected json or openapi", http.StatusBadRequest)
+			return
+		}
+
+		var apiList gatewayv1alpha1.APIRuleList
+		if err := reader.List(req.Context(), &apiList); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		c := Build(apiList.Items, defaultDomainName())
+
+		if format == "openapi" {
+			w.Header().Set("Content-Type", "application/yaml")
+			_ = WriteOpenAPI(w, c.OpenAPI())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(c)
+	})
+}
@@ -0,0 +1,230 @@
+package claims
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/helpers"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+//Claim is the outcome of the arbitration of a host between the APIRules exposing it.
+//The APIRule already holding the host keeps it. If no APIRule holds it, the APIRule created first gets it. APIRules only planning their changes,
+//in dry-run mode or while paused, don't take part unless they hold the host, so they can't change the live state. APIRules in the namespace
+//of the holder, using the same gateway, can share the host as long as their paths don't overlap with the paths of the APIRules already sharing it.
+type Claim struct {
+	//Host claimed, including the default domain name if needed
+	Host string
+	//APIRules sharing the host in claim order. The first one is the holder, which owns the Virtual Service of the host
+	Group []gatewayv1alpha1.APIRule
+	//Granted tells if the arbitrated APIRule is in the group
+	Granted bool
+	//ConflictsWith is the APIRule the host is lost to
+	ConflictsWith *gatewayv1alpha1.APIRule
+	//OverlappingPath is set if the host could be shared, but the path overlaps with a path of the APIRule the host is lost to
+	OverlappingPath string
+}
+
+//Holder returns the APIRule holding the host
+func (c *Claim) Holder() *gatewayv1alpha1.APIRule {
+	if len(c.Group) == 0 {
+		return nil
+	}
+	return &c.Group[0]
+}
+
+//IsShared tells if the host is shared by more than one APIRule
+func (c *Claim) IsShared() bool {
+	return len(c.Group) > 1
+}
+
+//Exposed returns the APIRules of the group, whose routes are exposed in the Virtual Service of the host. Apart from the arbitrated APIRule,
+//which has just been validated, only the APIRules successfully processed in their current generation are exposed.
+func (c *Claim) Exposed(api *gatewayv1alpha1.APIRule) []gatewayv1alpha1.APIRule {
+	var res []gatewayv1alpha1.APIRule
+	for _, member := range c.Group {
+		if member.Namespace == api.Namespace && member.Name == api.Name {
+			res = append(res, *api)
+			continue
+		}
+		if member.Status.ObservedGeneration == member.Generation && member.Status.APIRuleStatus != nil &&
+			member.Status.APIRuleStatus.Code == gatewayv1alpha1.StatusOK {
+			res = append(res, member)
+		}
+	}
+	return res
+}
+
+//Options describe the state of the cluster the arbitration depends on
+type Options struct {
+	//Holder is the APIRule owning the Virtual Service of the host, nil if there is none. It keeps the host as long as it claims it,
+	//so an APIRule created before it can't take the host over by changing its own host
+	Holder *types.NamespacedName
+	//Ignore tells if the claim of an APIRule other than the arbitrated one is ignored, because it doesn't expose the host
+	Ignore func(api *gatewayv1alpha1.APIRule) bool
+}
+
+//Arbitrate decides if the APIRule can expose its host. Claimants are all APIRules known in the cluster; the ones exposing other hosts,
+//the ones being deleted, the ones only planning their changes without holding the host and the ones ignored by the options are ignored. The arbitrated APIRule takes precedence over its own copy among the claimants.
+func Arbitrate(api *gatewayv1alpha1.APIRule, claimants []gatewayv1alpha1.APIRule, defaultDomainName string, opts Options) *Claim {
+	host := Host(api, defaultDomainName)
+
+	candidates := []gatewayv1alpha1.APIRule{*api}
+	for _, c := range claimants {
+		if c.Namespace == api.Namespace && c.Name == api.Name {
+			continue
+		}
+		if c.DeletionTimestamp != nil || Host(&c, defaultDomainName) != host {
+			continue
+		}
+		if plansOnly(&c) && !opts.isHolder(&c) {
+			continue
+		}
+		if opts.Ignore != nil && opts.Ignore(&c) {
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return claimedBefore(&candidates[i], &candidates[j])
+	})
+	if opts.Holder != nil {
+		for i := range candidates {
+			if opts.isHolder(&candidates[i]) {
+				holder := candidates[i]
+				copy(candidates[1:i+1], candidates[:i])
+				candidates[0] = holder
+				break
+			}
+		}
+	}
+
+	claim := &Claim{Host: host}
+	for i := range candidates {
+		candidate := candidates[i]
+		conflict, path := claim.join(&candidate)
+		if candidate.Namespace == api.Namespace && candidate.Name == api.Name {
+			claim.Granted = conflict == nil
+			claim.ConflictsWith = conflict
+			claim.OverlappingPath = path
+		}
+	}
+	return claim
+}
+
+func (o Options) isHolder(api *gatewayv1alpha1.APIRule) bool {
+	return o.Holder != nil && api.Namespace == o.Holder.Namespace && api.Name == o.Holder.Name
+}
+
+//plansOnly tells if the APIRule is annotated to report the changes of generated objects instead of applying them
+func plansOnly(api *gatewayv1alpha1.APIRule) bool {
+	return api.Annotations[gatewayv1alpha1.DryRunAnnotation] == "true" || api.Annotations[gatewayv1alpha1.PausedAnnotation] == "true"
+}
+
+//join adds the candidate to the group if it can share the host. Otherwise, returns the APIRule it conflicts with
+func (c *Claim) join(candidate *gatewayv1alpha1.APIRule) (*gatewayv1alpha1.APIRule, string) {
+	holder := c.Holder()
+	if holder == nil {
+		c.Group = append(c.Group, *candidate)
+		return nil, ""
+	}
+	if holder.Namespace != candidate.Namespace || !sameGateway(holder, candidate) {
+		return holder, ""
+	}
+	for i := range c.Group {
+		if path, ok := overlappingPath(candidate, &c.Group[i]); ok {
+			return &c.Group[i], path
+		}
+	}
+	c.Group = append(c.Group, *candidate)
+	return nil, ""
+}
+
+//Host returns the host exposed by the APIRule, including the default domain name if needed
+func Host(api *gatewayv1alpha1.APIRule, defaultDomainName string) string {
+	if api.Spec.Service == nil || api.Spec.Service.Host == nil {
+		return ""
+	}
+	return helpers.GetHostWithDomain(*api.Spec.Service.Host, defaultDomainName)
+}
+
+//Describe returns the reason why the host is not granted
+func (c *Claim) Describe() string {
+	if c.Granted || c.ConflictsWith == nil {
+		return ""
+	}
+	holder := types.NamespacedName{Namespace: c.ConflictsWith.Namespace, Name: c.ConflictsWith.Name}
+	if c.OverlappingPath != "" {
+		return fmt.Sprintf("Path %s overlaps with APIRule %s sharing host %s", c.OverlappingPath, holder, c.Host)
+	}
+	return fmt.Sprintf("Host %s is claimed by APIRule %s", c.Host, holder)
+}
+
+//claimedBefore orders APIRules by creation timestamp, which decides the holder of a host no APIRule holds yet.
+//APIRules created in the same second are ordered by namespace and name
+func claimedBefore(a, b *gatewayv1alpha1.APIRule) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+func sameGateway(a, b *gatewayv1alpha1.APIRule) bool {
+	if a.Spec.Gateway == nil || b.Spec.Gateway == nil {
+		return a.Spec.Gateway == b.Spec.Gateway
+	}
+	return *a.Spec.Gateway == *b.Spec.Gateway
+}
+
+func overlappingPath(a, b *gatewayv1alpha1.APIRule) (string, bool) {
+	for _, ra := range a.Spec.Rules {
+		for _, rb := range b.Spec.Rules {
+			if PathsOverlap(ra.MatchPath(), rb.MatchPath()) {
+				return ra.MatchPath(), true
+			}
+		}
+	}
+	return "", false
+}
+
+const regexMetaCharacters = `.*+?()[]{}|^$\`
+
+//PathsOverlap tells if two rule paths can match the same request path. Paths are regular expressions matching the whole path,
+//so the check is conservative: a literal path overlaps with a pattern matching it, and two patterns overlap unless their literal prefixes diverge.
+func PathsOverlap(a, b string) bool {
+	prefixA, literalA := literalPrefix(a)
+	prefixB, literalB := literalPrefix(b)
+
+	switch {
+	case literalA && literalB:
+		return a == b
+	case literalA:
+		return fullMatch(b, a)
+	case literalB:
+		return fullMatch(a, b)
+	}
+	return strings.HasPrefix(prefixA, prefixB) || strings.HasPrefix(prefixB, prefixA)
+}
+
+func literalPrefix(path string) (string, bool) {
+	i := strings.IndexAny(path, regexMetaCharacters)
+	if i < 0 {
+		return path, true
+	}
+	return path[:i], false
+}
+
+func fullMatch(pattern, path string) bool {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		//Invalid patterns are treated as overlapping everything
+		return true
+	}
+	return re.MatchString(path)
+}
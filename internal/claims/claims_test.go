@@ -0,0 +1,233 @@
+package claims
+
+import (
+	"testing"
+	"time"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	defaultDomain = "kyma.local"
+	sharedHost    = "shop." + defaultDomain
+)
+
+var (
+	created = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func TestClaims(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Claims Suite")
+}
+
+var _ = Describe("Arbitrate", func() {
+
+	It("Should grant a host claimed by a single APIRule", func() {
+		api := apiRule("default", "orders", sharedHost, 0, "/orders")
+
+		claim := Arbitrate(api, []gatewayv1alpha1.APIRule{*api}, defaultDomain, Options{})
+
+		Expect(claim.Granted).To(BeTrue())
+		Expect(claim.Host).To(Equal(sharedHost))
+		Expect(claim.Holder().Name).To(Equal("orders"))
+		Expect(claim.IsShared()).To(BeFalse())
+	})
+
+	It("Should grant the host to the APIRule created first", func() {
+		first := apiRule("team-a", "first", sharedHost, 0, "/.*")
+		second := apiRule("team-b", "second", "shop", 1, "/.*")
+		claimants := []gatewayv1alpha1.APIRule{*second, *first}
+
+		Expect(Arbitrate(first, claimants, defaultDomain, Options{}).Granted).To(BeTrue())
+
+		claim := Arbitrate(second, claimants, defaultDomain, Options{})
+		Expect(claim.Granted).To(BeFalse())
+		Expect(claim.ConflictsWith.Name).To(Equal("first"))
+		Expect(claim.Describe()).To(Equal("Host shop.kyma.local is claimed by APIRule team-a/first"))
+	})
+
+	It("Should keep the host with the APIRule holding it", func() {
+		//given
+		older := apiRule("team-a", "older", sharedHost, 0, "/.*")
+		holder := apiRule("team-b", "holder", sharedHost, 1, "/.*")
+		claimants := []gatewayv1alpha1.APIRule{*older, *holder}
+		opts := Options{Holder: &types.NamespacedName{Namespace: "team-b", Name: "holder"}}
+
+		//when
+		claim := Arbitrate(older, claimants, defaultDomain, opts)
+
+		//then
+		Expect(claim.Granted).To(BeFalse())
+		Expect(claim.ConflictsWith.Name).To(Equal("holder"))
+		Expect(Arbitrate(holder, claimants, defaultDomain, opts).Granted).To(BeTrue())
+	})
+
+	It("Should grant the host to the APIRule created first if the holder doesn't claim it anymore", func() {
+		//given
+		first := apiRule("team-a", "first", sharedHost, 0, "/.*")
+		second := apiRule("team-b", "second", sharedHost, 1, "/.*")
+		opts := Options{Holder: &types.NamespacedName{Namespace: "team-c", Name: "moved"}}
+
+		//when
+		claim := Arbitrate(second, []gatewayv1alpha1.APIRule{*first, *second}, defaultDomain, opts)
+
+		//then
+		Expect(claim.Granted).To(BeFalse())
+		Expect(claim.Holder().Name).To(Equal("first"))
+	})
+
+	DescribeTable("Should ignore APIRules only planning their changes",
+		func(annotation string) {
+			//given
+			planned := apiRule("team-a", "planned", sharedHost, 0, "/.*")
+			planned.Annotations = map[string]string{annotation: "true"}
+			api := apiRule("team-b", "api", sharedHost, 1, "/.*")
+
+			//when
+			claim := Arbitrate(api, []gatewayv1alpha1.APIRule{*planned, *api}, defaultDomain, Options{})
+
+			//then
+			Expect(claim.Granted).To(BeTrue())
+			Expect(claim.Group).To(HaveLen(1))
+		},
+		Entry("in dry-run mode", gatewayv1alpha1.DryRunAnnotation),
+		Entry("while paused", gatewayv1alpha1.PausedAnnotation),
+	)
+
+	It("Should keep the host with a paused APIRule holding it", func() {
+		//given
+		holder := apiRule("team-a", "holder", sharedHost, 1, "/.*")
+		holder.Annotations = map[string]string{gatewayv1alpha1.PausedAnnotation: "true"}
+		api := apiRule("team-b", "api", sharedHost, 0, "/.*")
+		opts := Options{Holder: &types.NamespacedName{Namespace: "team-a", Name: "holder"}}
+
+		//when
+		claim := Arbitrate(api, []gatewayv1alpha1.APIRule{*holder, *api}, defaultDomain, opts)
+
+		//then
+		Expect(claim.Granted).To(BeFalse())
+		Expect(claim.ConflictsWith.Name).To(Equal("holder"))
+	})
+
+	It("Should break ties by namespace and name", func() {
+		a := apiRule("team-a", "rule", sharedHost, 0, "/.*")
+		b := apiRule("team-b", "rule", sharedHost, 0, "/.*")
+		claimants := []gatewayv1alpha1.APIRule{*b, *a}
+
+		Expect(Arbitrate(a, claimants, defaultDomain, Options{}).Granted).To(BeTrue())
+		Expect(Arbitrate(b, claimants, defaultDomain, Options{}).Granted).To(BeFalse())
+	})
+
+	It("Should ignore APIRules being deleted and exposing other hosts", func() {
+		deleted := apiRule("team-a", "deleted", sharedHost, 0, "/.*")
+		deleted.DeletionTimestamp = &metav1.Time{Time: created}
+		other := apiRule("team-a", "other", "other."+defaultDomain, 0, "/.*")
+		api := apiRule("team-b", "api", sharedHost, 1, "/.*")
+
+		claim := Arbitrate(api, []gatewayv1alpha1.APIRule{*deleted, *other, *api}, defaultDomain, Options{})
+
+		Expect(claim.Granted).To(BeTrue())
+		Expect(claim.Group).To(HaveLen(1))
+	})
+
+	It("Should share the host between APIRules in the same namespace with paths not overlapping", func() {
+		orders := apiRule("default", "orders", sharedHost, 0, "/orders/.*")
+		cart := apiRule("default", "cart", sharedHost, 1, "/cart", "/cart/.*")
+		claimants := []gatewayv1alpha1.APIRule{*orders, *cart}
+
+		claim := Arbitrate(cart, claimants, defaultDomain, Options{})
+
+		Expect(claim.Granted).To(BeTrue())
+		Expect(claim.IsShared()).To(BeTrue())
+		Expect(claim.Holder().Name).To(Equal("orders"))
+		Expect(claim.Group[1].Name).To(Equal("cart"))
+	})
+
+	It("Should reject an APIRule with a path overlapping with a path of an APIRule sharing the host", func() {
+		orders := apiRule("default", "orders", sharedHost, 0, "/orders/.*")
+		cart := apiRule("default", "cart", sharedHost, 1, "/cart/.*")
+		all := apiRule("default", "all", sharedHost, 2, "/cart/items")
+		claimants := []gatewayv1alpha1.APIRule{*orders, *cart, *all}
+
+		claim := Arbitrate(all, claimants, defaultDomain, Options{})
+
+		Expect(claim.Granted).To(BeFalse())
+		Expect(claim.ConflictsWith.Name).To(Equal("cart"))
+		Expect(claim.Describe()).To(Equal("Path /cart/items overlaps with APIRule default/cart sharing host shop.kyma.local"))
+		Expect(claim.Group).To(HaveLen(2))
+	})
+
+	It("Should not share the host between APIRules using different gateways", func() {
+		orders := apiRule("default", "orders", sharedHost, 0, "/orders/.*")
+		cart := apiRule("default", "cart", sharedHost, 1, "/cart/.*")
+		gateway := "other-gateway.kyma-system.svc.cluster.local"
+		cart.Spec.Gateway = &gateway
+
+		claim := Arbitrate(cart, []gatewayv1alpha1.APIRule{*orders, *cart}, defaultDomain, Options{})
+
+		Expect(claim.Granted).To(BeFalse())
+		Expect(claim.OverlappingPath).To(BeEmpty())
+	})
+
+	It("Should expose only APIRules processed successfully in their current generation", func() {
+		orders := apiRule("default", "orders", sharedHost, 0, "/orders/.*")
+		orders.Generation = 2
+		orders.Status = gatewayv1alpha1.APIRuleStatus{ObservedGeneration: 1, APIRuleStatus: &gatewayv1alpha1.APIRuleResourceStatus{Code: gatewayv1alpha1.StatusOK}}
+		cart := apiRule("default", "cart", sharedHost, 1, "/cart/.*")
+		cart.Status = gatewayv1alpha1.APIRuleStatus{APIRuleStatus: &gatewayv1alpha1.APIRuleResourceStatus{Code: gatewayv1alpha1.StatusOK}}
+		users := apiRule("default", "users", sharedHost, 2, "/users/.*")
+		users.Status = gatewayv1alpha1.APIRuleStatus{APIRuleStatus: &gatewayv1alpha1.APIRuleResourceStatus{Code: gatewayv1alpha1.StatusError}}
+		api := apiRule("default", "api", sharedHost, 3, "/api/.*")
+
+		claim := Arbitrate(api, []gatewayv1alpha1.APIRule{*orders, *cart, *users}, defaultDomain, Options{})
+		exposed := claim.Exposed(api)
+
+		Expect(claim.Group).To(HaveLen(4))
+		Expect(exposed).To(HaveLen(2))
+		Expect(exposed[0].Name).To(Equal("cart"))
+		Expect(exposed[1].Name).To(Equal("api"))
+	})
+})
+
+var _ = Describe("PathsOverlap", func() {
+
+	DescribeTable("Should compare paths",
+		func(a, b string, expected bool) {
+			Expect(PathsOverlap(a, b)).To(Equal(expected))
+			Expect(PathsOverlap(b, a)).To(Equal(expected))
+		},
+		Entry("equal literal paths", "/orders", "/orders", true),
+		Entry("different literal paths", "/orders", "/cart", false),
+		Entry("literal path matching pattern", "/orders/1", "/orders/.*", true),
+		Entry("literal path not matching pattern", "/orders", "/orders/.*", false),
+		Entry("patterns with common prefix", "/.*", "/orders/.*", true),
+		Entry("patterns with diverging prefixes", "/orders/.*", "/cart/.*", false),
+		Entry("invalid pattern", "/orders/(", "/orders/1", true),
+	)
+})
+
+func apiRule(namespace, name, host string, createdAfter time.Duration, paths ...string) *gatewayv1alpha1.APIRule {
+	gateway := "kyma-gateway.kyma-system.svc.cluster.local"
+	var rules []gatewayv1alpha1.Rule
+	for _, path := range paths {
+		rules = append(rules, gatewayv1alpha1.Rule{Path: path})
+	}
+	return &gatewayv1alpha1.APIRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         namespace,
+			Name:              name,
+			CreationTimestamp: metav1.Time{Time: created.Add(createdAfter * time.Second)},
+		},
+		Spec: gatewayv1alpha1.APIRuleSpec{
+			Gateway: &gateway,
+			Service: &gatewayv1alpha1.Service{Host: &host},
+			Rules:   rules,
+		},
+	}
+}
@@ -0,0 +1,341 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/kyma-incubator/api-gateway/internal/validation"
+	"istio.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+//Config holds the settings of the controller. It can be built from command-line flags, loaded from a file or from a ConfigMap
+type Config struct {
+	// Oathkeeper proxy service address
+	OathkeeperSvcAddress string `json:"oathkeeperSvcAddress,omitempty"`
+	// Oathkeeper proxy service port
+	OathkeeperSvcPort uint32 `json:"oathkeeperSvcPort,omitempty"`
+	// URL of the provider's public key set to validate signature of the JWT
+	JWKSURI string `json:"jwksURI,omitempty"`
+	// List of services to be blocklisted from exposure, in the "service.namespace" format
+	ServiceBlockList []string `json:"serviceBlockList,omitempty"`
+	// List of domains to be allowed. Wildcards, such as `*.apps.example.com`, allow hosts with exactly one label in place of the asterisk
+	DomainAllowList []string `json:"domainAllowList,omitempty"`
+	// A default domain name for hostnames with no domain provided
+	DefaultDomainName string `json:"defaultDomainName,omitempty"`
+	// CORS settings of generated Virtual Services
+	Cors Cors `json:"cors,omitempty"`
+	// Labels added to generated objects
+	GeneratedObjectsLabels map[string]string `json:"generatedObjectsLabels,omitempty"`
+	// Compute the changes of generated objects for all APIRules without applying them
+	DryRun bool `json:"dryRun,omitempty"`
+	// How long the deletion of an APIRule waits for the objects generated for it to be deleted. No limit if zero
+	DeletionTimeout *metav1.Duration `json:"deletionTimeout,omitempty"`
+	// How often all APIRules are processed again, even if they didn't change. No periodic processing if zero
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
+	// Provisioning of Gateways and certificates for hosts not covered by the wildcard certificate. Disabled if not set
+	GatewayProvisioning *GatewayProvisioning `json:"gatewayProvisioning,omitempty"`
+	// Rate limiting of APIRule rules on the ingress gateway. Rate limits are not applied if not set
+	RateLimit *RateLimit `json:"rateLimit,omitempty"`
+	// Ingress gateway applying the source IP restrictions and the JWT validation of APIRule rules. Defaults to the istio: ingressgateway pods in istio-system
+	SourceIPs *SourceIPs `json:"sourceIPs,omitempty"`
+	// Checking of the keys of the api_key access strategy by the controller. The api_key access strategy can't be used if not set
+	APIKeys *APIKeys `json:"apiKeys,omitempty"`
+}
+
+//APIKeys holds the settings of the api_key access strategy, whose keys are checked by the controller on behalf of Oathkeeper
+type APIKeys struct {
+	// URL of the /api-keys endpoint of the controller, as reachable from Oathkeeper
+	CheckURL string `json:"checkURL,omitempty"`
+}
+
+//SourceIPs holds the settings of the AuthorizationPolicies and RequestAuthentications applying the source IP restrictions and the JWT
+//validation of APIRules on the ingress gateway
+type SourceIPs struct {
+	// Namespace of the Istio ingress gateway, in which the AuthorizationPolicies and RequestAuthentications are created and to which the
+	// Destination Rules of the SIMPLE and MUTUAL upstream TLS modes are exported. Defaults to istio-system
+	Namespace string `json:"namespace,omitempty"`
+	// Labels selecting the pods of the Istio ingress gateway. Defaults to istio: ingressgateway
+	Selector map[string]string `json:"selector,omitempty"`
+}
+
+//RateLimit holds the settings of the EnvoyFilters applying the rate limits and the WebSocket upgrades of APIRules on the ingress gateway
+type RateLimit struct {
+	// Namespace of the Istio ingress gateway, in which the EnvoyFilters are created. Defaults to istio-system
+	Namespace string `json:"namespace,omitempty"`
+	// Labels selecting the pods of the Istio ingress gateway. Defaults to istio: ingressgateway
+	Selector map[string]string `json:"selector,omitempty"`
+	// Global rate limit service counting the requests of every client. Rate limits with a key are not applied if not set
+	Global *GlobalRateLimit `json:"global,omitempty"`
+}
+
+//GlobalRateLimit holds the settings of the descriptors configuration of the global rate limit service
+type GlobalRateLimit struct {
+	// Domain of the rate limit filter of the ingress gateway
+	Domain string `json:"domain,omitempty"`
+	// Namespace of the ConfigMap with the descriptors configuration loaded by the rate limit service
+	ConfigMapNamespace string `json:"configMapNamespace,omitempty"`
+	// Name of the ConfigMap with the descriptors configuration. The descriptors of every APIRule are kept in a key of their own
+	ConfigMapName string `json:"configMapName,omitempty"`
+}
+
+//GatewayProvisioning holds the settings of the Istio Gateways and cert-manager Certificates generated for hosts not covered by the wildcard
+//certificate of the gateways set in APIRules
+type GatewayProvisioning struct {
+	// Domains covered by the wildcard certificate, such as `*.kyma.local`. Hosts in these domains are exposed with the gateway set in the APIRule
+	WildcardDomains []string `json:"wildcardDomains,omitempty"`
+	// Namespace of the Istio ingress gateway, in which the Certificates and their Secrets are created
+	Namespace string `json:"namespace,omitempty"`
+	// Labels selecting the pods of the Istio ingress gateway. Defaults to istio: ingressgateway
+	Selector map[string]string `json:"selector,omitempty"`
+	// Name of the cert-manager issuer of the certificates
+	IssuerName string `json:"issuerName,omitempty"`
+	// Kind of the cert-manager issuer, Issuer or ClusterIssuer. Defaults to ClusterIssuer
+	IssuerKind string `json:"issuerKind,omitempty"`
+}
+
+//Cors holds CORS settings of generated Virtual Services
+type Cors struct {
+	// List of allowed origins in the "matchType:value" format, where matchType is one of: regex, prefix, exact
+	AllowOrigins []string `json:"allowOrigins,omitempty"`
+	AllowMethods []string `json:"allowMethods,omitempty"`
+	AllowHeaders []string `json:"allowHeaders,omitempty"`
+}
+
+//Load reads the configuration from the given YAML or JSON document. Settings present in the document override the ones in base
+func Load(data []byte, base *Config) (*Config, error) {
+	res := base.DeepCopy()
+	if err := yaml.UnmarshalStrict(data, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+//DeepCopy returns a copy of the configuration
+func (c *Config) DeepCopy() *Config {
+	if c == nil {
+		return &Config{}
+	}
+	res := *c
+	res.ServiceBlockList = append([]string(nil), c.ServiceBlockList...)
+	res.DomainAllowList = append([]string(nil), c.DomainAllowList...)
+	res.Cors.AllowOrigins = append([]string(nil), c.Cors.AllowOrigins...)
+	res.Cors.AllowMethods = append([]string(nil), c.Cors.AllowMethods...)
+	res.Cors.AllowHeaders = append([]string(nil), c.Cors.AllowHeaders...)
+	if c.GeneratedObjectsLabels != nil {
+		res.GeneratedObjectsLabels = make(map[string]string, len(c.GeneratedObjectsLabels))
+		for k, v := range c.GeneratedObjectsLabels {
+			res.GeneratedObjectsLabels[k] = v
+		}
+	}
+	if c.DeletionTimeout != nil {
+		timeout := *c.DeletionTimeout
+		res.DeletionTimeout = &timeout
+	}
+	if c.ResyncInterval != nil {
+		interval := *c.ResyncInterval
+		res.ResyncInterval = &interval
+	}
+	if c.GatewayProvisioning != nil {
+		provisioning := *c.GatewayProvisioning
+		provisioning.WildcardDomains = append([]string(nil), c.GatewayProvisioning.WildcardDomains...)
+		if c.GatewayProvisioning.Selector != nil {
+			provisioning.Selector = make(map[string]string, len(c.GatewayProvisioning.Selector))
+			for k, v := range c.GatewayProvisioning.Selector {
+				provisioning.Selector[k] = v
+			}
+		}
+		res.GatewayProvisioning = &provisioning
+	}
+	if c.RateLimit != nil {
+		rateLimit := *c.RateLimit
+		if c.RateLimit.Selector != nil {
+			rateLimit.Selector = make(map[string]string, len(c.RateLimit.Selector))
+			for k, v := range c.RateLimit.Selector {
+				rateLimit.Selector[k] = v
+			}
+		}
+		if c.RateLimit.Global != nil {
+			global := *c.RateLimit.Global
+			rateLimit.Global = &global
+		}
+		res.RateLimit = &rateLimit
+	}
+	if c.SourceIPs != nil {
+		sourceIPs := *c.SourceIPs
+		if c.SourceIPs.Selector != nil {
+			sourceIPs.Selector = make(map[string]string, len(c.SourceIPs.Selector))
+			for k, v := range c.SourceIPs.Selector {
+				sourceIPs.Selector[k] = v
+			}
+		}
+		res.SourceIPs = &sourceIPs
+	}
+	if c.APIKeys != nil {
+		apiKeys := *c.APIKeys
+		res.APIKeys = &apiKeys
+	}
+	return &res
+}
+
+//Validate verifies if the configuration can be used by the controller
+func (c *Config) Validate() error {
+	if c.JWKSURI == "" {
+		return fmt.Errorf("jwks-uri required, but not supplied")
+	}
+	if c.OathkeeperSvcAddress == "" {
+		return fmt.Errorf("oathkeeper-svc-address can't be empty")
+	}
+	if c.OathkeeperSvcPort == 0 {
+		return fmt.Errorf("oathkeeper-svc-port can't be empty")
+	}
+	if len(c.DomainAllowList) == 0 {
+		return fmt.Errorf("domain-allowlist can't be empty")
+	}
+	for _, domain := range c.DomainAllowList {
+		if !validation.ValidateDomainPattern(domain) {
+			return fmt.Errorf("invalid domain in domain-allowlist: %s", domain)
+		}
+	}
+	if c.DefaultDomainName != "" && !validation.ValidateDomainName(c.DefaultDomainName) {
+		return fmt.Errorf("invalid default-domain-name: %s", c.DefaultDomainName)
+	}
+	for _, svc := range c.ServiceBlockList {
+		if !validation.ValidateServiceName(svc) {
+			return fmt.Errorf("invalid service in service-blocklist: %s", svc)
+		}
+	}
+	if c.DeletionTimeout != nil && c.DeletionTimeout.Duration < 0 {
+		return fmt.Errorf("deletion-timeout can't be negative")
+	}
+	if c.ResyncInterval != nil && c.ResyncInterval.Duration < 0 {
+		return fmt.Errorf("resync-interval can't be negative")
+	}
+	if err := c.GatewayProvisioning.validate(); err != nil {
+		return err
+	}
+	if err := c.RateLimit.validate(); err != nil {
+		return err
+	}
+	if err := c.APIKeys.validate(); err != nil {
+		return err
+	}
+	for _, origin := range c.Cors.AllowOrigins {
+		if _, err := toStringMatch(origin); err != nil {
+			return err
+		}
+	}
+	for key, value := range c.GeneratedObjectsLabels {
+		if err := validation.VerifyLabelKey(key); err != nil {
+			return fmt.Errorf("invalid label key: %w", err)
+		}
+		if err := validation.VerifyLabelValue(value); err != nil {
+			return fmt.Errorf("invalid label value: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *GatewayProvisioning) validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.Namespace == "" {
+		return fmt.Errorf("gateway-provisioning namespace can't be empty")
+	}
+	if p.IssuerName == "" {
+		return fmt.Errorf("gateway-provisioning issuerName can't be empty")
+	}
+	if p.IssuerKind != "" && p.IssuerKind != "Issuer" && p.IssuerKind != "ClusterIssuer" {
+		return fmt.Errorf("invalid gateway-provisioning issuerKind: %s, expected Issuer or ClusterIssuer", p.IssuerKind)
+	}
+	for _, domain := range p.WildcardDomains {
+		if !validation.ValidateDomainPattern(domain) {
+			return fmt.Errorf("invalid domain in gateway-provisioning wildcardDomains: %s", domain)
+		}
+	}
+	return nil
+}
+
+func (r *RateLimit) validate() error {
+	if r == nil {
+		return nil
+	}
+	if r.Namespace == "" {
+		return fmt.Errorf("rate-limit namespace can't be empty")
+	}
+	if r.Global == nil {
+		return nil
+	}
+	if r.Global.Domain == "" {
+		return fmt.Errorf("rate-limit global domain can't be empty")
+	}
+	if r.Global.ConfigMapNamespace == "" || r.Global.ConfigMapName == "" {
+		return fmt.Errorf("rate-limit global configMapNamespace and configMapName can't be empty")
+	}
+	return nil
+}
+
+func (k *APIKeys) validate() error {
+	if k == nil {
+		return nil
+	}
+	u, err := url.Parse(k.CheckURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid api-keys checkURL: %s, expected an http or https URL", k.CheckURL)
+	}
+	return nil
+}
+
+//Hash returns a digest of the configuration that changes whenever any setting changes
+func (c *Config) Hash() string {
+	data, _ := json.Marshal(c)
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+//ServiceBlockListMap returns the blocklisted services grouped by namespace
+func (c *Config) ServiceBlockListMap() map[string][]string {
+	result := make(map[string][]string)
+	for _, s := range c.ServiceBlockList {
+		namespacedService := strings.Split(s, ".")
+		namespace := namespacedService[1]
+		service := namespacedService[0]
+		result[namespace] = append(result[namespace], service)
+	}
+	return result
+}
+
+//CorsAllowOrigins returns the allowed origins converted to Istio string matches. Invalid entries are skipped
+func (c *Config) CorsAllowOrigins() []*v1beta1.StringMatch {
+	var result []*v1beta1.StringMatch
+	for _, s := range c.Cors.AllowOrigins {
+		stringMatch, err := toStringMatch(s)
+		if err != nil {
+			continue
+		}
+		result = append(result, stringMatch)
+	}
+	return result
+}
+
+func toStringMatch(raw string) (*v1beta1.StringMatch, error) {
+	matchTypePair := strings.SplitN(raw, ":", 2)
+	if len(matchTypePair) != 2 {
+		return nil, fmt.Errorf("invalid cors origin %s, expected matchType:value", raw)
+	}
+	matchType := matchTypePair[0]
+	value := matchTypePair[1]
+	switch matchType {
+	case "regex":
+		return &v1beta1.StringMatch{MatchType: &v1beta1.StringMatch_Regex{Regex: value}}, nil
+	case "prefix":
+		return &v1beta1.StringMatch{MatchType: &v1beta1.StringMatch_Prefix{Prefix: value}}, nil
+	case "exact":
+		return &v1beta1.StringMatch{MatchType: &v1beta1.StringMatch_Exact{Exact: value}}, nil
+	}
+	return nil, fmt.Errorf("invalid cors origin match type %s in %s", matchType, raw)
+}
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfig(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Config Suite")
+}
+
+var _ = Describe("Config", func() {
+
+	base := &Config{
+		OathkeeperSvcAddress: "ory-oathkeeper-proxy.kyma-system.svc.cluster.local",
+		OathkeeperSvcPort:    4455,
+		JWKSURI:              "https://example.com/.well-known/jwks.json",
+		ServiceBlockList:     []string{"kubernetes.default", "kube-dns.kube-system"},
+		DomainAllowList:      []string{"kyma.local"},
+		Cors: Cors{
+			AllowOrigins: []string{"regex:.*"},
+			AllowMethods: []string{"GET"},
+		},
+		GeneratedObjectsLabels: map[string]string{"managed-by": "api-gateway"},
+	}
+
+	Describe("Load", func() {
+
+		It("Should override settings present in the document", func() {
+			//when
+			cfg, err := Load([]byte(`
+domainAllowList: ["foo.bar", "bar.foo"]
+defaultDomainName: foo.bar
+cors:
+  allowOrigins: ["exact:https://example.com"]
+`), base)
+
+			//then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.DomainAllowList).To(Equal([]string{"foo.bar", "bar.foo"}))
+			Expect(cfg.DefaultDomainName).To(Equal("foo.bar"))
+			Expect(cfg.OathkeeperSvcPort).To(Equal(uint32(4455)))
+			Expect(cfg.CorsAllowOrigins()[0].GetExact()).To(Equal("https://example.com"))
+			Expect(cfg.Validate()).To(Succeed())
+
+			Expect(base.DomainAllowList).To(Equal([]string{"kyma.local"}))
+		})
+
+		It("Should read durations", func() {
+			//when
+			cfg, err := Load([]byte("deletionTimeout: 10m\nresyncInterval: 1h"), base)
+
+			//then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.DeletionTimeout.Duration).To(Equal(10 * time.Minute))
+			Expect(cfg.ResyncInterval.Duration).To(Equal(time.Hour))
+			Expect(base.DeletionTimeout).To(BeNil())
+			Expect(base.ResyncInterval).To(BeNil())
+		})
+
+		It("Should read gateway provisioning", func() {
+			//when
+			cfg, err := Load([]byte(`
+gatewayProvisioning:
+  wildcardDomains: ["*.kyma.local"]
+  namespace: istio-system
+  issuerName: letsencrypt
+`), base)
+
+			//then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.GatewayProvisioning.WildcardDomains).To(Equal([]string{"*.kyma.local"}))
+			Expect(cfg.GatewayProvisioning.Namespace).To(Equal("istio-system"))
+			Expect(cfg.Validate()).To(Succeed())
+			Expect(base.GatewayProvisioning).To(BeNil())
+		})
+
+		It("Should reject unknown settings", func() {
+			_, err := Load([]byte(`allowedDomains: ["foo.bar"]`), base)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Validate", func() {
+
+		It("Should reject invalid domain", func() {
+			cfg := base.DeepCopy()
+			cfg.DomainAllowList = []string{"foo..bar"}
+			Expect(cfg.Validate()).To(MatchError("invalid domain in domain-allowlist: foo..bar"))
+		})
+
+		It("Should accept wildcard domain", func() {
+			cfg := base.DeepCopy()
+			cfg.DomainAllowList = []string{"*.apps.kyma.local"}
+			Expect(cfg.Validate()).To(Succeed())
+		})
+
+		It("Should reject empty domain allowlist", func() {
+			cfg := base.DeepCopy()
+			cfg.DomainAllowList = nil
+			Expect(cfg.Validate()).To(MatchError("domain-allowlist can't be empty"))
+		})
+
+		It("Should reject invalid blocklisted service", func() {
+			cfg := base.DeepCopy()
+			cfg.ServiceBlockList = []string{"kubernetes"}
+			Expect(cfg.Validate()).To(MatchError("invalid service in service-blocklist: kubernetes"))
+		})
+
+		It("Should reject negative deletion timeout", func() {
+			cfg := base.DeepCopy()
+			cfg.DeletionTimeout = &metav1.Duration{Duration: -time.Second}
+			Expect(cfg.Validate()).To(MatchError("deletion-timeout can't be negative"))
+		})
+
+		It("Should reject negative resync interval", func() {
+			cfg := base.DeepCopy()
+			cfg.ResyncInterval = &metav1.Duration{Duration: -time.Second}
+			Expect(cfg.Validate()).To(MatchError("resync-interval can't be negative"))
+		})
+
+		It("Should reject incomplete gateway provisioning", func() {
+			cfg := base.DeepCopy()
+			cfg.GatewayProvisioning = &GatewayProvisioning{Namespace: "istio-system"}
+			Expect(cfg.Validate()).To(MatchError("gateway-provisioning issuerName can't be empty"))
+
+			cfg.GatewayProvisioning.IssuerName = "letsencrypt"
+			cfg.GatewayProvisioning.IssuerKind = "Vault"
+			Expect(cfg.Validate()).To(MatchError("invalid gateway-provisioning issuerKind: Vault, expected Issuer or ClusterIssuer"))
+		})
+
+		It("Should reject incomplete rate limiting", func() {
+			cfg := base.DeepCopy()
+			cfg.RateLimit = &RateLimit{}
+			Expect(cfg.Validate()).To(MatchError("rate-limit namespace can't be empty"))
+
+			cfg.RateLimit.Namespace = "istio-system"
+			Expect(cfg.Validate()).To(Succeed())
+
+			cfg.RateLimit.Global = &GlobalRateLimit{Domain: "api-gateway", ConfigMapName: "ratelimit-config"}
+			Expect(cfg.Validate()).To(MatchError("rate-limit global configMapNamespace and configMapName can't be empty"))
+
+			cfg.RateLimit.Global.ConfigMapNamespace = "rate-limit"
+			Expect(cfg.Validate()).To(Succeed())
+			Expect(base.RateLimit).To(BeNil())
+		})
+
+		It("Should reject an invalid URL checking API keys", func() {
+			cfg := base.DeepCopy()
+			cfg.APIKeys = &APIKeys{CheckURL: "api-gateway:8080/api-keys"}
+			Expect(cfg.Validate()).To(MatchError("invalid api-keys checkURL: api-gateway:8080/api-keys, expected an http or https URL"))
+
+			cfg.APIKeys.CheckURL = "http://api-gateway-metrics.kyma-system.svc.cluster.local:8080/api-keys"
+			Expect(cfg.Validate()).To(Succeed())
+		})
+
+		It("Should reject invalid cors origin", func() {
+			cfg := base.DeepCopy()
+			cfg.Cors.AllowOrigins = []string{"suffix:.com"}
+			Expect(cfg.Validate()).To(HaveOccurred())
+		})
+	})
+
+	It("Should group blocklisted services by namespace", func() {
+		Expect(base.ServiceBlockListMap()).To(Equal(map[string][]string{
+			"default":     {"kubernetes"},
+			"kube-system": {"kube-dns"},
+		}))
+	})
+
+	It("Should change hash when settings change", func() {
+		cfg := base.DeepCopy()
+		Expect(cfg.Hash()).To(Equal(base.Hash()))
+		cfg.GeneratedObjectsLabels["other"] = "value"
+		Expect(cfg.Hash()).NotTo(Equal(base.Hash()))
+	})
+
+	Describe("Sources", func() {
+
+		It("Should keep the settings of the configuration file when the ConfigMap changes", func() {
+			//given
+			sources := NewSources(base)
+			_, err := sources.SetFile([]byte("defaultDomainName: kyma.local\ndryRun: true"))
+			Expect(err).NotTo(HaveOccurred())
+
+			//when
+			cfg, err := sources.SetConfigMap([]byte("dryRun: false\nresyncInterval: 1h"))
+
+			//then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.DefaultDomainName).To(Equal("kyma.local"))
+			Expect(cfg.DryRun).To(BeFalse())
+			Expect(cfg.ResyncInterval.Duration).To(Equal(time.Hour))
+			Expect(cfg.OathkeeperSvcPort).To(Equal(uint32(4455)))
+
+			//when
+			cfg, err = sources.SetFile([]byte("defaultDomainName: kyma.local\ndryRun: true\ngeneratedObjectsLabels: {team: gateway}"))
+
+			//then
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.DryRun).To(BeFalse())
+			Expect(cfg.ResyncInterval.Duration).To(Equal(time.Hour))
+			Expect(cfg.GeneratedObjectsLabels).To(HaveKeyWithValue("team", "gateway"))
+		})
+
+		It("Should reject an invalid source and keep the previous content", func() {
+			//given
+			sources := NewSources(base)
+			_, err := sources.SetConfigMap([]byte("defaultDomainName: kyma.local"))
+			Expect(err).NotTo(HaveOccurred())
+
+			//when
+			_, err = sources.SetConfigMap([]byte("domainAllowList: []"))
+
+			//then
+			Expect(err).To(HaveOccurred())
+			cfg, err := sources.Config()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.DefaultDomainName).To(Equal("kyma.local"))
+			Expect(cfg.DomainAllowList).To(Equal([]string{"kyma.local"}))
+		})
+	})
+})
+
+var _ = Describe("Flags", func() {
+
+	It("Should parse lists skipping empty entries", func() {
+		Expect(ParseList(" kyma.local, ,foo.bar")).To(Equal([]string{"kyma.local", "foo.bar"}))
+		Expect(ParseList("")).To(BeNil())
+	})
+
+	It("Should parse labels", func() {
+		labels, err := ParseLabels("managed-by=api-gateway, team=gateway")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(labels).To(Equal(map[string]string{"managed-by": "api-gateway", "team": "gateway"}))
+
+		_, err = ParseLabels("team=a,team=b")
+		Expect(err).To(MatchError("duplicated label: team"))
+
+		_, err = ParseLabels("team")
+		Expect(err).To(MatchError("invalid label format"))
+	})
+})
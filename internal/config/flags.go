@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kyma-incubator/api-gateway/internal/validation"
+	"github.com/pkg/errors"
+)
+
+//ParseList splits a comma-separated list given in a flag, skipping empty entries
+func ParseList(raw string) []string {
+	var result []string
+	for _, s := range strings.Split(raw, ",") {
+		trim := strings.TrimSpace(s)
+		if trim != "" {
+			result = append(result, trim)
+		}
+	}
+	return result
+}
+
+//ParseLabels parses a comma-separated list of key=value pairs given in a flag
+func ParseLabels(labelsString string) (map[string]string, error) {
+
+	output := make(map[string]string)
+
+	if labelsString == "" {
+		return output, nil
+	}
+
+	var err error
+
+	for _, labelString := range strings.Split(labelsString, ",") {
+		trim := strings.TrimSpace(labelString)
+		if trim != "" {
+			label := strings.Split(trim, "=")
+			if len(label) != 2 {
+				return nil, errors.New("invalid label format")
+			}
+
+			key, value := label[0], label[1]
+
+			if err = validation.VerifyLabelKey(key); err != nil {
+				return nil, errors.Wrap(err, "invalid label key")
+			}
+
+			if err = validation.VerifyLabelValue(value); err != nil {
+				return nil, errors.Wrap(err, "invalid label value")
+			}
+
+			_, exists := output[key]
+			if exists {
+				return nil, fmt.Errorf("duplicated label: %s", key)
+			}
+
+			output[key] = value
+		}
+	}
+
+	return output, nil
+}
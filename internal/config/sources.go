@@ -0,0 +1,72 @@
+package config
+
+import (
+	"sync"
+)
+
+//Sources merges the settings from flags, the configuration file and the ConfigMap, in the order of increasing precedence.
+//The last valid content of each source is kept, so a change of one source keeps the settings from the other ones
+type Sources struct {
+	mu        sync.Mutex
+	base      *Config
+	file      []byte
+	configMap []byte
+}
+
+//NewSources creates the sources with the settings from flags, used for settings missing in the configuration file and the ConfigMap
+func NewSources(base *Config) *Sources {
+	return &Sources{base: base}
+}
+
+//SetFile replaces the content of the configuration file and returns the merged configuration.
+//An invalid configuration is rejected and the previous content is kept
+func (s *Sources) SetFile(data []byte) (*Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg, err := s.merge(data, s.configMap)
+	if err != nil {
+		return nil, err
+	}
+	s.file = data
+	return cfg, nil
+}
+
+//SetConfigMap replaces the content of the ConfigMap and returns the merged configuration.
+//An invalid configuration is rejected and the previous content is kept
+func (s *Sources) SetConfigMap(data []byte) (*Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg, err := s.merge(s.file, data)
+	if err != nil {
+		return nil, err
+	}
+	s.configMap = data
+	return cfg, nil
+}
+
+//Config returns the configuration merged from the last valid content of all sources
+func (s *Sources) Config() (*Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.merge(s.file, s.configMap)
+}
+
+func (s *Sources) merge(file, configMap []byte) (*Config, error) {
+	cfg := s.base.DeepCopy()
+	for _, data := range [][]byte{file, configMap} {
+		if data == nil {
+			continue
+		}
+		var err error
+		if cfg, err = Load(data, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
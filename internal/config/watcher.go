@@ -0,0 +1,73 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+//ApplyFunc is called with every new valid configuration
+type ApplyFunc func(cfg *Config)
+
+//FileWatcher periodically reads the configuration file and applies it when its content changes.
+//It works with files projected from a ConfigMap, which are replaced by the kubelet on update.
+type FileWatcher struct {
+	Path string
+	//Sources merge the file with the settings from flags and the ConfigMap
+	Sources  *Sources
+	Interval time.Duration
+	Apply    ApplyFunc
+	Log      logr.Logger
+
+	lastContent []byte
+}
+
+//Read loads the configuration file and returns the configuration merged from all sources. The file is kept in the sources only if it is valid
+func (w *FileWatcher) Read() (*Config, []byte, error) {
+	data, err := ioutil.ReadFile(w.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := w.Sources.SetFile(data)
+	if err != nil {
+		return nil, data, err
+	}
+	return cfg, data, nil
+}
+
+//Start implements manager.Runnable. It polls the file until the context is cancelled
+func (w *FileWatcher) Start(ctx context.Context) error {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			w.reload()
+		}
+	}
+}
+
+func (w *FileWatcher) reload() {
+	cfg, data, err := w.Read()
+	if data != nil && bytes.Equal(data, w.lastContent) {
+		return
+	}
+	w.lastContent = data
+	if err != nil {
+		w.Log.Error(err, "Invalid configuration rejected", "file", w.Path)
+		return
+	}
+	w.Log.Info("Applying new configuration", "file", w.Path)
+	w.Apply(cfg)
+}
+
+//MarkApplied records the content of the configuration already applied at startup
+func (w *FileWatcher) MarkApplied(data []byte) {
+	w.lastContent = data
+}
@@ -0,0 +1,15 @@
+package handlers
+
+func init() {
+	//our internal constant, does not exist in ORY
+	MustRegister(AccessStrategy{Name: "allow", Secured: false, Public: true})
+	MustRegister(AccessStrategy{Name: "noop", Secured: true, Public: true})
+	MustRegister(AccessStrategy{Name: "unauthorized", Secured: true})
+	MustRegister(AccessStrategy{Name: "anonymous", Secured: true, Public: true})
+	MustRegister(AccessStrategy{Name: "cookie_session", Secured: true})
+	MustRegister(AccessStrategy{Name: "oauth2_client_credentials", Secured: true, Config: &ConfigSchema{}})
+	MustRegister(AccessStrategy{Name: "oauth2_introspection", Secured: true, Config: &ConfigSchema{}})
+	MustRegister(AccessStrategy{Name: "jwt", Secured: true, Config: &ConfigSchema{Required: true}})
+	//converted into the bearer_token authenticator checking the keys with the controller
+	MustRegister(AccessStrategy{Name: "api_key", Secured: true, Config: &ConfigSchema{Required: true}})
+}
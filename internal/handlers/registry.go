@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+//TransformFunc converts a handler defined in an APIRule into the handler placed in the generated access rule
+type TransformFunc func(handler *gatewayv1alpha1.Handler) *gatewayv1alpha1.Handler
+
+//AccessStrategy describes an access strategy handler supported by the controller
+type AccessStrategy struct {
+	// Name of the handler as used in APIRule accessStrategies
+	Name string `json:"name"`
+	// Secured tells if requests handled by this strategy are routed through Oathkeeper
+	Secured bool `json:"secured"`
+	// Public tells if the handler lets requests in without credentials
+	Public bool `json:"public,omitempty"`
+	// Config describes the configuration accepted by the handler. Nil means the handler does not support configuration
+	Config *ConfigSchema `json:"config,omitempty"`
+	// OutputName is the name of the handler used in generated access rules. Defaults to Name
+	OutputName string `json:"outputName,omitempty"`
+	// Transform is applied to the handler before it is placed in the generated access rule. Available only for handlers registered in code
+	Transform TransformFunc `json:"-"`
+}
+
+//ConfigSchema is a minimal description of the configuration object accepted by a handler
+type ConfigSchema struct {
+	// Required tells if the configuration must be supplied
+	Required bool `json:"required,omitempty"`
+	// Properties maps allowed configuration keys to their JSON types: string, number, boolean, array or object.
+	// Empty map means any key is allowed
+	Properties map[string]string `json:"properties,omitempty"`
+	// RequiredProperties lists the keys that must be present in the configuration
+	RequiredProperties []string `json:"requiredProperties,omitempty"`
+}
+
+//Config is the format of the file used to register additional access strategies
+type Config struct {
+	AccessStrategies []AccessStrategy `json:"accessStrategies"`
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]AccessStrategy{}
+)
+
+//Register adds an access strategy to the registry. It fails if the name is empty or already registered
+func Register(strategy AccessStrategy) error {
+	if strategy.Name == "" {
+		return fmt.Errorf("access strategy name can't be empty")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := registry[strategy.Name]; exists {
+		return fmt.Errorf("access strategy %s is already registered", strategy.Name)
+	}
+	registry[strategy.Name] = strategy
+	return nil
+}
+
+//MustRegister is like Register but panics on error. Intended for handlers registered in init functions
+func MustRegister(strategy AccessStrategy) {
+	if err := Register(strategy); err != nil {
+		panic(err)
+	}
+}
+
+//Lookup returns the access strategy registered under the given name
+func Lookup(name string) (AccessStrategy, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	strategy, ok := registry[name]
+	return strategy, ok
+}
+
+//Names returns sorted names of all registered access strategies
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//IsSecured tells if requests handled by the given access strategy are routed through Oathkeeper.
+//Unknown strategies are considered secured.
+func IsSecured(name string) bool {
+	strategy, ok := Lookup(name)
+	if !ok {
+		return true
+	}
+	return strategy.Secured
+}
+
+//IsPublic tells if the given access strategy lets requests in without credentials, either by itself or through the handler
+//used in generated access rules. Unknown strategies are not public.
+func IsPublic(name string) bool {
+	strategy, ok := Lookup(name)
+	if !ok {
+		return false
+	}
+	if strategy.Public {
+		return true
+	}
+	if strategy.OutputName == "" || strategy.OutputName == name {
+		return false
+	}
+	output, ok := Lookup(strategy.OutputName)
+	return ok && output.Public
+}
+
+//ToOutput converts a handler defined in an APIRule into the handler placed in the generated access rule
+func ToOutput(handler *gatewayv1alpha1.Handler) *gatewayv1alpha1.Handler {
+	if handler == nil {
+		return nil
+	}
+
+	strategy, ok := Lookup(handler.Name)
+	if !ok {
+		return handler
+	}
+
+	res := handler
+	if strategy.Transform != nil {
+		res = strategy.Transform(handler)
+	}
+	if strategy.OutputName != "" && strategy.OutputName != res.Name {
+		res = &gatewayv1alpha1.Handler{Name: strategy.OutputName, Config: res.Config}
+	}
+	return res
+}
+
+//LoadFile registers access strategies defined in the given YAML or JSON file
+func LoadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return Load(data)
+}
+
+//Load registers access strategies defined in the given YAML or JSON document. The whole document is validated first,
+//so either all access strategies are registered or none of them
+func Load(data []byte) error {
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	loaded := make(map[string]bool)
+	for _, strategy := range cfg.AccessStrategies {
+		if strategy.Name == "" {
+			return fmt.Errorf("access strategy name can't be empty")
+		}
+		if _, exists := registry[strategy.Name]; exists || loaded[strategy.Name] {
+			return fmt.Errorf("access strategy %s is already registered", strategy.Name)
+		}
+		if err := validateSchema(strategy.Config); err != nil {
+			return fmt.Errorf("access strategy %s: %w", strategy.Name, err)
+		}
+		loaded[strategy.Name] = true
+	}
+
+	for _, strategy := range cfg.AccessStrategies {
+		registry[strategy.Name] = strategy
+	}
+	return nil
+}
+
+func validateSchema(schema *ConfigSchema) error {
+	if schema == nil {
+		return nil
+	}
+	for key, kind := range schema.Properties {
+		switch kind {
+		case "string", "number", "boolean", "array", "object":
+		default:
+			return fmt.Errorf("unsupported type %s of config property %s", kind, key)
+		}
+	}
+	return nil
+}
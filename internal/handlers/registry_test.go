@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"testing"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestHandlers(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Handlers Suite")
+}
+
+var _ = Describe("Registry", func() {
+
+	It("Should contain built-in access strategies", func() {
+		Expect(Names()).To(ContainElements("allow", "noop", "jwt", "oauth2_introspection"))
+		Expect(IsSecured("allow")).To(BeFalse())
+		Expect(IsSecured("jwt")).To(BeTrue())
+	})
+
+	It("Should tell the access strategies letting requests in without credentials", func() {
+		//given
+		Expect(Register(AccessStrategy{Name: "test_noop_alias", Secured: true, OutputName: "noop"})).To(Succeed())
+		defer unregister("test_noop_alias")
+
+		//then
+		Expect(IsPublic("allow")).To(BeTrue())
+		Expect(IsPublic("anonymous")).To(BeTrue())
+		Expect(IsPublic("test_noop_alias")).To(BeTrue())
+		Expect(IsPublic("jwt")).To(BeFalse())
+		Expect(IsPublic("not-registered")).To(BeFalse())
+	})
+
+	It("Should treat unknown access strategies as secured", func() {
+		Expect(IsSecured("not-registered")).To(BeTrue())
+	})
+
+	It("Should fail to register a duplicated access strategy", func() {
+		err := Register(AccessStrategy{Name: "noop"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("access strategy noop is already registered"))
+	})
+
+	It("Should fail to register an access strategy with no name", func() {
+		Expect(Register(AccessStrategy{})).To(HaveOccurred())
+	})
+
+	It("Should load access strategies from config", func() {
+		//given
+		config := `
+accessStrategies:
+  - name: test_loaded
+    secured: true
+    outputName: noop
+    config:
+      properties:
+        url: string
+`
+		//when
+		err := Load([]byte(config))
+		defer unregister("test_loaded")
+
+		//then
+		Expect(err).NotTo(HaveOccurred())
+		strategy, ok := Lookup("test_loaded")
+		Expect(ok).To(BeTrue())
+		Expect(strategy.Secured).To(BeTrue())
+		Expect(strategy.Config.Properties).To(HaveKeyWithValue("url", "string"))
+		Expect(ToOutput(&gatewayv1alpha1.Handler{Name: "test_loaded"}).Name).To(Equal("noop"))
+	})
+
+	It("Should reject config with unsupported property type", func() {
+		config := `
+accessStrategies:
+  - name: test_invalid_type
+    config:
+      properties:
+        url: uri
+`
+		err := Load([]byte(config))
+		Expect(err).To(HaveOccurred())
+		_, ok := Lookup("test_invalid_type")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("Should register none of the access strategies of an invalid config", func() {
+		config := `
+accessStrategies:
+  - name: test_valid
+  - name: test_duplicated
+  - name: test_duplicated
+`
+		err := Load([]byte(config))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("access strategy test_duplicated is already registered"))
+		_, ok := Lookup("test_valid")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("Should apply transformation registered in code", func() {
+		//given
+		Expect(Register(AccessStrategy{
+			Name:    "test_transformed",
+			Secured: true,
+			Transform: func(handler *gatewayv1alpha1.Handler) *gatewayv1alpha1.Handler {
+				return &gatewayv1alpha1.Handler{Name: "oauth2_introspection", Config: &runtime.RawExtension{Raw: []byte(`{"required_scope":["read"]}`)}}
+			},
+		})).To(Succeed())
+		defer unregister("test_transformed")
+
+		//when
+		res := ToOutput(&gatewayv1alpha1.Handler{Name: "test_transformed"})
+
+		//then
+		Expect(res.Name).To(Equal("oauth2_introspection"))
+		Expect(string(res.Config.Raw)).To(Equal(`{"required_scope":["read"]}`))
+	})
+})
+
+//unregister removes an access strategy registered by a test, so it doesn't leak into the other tests
+func unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	delete(registry, name)
+}
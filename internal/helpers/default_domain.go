@@ -19,3 +19,22 @@ func HostIncludesDomain(host string) bool {
 func GetHostWithDefaultDomain(host, defaultDomainName string) string {
 	return fmt.Sprintf("%s.%s", host, defaultDomainName)
 }
+
+//HostMatchesDomain tells if the host belongs to the domain. The domain can be a wildcard, such as `*.apps.example.com`,
+//which matches hosts with exactly one label in place of the asterisk. Other domains match the host equal to the domain and all its subdomains.
+func HostMatchesDomain(host, domain string) bool {
+	if strings.HasPrefix(domain, "*.") {
+		suffix := domain[1:]
+		if !strings.HasSuffix(host, suffix) {
+			return false
+		}
+		label := strings.TrimSuffix(host, suffix)
+		return label != "" && !strings.Contains(label, ".")
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+//IsWildcardDomain tells if the domain is a wildcard, such as `*.apps.example.com`
+func IsWildcardDomain(domain string) bool {
+	return strings.HasPrefix(domain, "*.")
+}
@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"regexp"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+)
+
+//pathWildcard ends the regular expressions of paths matched by a prefix
+const pathWildcard = ".*"
+
+//PathPattern converts the regular expression matching the paths of a rule to the path of an Istio AuthorizationPolicy, which matches
+//exact paths and prefixes ending with an asterisk. Literal paths are matched exactly, and literal prefixes followed by .* by their prefix.
+//Returns false for other expressions, such as [^/]+, which would match paths of other rules once widened to a prefix.
+func PathPattern(pathRegex string) (string, bool) {
+	if path, ok := literalPath(pathRegex); ok {
+		return path, true
+	}
+	if !strings.HasSuffix(pathRegex, pathWildcard) {
+		return "", false
+	}
+	if prefix, ok := literalPath(strings.TrimSuffix(pathRegex, pathWildcard)); ok {
+		return prefix + "*", true
+	}
+	return "", false
+}
+
+//RulePathPattern converts the paths matched by the rule to the path of an Istio AuthorizationPolicy, like PathPattern. A gRPC match of
+//all methods of a service is matched by the prefix of the service, as gRPC calls have a single segment after it
+func RulePathPattern(rule gatewayv1alpha1.Rule) (string, bool) {
+	if rule.GRPC != nil && rule.GRPC.Method == "" {
+		return "/" + rule.GRPC.Service + "/*", true
+	}
+	return PathPattern(rule.MatchPath())
+}
+
+func literalPath(pathRegex string) (string, bool) {
+	re, err := regexp.Compile(pathRegex)
+	if err != nil {
+		return "", false
+	}
+	path, complete := re.LiteralPrefix()
+	return path, complete && !strings.Contains(path, "*")
+}
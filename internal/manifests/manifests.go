@@ -0,0 +1,156 @@
+package manifests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+)
+
+//Scheme holds the kinds of objects, which can be read from and written to manifests
+var Scheme = runtime.NewScheme()
+
+func init() {
+	_ = gatewayv1alpha1.AddToScheme(Scheme)
+	_ = networkingv1alpha3.AddToScheme(Scheme)
+	_ = networkingv1beta1.AddToScheme(Scheme)
+	_ = securityv1beta1.AddToScheme(Scheme)
+	_ = rulev1alpha1.AddToScheme(Scheme)
+}
+
+//ReadFiles reads the objects from the manifest files. The "-" path stands for the standard input
+func ReadFiles(paths ...string) ([]client.Object, error) {
+	var res []client.Object
+	for _, path := range paths {
+		var objs []client.Object
+		var err error
+		if path == "-" {
+			objs, err = Read(os.Stdin)
+		} else {
+			objs, err = readFile(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		res = append(res, objs...)
+	}
+	return res, nil
+}
+
+func readFile(path string) ([]client.Object, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+//Read decodes the objects from YAML or JSON documents. Lists, such as the ones printed by kubectl, are expanded into their items.
+//Unknown kinds and unknown fields are rejected.
+func Read(r io.Reader) ([]client.Object, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+	var res []client.Object
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		objs, err := decode(doc)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, objs...)
+	}
+}
+
+func decode(doc []byte) ([]client.Object, error) {
+	if len(bytes.TrimSpace(doc)) == 0 {
+		return nil, nil
+	}
+	var typeMeta metav1.TypeMeta
+	if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+		return nil, err
+	}
+	if typeMeta.Kind == "" && typeMeta.APIVersion == "" {
+		//A document holding only comments
+		return nil, nil
+	}
+
+	if strings.HasSuffix(typeMeta.Kind, "List") {
+		var list struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := yaml.Unmarshal(doc, &list); err != nil {
+			return nil, err
+		}
+		var res []client.Object
+		for _, item := range list.Items {
+			objs, err := decode(item)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, objs...)
+		}
+		return res, nil
+	}
+
+	gvk := schema.FromAPIVersionAndKind(typeMeta.APIVersion, typeMeta.Kind)
+	newObj, err := Scheme.New(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported kind %s", gvk)
+	}
+	obj, ok := newObj.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("unsupported kind %s", gvk)
+	}
+	if err := yaml.UnmarshalStrict(doc, obj); err != nil {
+		return nil, fmt.Errorf("%s %s: %w", gvk.Kind, obj.GetName(), err)
+	}
+	return []client.Object{obj}, nil
+}
+
+//Write encodes the objects as YAML documents. The kind and version of the objects are set if missing
+func Write(w io.Writer, objs []client.Object) error {
+	for i, obj := range objs {
+		if obj.GetObjectKind().GroupVersionKind().Empty() {
+			gvk, err := apiutil.GVKForObject(obj, Scheme)
+			if err != nil {
+				return err
+			}
+			obj.GetObjectKind().SetGroupVersionKind(gvk)
+		}
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
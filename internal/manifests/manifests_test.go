@@ -0,0 +1,83 @@
+package manifests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestManifests(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Manifests Suite")
+}
+
+var _ = Describe("Read", func() {
+
+	It("Should read objects from all documents and lists", func() {
+		//when
+		objs, err := Read(strings.NewReader(`
+# comment only
+---
+apiVersion: gateway.kyma-project.io/v1alpha1
+kind: APIRule
+metadata:
+  name: httpbin
+spec:
+  gateway: kyma-gateway.kyma-system.svc.cluster.local
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: networking.istio.io/v1beta1
+  kind: VirtualService
+  metadata:
+    name: other
+  spec:
+    hosts: ["other.kyma.local"]
+`))
+
+		//then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objs).To(HaveLen(2))
+		Expect(*objs[0].(*gatewayv1alpha1.APIRule).Spec.Gateway).To(Equal("kyma-gateway.kyma-system.svc.cluster.local"))
+		Expect(objs[1].(*networkingv1beta1.VirtualService).Spec.Hosts).To(ConsistOf("other.kyma.local"))
+	})
+
+	It("Should reject unknown kinds and fields", func() {
+		_, err := Read(strings.NewReader("apiVersion: v1\nkind: ConfigMap\n"))
+		Expect(err).To(MatchError(ContainSubstring("unsupported kind")))
+
+		_, err = Read(strings.NewReader("apiVersion: gateway.kyma-project.io/v1alpha1\nkind: APIRule\nspec:\n  gatway: typo\n"))
+		Expect(err).To(MatchError(ContainSubstring("gatway")))
+	})
+})
+
+var _ = Describe("Write", func() {
+
+	It("Should write YAML documents with the kind of the objects", func() {
+		//given
+		vs := &networkingv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{Name: "httpbin", Namespace: "default"}}
+		vs.Spec.Hosts = []string{"httpbin.kyma.local"}
+		api := &gatewayv1alpha1.APIRule{ObjectMeta: metav1.ObjectMeta{Name: "httpbin", Namespace: "default"}}
+
+		//when
+		var buf bytes.Buffer
+		Expect(Write(&buf, []client.Object{vs, api})).To(Succeed())
+
+		//then
+		Expect(buf.String()).To(ContainSubstring("kind: VirtualService\n"))
+		Expect(buf.String()).To(ContainSubstring("---\napiVersion: gateway.kyma-project.io/v1alpha1\nkind: APIRule\n"))
+
+		objs, err := Read(&buf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objs).To(HaveLen(2))
+		Expect(objs[0].(*networkingv1beta1.VirtualService).Spec.Hosts).To(ConsistOf("httpbin.kyma.local"))
+	})
+})
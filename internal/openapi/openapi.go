@@ -0,0 +1,332 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/claims"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+//Document is the part of an OpenAPI 3 document describing operations and their security
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       *Info               `json:"info,omitempty"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components,omitempty"`
+	//Security required by operations, which don't override it
+	Security []SecurityRequirement `json:"security,omitempty"`
+}
+
+//Info describes the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+//Server is a server hosting the API
+type Server struct {
+	URL string `json:"url"`
+}
+
+//PathItem describes the operations available on a path
+type PathItem struct {
+	Get     *Operation `json:"get,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Options *Operation `json:"options,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+	Trace   *Operation `json:"trace,omitempty"`
+	//XPathRegex is the regular expression of the APIRule rule the path is exported from
+	XPathRegex string `json:"x-kyma-path-regex,omitempty"`
+}
+
+//Operation is a single API operation on a path
+type Operation struct {
+	OperationID string `json:"operationId,omitempty"`
+	//Security overrides the security of the document. An empty list makes the operation public
+	Security  *[]SecurityRequirement `json:"security,omitempty"`
+	Responses map[string]Response    `json:"responses,omitempty"`
+	//XAccessStrategies lists the access strategies of the APIRule rule the operation is exported from
+	XAccessStrategies []string `json:"x-kyma-access-strategies,omitempty"`
+}
+
+//Response describes a response of an operation
+type Response struct {
+	Description string `json:"description"`
+}
+
+//SecurityRequirement lists the security schemes required together, with the scopes required for each of them
+type SecurityRequirement map[string][]string
+
+//Components holds the security schemes referenced by security requirements
+type Components struct {
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+//SecurityScheme describes how requests are authenticated
+type SecurityScheme struct {
+	Type             string `json:"type"`
+	Scheme           string `json:"scheme,omitempty"`
+	BearerFormat     string `json:"bearerFormat,omitempty"`
+	OpenIDConnectURL string `json:"openIdConnectUrl,omitempty"`
+}
+
+//Options control how rules are built from the document
+type Options struct {
+	//BasePath is prefixed to the paths of the document. The path of the first server URL is used if not set
+	BasePath *string
+	//PublicHandler is the access strategy of public operations: allow or noop
+	PublicHandler string
+	//JWTIssuer is the issuer trusted for bearer tokens in the JWT format
+	JWTIssuer string
+}
+
+//Parse reads an OpenAPI 3 document in the YAML or JSON format
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q, expected 3.x", doc.OpenAPI)
+	}
+	return &doc, nil
+}
+
+//Rules builds APIRule rules from the operations of the document. Operations on a path with the same security share a rule.
+//Paths, which can't be exposed, are skipped and reported with the errors. The warnings report paths matching the same requests.
+func Rules(doc *Document, opts Options) ([]gatewayv1alpha1.Rule, []error, []string) {
+	basePath := serverBasePath(doc)
+	if opts.BasePath != nil {
+		basePath = *opts.BasePath
+	}
+	basePath = strings.TrimRight(basePath, "/")
+	if opts.PublicHandler == "" {
+		opts.PublicHandler = "allow"
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	//Literal paths go first, so their routes take precedence over the routes of templates matching them
+	sort.Slice(paths, func(i, j int) bool {
+		ti, tj := strings.Contains(paths[i], "{"), strings.Contains(paths[j], "{")
+		if ti != tj {
+			return tj
+		}
+		return paths[i] < paths[j]
+	})
+
+	var rules []gatewayv1alpha1.Rule
+	var errs []error
+	for _, path := range paths {
+		rule, err := buildRule(doc, basePath+path, doc.Paths[path], opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("path %s: %w", path, err))
+			continue
+		}
+		if rule != nil {
+			rules = append(rules, *rule)
+		}
+	}
+
+	var warnings []string
+	for i := range rules {
+		for j := i + 1; j < len(rules); j++ {
+			if claims.PathsOverlap(rules[i].Path, rules[j].Path) {
+				warnings = append(warnings, fmt.Sprintf("paths %s and %s overlap: secured requests matching both are rejected by Oathkeeper", rules[i].Path, rules[j].Path))
+			}
+		}
+	}
+	return rules, errs, warnings
+}
+
+func buildRule(doc *Document, path string, item PathItem, opts Options) (*gatewayv1alpha1.Rule, error) {
+	var methods []string
+	var strategies []*gatewayv1alpha1.Authenticator
+	var strategiesKey string
+	for _, op := range item.operations() {
+		security := doc.Security
+		if op.operation.Security != nil {
+			security = *op.operation.Security
+		}
+		s, err := accessStrategies(doc, security, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op.method, err)
+		}
+		key, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		if strategies != nil && string(key) != strategiesKey {
+			//Rules of an APIRule can't share a path, so all operations on the path need the same security
+			return nil, fmt.Errorf("operations %s and %s require different security, which can't be expressed by a single rule", methods[0], op.method)
+		}
+		strategies, strategiesKey = s, string(key)
+		methods = append(methods, op.method)
+	}
+	if len(methods) == 0 {
+		return nil, nil
+	}
+	return &gatewayv1alpha1.Rule{
+		Path:             pathRegex(path),
+		Methods:          methods,
+		AccessStrategies: strategies,
+	}, nil
+}
+
+//SetOperation sets the operation of the HTTP method. Methods with no field in the path item are ignored
+func (p *PathItem) SetOperation(method string, op *Operation) {
+	switch strings.ToUpper(method) {
+	case "GET":
+		p.Get = op
+	case "PUT":
+		p.Put = op
+	case "POST":
+		p.Post = op
+	case "DELETE":
+		p.Delete = op
+	case "OPTIONS":
+		p.Options = op
+	case "HEAD":
+		p.Head = op
+	case "PATCH":
+		p.Patch = op
+	case "TRACE":
+		p.Trace = op
+	}
+}
+
+type methodOperation struct {
+	method    string
+	operation *Operation
+}
+
+func (p PathItem) operations() []methodOperation {
+	var res []methodOperation
+	for _, op := range []methodOperation{
+		{"GET", p.Get}, {"PUT", p.Put}, {"POST", p.Post}, {"DELETE", p.Delete},
+		{"OPTIONS", p.Options}, {"HEAD", p.Head}, {"PATCH", p.Patch}, {"TRACE", p.Trace},
+	} {
+		if op.operation != nil {
+			res = append(res, op)
+		}
+	}
+	return res
+}
+
+//accessStrategies maps the alternative security requirements to access strategies, which Oathkeeper tries in order.
+//Operations with no security requirements are public. An empty requirement next to others makes the security optional,
+//which maps to the anonymous authenticator tried last, as the public handlers can't be combined with authenticators.
+func accessStrategies(doc *Document, security []SecurityRequirement, opts Options) ([]*gatewayv1alpha1.Authenticator, error) {
+	optional := false
+	var res []*gatewayv1alpha1.Authenticator
+	for _, requirement := range security {
+		if len(requirement) == 0 {
+			optional = true
+			continue
+		}
+		if len(requirement) > 1 {
+			return nil, fmt.Errorf("security requirements combining several schemes are not supported")
+		}
+		for name, scopes := range requirement {
+			scheme, ok := doc.Components.SecuritySchemes[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown security scheme %s", name)
+			}
+			handler, err := securityHandler(name, scheme, scopes, opts)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, &gatewayv1alpha1.Authenticator{Handler: handler})
+		}
+	}
+	if len(res) == 0 {
+		return []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: opts.PublicHandler}}}, nil
+	}
+	if optional {
+		res = append(res, &gatewayv1alpha1.Authenticator{Handler: &gatewayv1alpha1.Handler{Name: "anonymous"}})
+	}
+	return res, nil
+}
+
+//securityHandler maps a security scheme to an access strategy: OpenID Connect and bearer tokens in the JWT format are verified with the jwt
+//handler, OAuth2 and other bearer tokens are introspected with the oauth2_introspection handler
+func securityHandler(name string, scheme SecurityScheme, scopes []string, opts Options) (*gatewayv1alpha1.Handler, error) {
+	config := map[string]interface{}{}
+	if len(scopes) > 0 {
+		config["required_scope"] = scopes
+	}
+
+	var handlerName string
+	switch {
+	case scheme.Type == "openIdConnect":
+		handlerName = "jwt"
+		config["trusted_issuers"] = []string{strings.TrimSuffix(scheme.OpenIDConnectURL, "/.well-known/openid-configuration")}
+	case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer") && strings.EqualFold(scheme.BearerFormat, "JWT"):
+		if opts.JWTIssuer == "" {
+			return nil, fmt.Errorf("security scheme %s accepts JWT bearer tokens, but the trusted issuer is not set", name)
+		}
+		handlerName = "jwt"
+		config["trusted_issuers"] = []string{opts.JWTIssuer}
+	case scheme.Type == "oauth2", scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer"):
+		handlerName = "oauth2_introspection"
+	default:
+		return nil, fmt.Errorf("security scheme %s of type %s is not supported", name, describeScheme(scheme))
+	}
+
+	handler := &gatewayv1alpha1.Handler{Name: handlerName}
+	if len(config) > 0 {
+		raw, err := json.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+		handler.Config = &runtime.RawExtension{Raw: raw}
+	}
+	return handler, nil
+}
+
+func describeScheme(scheme SecurityScheme) string {
+	if scheme.Scheme != "" {
+		return scheme.Type + " " + scheme.Scheme
+	}
+	return scheme.Type
+}
+
+var pathParameter = regexp.MustCompile(`\{[^}/]+\}`)
+
+//pathRegex converts a path template to a regular expression matching the whole path. Parameters match a single path segment
+func pathRegex(template string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range pathParameter.FindAllStringIndex(template, -1) {
+		sb.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		sb.WriteString("[^/]+")
+		last = loc[1]
+	}
+	sb.WriteString(regexp.QuoteMeta(template[last:]))
+	return sb.String()
+}
+
+func serverBasePath(doc *Document) string {
+	if len(doc.Servers) == 0 {
+		return ""
+	}
+	//Server URLs can be templates with variables in braces, which are not parsed as URLs
+	u, err := url.Parse(pathParameter.ReplaceAllString(doc.Servers[0].URL, "x"))
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
@@ -0,0 +1,211 @@
+package openapi
+
+import (
+	"testing"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+func TestOpenAPI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OpenAPI Suite")
+}
+
+const spec = `openapi: 3.0.3
+servers:
+- url: https://{environment}.example.com/api/v1
+security:
+- oidc: [orders:read]
+paths:
+  /orders:
+    get: {}
+    post: {}
+  /orders/{orderId}:
+    get: {}
+    delete:
+      security:
+      - oidc: [orders:read]
+  /health:
+    get:
+      security: []
+  /stats:
+    get:
+      security:
+      - introspection: [stats]
+      - {}
+components:
+  securitySchemes:
+    oidc:
+      type: openIdConnect
+      openIdConnectUrl: https://dex.kyma.local/.well-known/openid-configuration
+    introspection:
+      type: oauth2
+`
+
+var _ = Describe("Rules", func() {
+
+	parse := func(data string) *Document {
+		doc, err := Parse([]byte(data))
+		Expect(err).NotTo(HaveOccurred())
+		return doc
+	}
+
+	handlers := func(rule gatewayv1alpha1.Rule) []string {
+		var res []string
+		for _, strategy := range rule.AccessStrategies {
+			res = append(res, strategy.Name)
+		}
+		return res
+	}
+
+	It("Should build a rule per path with the methods and the access strategies of its operations", func() {
+		//when
+		rules, errs, warnings := Rules(parse(spec), Options{})
+
+		//then
+		Expect(errs).To(BeEmpty())
+		Expect(warnings).To(BeEmpty())
+		Expect(rules).To(HaveLen(4))
+
+		Expect(rules[0].Path).To(Equal("/api/v1/health"))
+		Expect(handlers(rules[0])).To(Equal([]string{"allow"}))
+
+		Expect(rules[1].Path).To(Equal("/api/v1/orders"))
+		Expect(rules[1].Methods).To(Equal([]string{"GET", "POST"}))
+		Expect(handlers(rules[1])).To(Equal([]string{"jwt"}))
+		Expect(string(rules[1].AccessStrategies[0].Config.Raw)).To(Equal(`{"required_scope":["orders:read"],"trusted_issuers":["https://dex.kyma.local"]}`))
+
+		Expect(rules[2].Path).To(Equal("/api/v1/stats"))
+		Expect(handlers(rules[2])).To(Equal([]string{"oauth2_introspection", "anonymous"}))
+		Expect(string(rules[2].AccessStrategies[0].Config.Raw)).To(Equal(`{"required_scope":["stats"]}`))
+
+		Expect(rules[3].Path).To(Equal("/api/v1/orders/[^/]+"))
+		Expect(rules[3].Methods).To(Equal([]string{"GET", "DELETE"}))
+	})
+
+	It("Should use the base path and the public handler from the options", func() {
+		//given
+		basePath := "/"
+
+		//when
+		rules, _, _ := Rules(parse(spec), Options{BasePath: &basePath, PublicHandler: "noop"})
+
+		//then
+		Expect(rules[0].Path).To(Equal("/health"))
+		Expect(handlers(rules[0])).To(Equal([]string{"noop"}))
+	})
+
+	It("Should try the anonymous authenticator after the others if the security is optional", func() {
+		//given
+		doc := parse(`openapi: 3.0.0
+paths:
+  /items:
+    get:
+      security:
+      - {}
+      - bearer: []
+  /public:
+    get:
+      security:
+      - {}
+components:
+  securitySchemes:
+    bearer:
+      type: http
+      scheme: bearer
+`)
+
+		//when
+		rules, errs, _ := Rules(doc, Options{PublicHandler: "noop"})
+
+		//then
+		Expect(errs).To(BeEmpty())
+		Expect(rules).To(HaveLen(2))
+		Expect(handlers(rules[0])).To(Equal([]string{"oauth2_introspection", "anonymous"}))
+		Expect(handlers(rules[1])).To(Equal([]string{"noop"}))
+	})
+
+	It("Should skip paths with operations requiring different security", func() {
+		//given
+		doc := parse(`openapi: 3.0.0
+paths:
+  /items:
+    get:
+      security: []
+    post:
+      security:
+      - bearer: []
+  /other:
+    get: {}
+components:
+  securitySchemes:
+    bearer:
+      type: http
+      scheme: bearer
+`)
+
+		//when
+		rules, errs, _ := Rules(doc, Options{})
+
+		//then
+		Expect(rules).To(HaveLen(1))
+		Expect(rules[0].Path).To(Equal("/other"))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0]).To(MatchError("path /items: operations GET and POST require different security, which can't be expressed by a single rule"))
+	})
+
+	It("Should warn about paths matching the same requests", func() {
+		//given
+		doc := parse(`openapi: 3.0.0
+paths:
+  /users/{id}:
+    get: {}
+  /users/me:
+    get: {}
+`)
+
+		//when
+		rules, _, warnings := Rules(doc, Options{})
+
+		//then
+		Expect(rules[0].Path).To(Equal("/users/me"))
+		Expect(warnings).To(ConsistOf(ContainSubstring("paths /users/me and /users/[^/]+ overlap")))
+	})
+
+	table.DescribeTable("Should map security schemes to access strategies",
+		func(scheme SecurityScheme, opts Options, expectedHandler, expectedConfig, expectedErr string) {
+			handler, err := securityHandler("scheme", scheme, nil, opts)
+
+			if expectedErr != "" {
+				Expect(err).To(MatchError(expectedErr))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(handler.Name).To(Equal(expectedHandler))
+			if expectedConfig == "" {
+				Expect(handler.Config).To(BeNil())
+			} else {
+				Expect(string(handler.Config.Raw)).To(Equal(expectedConfig))
+			}
+		},
+		table.Entry("JWT bearer token", SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}, Options{JWTIssuer: "https://issuer"}, "jwt", `{"trusted_issuers":["https://issuer"]}`, ""),
+		table.Entry("JWT bearer token with no issuer", SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}, Options{}, "", "", "security scheme scheme accepts JWT bearer tokens, but the trusted issuer is not set"),
+		table.Entry("opaque bearer token", SecurityScheme{Type: "http", Scheme: "Bearer"}, Options{}, "oauth2_introspection", "", ""),
+		table.Entry("OAuth2", SecurityScheme{Type: "oauth2"}, Options{}, "oauth2_introspection", "", ""),
+		table.Entry("basic authentication", SecurityScheme{Type: "http", Scheme: "basic"}, Options{}, "", "", "security scheme scheme of type http basic is not supported"),
+		table.Entry("API key", SecurityScheme{Type: "apiKey"}, Options{}, "", "", "security scheme scheme of type apiKey is not supported"),
+	)
+
+	It("Should reject documents in other versions than OpenAPI 3", func() {
+		_, err := Parse([]byte(`swagger: "2.0"`))
+
+		Expect(err).To(MatchError(`unsupported OpenAPI version "", expected 3.x`))
+	})
+
+	It("Should quote the literal parts of path templates", func() {
+		Expect(pathRegex("/files/{name}.json")).To(Equal(`/files/[^/]+\.json`))
+	})
+})
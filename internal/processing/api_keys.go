@@ -0,0 +1,59 @@
+package processing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/types/ory"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+//APIKeys is an internal representation of the settings of the api_key access strategy
+type APIKeys struct {
+	//URL of the endpoint of the controller checking the keys, as reachable from Oathkeeper
+	CheckURL string
+}
+
+//withAPIKeyCheck replaces the api_key access strategies with the bearer_token authenticator, which takes the key from the configured header
+//or query parameter and checks it with the controller. The URL checking the key identifies the Secret holding the keys and where the key is
+//carried, as Oathkeeper forwards the request to it with the query, but not the path. Oathkeeper forwards only the listed headers, so the
+//header carrying the key is listed.
+//The access strategies are returned unchanged if API keys are not enabled, which is reported by the validation.
+func (f *Factory) withAPIKeyCheck(api *gatewayv1alpha1.APIRule, accessStrategies []*gatewayv1alpha1.Authenticator) []*gatewayv1alpha1.Authenticator {
+	if f.APIKeys == nil {
+		return accessStrategies
+	}
+
+	res := make([]*gatewayv1alpha1.Authenticator, len(accessStrategies))
+	for i, strategy := range accessStrategies {
+		res[i] = strategy
+		if strategy.Handler == nil || strategy.Name != "api_key" || strategy.Config == nil {
+			continue
+		}
+		var config gatewayv1alpha1.APIKeyAccStrConfig
+		if err := json.Unmarshal(strategy.Config.Raw, &config); err != nil {
+			continue
+		}
+
+		location, tokenFrom, forwardedHeaders := "header", &ory.TokenFrom{Header: config.Header}, []string{config.Header}
+		if config.QueryParameter != "" {
+			location, tokenFrom, forwardedHeaders = "query", &ory.TokenFrom{QueryParameter: config.QueryParameter}, nil
+		}
+		name := config.Header + config.QueryParameter
+		raw, err := json.Marshal(ory.BearerTokenConfig{
+			CheckSessionURL: fmt.Sprintf("%s/%s/%s/%s/%s", strings.TrimSuffix(f.APIKeys.CheckURL, "/"),
+				api.Namespace, config.SecretName, location, url.PathEscape(name)),
+			PreservePath:       true,
+			TokenFrom:          tokenFrom,
+			ForwardHTTPHeaders: forwardedHeaders,
+		})
+		if err != nil {
+			continue
+		}
+		res[i] = &gatewayv1alpha1.Authenticator{Handler: &gatewayv1alpha1.Handler{Name: "bearer_token", Config: &runtime.RawExtension{Raw: raw}}}
+	}
+	return res
+}
@@ -0,0 +1,66 @@
+package processing
+
+import (
+	"encoding/json"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/types/ory"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("API keys", func() {
+
+	apiKey := func(config string) []*gatewayv1alpha1.Authenticator {
+		return []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "api_key", Config: &runtime.RawExtension{Raw: []byte(config)}}}}
+	}
+
+	newFactory := func() *Factory {
+		f := NewFactory(nil, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+		f.APIKeys = &APIKeys{CheckURL: "http://api-gateway-metrics.kyma-system.svc.cluster.local:8080/api-keys/"}
+		return f
+	}
+
+	bearerToken := func(state *State) ory.BearerTokenConfig {
+		Expect(state.accessRules).To(HaveLen(1))
+		var authenticator *rulev1alpha1.Handler
+		for _, rule := range state.accessRules {
+			Expect(rule.Spec.Authenticators).To(HaveLen(1))
+			authenticator = rule.Spec.Authenticators[0].Handler
+		}
+		Expect(authenticator.Name).To(Equal("bearer_token"))
+		var config ory.BearerTokenConfig
+		Expect(json.Unmarshal(authenticator.Config.Raw, &config)).To(Succeed())
+		return config
+	}
+
+	It("should check keys from a header with the controller", func() {
+		//given
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, nil, apiKey(`{"secretName":"partner-keys","header":"X-API-Key"}`))})
+
+		//when
+		config := bearerToken(newFactory().CalculateRequiredState(apiRule))
+
+		//then
+		Expect(config.CheckSessionURL).To(Equal("http://api-gateway-metrics.kyma-system.svc.cluster.local:8080/api-keys/" + apiNamespace + "/partner-keys/header/X-API-Key"))
+		Expect(config.PreservePath).To(BeTrue())
+		Expect(config.TokenFrom).To(Equal(&ory.TokenFrom{Header: "X-API-Key"}))
+		Expect(config.ForwardHTTPHeaders).To(Equal([]string{"X-API-Key"}))
+	})
+
+	It("should check keys from a query parameter with the controller", func() {
+		//given
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, nil, apiKey(`{"secretName":"partner-keys","queryParameter":"api_key"}`))})
+
+		//when
+		config := bearerToken(newFactory().CalculateRequiredState(apiRule))
+
+		//then
+		Expect(config.CheckSessionURL).To(HaveSuffix("/api-keys/" + apiNamespace + "/partner-keys/query/api_key"))
+		Expect(config.TokenFrom).To(Equal(&ory.TokenFrom{QueryParameter: "api_key"}))
+		Expect(config.ForwardHTTPHeaders).To(BeEmpty())
+	})
+})
@@ -0,0 +1,140 @@
+package processing
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	k8sMeta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	//maxChildNamePrefix keeps names of generated objects within 63 characters
+	maxChildNamePrefix  = 52
+	childNameHashLength = 10
+
+	virtualServiceKind        = "VirtualService"
+	destinationRuleKind       = "DestinationRule"
+	gatewayKind               = "Gateway"
+	certificateKind           = "Certificate"
+	envoyFilterKind           = "EnvoyFilter"
+	authorizationPolicyKind   = "AuthorizationPolicy"
+	requestAuthenticationKind = "RequestAuthentication"
+	accessRuleKind            = "AccessRule"
+)
+
+//childName returns the name of an object generated for the APIRule. The name is deterministic, so objects created by retried or racing
+//reconciliations collide instead of being duplicated. The suffix is a hash of the APIRule and the given parts, such as the matched URL.
+func childName(api *gatewayv1alpha1.APIRule, parts ...string) string {
+	key := strings.Join(append([]string{api.ObjectMeta.Namespace, api.ObjectMeta.Name}, parts...), "/")
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(key)))[:childNameHashLength]
+
+	prefix := api.ObjectMeta.Name
+	if len(prefix) > maxChildNamePrefix {
+		prefix = prefix[:maxChildNamePrefix]
+	}
+	return fmt.Sprintf("%s-%s", strings.TrimRight(prefix, "-."), hash)
+}
+
+//pickChild selects the object to keep among the objects generated for the same purpose. The object with the expected name is preferred.
+//Otherwise the oldest one is kept, so objects created with generated names before are not recreated. The other objects are duplicates.
+func pickChild(objs []client.Object, name string) (client.Object, []client.Object) {
+	if len(objs) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(objs, func(i, j int) bool {
+		if (objs[i].GetName() == name) != (objs[j].GetName() == name) {
+			return objs[i].GetName() == name
+		}
+		ti, tj := objs[i].GetCreationTimestamp(), objs[j].GetCreationTimestamp()
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return objs[i].GetName() < objs[j].GetName()
+	})
+	return objs[0], objs[1:]
+}
+
+//getOrphan returns the object with the name of the required one existing without the owner label, so applying the required object adopts it.
+//Returns nil if there is no such object. Objects controlled by others are not adopted.
+func (f *Factory) getOrphan(ctx context.Context, required client.Object) (client.Object, error) {
+	existing, ok := required.DeepCopyObject().(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("cannot adopt %s", required.GetName())
+	}
+	if err := f.client.Get(ctx, client.ObjectKeyFromObject(required), existing); err != nil {
+		if apierrs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if owner := k8sMeta.GetControllerOf(existing); owner != nil {
+		requiredOwner := k8sMeta.GetControllerOf(required)
+		if requiredOwner == nil || owner.UID != requiredOwner.UID {
+			return nil, fmt.Errorf("%s %s/%s is controlled by %s %s", kindOf(required), existing.GetNamespace(), existing.GetName(), owner.Kind, owner.Name)
+		}
+	}
+	return existing, nil
+}
+
+//isUserManaged tells if the object is marked to be left unchanged by the controller
+func isUserManaged(obj client.Object) bool {
+	return obj != nil && obj.GetAnnotations()[gatewayv1alpha1.UserManagedAnnotation] == "true"
+}
+
+//withNameOf names the required object after the existing one, so it is applied to the existing object even if it was created with another name
+func withNameOf(required, existing client.Object) client.Object {
+	required.SetName(existing.GetName())
+	return required
+}
+
+//setTypeMeta sets the kind and version of the object, which are required by server-side apply
+func setTypeMeta(obj client.Object) {
+	switch obj.(type) {
+	case *networkingv1beta1.VirtualService:
+		obj.GetObjectKind().SetGroupVersionKind(networkingv1beta1.SchemeGroupVersion.WithKind("VirtualService"))
+	case *networkingv1beta1.DestinationRule:
+		obj.GetObjectKind().SetGroupVersionKind(networkingv1beta1.SchemeGroupVersion.WithKind("DestinationRule"))
+	case *networkingv1beta1.Gateway:
+		obj.GetObjectKind().SetGroupVersionKind(networkingv1beta1.SchemeGroupVersion.WithKind("Gateway"))
+	case *networkingv1alpha3.EnvoyFilter:
+		obj.GetObjectKind().SetGroupVersionKind(networkingv1alpha3.SchemeGroupVersion.WithKind("EnvoyFilter"))
+	case *securityv1beta1.AuthorizationPolicy:
+		obj.GetObjectKind().SetGroupVersionKind(securityv1beta1.SchemeGroupVersion.WithKind("AuthorizationPolicy"))
+	case *securityv1beta1.RequestAuthentication:
+		obj.GetObjectKind().SetGroupVersionKind(securityv1beta1.SchemeGroupVersion.WithKind("RequestAuthentication"))
+	case *rulev1alpha1.Rule:
+		obj.GetObjectKind().SetGroupVersionKind(rulev1alpha1.GroupVersion.WithKind("Rule"))
+	}
+}
+
+func kindOf(obj client.Object) string {
+	switch obj.(type) {
+	case *networkingv1beta1.VirtualService:
+		return virtualServiceKind
+	case *networkingv1beta1.DestinationRule:
+		return destinationRuleKind
+	case *networkingv1beta1.Gateway:
+		return gatewayKind
+	case *networkingv1alpha3.EnvoyFilter:
+		return envoyFilterKind
+	case *securityv1beta1.AuthorizationPolicy:
+		return authorizationPolicyKind
+	case *securityv1beta1.RequestAuthentication:
+		return requestAuthenticationKind
+	case *unstructured.Unstructured:
+		return certificateKind
+	}
+	return accessRuleKind
+}
@@ -0,0 +1,358 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Child objects", func() {
+
+	allow := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "allow"}}}
+	noop := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "noop"}}}
+
+	newFactory := func(objs ...runtime.Object) (*Factory, client.Client) {
+		s := runtime.NewScheme()
+		Expect(networkingv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(networkingv1alpha3.AddToScheme(s)).To(Succeed())
+		Expect(securityv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(rulev1alpha1.AddToScheme(s)).To(Succeed())
+		c := &applyClient{Client: fake.NewFakeClientWithScheme(s, objs...)}
+		return NewFactory(c, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain), c
+	}
+
+	It("should use deterministic names", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, nil, allow)})
+
+		Expect(childName(apiRule)).To(Equal(childName(apiRule)))
+		Expect(childName(apiRule)).To(HavePrefix(apiName + "-"))
+		Expect(childName(apiRule, "/a")).NotTo(Equal(childName(apiRule, "/b")))
+
+		apiRule.Name = strings.Repeat("a", 253)
+		Expect(len(childName(apiRule))).To(BeNumerically("<=", 63))
+	})
+
+	It("should delete duplicated objects and report the repair", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(headersAPIPath, apiMethods, nil, noop)})
+		labels := map[string]string{OwnerLabel: fmt.Sprintf("%s.%s", apiName, apiNamespace)}
+		kept := &networkingv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{Name: childName(apiRule), Namespace: apiNamespace, Labels: labels}}
+		duplicate := &networkingv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{Name: apiName + "-x7k2p", Namespace: apiNamespace, Labels: labels}}
+		f, c := newFactory(kept, duplicate)
+
+		desiredState := f.CalculateRequiredState(apiRule)
+		actualState, err := f.GetActualState(context.Background(), apiRule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actualState.virtualService.Name).To(Equal(childName(apiRule)))
+
+		patch := f.CalculateDiff(desiredState, actualState)
+		Expect(f.ApplyDiff(context.Background(), patch)).To(Succeed())
+
+		var vsList networkingv1beta1.VirtualServiceList
+		Expect(c.List(context.Background(), &vsList)).To(Succeed())
+		Expect(vsList.Items).To(HaveLen(1))
+		Expect(vsList.Items[0].Name).To(Equal(childName(apiRule)))
+		Expect(patch.VirtualServiceRepairs()).To(Equal("Repaired: deleted duplicate " + apiName + "-x7k2p"))
+		Expect(patch.AccessRuleRepairs()).To(BeEmpty())
+	})
+
+	It("should keep the oldest object if none has the deterministic name", func() {
+		older := &networkingv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{Name: "b", CreationTimestamp: metav1.Unix(10, 0)}}
+		newer := &networkingv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{Name: "a", CreationTimestamp: metav1.Unix(20, 0)}}
+
+		kept, duplicates := pickChild([]client.Object{newer, older}, "c")
+
+		Expect(kept.GetName()).To(Equal("b"))
+		Expect(duplicates).To(HaveLen(1))
+		Expect(duplicates[0].GetName()).To(Equal("a"))
+	})
+
+	It("should adopt an orphaned object with the deterministic name", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(headersAPIPath, apiMethods, nil, noop)})
+		desiredURL := fmt.Sprintf("<http|https>://%s<%s>", serviceHost, headersAPIPath)
+		orphan := &rulev1alpha1.Rule{ObjectMeta: metav1.ObjectMeta{Name: childName(apiRule, desiredURL), Namespace: apiNamespace}}
+		f, c := newFactory(orphan)
+
+		desiredState := f.CalculateRequiredState(apiRule)
+		actualState, err := f.GetActualState(context.Background(), apiRule)
+		Expect(err).NotTo(HaveOccurred())
+
+		patch := f.CalculateDiff(desiredState, actualState)
+		Expect(f.ApplyDiff(context.Background(), patch)).To(Succeed())
+
+		adopted := &rulev1alpha1.Rule{}
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(orphan), adopted)).To(Succeed())
+		Expect(adopted.Labels[OwnerLabel]).To(Equal(fmt.Sprintf("%s.%s", apiName, apiNamespace)))
+		Expect(adopted.OwnerReferences[0].UID).To(Equal(apiUID))
+		Expect(adopted.Spec.Match.URL).To(Equal(desiredURL))
+		Expect(patch.AccessRuleRepairs()).To(Equal("Repaired: adopted orphan " + orphan.Name))
+	})
+
+	It("should leave user-managed objects unchanged and report them", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(headersAPIPath, apiMethods, nil, noop)})
+		labels := map[string]string{OwnerLabel: fmt.Sprintf("%s.%s", apiName, apiNamespace)}
+		annotations := map[string]string{gatewayv1alpha1.UserManagedAnnotation: "true"}
+		managed := &networkingv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{Name: childName(apiRule), Namespace: apiNamespace, Labels: labels, Annotations: annotations}}
+		f, c := newFactory(managed)
+
+		desiredState := f.CalculateRequiredState(apiRule)
+		actualState, err := f.GetActualState(context.Background(), apiRule)
+		Expect(err).NotTo(HaveOccurred())
+
+		patch := f.CalculateDiff(desiredState, actualState)
+		Expect(f.ApplyDiff(context.Background(), patch)).To(Succeed())
+
+		vs := &networkingv1beta1.VirtualService{}
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(managed), vs)).To(Succeed())
+		Expect(vs.Spec.Hosts).To(BeEmpty())
+		Expect(patch.VirtualServiceRepairs()).To(Equal("Left unchanged as user-managed: " + managed.Name))
+
+		var arList rulev1alpha1.RuleList
+		Expect(c.List(context.Background(), &arList)).To(Succeed())
+		Expect(arList.Items).To(HaveLen(1))
+	})
+
+	It("should not adopt a user-managed orphan", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(headersAPIPath, apiMethods, nil, noop)})
+		desiredURL := fmt.Sprintf("<http|https>://%s<%s>", serviceHost, headersAPIPath)
+		orphan := &rulev1alpha1.Rule{ObjectMeta: metav1.ObjectMeta{
+			Name:        childName(apiRule, desiredURL),
+			Namespace:   apiNamespace,
+			Annotations: map[string]string{gatewayv1alpha1.UserManagedAnnotation: "true"},
+		}}
+		f, c := newFactory(orphan)
+
+		desiredState := f.CalculateRequiredState(apiRule)
+		actualState, err := f.GetActualState(context.Background(), apiRule)
+		Expect(err).NotTo(HaveOccurred())
+
+		patch := f.CalculateDiff(desiredState, actualState)
+		Expect(f.ApplyDiff(context.Background(), patch)).To(Succeed())
+
+		kept := &rulev1alpha1.Rule{}
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(orphan), kept)).To(Succeed())
+		Expect(kept.Labels).NotTo(HaveKey(OwnerLabel))
+		Expect(kept.Spec.Match).To(BeNil())
+		Expect(patch.AccessRuleRepairs()).To(Equal("Left unchanged as user-managed: " + orphan.Name))
+	})
+
+	It("should not adopt an object controlled by another owner", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, nil, allow)})
+		controller := true
+		foreign := &networkingv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{
+			Name:            childName(apiRule),
+			Namespace:       apiNamespace,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "other", UID: "other-uid", Controller: &controller}},
+		}}
+		f, _ := newFactory(foreign)
+
+		desiredState := f.CalculateRequiredState(apiRule)
+		actualState, err := f.GetActualState(context.Background(), apiRule)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = f.ApplyDiff(context.Background(), f.CalculateDiff(desiredState, actualState))
+		Expect(err).To(MatchError(ContainSubstring("is controlled by Deployment other")))
+	})
+
+	It("should tell the holder of a host from the oldest Virtual Service generated for it", func() {
+		vs := func(name, owner string, createdAfter int) networkingv1beta1.VirtualService {
+			res := networkingv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         apiNamespace,
+				CreationTimestamp: metav1.Unix(int64(createdAfter), 0),
+			}}
+			if owner != "" {
+				res.Labels = map[string]string{OwnerLabel: owner}
+			}
+			res.Spec.Hosts = []string{serviceHost}
+			return res
+		}
+		vsList := networkingv1beta1.VirtualServiceList{Items: []networkingv1beta1.VirtualService{
+			vs("user", "", 0),
+			vs("oldest", "shop.v1."+apiNamespace, 1),
+			vs("newer", "cart."+apiNamespace, 2),
+		}}
+
+		Expect(HostHolder(vsList, serviceHost)).To(Equal(&types.NamespacedName{Namespace: apiNamespace, Name: "shop.v1"}))
+		Expect(HostHolder(vsList, "other."+defaultDomain)).To(BeNil())
+	})
+})
+
+var _ = Describe("ApplyDiff", func() {
+
+	noop := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "noop"}}}
+
+	newFactory := func(c client.Client) *Factory {
+		return NewFactory(c, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+	}
+
+	newClient := func(objs ...runtime.Object) *applyClient {
+		s := runtime.NewScheme()
+		Expect(networkingv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(networkingv1alpha3.AddToScheme(s)).To(Succeed())
+		Expect(securityv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(rulev1alpha1.AddToScheme(s)).To(Succeed())
+		return &applyClient{Client: fake.NewFakeClientWithScheme(s, objs...)}
+	}
+
+	It("should apply objects with the field manager of the controller", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(headersAPIPath, apiMethods, nil, noop)})
+		c := newClient()
+		f := newFactory(c)
+
+		desiredState := f.CalculateRequiredState(apiRule)
+		patch := f.CalculateDiff(desiredState, &State{})
+		Expect(f.ApplyDiff(context.Background(), patch)).To(Succeed())
+
+		Expect(c.fieldOwners).To(ConsistOf(FieldManager, FieldManager))
+		vs := patch.virtualService.obj.(*networkingv1beta1.VirtualService)
+		Expect(vs.APIVersion).To(Equal("networking.istio.io/v1beta1"))
+		Expect(vs.Kind).To(Equal("VirtualService"))
+	})
+
+	It("should keep labels set by others when updating objects", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(headersAPIPath, apiMethods, nil, noop)})
+		existing := &networkingv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{
+			Name:      childName(apiRule),
+			Namespace: apiNamespace,
+			Labels:    map[string]string{OwnerLabel: fmt.Sprintf("%s.%s", apiName, apiNamespace), "team": "shop"},
+		}}
+		c := newClient(existing)
+		f := newFactory(c)
+
+		desiredState := f.CalculateRequiredState(apiRule)
+		actualState, err := f.GetActualState(context.Background(), apiRule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.ApplyDiff(context.Background(), f.CalculateDiff(desiredState, actualState))).To(Succeed())
+
+		vs := &networkingv1beta1.VirtualService{}
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(existing), vs)).To(Succeed())
+		Expect(vs.Labels).To(HaveKeyWithValue("team", "shop"))
+		Expect(vs.Labels).To(HaveKeyWithValue(testLabelKey, testLabelValue))
+		Expect(vs.Spec.Http).To(HaveLen(1))
+	})
+
+	It("should take over the fields of objects updated before the controller used server-side apply", func() {
+		//given
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(headersAPIPath, apiMethods, nil, noop)})
+		existing := &networkingv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{
+			Name:          childName(apiRule),
+			Namespace:     apiNamespace,
+			Labels:        map[string]string{OwnerLabel: fmt.Sprintf("%s.%s", apiName, apiNamespace)},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "manager", Operation: metav1.ManagedFieldsOperationUpdate}},
+		}}
+		c := newClient(existing)
+		c.conflicting = childName(apiRule)
+		f := newFactory(c)
+
+		//when
+		actualState, err := f.GetActualState(context.Background(), apiRule)
+		Expect(err).NotTo(HaveOccurred())
+		patch := f.CalculateDiff(f.CalculateRequiredState(apiRule), actualState)
+		Expect(f.ApplyDiff(context.Background(), patch)).To(Succeed())
+
+		//then
+		Expect(patch.HasConflicts()).To(BeFalse())
+		Expect(c.forced).To(ConsistOf(childName(apiRule)))
+	})
+
+	It("should not force the ownership of objects applied by the controller", func() {
+		//given
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(headersAPIPath, apiMethods, nil, noop)})
+		existing := &networkingv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{
+			Name:      childName(apiRule),
+			Namespace: apiNamespace,
+			Labels:    map[string]string{OwnerLabel: fmt.Sprintf("%s.%s", apiName, apiNamespace)},
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: FieldManager, Operation: metav1.ManagedFieldsOperationApply},
+				{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate},
+			},
+		}}
+		c := newClient(existing)
+		c.conflicting = childName(apiRule)
+		f := newFactory(c)
+
+		//when
+		actualState, err := f.GetActualState(context.Background(), apiRule)
+		Expect(err).NotTo(HaveOccurred())
+		patch := f.CalculateDiff(f.CalculateRequiredState(apiRule), actualState)
+		Expect(f.ApplyDiff(context.Background(), patch)).To(Succeed())
+
+		//then
+		Expect(patch.HasConflicts()).To(BeTrue())
+		Expect(c.forced).To(BeEmpty())
+	})
+
+	It("should report conflicts and apply the other objects", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(headersAPIPath, apiMethods, nil, noop)})
+		c := newClient()
+		c.conflicting = childName(apiRule)
+		f := newFactory(c)
+
+		desiredState := f.CalculateRequiredState(apiRule)
+		patch := f.CalculateDiff(desiredState, &State{})
+		Expect(f.ApplyDiff(context.Background(), patch)).To(Succeed())
+
+		Expect(patch.HasConflicts()).To(BeTrue())
+		Expect(patch.VirtualServiceConflicts()).To(HavePrefix("Conflicts: " + childName(apiRule) + ": "))
+		Expect(patch.VirtualServiceConflicts()).To(ContainSubstring(".spec.http"))
+		Expect(patch.AccessRuleConflicts()).To(BeEmpty())
+
+		var ruleList rulev1alpha1.RuleList
+		Expect(c.List(context.Background(), &ruleList)).To(Succeed())
+		Expect(ruleList.Items).To(HaveLen(1))
+	})
+})
+
+//applyClient emulates server-side apply, which is not supported by the fake client, with create and merge patch
+type applyClient struct {
+	client.Client
+	//conflicting names the object rejected with a conflict
+	conflicting string
+	//fieldOwners lists the field managers of all apply requests
+	fieldOwners []string
+	//forced lists the names of the objects applied with forced ownership
+	forced []string
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+	patchOptions := &client.PatchOptions{}
+	patchOptions.ApplyOptions(opts)
+	c.fieldOwners = append(c.fieldOwners, patchOptions.FieldManager)
+	if patchOptions.Force != nil && *patchOptions.Force {
+		c.forced = append(c.forced, obj.GetName())
+	} else if obj.GetName() == c.conflicting {
+		return apierrs.NewConflict(schema.GroupResource{}, obj.GetName(), fmt.Errorf("conflict with \"kubectl-edit\": .spec.http"))
+	}
+
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		if apierrs.IsNotFound(err) {
+			return c.Create(ctx, obj)
+		}
+		return err
+	}
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	return c.Client.Patch(ctx, obj, client.RawPatch(types.MergePatchType, data))
+}
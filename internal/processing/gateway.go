@@ -0,0 +1,155 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/builders"
+	"github.com/kyma-incubator/api-gateway/internal/helpers"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//CertificateGVK is the kind of cert-manager Certificates generated for provisioned hosts. cert-manager is an optional dependency,
+//so Certificates are handled as unstructured objects
+var CertificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+var defaultGatewaySelector = map[string]string{"istio": "ingressgateway"}
+
+const defaultGatewayNamespace = "istio-system"
+
+const defaultIssuerKind = "ClusterIssuer"
+
+//GatewayProvisioning is an internal representation of the settings of Gateways and Certificates generated for hosts not covered by the
+//wildcard certificate
+type GatewayProvisioning struct {
+	WildcardDomains []string
+	Namespace       string
+	Selector        map[string]string
+	IssuerName      string
+	IssuerKind      string
+}
+
+//provisions tells if a Gateway and a Certificate are generated for the host. Hosts covered by the wildcard certificate are exposed
+//with the gateway set in the APIRule
+func (p *GatewayProvisioning) provisions(host string) bool {
+	if p == nil {
+		return false
+	}
+	for _, domain := range p.WildcardDomains {
+		if helpers.IsWildcardDomain(domain) && helpers.HostMatchesDomain(host, domain) || host == domain {
+			return false
+		}
+	}
+	return true
+}
+
+//generateGateway returns the Gateway terminating TLS for the host of the holder with the certificate provisioned for it
+func (f *Factory) generateGateway(holder *gatewayv1alpha1.APIRule, host string) *networkingv1beta1.Gateway {
+	ownerRef := generateOwnerRef(holder)
+
+	selector := f.GatewayProvisioning.Selector
+	if len(selector) == 0 {
+		selector = defaultGatewaySelector
+	}
+
+	gwBuilder := builders.Gateway().
+		Name(childName(holder)).
+		Namespace(holder.ObjectMeta.Namespace).
+		Owner(builders.OwnerReference().From(&ownerRef)).
+		Label(OwnerLabel, fmt.Sprintf("%s.%s", holder.ObjectMeta.Name, holder.ObjectMeta.Namespace)).
+		Spec(builders.GatewaySpec().
+			Selector(selector).
+			HTTPSServer(host, childName(holder)).
+			HTTPRedirectServer(host))
+
+	for k, v := range f.additionalLabels {
+		gwBuilder.Label(k, v)
+	}
+
+	return gwBuilder.Get()
+}
+
+//generateCertificate returns the Certificate for the host of the holder. The Certificate and its Secret are created in the namespace of
+//the ingress gateway, which reads the Secret, so they can't be owned by the APIRule and are deleted by the owner label instead
+func (f *Factory) generateCertificate(holder *gatewayv1alpha1.APIRule, host string) *unstructured.Unstructured {
+	issuerKind := f.GatewayProvisioning.IssuerKind
+	if issuerKind == "" {
+		issuerKind = defaultIssuerKind
+	}
+
+	cert := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"secretName": childName(holder),
+			"dnsNames":   []interface{}{host},
+			"issuerRef": map[string]interface{}{
+				"name":  f.GatewayProvisioning.IssuerName,
+				"kind":  issuerKind,
+				"group": CertificateGVK.Group,
+			},
+		},
+	}}
+	cert.SetGroupVersionKind(CertificateGVK)
+	cert.SetName(childName(holder))
+	cert.SetNamespace(f.GatewayProvisioning.Namespace)
+
+	labels := map[string]string{OwnerLabel: fmt.Sprintf("%s.%s", holder.ObjectMeta.Name, holder.ObjectMeta.Namespace)}
+	for k, v := range f.additionalLabels {
+		labels[k] = v
+	}
+	cert.SetLabels(labels)
+
+	return cert
+}
+
+//listCertificates returns the Certificates generated for the api. cert-manager isn't required unless provisioning is enabled,
+//so no Certificates are listed if it's disabled or the Certificate kind isn't installed
+func (f *Factory) listCertificates(ctx context.Context, api *gatewayv1alpha1.APIRule) ([]client.Object, error) {
+	if f.GatewayProvisioning == nil {
+		return nil, nil
+	}
+	var certList unstructured.UnstructuredList
+	certList.SetGroupVersionKind(CertificateGVK.GroupVersion().WithKind(CertificateGVK.Kind + "List"))
+	//Certificates are not cached, so they are listed by the owner label only
+	err := f.client.List(ctx, &certList, client.InNamespace(f.GatewayProvisioning.Namespace),
+		client.MatchingLabels{OwnerLabel: fmt.Sprintf("%s.%s", api.ObjectMeta.Name, api.ObjectMeta.Namespace)})
+	if meta.IsNoMatchError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := make([]client.Object, 0, len(certList.Items))
+	for i := range certList.Items {
+		res = append(res, &certList.Items[i])
+	}
+	return res, nil
+}
+
+//certificateReady tells if the Ready condition of the Certificate is true
+func certificateReady(cert *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(cert.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == "Ready" {
+			return condition["status"] == "True"
+		}
+	}
+	return false
+}
+
+//CertificateStatus tells if the Certificate provisioned for the host is ready. Returns nil if no Certificate is provisioned
+func (p *Patch) CertificateStatus() *gatewayv1alpha1.APIRuleResourceStatus {
+	if p.certificate == nil || p.certificate.action == "delete" {
+		return nil
+	}
+	name := p.certificate.obj.GetName()
+	if existing, ok := p.certificate.existing().(*unstructured.Unstructured); ok && certificateReady(existing) {
+		return &gatewayv1alpha1.APIRuleResourceStatus{Code: gatewayv1alpha1.StatusOK, Description: fmt.Sprintf("Certificate %s is ready", name)}
+	}
+	return &gatewayv1alpha1.APIRuleResourceStatus{Code: gatewayv1alpha1.StatusPending, Description: fmt.Sprintf("Certificate %s is not ready yet", name)}
+}
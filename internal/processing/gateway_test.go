@@ -0,0 +1,175 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	"istio.io/api/networking/v1beta1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Gateway provisioning", func() {
+
+	noop := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "noop"}}}
+
+	provisioning := &GatewayProvisioning{
+		WildcardDomains: []string{"*." + defaultDomain},
+		Namespace:       "istio-system",
+		IssuerName:      "letsencrypt",
+	}
+
+	newFactory := func(c client.Client) *Factory {
+		f := NewFactory(c, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+		f.GatewayProvisioning = provisioning
+		return f
+	}
+
+	newClient := func(objs ...runtime.Object) *applyClient {
+		s := runtime.NewScheme()
+		Expect(networkingv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(networkingv1alpha3.AddToScheme(s)).To(Succeed())
+		Expect(securityv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(rulev1alpha1.AddToScheme(s)).To(Succeed())
+		s.AddKnownTypeWithName(CertificateGVK, &unstructured.Unstructured{})
+		s.AddKnownTypeWithName(CertificateGVK.GroupVersion().WithKind(CertificateGVK.Kind+"List"), &unstructured.UnstructuredList{})
+		return &applyClient{Client: fake.NewFakeClientWithScheme(s, objs...)}
+	}
+
+	customHost := "shop.example.com"
+
+	It("should produce Gateway and Certificate for hosts outside the wildcard domains", func() {
+		//given
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, nil, noop)})
+		apiRule.Spec.Service.Host = &customHost
+		f := newFactory(nil)
+
+		//when
+		desiredState := f.CalculateRequiredState(apiRule)
+
+		//then
+		gw := desiredState.gateway
+		Expect(gw).NotTo(BeNil())
+		Expect(gw.Name).To(Equal(childName(apiRule)))
+		Expect(gw.Namespace).To(Equal(apiNamespace))
+		Expect(gw.Labels).To(HaveKeyWithValue(OwnerLabel, apiName+"."+apiNamespace))
+		Expect(gw.Labels).To(HaveKeyWithValue(testLabelKey, testLabelValue))
+		Expect(gw.OwnerReferences[0].UID).To(Equal(apiUID))
+		Expect(gw.Spec.Selector).To(Equal(map[string]string{"istio": "ingressgateway"}))
+		Expect(gw.Spec.Servers).To(HaveLen(2))
+		Expect(gw.Spec.Servers[0].Hosts).To(ConsistOf(customHost))
+		Expect(gw.Spec.Servers[0].Tls.Mode).To(Equal(v1beta1.ServerTLSSettings_SIMPLE))
+		Expect(gw.Spec.Servers[0].Tls.CredentialName).To(Equal(childName(apiRule)))
+		Expect(gw.Spec.Servers[1].Tls.HttpsRedirect).To(BeTrue())
+
+		cert := desiredState.certificate
+		Expect(cert).NotTo(BeNil())
+		Expect(cert.GroupVersionKind()).To(Equal(CertificateGVK))
+		Expect(cert.GetNamespace()).To(Equal("istio-system"))
+		Expect(cert.GetOwnerReferences()).To(BeEmpty())
+		Expect(cert.GetLabels()).To(HaveKeyWithValue(OwnerLabel, apiName+"."+apiNamespace))
+		dnsNames, _, _ := unstructured.NestedSlice(cert.Object, "spec", "dnsNames")
+		Expect(dnsNames).To(ConsistOf(customHost))
+		secretName, _, _ := unstructured.NestedString(cert.Object, "spec", "secretName")
+		Expect(secretName).To(Equal(childName(apiRule)))
+		issuerKind, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "kind")
+		Expect(issuerKind).To(Equal("ClusterIssuer"))
+
+		Expect(desiredState.virtualService.Spec.Gateways).To(ConsistOf(fmt.Sprintf("%s/%s", apiNamespace, childName(apiRule))))
+	})
+
+	It("should use the gateway of the APIRule for hosts covered by the wildcard certificate", func() {
+		//given
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, nil, noop)})
+		f := newFactory(nil)
+
+		//when
+		desiredState := f.CalculateRequiredState(apiRule)
+
+		//then
+		Expect(desiredState.gateway).To(BeNil())
+		Expect(desiredState.certificate).To(BeNil())
+		Expect(desiredState.virtualService.Spec.Gateways).To(ConsistOf(apiGateway))
+	})
+
+	It("should provision the host only for its holder", func() {
+		//given
+		holder := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, nil, noop)})
+		holder.Spec.Service.Host = &customHost
+		api := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(headersAPIPath, apiMethods, nil, noop)})
+		api.Name = "other-apirule"
+		api.Spec.Service.Host = &customHost
+		f := newFactory(nil)
+
+		//when
+		desiredState := f.CalculateRequiredStateForHost(api, holder, []gatewayv1alpha1.APIRule{*holder, *api})
+
+		//then
+		Expect(desiredState.gateway).To(BeNil())
+		Expect(desiredState.certificate).To(BeNil())
+		Expect(desiredState.virtualService.Spec.Gateways).To(ConsistOf(fmt.Sprintf("%s/%s", apiNamespace, childName(holder))))
+	})
+
+	It("should report the certificate readiness and delete provisioned objects with the APIRule", func() {
+		//given
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, nil, noop)})
+		apiRule.Spec.Service.Host = &customHost
+		c := newClient()
+		f := newFactory(c)
+
+		//when
+		patch := f.CalculateDiff(f.CalculateRequiredState(apiRule), &State{})
+		Expect(f.ApplyDiff(context.Background(), patch)).To(Succeed())
+
+		//then
+		Expect(patch.CertificateStatus().Code).To(Equal(gatewayv1alpha1.StatusPending))
+
+		//when
+		cert := &unstructured.Unstructured{}
+		cert.SetGroupVersionKind(CertificateGVK)
+		Expect(c.Get(context.Background(), client.ObjectKey{Namespace: "istio-system", Name: childName(apiRule)}, cert)).To(Succeed())
+		Expect(unstructured.SetNestedSlice(cert.Object, []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}}, "status", "conditions")).To(Succeed())
+		Expect(c.Update(context.Background(), cert)).To(Succeed())
+
+		actualState, err := f.GetActualState(context.Background(), apiRule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actualState.gateway).NotTo(BeNil())
+		patch = f.CalculateDiff(f.CalculateRequiredState(apiRule), actualState)
+
+		//then
+		Expect(patch.gateway.action).To(Equal("update"))
+		Expect(patch.certificate.action).To(Equal("update"))
+		Expect(patch.CertificateStatus().Code).To(Equal(gatewayv1alpha1.StatusOK))
+
+		//when
+		remaining, err := f.DeleteGenerated(context.Background(), apiRule)
+
+		//then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remaining).To(Equal(4))
+		var certList unstructured.UnstructuredList
+		certList.SetGroupVersionKind(CertificateGVK.GroupVersion().WithKind(CertificateGVK.Kind + "List"))
+		Expect(c.List(context.Background(), &certList)).To(Succeed())
+		Expect(certList.Items).To(BeEmpty())
+		var gwList networkingv1beta1.GatewayList
+		Expect(c.List(context.Background(), &gwList)).To(Succeed())
+		Expect(gwList.Items).To(BeEmpty())
+	})
+
+	It("should not report the certificate status for hosts covered by the wildcard certificate", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, nil, noop)})
+		f := newFactory(nil)
+
+		Expect(f.CalculateDiff(f.CalculateRequiredState(apiRule), &State{}).CertificateStatus()).To(BeNil())
+	})
+})
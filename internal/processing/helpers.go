@@ -2,29 +2,31 @@ package processing
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/kyma-incubator/api-gateway/internal/helpers"
 
 	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
 	"github.com/kyma-incubator/api-gateway/internal/builders"
+	"github.com/kyma-incubator/api-gateway/internal/handlers"
 	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	"istio.io/api/networking/v1beta1"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
 	k8sMeta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func modifyAccessRule(existing, required *rulev1alpha1.Rule) {
-	existing.Spec = required.Spec
-}
-
 func generateAccessRule(api *gatewayv1alpha1.APIRule, rule gatewayv1alpha1.Rule, accessStrategies []*gatewayv1alpha1.Authenticator, additionalLabels map[string]string, defaultDomainName string) *rulev1alpha1.Rule {
-	namePrefix := fmt.Sprintf("%s-", api.ObjectMeta.Name)
 	namespace := api.ObjectMeta.Namespace
 	ownerRef := generateOwnerRef(api)
+	spec := generateAccessRuleSpec(api, rule, accessStrategies, defaultDomainName)
 
 	arBuilder := builders.AccessRule().
-		GenerateName(namePrefix).
+		Name(childName(api, spec.Match.URL)).
 		Namespace(namespace).
 		Owner(builders.OwnerReference().From(&ownerRef)).
-		Spec(builders.AccessRuleSpec().From(generateAccessRuleSpec(api, rule, accessStrategies, defaultDomainName))).
+		Spec(builders.AccessRuleSpec().From(spec)).
 		Label(OwnerLabel, fmt.Sprintf("%s.%s", api.ObjectMeta.Name, api.ObjectMeta.Namespace))
 
 	for k, v := range additionalLabels {
@@ -34,31 +36,114 @@ func generateAccessRule(api *gatewayv1alpha1.APIRule, rule gatewayv1alpha1.Rule,
 	return arBuilder.Get()
 }
 
+//generateDestinationRule returns the Destination Rule applying the upstream TLS settings of the api to the exposed port of the service and
+//upgrading the connections to gRPC services to HTTP/2, or nil if neither is needed. Only gateways can read the Secrets of the SIMPLE and
+//MUTUAL modes, so the Destination Rule is then exported to the ingress gateway only, and Oathkeeper originates TLS on its own
+func (f *Factory) generateDestinationRule(api *gatewayv1alpha1.APIRule) *networkingv1beta1.DestinationRule {
+	tls := api.Spec.Service.TLS
+	if tls == nil && !usesHTTP2(api) {
+		return nil
+	}
+	ownerRef := generateOwnerRef(api)
+
+	specBuilder := builders.DestinationRuleSpec().
+		Host(fmt.Sprintf("%s.%s.svc.cluster.local", *api.Spec.Service.Name, api.ServiceNamespace()))
+	if tls != nil {
+		tlsBuilder := builders.ClientTLSSettings().Mode(v1beta1.ClientTLSSettings_TLSmode(v1beta1.ClientTLSSettings_TLSmode_value[tls.Mode]))
+		switch {
+		case tls.Mode == gatewayv1alpha1.TLSModeSimple && tls.CASecretName != nil:
+			tlsBuilder.CredentialName(*tls.CASecretName)
+		case tls.Mode == gatewayv1alpha1.TLSModeMutual && tls.ClientSecretName != nil:
+			tlsBuilder.CredentialName(*tls.ClientSecretName)
+		}
+		specBuilder.PortTLS(*api.Spec.Service.Port, tlsBuilder)
+	}
+	if oathkeeperOriginatesTLS(api) {
+		gatewayNamespace, _ := f.gatewayPolicyWorkload()
+		specBuilder.ExportTo(".", gatewayNamespace)
+	}
+	if usesHTTP2(api) {
+		specBuilder.PortHTTP2Upgrade(*api.Spec.Service.Port)
+	}
+
+	drBuilder := builders.DestinationRule().
+		Name(childName(api)).
+		Namespace(api.ObjectMeta.Namespace).
+		Owner(builders.OwnerReference().From(&ownerRef)).
+		Label(OwnerLabel, fmt.Sprintf("%s.%s", api.ObjectMeta.Name, api.ObjectMeta.Namespace)).
+		Spec(specBuilder)
+
+	for k, v := range f.additionalLabels {
+		drBuilder.Label(k, v)
+	}
+
+	return drBuilder.Get()
+}
+
 func generateAccessRuleSpec(api *gatewayv1alpha1.APIRule, rule gatewayv1alpha1.Rule, accessStrategies []*gatewayv1alpha1.Authenticator, defaultDomainName string) *rulev1alpha1.RuleSpec {
 	return builders.AccessRuleSpec().
 		Upstream(builders.Upstream().
-			URL(fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", *api.Spec.Service.Name, api.ObjectMeta.Namespace, int(*api.Spec.Service.Port)))).
+			URL(fmt.Sprintf("%s://%s.%s.svc.cluster.local:%d", upstreamScheme(api), *api.Spec.Service.Name, api.ServiceNamespace(), int(*api.Spec.Service.Port)))).
 		Match(builders.Match().
-			URL(fmt.Sprintf("<http|https>://%s<%s>", helpers.GetHostWithDomain(*api.Spec.Service.Host, defaultDomainName), rule.Path)).
+			URL(fmt.Sprintf("<http|https>://%s<%s>", helpers.GetHostWithDomain(*api.Spec.Service.Host, defaultDomainName), rule.MatchPath())).
 			Methods(rule.Methods)).
 		Authorizer(builders.Authorizer().Handler(builders.Handler().
 			Name("allow"))).
-		Authenticators(builders.Authenticators().From(accessStrategies)).
+		Authenticators(builders.Authenticators().From(toOutputAuthenticators(accessStrategies))).
 		Mutators(builders.Mutators().From(rule.Mutators)).Get()
 }
 
+//oathkeeperOriginatesTLS tells if Oathkeeper connects to the service of the api over TLS itself, as its sidecar can't read the Secrets
+//of the SIMPLE and MUTUAL modes
+func oathkeeperOriginatesTLS(api *gatewayv1alpha1.APIRule) bool {
+	tls := api.Spec.Service.TLS
+	return tls != nil && (tls.Mode == gatewayv1alpha1.TLSModeSimple || tls.Mode == gatewayv1alpha1.TLSModeMutual)
+}
+
+//upstreamScheme returns the scheme of the URL of the service in the access rules
+func upstreamScheme(api *gatewayv1alpha1.APIRule) string {
+	if oathkeeperOriginatesTLS(api) {
+		return "https"
+	}
+	return "http"
+}
+
+//usesHTTP2 tells if the service of the api speaks gRPC, which requires HTTP/2 connections. The gateway translates gRPC-Web calls to gRPC
+func usesHTTP2(api *gatewayv1alpha1.APIRule) bool {
+	protocol := api.ServiceProtocol()
+	return protocol == gatewayv1alpha1.ProtocolGRPC || protocol == gatewayv1alpha1.ProtocolGRPCWeb
+}
+
+//routedThroughOathkeeper tells if the requests matching the rule of the api are routed through Oathkeeper. Oathkeeper proxies requests
+//over HTTP/1.1 only, so the secured rules of gRPC services are checked by Istio on the gateway instead
+func routedThroughOathkeeper(api *gatewayv1alpha1.APIRule, rule gatewayv1alpha1.Rule) bool {
+	return isSecured(rule) && !usesHTTP2(api)
+}
+
 func isSecured(rule gatewayv1alpha1.Rule) bool {
 	if len(rule.Mutators) > 0 {
 		return true
 	}
 	for _, strat := range rule.AccessStrategies {
-		if strat.Name != "allow" {
+		if handlers.IsSecured(strat.Name) {
 			return true
 		}
 	}
 	return false
 }
 
+//toOutputAuthenticators applies output transformations declared in the handlers registry
+func toOutputAuthenticators(accessStrategies []*gatewayv1alpha1.Authenticator) []*gatewayv1alpha1.Authenticator {
+	if accessStrategies == nil {
+		return nil
+	}
+	res := make([]*gatewayv1alpha1.Authenticator, len(accessStrategies))
+	for i, strat := range accessStrategies {
+		res[i] = &gatewayv1alpha1.Authenticator{Handler: handlers.ToOutput(strat.Handler)}
+	}
+	return res
+}
+
 func generateOwnerRef(api *gatewayv1alpha1.APIRule) k8sMeta.OwnerReference {
 	return *builders.OwnerReference().
 		Name(api.ObjectMeta.Name).
@@ -68,3 +153,64 @@ func generateOwnerRef(api *gatewayv1alpha1.APIRule) k8sMeta.OwnerReference {
 		Controller(true).
 		Get()
 }
+
+//ownedBy returns the options listing the objects generated for the api with the OwnerLabelIndex, which is served by the cache of the manager only.
+//The label selector keeps the result correct for clients ignoring field selectors, like the fake client.
+func ownedBy(api *gatewayv1alpha1.APIRule) []client.ListOption {
+	owner := fmt.Sprintf("%s.%s", api.ObjectMeta.Name, api.ObjectMeta.Namespace)
+	return []client.ListOption{
+		client.MatchingFields{OwnerLabelIndex: owner},
+		client.MatchingLabels{OwnerLabel: owner},
+	}
+}
+
+func isSameAPIRule(a, b *gatewayv1alpha1.APIRule) bool {
+	return a.ObjectMeta.Namespace == b.ObjectMeta.Namespace && a.ObjectMeta.Name == b.ObjectMeta.Name
+}
+
+//HostHolder returns the APIRule owning the Virtual Service generated for the host, nil if there is none.
+//If there are several, the oldest one tells the holder, as the other APIRules remove their Virtual Services when they lose the host
+func HostHolder(vsList networkingv1beta1.VirtualServiceList, host string) *types.NamespacedName {
+	var holderVS *networkingv1beta1.VirtualService
+	for i := range vsList.Items {
+		vs := &vsList.Items[i]
+		if _, ok := vs.Labels[OwnerLabel]; !ok || !containsHost(vs.Spec.Hosts, host) {
+			continue
+		}
+		if holderVS == nil || vs.CreationTimestamp.Before(&holderVS.CreationTimestamp) ||
+			(vs.CreationTimestamp.Equal(&holderVS.CreationTimestamp) && vs.Name < holderVS.Name) {
+			holderVS = vs
+		}
+	}
+	if holderVS == nil {
+		return nil
+	}
+	//The label holds the name and the namespace of the APIRule. Namespaces can't contain dots, unlike names
+	owner := holderVS.Labels[OwnerLabel]
+	i := strings.LastIndex(owner, ".")
+	if i < 0 {
+		return nil
+	}
+	return &types.NamespacedName{Namespace: owner[i+1:], Name: owner[:i]}
+}
+
+func containsHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+//WithoutGeneratedVirtualServices drops the Virtual Services generated for APIRules.
+//Conflicts between APIRules are resolved by host claims, so only other Virtual Services can occupy a host.
+func WithoutGeneratedVirtualServices(vsList networkingv1beta1.VirtualServiceList) networkingv1beta1.VirtualServiceList {
+	var res networkingv1beta1.VirtualServiceList
+	for _, vs := range vsList.Items {
+		if _, ok := vs.Labels[OwnerLabel]; !ok {
+			res.Items = append(res.Items, vs)
+		}
+	}
+	return res
+}
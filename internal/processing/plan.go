@@ -0,0 +1,165 @@
+package processing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const planActionNone = "none"
+
+//Plan returns the changes of generated objects made by applying the patch, without applying it. Created and updated objects come with
+//the differences between the spec of the existing object and the required one. Updates not changing the spec are planned as "none".
+func (p *Patch) Plan() ([]gatewayv1alpha1.PlannedChange, error) {
+	objs := []*objToPatch{p.virtualService, p.destinationRule, p.gateway, p.certificate, p.envoyFilter, p.authorizationPolicy, p.requestAuthentication}
+
+	paths := make([]string, 0, len(p.accessRule))
+	for path := range p.accessRule {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		objs = append(objs, p.accessRule[path])
+	}
+
+	objs = append(objs, p.obsoleteVirtualServices...)
+	objs = append(objs, p.duplicates...)
+
+	var changes []gatewayv1alpha1.PlannedChange
+	for _, obj := range objs {
+		if obj == nil || obj.obj == nil {
+			continue
+		}
+		change, err := planChange(obj)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func planChange(objToPatch *objToPatch) (gatewayv1alpha1.PlannedChange, error) {
+	change := gatewayv1alpha1.PlannedChange{
+		Kind:   kindOf(objToPatch.obj),
+		Name:   objToPatch.obj.GetName(),
+		Action: objToPatch.action,
+	}
+
+	//Values of Secrets are never shown: the redacted objects are planned instead. The current access rule may hold values of Secrets the
+	//required one doesn't reference anymore, so it's redacted even if the required one holds none
+	currentObj, requiredObj := objToPatch.current, objToPatch.obj
+	if objToPatch.redacted != nil {
+		requiredObj = objToPatch.redacted
+	}
+	if current, ok := currentObj.(*rulev1alpha1.Rule); ok {
+		currentObj = redactAccessRule(current, objToPatch.obj.(*rulev1alpha1.Rule), requiredObj.(*rulev1alpha1.Rule))
+	}
+
+	var current, required string
+	var err error
+	switch objToPatch.action {
+	case "create":
+		required, err = specYAML(requiredObj)
+	case "update":
+		if current, err = specYAML(currentObj); err == nil {
+			required, err = specYAML(requiredObj)
+		}
+	default:
+		return change, nil
+	}
+	if err != nil {
+		return change, err
+	}
+
+	if current == required {
+		change.Action = planActionNone
+		return change, nil
+	}
+	change.Diff = diffLines(current, required)
+	return change, nil
+}
+
+func specYAML(obj client.Object) (string, error) {
+	var spec interface{}
+	switch o := obj.(type) {
+	case *networkingv1beta1.VirtualService:
+		spec = &o.Spec
+	case *networkingv1beta1.DestinationRule:
+		spec = &o.Spec
+	case *networkingv1beta1.Gateway:
+		spec = &o.Spec
+	case *networkingv1alpha3.EnvoyFilter:
+		spec = &o.Spec
+	case *securityv1beta1.AuthorizationPolicy:
+		spec = &o.Spec
+	case *unstructured.Unstructured:
+		spec = o.Object["spec"]
+	case *rulev1alpha1.Rule:
+		spec = &o.Spec
+	default:
+		return "", fmt.Errorf("cannot plan changes of %T", obj)
+	}
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+//diffLines compares two texts line by line. Lines of the longest common subsequence are kept, the other lines are removed from a or added from b
+func diffLines(a, b string) string {
+	x, y := splitLines(a), splitLines(b)
+
+	//lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			sb.WriteString("  " + x[i] + "\n")
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("- " + x[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+ " + y[j] + "\n")
+			j++
+		}
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
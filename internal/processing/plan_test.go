@@ -0,0 +1,101 @@
+package processing
+
+import (
+	"context"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Plan", func() {
+
+	noop := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "noop"}}}
+
+	newFactory := func() (*Factory, client.Client) {
+		s := runtime.NewScheme()
+		Expect(networkingv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(networkingv1alpha3.AddToScheme(s)).To(Succeed())
+		Expect(securityv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(rulev1alpha1.AddToScheme(s)).To(Succeed())
+		c := &applyClient{Client: fake.NewFakeClientWithScheme(s)}
+		return NewFactory(c, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain), c
+	}
+
+	plan := func(f *Factory, apiRule *gatewayv1alpha1.APIRule) *Patch {
+		actualState, err := f.GetActualState(context.Background(), apiRule)
+		Expect(err).NotTo(HaveOccurred())
+		return f.CalculateDiff(f.CalculateRequiredState(apiRule), actualState)
+	}
+
+	It("should plan the creation of objects with their spec", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, nil, noop)})
+		f, c := newFactory()
+
+		changes, err := plan(f, apiRule).Plan()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(changes).To(HaveLen(2))
+		Expect(changes[0].Kind).To(Equal("VirtualService"))
+		Expect(changes[0].Name).To(Equal(childName(apiRule)))
+		Expect(changes[0].Action).To(Equal("create"))
+		Expect(changes[0].Diff).To(ContainSubstring("+ - " + serviceHost + "\n"))
+		Expect(changes[1].Kind).To(Equal("AccessRule"))
+		Expect(changes[1].Action).To(Equal("create"))
+		Expect(changes[1].Diff).To(ContainSubstring("+ - handler: noop\n"))
+
+		var vsList networkingv1beta1.VirtualServiceList
+		Expect(c.List(context.Background(), &vsList)).To(Succeed())
+		Expect(vsList.Items).To(BeEmpty())
+	})
+
+	It("should plan no action for objects up to date", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, nil, noop)})
+		f, _ := newFactory()
+		Expect(f.ApplyDiff(context.Background(), plan(f, apiRule))).To(Succeed())
+
+		changes, err := plan(f, apiRule).Plan()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(changes).To(HaveLen(2))
+		for _, change := range changes {
+			Expect(change.Action).To(Equal("none"))
+			Expect(change.Diff).To(BeEmpty())
+		}
+	})
+
+	It("should plan updates with the differences of the spec", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, nil, noop)})
+		f, _ := newFactory()
+		Expect(f.ApplyDiff(context.Background(), plan(f, apiRule))).To(Succeed())
+
+		apiRule.Spec.Rules[0].Path = headersAPIPath
+		changes, err := plan(f, apiRule).Plan()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(changes).To(HaveLen(3))
+		Expect(changes[0].Action).To(Equal("update"))
+		Expect(changes[0].Diff).To(ContainSubstring("-       regex: " + apiPath + "\n"))
+		Expect(changes[0].Diff).To(ContainSubstring("+       regex: " + headersAPIPath + "\n"))
+		Expect(changes[0].Diff).To(ContainSubstring("  - " + serviceHost + "\n"))
+		Expect(changes[1].Kind).To(Equal("AccessRule"))
+		Expect(changes[1].Action).To(Equal("delete"))
+		Expect(changes[2].Kind).To(Equal("AccessRule"))
+		Expect(changes[2].Action).To(Equal("create"))
+	})
+
+	It("should diff texts line by line", func() {
+		Expect(diffLines("a\nb\nc\n", "a\nx\nc\n")).To(Equal("  a\n- b\n+ x\n  c\n"))
+		Expect(diffLines("", "a\n")).To(Equal("+ a\n"))
+		Expect(diffLines("a\n", "")).To(Equal("- a\n"))
+		Expect(diffLines("a\n", "a\n")).To(Equal("  a\n"))
+	})
+})
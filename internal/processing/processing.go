@@ -3,6 +3,9 @@ package processing
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/kyma-incubator/api-gateway/internal/helpers"
 	"istio.io/api/networking/v1beta1"
 
@@ -12,7 +15,13 @@ import (
 	"github.com/go-logr/logr"
 	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
 	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 var (
@@ -20,6 +29,22 @@ var (
 	OwnerLabel = fmt.Sprintf("%s.%s", "apirule", gatewayv1alpha1.GroupVersion.String())
 )
 
+//FieldManager is the name of the field manager applying generated objects with server-side apply.
+//Only the fields set by the controller are owned by it, so fields set by other controllers or users are kept.
+const FieldManager = "api-gateway-controller"
+
+//OwnerLabelIndex is the name of the cache index of generated objects by the value of the OwnerLabel
+const OwnerLabelIndex = "metadata.labels.owner"
+
+//IndexByOwnerLabel extracts the value of the OwnerLabel for the OwnerLabelIndex
+func IndexByOwnerLabel(obj client.Object) []string {
+	owner, ok := obj.GetLabels()[OwnerLabel]
+	if !ok {
+		return nil
+	}
+	return []string{owner}
+}
+
 //Factory .
 type Factory struct {
 	client            client.Client
@@ -30,6 +55,16 @@ type Factory struct {
 	corsConfig        *CorsConfig
 	additionalLabels  map[string]string
 	defaultDomainName string
+	//GatewayProvisioning enables Gateways and Certificates generated for hosts not covered by the wildcard certificate, nil if disabled
+	GatewayProvisioning *GatewayProvisioning
+	//RateLimit enables the rate limits of the rules applied with EnvoyFilters, nil if disabled
+	RateLimit *RateLimit
+	//SourceIPs selects the ingress gateway applying the source IP restrictions of the rules, the istio: ingressgateway pods in istio-system if nil
+	SourceIPs *SourceIPs
+	//APIKeys enables the api_key access strategy checking the keys with the controller, nil if disabled
+	APIKeys *APIKeys
+	//Secrets referenced by the handler configs of the api by name. Placeholders of missing Secrets are replaced by their references
+	Secrets map[string]*corev1.Secret
 }
 
 //NewFactory .
@@ -55,72 +90,367 @@ type CorsConfig struct {
 
 // CalculateRequiredState returns required state of all objects related to given api
 func (f *Factory) CalculateRequiredState(api *gatewayv1alpha1.APIRule) *State {
+	return f.CalculateRequiredStateForHost(api, api, []gatewayv1alpha1.APIRule{*api})
+}
+
+//CalculateRequiredStateForHost returns required state of all objects related to given api, which shares its host with other APIRules.
+//The Virtual Service routes requests to all exposed APIRules and is owned by the holder of the host.
+func (f *Factory) CalculateRequiredStateForHost(api, holder *gatewayv1alpha1.APIRule, exposed []gatewayv1alpha1.APIRule) *State {
 	var res State
 
 	res.accessRules = make(map[string]*rulev1alpha1.Rule)
 
+	res.redactedAccessRules = make(map[string]*rulev1alpha1.Rule)
+
 	for _, rule := range api.Spec.Rules {
-		if isSecured(rule) {
-			ar := generateAccessRule(api, rule, rule.AccessStrategies, f.additionalLabels, f.defaultDomainName)
+		if routedThroughOathkeeper(api, rule) {
+			rule.AccessStrategies = f.withAPIKeyCheck(api, rule.AccessStrategies)
+			resolved, hasSecretRefs := withSecretValues(rule, f.lookupSecretValue)
+			ar := generateAccessRule(api, resolved, resolved.AccessStrategies, f.additionalLabels, f.defaultDomainName)
 			res.accessRules[ar.Spec.Match.URL] = ar
+			if hasSecretRefs {
+				redacted, _ := withSecretValues(rule, noSecretValues)
+				res.redactedAccessRules[ar.Spec.Match.URL] = generateAccessRule(api, redacted, redacted.AccessStrategies, f.additionalLabels, f.defaultDomainName)
+			}
 		}
 	}
 
 	//Only one vs
-	vs := f.generateVirtualService(api)
+	vs := f.generateVirtualService(holder, exposed)
 	res.virtualService = vs
 
+	res.destinationRule = f.generateDestinationRule(api)
+
+	//The Gateway and the Certificate of a provisioned host are owned by the holder of the host, like the Virtual Service
+	host := helpers.GetHostWithDomain(*holder.Spec.Service.Host, f.defaultDomainName)
+	if isSameAPIRule(api, holder) && f.GatewayProvisioning.provisions(host) {
+		res.gateway = f.generateGateway(holder, host)
+		res.certificate = f.generateCertificate(holder, host)
+	}
+
+	res.envoyFilter, res.rateLimitDescriptors, res.rateLimitStatus = f.generateEnvoyFilter(api)
+	res.authorizationPolicy = f.generateAuthorizationPolicy(api)
+	res.requestAuthentication = f.generateRequestAuthentication(api)
+
 	return &res
 }
 
-//State represents desired or actual state of Istio Virtual Services and Oathkeeper Rules
+//State represents desired or actual state of Istio Virtual Services, Destination Rules, Gateways, Certificates, EnvoyFilters, AuthorizationPolicies,
+//RequestAuthentications and Oathkeeper Rules
 type State struct {
 	virtualService *networkingv1beta1.VirtualService
-	accessRules    map[string]*rulev1alpha1.Rule
+	//Destination Rule applying the upstream TLS settings and the HTTP/2 upgrade, nil if the api needs neither
+	destinationRule *networkingv1beta1.DestinationRule
+	accessRules     map[string]*rulev1alpha1.Rule
+	//Access rules with the values of Secrets replaced by their references, by the URL they match. Only set in the desired state for the access
+	//rules with handler configs referencing Secrets
+	redactedAccessRules map[string]*rulev1alpha1.Rule
+	//Gateway and Certificate provisioned for the host, nil if the host is covered by the wildcard certificate
+	gateway     *networkingv1beta1.Gateway
+	certificate *unstructured.Unstructured
+	//EnvoyFilter applying the rate limits and the WebSocket upgrade of the rules, nil if no route needs them
+	envoyFilter *networkingv1alpha3.EnvoyFilter
+	//AuthorizationPolicy applying the source IP restrictions of the rules and requiring JWTs on the rules secured on the gateway, nil if no rule needs it
+	authorizationPolicy *securityv1beta1.AuthorizationPolicy
+	//RequestAuthentication validating the JWTs of the rules secured on the gateway, nil if no rule is secured there
+	requestAuthentication *securityv1beta1.RequestAuthentication
+	//Descriptors of the rate limits with a key, nil if the global rate limit service isn't configured. Only set in the desired state
+	rateLimitDescriptors *rateLimitDescriptors
+	//Status of the rate limits, nil if no rule is rate limited. Only set in the desired state
+	rateLimitStatus *gatewayv1alpha1.APIRuleResourceStatus
+	//Virtual Services of the api, which are replaced by the Virtual Service of the holder of the host
+	obsoleteVirtualServices []*networkingv1beta1.VirtualService
+	//Objects duplicating the ones kept, created by retried or racing reconciliations
+	duplicates []client.Object
+}
+
+//Objects returns the Virtual Service, the Destination Rule, the Gateway, the Certificate, the EnvoyFilter, the AuthorizationPolicy and the RequestAuthentication
+//followed by the access rules ordered by the URL they match
+func (s *State) Objects() []client.Object {
+	var res []client.Object
+	if s.virtualService != nil {
+		res = append(res, s.virtualService)
+	}
+	if s.destinationRule != nil {
+		res = append(res, s.destinationRule)
+	}
+	if s.gateway != nil {
+		res = append(res, s.gateway)
+	}
+	if s.certificate != nil {
+		res = append(res, s.certificate)
+	}
+	if s.envoyFilter != nil {
+		res = append(res, s.envoyFilter)
+	}
+	if s.authorizationPolicy != nil {
+		res = append(res, s.authorizationPolicy)
+	}
+	if s.requestAuthentication != nil {
+		res = append(res, s.requestAuthentication)
+	}
+	urls := make([]string, 0, len(s.accessRules))
+	for url := range s.accessRules {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	for _, url := range urls {
+		res = append(res, s.accessRules[url])
+	}
+	return res
 }
 
 //GetActualState methods gets actual state of Istio Virtual Services and Oathkeeper Rules
 func (f *Factory) GetActualState(ctx context.Context, api *gatewayv1alpha1.APIRule) (*State, error) {
-	labels := make(map[string]string)
-	labels[OwnerLabel] = fmt.Sprintf("%s.%s", api.ObjectMeta.Name, api.ObjectMeta.Namespace)
+	return f.GetActualStateForHost(ctx, api, api)
+}
+
+//GetActualStateForHost gets actual state of the Virtual Service owned by the holder of the host and of the Destination Rule, Gateway, Certificate,
+//EnvoyFilter, AuthorizationPolicy, RequestAuthentication and Oathkeeper Rules of given api
+func (f *Factory) GetActualStateForHost(ctx context.Context, api, holder *gatewayv1alpha1.APIRule) (*State, error) {
 	var state State
 
 	var vsList networkingv1beta1.VirtualServiceList
-	if err := f.client.List(ctx, &vsList, client.MatchingLabels(labels)); err != nil {
+	if err := f.client.List(ctx, &vsList, ownedBy(holder)...); err != nil {
 		return nil, err
 	}
 
-	//what to do if len(vsList) > 1?
+	virtualServices := make([]client.Object, 0, len(vsList.Items))
+	for i := range vsList.Items {
+		virtualServices = append(virtualServices, &vsList.Items[i])
+	}
+	vs, duplicates := pickChild(virtualServices, childName(holder))
+	if vs != nil {
+		state.virtualService = vs.(*networkingv1beta1.VirtualService)
+	}
+	state.duplicates = append(state.duplicates, duplicates...)
 
-	if len(vsList.Items) == 1 {
-		state.virtualService = &vsList.Items[0]
-	} else {
-		state.virtualService = nil
+	if !isSameAPIRule(api, holder) {
+		var ownVsList networkingv1beta1.VirtualServiceList
+		if err := f.client.List(ctx, &ownVsList, ownedBy(api)...); err != nil {
+			return nil, err
+		}
+		for i := range ownVsList.Items {
+			state.obsoleteVirtualServices = append(state.obsoleteVirtualServices, &ownVsList.Items[i])
+		}
+	}
+
+	var drList networkingv1beta1.DestinationRuleList
+	if err := f.client.List(ctx, &drList, ownedBy(api)...); err != nil {
+		return nil, err
+	}
+	destinationRules := make([]client.Object, 0, len(drList.Items))
+	for i := range drList.Items {
+		destinationRules = append(destinationRules, &drList.Items[i])
+	}
+	dr, duplicates := pickChild(destinationRules, childName(api))
+	if dr != nil {
+		state.destinationRule = dr.(*networkingv1beta1.DestinationRule)
+	}
+	state.duplicates = append(state.duplicates, duplicates...)
+
+	var gwList networkingv1beta1.GatewayList
+	if err := f.client.List(ctx, &gwList, ownedBy(api)...); err != nil {
+		return nil, err
+	}
+	gateways := make([]client.Object, 0, len(gwList.Items))
+	for i := range gwList.Items {
+		gateways = append(gateways, &gwList.Items[i])
+	}
+	gw, duplicates := pickChild(gateways, childName(api))
+	if gw != nil {
+		state.gateway = gw.(*networkingv1beta1.Gateway)
+	}
+	state.duplicates = append(state.duplicates, duplicates...)
+
+	certificates, err := f.listCertificates(ctx, api)
+	if err != nil {
+		return nil, err
+	}
+	cert, duplicates := pickChild(certificates, childName(api))
+	if cert != nil {
+		state.certificate = cert.(*unstructured.Unstructured)
+	}
+	state.duplicates = append(state.duplicates, duplicates...)
+
+	envoyFilters, err := f.listEnvoyFilters(ctx, api)
+	if err != nil {
+		return nil, err
+	}
+	ef, duplicates := pickChild(envoyFilters, childName(api))
+	if ef != nil {
+		state.envoyFilter = ef.(*networkingv1alpha3.EnvoyFilter)
+	}
+	state.duplicates = append(state.duplicates, duplicates...)
+
+	authorizationPolicies, err := f.listAuthorizationPolicies(ctx, api)
+	if err != nil {
+		return nil, err
 	}
+	ap, duplicates := pickChild(authorizationPolicies, childName(api))
+	if ap != nil {
+		state.authorizationPolicy = ap.(*securityv1beta1.AuthorizationPolicy)
+	}
+	state.duplicates = append(state.duplicates, duplicates...)
+
+	requestAuthentications, err := f.listRequestAuthentications(ctx, api)
+	if err != nil {
+		return nil, err
+	}
+	ra, duplicates := pickChild(requestAuthentications, childName(api))
+	if ra != nil {
+		state.requestAuthentication = ra.(*securityv1beta1.RequestAuthentication)
+	}
+	state.duplicates = append(state.duplicates, duplicates...)
 
 	var arList rulev1alpha1.RuleList
-	if err := f.client.List(ctx, &arList, client.MatchingLabels(labels)); err != nil {
+	if err := f.client.List(ctx, &arList, ownedBy(api)...); err != nil {
 		return nil, err
 	}
 
 	state.accessRules = make(map[string]*rulev1alpha1.Rule)
 
+	rulesByURL := make(map[string][]client.Object)
 	for i := range arList.Items {
-		obj := arList.Items[i]
-		state.accessRules[obj.Spec.Match.URL] = &obj
+		obj := &arList.Items[i]
+		rulesByURL[obj.Spec.Match.URL] = append(rulesByURL[obj.Spec.Match.URL], obj)
+	}
+	for url, rules := range rulesByURL {
+		rule, duplicates := pickChild(rules, childName(api, url))
+		state.accessRules[url] = rule.(*rulev1alpha1.Rule)
+		state.duplicates = append(state.duplicates, duplicates...)
 	}
 	return &state, nil
 }
 
 //Patch represents diff between desired and actual state
 type Patch struct {
-	virtualService *objToPatch
-	accessRule     map[string]*objToPatch
+	virtualService          *objToPatch
+	destinationRule         *objToPatch
+	gateway                 *objToPatch
+	certificate             *objToPatch
+	envoyFilter             *objToPatch
+	authorizationPolicy     *objToPatch
+	requestAuthentication   *objToPatch
+	accessRule              map[string]*objToPatch
+	obsoleteVirtualServices []*objToPatch
+	duplicates              []*objToPatch
+	//Repairs done while applying the patch by kind of object
+	repairs map[string][]string
+	//Objects not applied because of fields managed by other field managers, by kind of object
+	conflicts map[string][]string
+	//Objects left unchanged because they are marked as user-managed, by kind of object
+	userManaged map[string][]string
+	//Descriptors of the rate limits with a key replacing the ones of the api in the configuration of the global rate limit service, nil if not configured
+	rateLimitDescriptors *rateLimitDescriptors
+	rateLimitStatus      *gatewayv1alpha1.APIRuleResourceStatus
+}
+
+//VirtualServiceRepairs describes the duplicated Virtual Services, Destination Rules, Gateways, Certificates, EnvoyFilters, AuthorizationPolicies and RequestAuthentications
+//deleted, the orphaned ones adopted and the user-managed ones left unchanged while applying the patch
+func (p *Patch) VirtualServiceRepairs() string {
+	return describeRepairs(istioEntries(p.repairs), istioEntries(p.userManaged))
+}
+
+//AccessRuleRepairs describes the duplicated Oathkeeper Rules deleted, the orphaned ones adopted and the user-managed ones left unchanged
+//while applying the patch
+func (p *Patch) AccessRuleRepairs() string {
+	return describeRepairs(p.repairs[accessRuleKind], p.userManaged[accessRuleKind])
+}
+
+//VirtualServiceConflicts describes the Virtual Services, Destination Rules, Gateways, Certificates, EnvoyFilters, AuthorizationPolicies and RequestAuthentications
+//not applied because of fields managed by other field managers
+func (p *Patch) VirtualServiceConflicts() string {
+	return describeConflicts(istioEntries(p.conflicts))
+}
+
+//istioEntries returns the entries of Virtual Services followed by the ones of Destination Rules, Gateways, Certificates, EnvoyFilters, AuthorizationPolicies and
+//RequestAuthentications, which are reported together in the status of the Virtual Service
+func istioEntries(entries map[string][]string) []string {
+	res := entries[virtualServiceKind]
+	for _, kind := range []string{destinationRuleKind, gatewayKind, certificateKind, envoyFilterKind, authorizationPolicyKind, requestAuthenticationKind} {
+		for _, entry := range entries[kind] {
+			res = append(res, kind+" "+entry)
+		}
+	}
+	return res
+}
+
+//AccessRuleConflicts describes the Oathkeeper Rules not applied because of fields managed by other field managers
+func (p *Patch) AccessRuleConflicts() string {
+	return describeConflicts(p.conflicts[accessRuleKind])
+}
+
+//HasConflicts tells if any object wasn't applied because of fields managed by other field managers
+func (p *Patch) HasConflicts() bool {
+	return len(p.conflicts) > 0
 }
 
+func describeConflicts(conflicts []string) string {
+	if len(conflicts) == 0 {
+		return ""
+	}
+	return "Conflicts: " + strings.Join(conflicts, "; ")
+}
+
+func (p *Patch) addConflict(obj client.Object, err error) {
+	if p.conflicts == nil {
+		p.conflicts = make(map[string][]string)
+	}
+	kind := kindOf(obj)
+	p.conflicts[kind] = append(p.conflicts[kind], fmt.Sprintf("%s: %s", obj.GetName(), err.Error()))
+}
+
+func describeRepairs(repairs, userManaged []string) string {
+	var res []string
+	if len(repairs) > 0 {
+		res = append(res, "Repaired: "+strings.Join(repairs, ", "))
+	}
+	if len(userManaged) > 0 {
+		res = append(res, "Left unchanged as user-managed: "+strings.Join(userManaged, ", "))
+	}
+	return strings.Join(res, "\n")
+}
+
+//leaveUserManaged records the user-managed object left unchanged
+func (f *Factory) leaveUserManaged(patch *Patch, obj client.Object) {
+	f.Log.Info("Left user-managed object unchanged", "kind", kindOf(obj), "namespace", obj.GetNamespace(), "name", obj.GetName())
+	if patch.userManaged == nil {
+		patch.userManaged = make(map[string][]string)
+	}
+	kind := kindOf(obj)
+	patch.userManaged[kind] = append(patch.userManaged[kind], obj.GetName())
+}
+
+func (p *Patch) addRepair(obj client.Object, repair string) {
+	if p.repairs == nil {
+		p.repairs = make(map[string][]string)
+	}
+	kind := kindOf(obj)
+	p.repairs[kind] = append(p.repairs[kind], fmt.Sprintf("%s %s", repair, obj.GetName()))
+}
+
+//actionSkip leaves an existing object marked as user-managed unchanged
+const actionSkip = "skip"
+
 type objToPatch struct {
 	action string
 	obj    client.Object
+	//current is the existing object to be updated
+	current client.Object
+	//redacted is the object with the values of Secrets replaced by their references, shown in plans instead of obj. Nil if obj holds no value of a Secret
+	redacted client.Object
+}
+
+//existing returns the existing object changed by the patch, or nil if the object is created
+func (o *objToPatch) existing() client.Object {
+	switch {
+	case o.current != nil:
+		return o.current
+	case o.action == "delete":
+		return o.obj
+	}
+	return nil
 }
 
 //CalculateDiff methods compute diff between desired & actual state
@@ -132,13 +462,17 @@ func (f *Factory) CalculateDiff(requiredState *State, actualState *State) *Patch
 
 		if actualState.accessRules[path] != nil {
 			rulePatch.action = "update"
-			modifyAccessRule(actualState.accessRules[path], rule)
-			rulePatch.obj = actualState.accessRules[path]
+			rulePatch.current = actualState.accessRules[path]
+			rulePatch.obj = withNameOf(rule, actualState.accessRules[path])
 		} else {
 			rulePatch.action = "create"
 			rulePatch.obj = rule
 		}
 
+		if redacted := requiredState.redactedAccessRules[path]; redacted != nil {
+			rulePatch.redacted = redacted
+		}
+
 		arPatch[path] = rulePatch
 	}
 
@@ -152,44 +486,321 @@ func (f *Factory) CalculateDiff(requiredState *State, actualState *State) *Patch
 	vsPatch := &objToPatch{}
 	if actualState.virtualService != nil {
 		vsPatch.action = "update"
-		f.updateVirtualService(actualState.virtualService, requiredState.virtualService)
-		vsPatch.obj = actualState.virtualService
+		vsPatch.current = actualState.virtualService
+		vsPatch.obj = withNameOf(requiredState.virtualService, actualState.virtualService)
 	} else {
 		vsPatch.action = "create"
 		vsPatch.obj = requiredState.virtualService
 	}
 
-	return &Patch{virtualService: vsPatch, accessRule: arPatch}
+	var drPatch *objToPatch
+	switch {
+	case requiredState.destinationRule != nil && actualState.destinationRule != nil:
+		drPatch = &objToPatch{action: "update", current: actualState.destinationRule, obj: withNameOf(requiredState.destinationRule, actualState.destinationRule)}
+	case requiredState.destinationRule != nil:
+		drPatch = &objToPatch{action: "create", obj: requiredState.destinationRule}
+	case actualState.destinationRule != nil:
+		drPatch = &objToPatch{action: "delete", obj: actualState.destinationRule}
+	}
+
+	var gwPatch *objToPatch
+	switch {
+	case requiredState.gateway != nil && actualState.gateway != nil:
+		gwPatch = &objToPatch{action: "update", current: actualState.gateway, obj: withNameOf(requiredState.gateway, actualState.gateway)}
+	case requiredState.gateway != nil:
+		gwPatch = &objToPatch{action: "create", obj: requiredState.gateway}
+	case actualState.gateway != nil:
+		gwPatch = &objToPatch{action: "delete", obj: actualState.gateway}
+	}
+
+	var certPatch *objToPatch
+	switch {
+	case requiredState.certificate != nil && actualState.certificate != nil:
+		certPatch = &objToPatch{action: "update", current: actualState.certificate, obj: withNameOf(requiredState.certificate, actualState.certificate)}
+	case requiredState.certificate != nil:
+		certPatch = &objToPatch{action: "create", obj: requiredState.certificate}
+	case actualState.certificate != nil:
+		certPatch = &objToPatch{action: "delete", obj: actualState.certificate}
+	}
+
+	var efPatch *objToPatch
+	switch {
+	case requiredState.envoyFilter != nil && actualState.envoyFilter != nil:
+		efPatch = &objToPatch{action: "update", current: actualState.envoyFilter, obj: withNameOf(requiredState.envoyFilter, actualState.envoyFilter)}
+	case requiredState.envoyFilter != nil:
+		efPatch = &objToPatch{action: "create", obj: requiredState.envoyFilter}
+	case actualState.envoyFilter != nil:
+		efPatch = &objToPatch{action: "delete", obj: actualState.envoyFilter}
+	}
+
+	var apPatch *objToPatch
+	switch {
+	case requiredState.authorizationPolicy != nil && actualState.authorizationPolicy != nil:
+		apPatch = &objToPatch{action: "update", current: actualState.authorizationPolicy, obj: withNameOf(requiredState.authorizationPolicy, actualState.authorizationPolicy)}
+	case requiredState.authorizationPolicy != nil:
+		apPatch = &objToPatch{action: "create", obj: requiredState.authorizationPolicy}
+	case actualState.authorizationPolicy != nil:
+		apPatch = &objToPatch{action: "delete", obj: actualState.authorizationPolicy}
+	}
+
+	var raPatch *objToPatch
+	switch {
+	case requiredState.requestAuthentication != nil && actualState.requestAuthentication != nil:
+		raPatch = &objToPatch{action: "update", current: actualState.requestAuthentication, obj: withNameOf(requiredState.requestAuthentication, actualState.requestAuthentication)}
+	case requiredState.requestAuthentication != nil:
+		raPatch = &objToPatch{action: "create", obj: requiredState.requestAuthentication}
+	case actualState.requestAuthentication != nil:
+		raPatch = &objToPatch{action: "delete", obj: actualState.requestAuthentication}
+	}
+
+	var obsoletePatch []*objToPatch
+	for _, vs := range actualState.obsoleteVirtualServices {
+		obsoletePatch = append(obsoletePatch, &objToPatch{action: "delete", obj: vs})
+	}
+
+	var duplicatesPatch []*objToPatch
+	for _, obj := range actualState.duplicates {
+		duplicatesPatch = append(duplicatesPatch, &objToPatch{action: "delete", obj: obj})
+	}
+
+	patch := &Patch{virtualService: vsPatch, destinationRule: drPatch, gateway: gwPatch, certificate: certPatch, envoyFilter: efPatch, authorizationPolicy: apPatch,
+		requestAuthentication: raPatch, accessRule: arPatch, obsoleteVirtualServices: obsoletePatch, duplicates: duplicatesPatch,
+		rateLimitDescriptors: requiredState.rateLimitDescriptors, rateLimitStatus: requiredState.rateLimitStatus}
+	for _, objToPatch := range patch.all() {
+		if isUserManaged(objToPatch.existing()) {
+			objToPatch.action = actionSkip
+		}
+	}
+	return patch
 }
 
-//ApplyDiff method applies computed diff
+//all returns the changes of all objects in the patch
+func (p *Patch) all() []*objToPatch {
+	res := []*objToPatch{p.virtualService}
+	for _, obj := range []*objToPatch{p.destinationRule, p.gateway, p.certificate, p.envoyFilter, p.authorizationPolicy, p.requestAuthentication} {
+		if obj != nil {
+			res = append(res, obj)
+		}
+	}
+	for _, rule := range p.accessRule {
+		res = append(res, rule)
+	}
+	res = append(res, p.obsoleteVirtualServices...)
+	return append(res, p.duplicates...)
+}
+
+//ApplyDiff method applies computed diff. Objects conflicting with fields managed by other field managers are skipped and reported by the patch
 func (f *Factory) ApplyDiff(ctx context.Context, patch *Patch) error {
 
-	err := f.applyObjDiff(ctx, patch.virtualService)
+	//The Certificate and the Gateway serving the host are applied before the Virtual Service referencing the Gateway
+	for _, obj := range []*objToPatch{patch.certificate, patch.gateway} {
+		if obj != nil {
+			if err := f.applyObjDiff(ctx, patch, obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	//The RequestAuthentication validating the JWTs and the AuthorizationPolicy restricting the source IPs and requiring the JWTs are applied
+	//before the Virtual Service exposing the rules
+	for _, obj := range []*objToPatch{patch.requestAuthentication, patch.authorizationPolicy} {
+		if obj != nil {
+			if err := f.applyObjDiff(ctx, patch, obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	err := f.applyObjDiff(ctx, patch, patch.virtualService)
 	if err != nil {
 		return err
 	}
 
+	if patch.destinationRule != nil {
+		if err := f.applyObjDiff(ctx, patch, patch.destinationRule); err != nil {
+			return err
+		}
+	}
+
+	//The EnvoyFilter matches the routes of the Virtual Service by name, so it is applied after it
+	if patch.envoyFilter != nil {
+		if err := f.applyObjDiff(ctx, patch, patch.envoyFilter); err != nil {
+			return err
+		}
+	}
+
+	if patch.rateLimitDescriptors != nil {
+		if err := f.applyRateLimitDescriptors(ctx, patch.rateLimitDescriptors); err != nil {
+			return err
+		}
+	}
+
 	for _, rule := range patch.accessRule {
-		err := f.applyObjDiff(ctx, rule)
+		err := f.applyObjDiff(ctx, patch, rule)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, vs := range patch.obsoleteVirtualServices {
+		err := f.applyObjDiff(ctx, patch, vs)
 		if err != nil {
 			return err
 		}
 	}
 
+	for _, duplicate := range patch.duplicates {
+		if duplicate.action == actionSkip {
+			f.leaveUserManaged(patch, duplicate.obj)
+			continue
+		}
+		err := f.client.Delete(ctx, duplicate.obj)
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		f.Log.Info("Deleted duplicated object", "kind", kindOf(duplicate.obj), "namespace", duplicate.obj.GetNamespace(), "name", duplicate.obj.GetName())
+		patch.addRepair(duplicate.obj, "deleted duplicate")
+	}
+
 	return nil
 }
 
-func (f *Factory) applyObjDiff(ctx context.Context, objToPatch *objToPatch) error {
+//ReleaseHost deletes the Virtual Services, Gateways and Certificates of the api, which lost its host to another APIRule. User-managed objects are kept
+func (f *Factory) ReleaseHost(ctx context.Context, api *gatewayv1alpha1.APIRule) error {
+	var vsList networkingv1beta1.VirtualServiceList
+	if err := f.client.List(ctx, &vsList, ownedBy(api)...); err != nil {
+		return err
+	}
+	var gwList networkingv1beta1.GatewayList
+	if err := f.client.List(ctx, &gwList, ownedBy(api)...); err != nil {
+		return err
+	}
+	objs, err := f.listCertificates(ctx, api)
+	if err != nil {
+		return err
+	}
+	for i := range vsList.Items {
+		objs = append(objs, &vsList.Items[i])
+	}
+	for i := range gwList.Items {
+		objs = append(objs, &gwList.Items[i])
+	}
+
+	for _, obj := range objs {
+		if isUserManaged(obj) {
+			continue
+		}
+		if err := f.client.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//DeleteGenerated deletes the Virtual Services, Destination Rules, Gateways, Certificates, EnvoyFilters, rate limit descriptors, AuthorizationPolicies,
+//RequestAuthentications and Oathkeeper Rules generated for the api, except for the user-managed ones. Returns the number of objects still present,
+//which are being deleted or haven't yet disappeared from the cache, so the deletion has to be checked again until none is left
+func (f *Factory) DeleteGenerated(ctx context.Context, api *gatewayv1alpha1.APIRule) (int, error) {
+	var vsList networkingv1beta1.VirtualServiceList
+	if err := f.client.List(ctx, &vsList, ownedBy(api)...); err != nil {
+		return 0, err
+	}
+	var drList networkingv1beta1.DestinationRuleList
+	if err := f.client.List(ctx, &drList, ownedBy(api)...); err != nil {
+		return 0, err
+	}
+	var gwList networkingv1beta1.GatewayList
+	if err := f.client.List(ctx, &gwList, ownedBy(api)...); err != nil {
+		return 0, err
+	}
+	var arList rulev1alpha1.RuleList
+	if err := f.client.List(ctx, &arList, ownedBy(api)...); err != nil {
+		return 0, err
+	}
+	objs, err := f.listCertificates(ctx, api)
+	if err != nil {
+		return 0, err
+	}
+	envoyFilters, err := f.listEnvoyFilters(ctx, api)
+	if err != nil {
+		return 0, err
+	}
+	objs = append(objs, envoyFilters...)
+	authorizationPolicies, err := f.listAuthorizationPolicies(ctx, api)
+	if err != nil {
+		return 0, err
+	}
+	objs = append(objs, authorizationPolicies...)
+	requestAuthentications, err := f.listRequestAuthentications(ctx, api)
+	if err != nil {
+		return 0, err
+	}
+	objs = append(objs, requestAuthentications...)
+	if f.RateLimit != nil && f.RateLimit.Global != nil {
+		if err := f.applyRateLimitDescriptors(ctx, &rateLimitDescriptors{name: childName(api)}); err != nil {
+			return 0, err
+		}
+	}
+
+	for i := range vsList.Items {
+		objs = append(objs, &vsList.Items[i])
+	}
+	for i := range drList.Items {
+		objs = append(objs, &drList.Items[i])
+	}
+	for i := range gwList.Items {
+		objs = append(objs, &gwList.Items[i])
+	}
+	for i := range arList.Items {
+		objs = append(objs, &arList.Items[i])
+	}
+
+	remaining := 0
+	for _, obj := range objs {
+		if isUserManaged(obj) {
+			continue
+		}
+		err := f.client.Delete(ctx, obj)
+		if apierrs.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return remaining, err
+		}
+		if obj.GetDeletionTimestamp() == nil {
+			f.Log.Info("Deleted generated object", "kind", kindOf(obj), "namespace", obj.GetNamespace(), "name", obj.GetName())
+		}
+		remaining++
+	}
+	return remaining, nil
+}
+
+func (f *Factory) applyObjDiff(ctx context.Context, patch *Patch, objToPatch *objToPatch) error {
 	var err error
 
 	switch objToPatch.action {
 	case "create":
-		err = f.client.Create(ctx, objToPatch.obj)
+		var orphan client.Object
+		orphan, err = f.getOrphan(ctx, objToPatch.obj)
+		if err != nil {
+			return err
+		}
+		if isUserManaged(orphan) {
+			f.leaveUserManaged(patch, orphan)
+			return nil
+		}
+		err = f.apply(ctx, patch, objToPatch.obj, orphan)
+		if err == nil && orphan != nil {
+			//The object exists without the owner label, so it hasn't been found among the objects generated for the APIRule
+			f.Log.Info("Adopted orphaned object", "kind", kindOf(objToPatch.obj), "namespace", objToPatch.obj.GetNamespace(), "name", objToPatch.obj.GetName())
+			patch.addRepair(objToPatch.obj, "adopted orphan")
+		}
 	case "update":
-		err = f.client.Update(ctx, objToPatch.obj)
+		err = f.apply(ctx, patch, objToPatch.obj, objToPatch.current)
 	case "delete":
 		err = f.client.Delete(ctx, objToPatch.obj)
+	case actionSkip:
+		f.leaveUserManaged(patch, objToPatch.existing())
 	}
 
 	if err != nil {
@@ -199,42 +810,82 @@ func (f *Factory) applyObjDiff(ctx context.Context, objToPatch *objToPatch) erro
 	return nil
 }
 
-func (f *Factory) updateVirtualService(existing, required *networkingv1beta1.VirtualService) {
-	existing.Spec = required.Spec
+//apply creates or updates the object with server-side apply. Conflicts with other field managers are recorded in the patch instead of failing.
+//An existing object the controller has never applied was created or updated before the controller used server-side apply, so its fields
+//are owned by the field manager of those updates. The ownership is forced on the first apply to take them over.
+func (f *Factory) apply(ctx context.Context, patch *Patch, obj client.Object, existing client.Object) error {
+	opts := []client.PatchOption{client.FieldOwner(FieldManager)}
+	if existing != nil && !appliedByController(existing) {
+		opts = append(opts, client.ForceOwnership)
+	}
+	setTypeMeta(obj)
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+
+	err := f.client.Patch(ctx, obj, client.Apply, opts...)
+	if apierrs.IsConflict(err) {
+		f.Log.Info("Object not applied because of conflicts", "kind", kindOf(obj), "namespace", obj.GetNamespace(), "name", obj.GetName(), "conflicts", err.Error())
+		patch.addConflict(obj, err)
+		return nil
+	}
+	return err
 }
 
-func (f *Factory) generateVirtualService(api *gatewayv1alpha1.APIRule) *networkingv1beta1.VirtualService {
-	virtualServiceNamePrefix := fmt.Sprintf("%s-", api.ObjectMeta.Name)
-	ownerRef := generateOwnerRef(api)
-
-	vsSpecBuilder := builders.VirtualServiceSpec()
-	vsSpecBuilder.Host(helpers.GetHostWithDomain(*api.Spec.Service.Host, f.defaultDomainName))
-	vsSpecBuilder.Gateway(*api.Spec.Gateway)
+//appliedByController tells if the object has been applied with the FieldManager of the controller
+func appliedByController(obj client.Object) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager == FieldManager && entry.Operation == metav1.ManagedFieldsOperationApply {
+			return true
+		}
+	}
+	return false
+}
 
-	for _, rule := range api.Spec.Rules {
+func (f *Factory) generateVirtualService(holder *gatewayv1alpha1.APIRule, exposed []gatewayv1alpha1.APIRule) *networkingv1beta1.VirtualService {
+	ownerRef := generateOwnerRef(holder)
 
-		httpRouteBuilder := builders.HTTPRoute()
-		host, port := f.oathkeeperSvc, f.oathkeeperSvcPort
+	vsSpecBuilder := builders.VirtualServiceSpec()
+	host := helpers.GetHostWithDomain(*holder.Spec.Service.Host, f.defaultDomainName)
+	vsSpecBuilder.Host(host)
+	if f.GatewayProvisioning.provisions(host) {
+		vsSpecBuilder.Gateway(fmt.Sprintf("%s/%s", holder.ObjectMeta.Namespace, childName(holder)))
+	} else {
+		vsSpecBuilder.Gateway(*holder.Spec.Gateway)
+	}
 
-		if !isSecured(rule) {
-			host = fmt.Sprintf("%s.%s.svc.cluster.local", *api.Spec.Service.Name, api.ObjectMeta.Namespace)
-			port = *api.Spec.Service.Port
+	for _, api := range exposed {
+		for _, rule := range api.Spec.Rules {
+
+			httpRouteBuilder := builders.HTTPRoute()
+			host, port := f.oathkeeperSvc, f.oathkeeperSvcPort
+
+			if !routedThroughOathkeeper(&api, rule) {
+				host = fmt.Sprintf("%s.%s.svc.cluster.local", *api.Spec.Service.Name, api.ServiceNamespace())
+				port = *api.Spec.Service.Port
+			}
+
+			httpRouteBuilder.Route(builders.RouteDestination().Host(host).Port(port))
+			httpRouteBuilder.Match(builders.MatchRequest().Uri().Regex(rule.MatchPath()))
+			if rule.RateLimit != nil || api.ServiceProtocol() == gatewayv1alpha1.ProtocolWebSocket {
+				httpRouteBuilder.Name(routeName(&api, rule))
+			}
+			corsPolicyBuilder := builders.CorsPolicy().
+				AllowOrigins(f.corsConfig.AllowOrigins...).
+				AllowMethods(f.corsConfig.AllowMethods...).
+				AllowHeaders(f.corsConfig.AllowHeaders...)
+			if api.ServiceProtocol() == gatewayv1alpha1.ProtocolGRPCWeb {
+				corsPolicyBuilder.AllowHeaders(grpcWebAllowHeaders...).ExposeHeaders(grpcWebExposeHeaders...)
+			}
+			httpRouteBuilder.CorsPolicy(corsPolicyBuilder)
+			vsSpecBuilder.HTTP(httpRouteBuilder)
 		}
-
-		httpRouteBuilder.Route(builders.RouteDestination().Host(host).Port(port))
-		httpRouteBuilder.Match(builders.MatchRequest().Uri().Regex(rule.Path))
-		httpRouteBuilder.CorsPolicy(builders.CorsPolicy().
-			AllowOrigins(f.corsConfig.AllowOrigins...).
-			AllowMethods(f.corsConfig.AllowMethods...).
-			AllowHeaders(f.corsConfig.AllowHeaders...))
-		vsSpecBuilder.HTTP(httpRouteBuilder)
 	}
 
 	vsBuilder := builders.VirtualService().
-		GenerateName(virtualServiceNamePrefix).
-		Namespace(api.ObjectMeta.Namespace).
+		Name(childName(holder)).
+		Namespace(holder.ObjectMeta.Namespace).
 		Owner(builders.OwnerReference().From(&ownerRef)).
-		Label(OwnerLabel, fmt.Sprintf("%s.%s", api.ObjectMeta.Name, api.ObjectMeta.Namespace))
+		Label(OwnerLabel, fmt.Sprintf("%s.%s", holder.ObjectMeta.Name, holder.ObjectMeta.Namespace))
 
 	for k, v := range f.additionalLabels {
 		vsBuilder.Label(k, v)
@@ -244,3 +895,9 @@ func (f *Factory) generateVirtualService(api *gatewayv1alpha1.APIRule) *networki
 
 	return vsBuilder.Get()
 }
+
+//grpcWebAllowHeaders and grpcWebExposeHeaders are the headers browsers have to send and read in cross-origin gRPC-Web calls
+var (
+	grpcWebAllowHeaders  = []string{"x-grpc-web", "x-user-agent", "grpc-timeout"}
+	grpcWebExposeHeaders = []string{"grpc-status", "grpc-message", "grpc-status-details-bin"}
+)
@@ -13,6 +13,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 
 	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/handlers"
 	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -67,6 +68,13 @@ func TestProcessing(t *testing.T) {
 	RunSpecs(t, "Processing Suite")
 }
 
+//Access strategies registered for the tests of the handlers registry. The registry can't be cleaned up from another package,
+//so they are registered once for the whole suite
+var _ = BeforeSuite(func() {
+	Expect(handlers.Register(handlers.AccessStrategy{Name: "test_public", Secured: false})).To(Succeed())
+	Expect(handlers.Register(handlers.AccessStrategy{Name: "test_session", Secured: true, OutputName: "cookie_session"})).To(Succeed())
+})
+
 var _ = Describe("Factory", func() {
 	Describe("CalculateRequiredState", func() {
 		Context("APIRule", func() {
@@ -108,8 +116,8 @@ var _ = Describe("Factory", func() {
 				Expect(vs.Spec.Http[0].CorsPolicy.AllowMethods).To(Equal(testCors.AllowMethods))
 				Expect(vs.Spec.Http[0].CorsPolicy.AllowHeaders).To(Equal(testCors.AllowHeaders))
 
-				Expect(vs.ObjectMeta.Name).To(BeEmpty())
-				Expect(vs.ObjectMeta.GenerateName).To(Equal(apiName + "-"))
+				Expect(vs.ObjectMeta.Name).To(Equal(childName(apiRule)))
+				Expect(vs.ObjectMeta.GenerateName).To(BeEmpty())
 				Expect(vs.ObjectMeta.Namespace).To(Equal(apiNamespace))
 				Expect(vs.ObjectMeta.Labels[testLabelKey]).To(Equal(testLabelValue))
 
@@ -122,6 +130,143 @@ var _ = Describe("Factory", func() {
 				Expect(len(accessRules)).To(Equal(0))
 			})
 
+			It("should match gRPC calls and allow gRPC-Web headers", func() {
+				allow := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "allow"}}}
+				grpcRule := getRuleFor("", []string{"POST"}, []*gatewayv1alpha1.Mutator{}, allow)
+				grpcRule.GRPC = &gatewayv1alpha1.GRPCMatch{Service: "helloworld.Greeter", Method: "SayHello"}
+				apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{grpcRule})
+				protocol := gatewayv1alpha1.ProtocolGRPCWeb
+				apiRule.Spec.Service.Protocol = &protocol
+
+				f := NewFactory(nil, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+
+				desiredState := f.CalculateRequiredState(apiRule)
+				vs := desiredState.virtualService
+
+				//verify VS
+				Expect(vs.Spec.Http).To(HaveLen(1))
+				Expect(vs.Spec.Http[0].Match[0].Uri.GetRegex()).To(Equal(`/helloworld\.Greeter/SayHello`))
+				Expect(vs.Spec.Http[0].CorsPolicy.AllowHeaders).To(Equal([]string{"header1", "header2", "x-grpc-web", "x-user-agent", "grpc-timeout"}))
+				Expect(vs.Spec.Http[0].CorsPolicy.ExposeHeaders).To(ConsistOf("grpc-status", "grpc-message", "grpc-status-details-bin"))
+				Expect(testCors.AllowHeaders).To(Equal(testAllowHeaders))
+				Expect(vs.Spec.Http[0].Route[0].Destination.Host).To(Equal(serviceName + "." + apiNamespace + ".svc.cluster.local"))
+
+				//Verify AR
+				Expect(desiredState.accessRules).To(BeEmpty())
+
+				//verify DR
+				dr := desiredState.destinationRule
+				Expect(dr).NotTo(BeNil())
+				Expect(dr.Spec.TrafficPolicy.PortLevelSettings).To(HaveLen(1))
+				Expect(dr.Spec.TrafficPolicy.PortLevelSettings[0].Port.Number).To(Equal(servicePort))
+				Expect(dr.Spec.TrafficPolicy.PortLevelSettings[0].Tls).To(BeNil())
+				Expect(dr.Spec.TrafficPolicy.PortLevelSettings[0].ConnectionPool.Http.H2UpgradePolicy).To(Equal(v1beta1.ConnectionPoolSettings_HTTPSettings_UPGRADE))
+			})
+
+			It("should produce DR applying upstream TLS", func() {
+				allow := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "allow"}}}
+				apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, []*gatewayv1alpha1.Mutator{}, allow)})
+				clientSecret := "example-client"
+				apiRule.Spec.Service.TLS = &gatewayv1alpha1.UpstreamTLS{Mode: gatewayv1alpha1.TLSModeMutual, ClientSecretName: &clientSecret}
+
+				f := NewFactory(nil, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+
+				desiredState := f.CalculateRequiredState(apiRule)
+				dr := desiredState.destinationRule
+
+				//verify DR
+				Expect(dr).NotTo(BeNil())
+				Expect(dr.ObjectMeta.Name).To(Equal(childName(apiRule)))
+				Expect(dr.ObjectMeta.Namespace).To(Equal(apiNamespace))
+				Expect(dr.ObjectMeta.Labels).To(HaveKeyWithValue(OwnerLabel, apiName+"."+apiNamespace))
+				Expect(dr.ObjectMeta.OwnerReferences[0].UID).To(Equal(apiUID))
+				Expect(dr.Spec.Host).To(Equal(serviceName + "." + apiNamespace + ".svc.cluster.local"))
+				Expect(dr.Spec.TrafficPolicy.PortLevelSettings).To(HaveLen(1))
+				Expect(dr.Spec.TrafficPolicy.PortLevelSettings[0].Port.Number).To(Equal(servicePort))
+				Expect(dr.Spec.TrafficPolicy.PortLevelSettings[0].Tls.Mode).To(Equal(v1beta1.ClientTLSSettings_MUTUAL))
+				Expect(dr.Spec.TrafficPolicy.PortLevelSettings[0].Tls.CredentialName).To(Equal(clientSecret))
+				Expect(dr.Spec.ExportTo).To(Equal([]string{".", "istio-system"}))
+				Expect(desiredState.Objects()).To(HaveLen(2))
+			})
+
+			It("should use HTTPS upstream in AR when the gateway only can read the TLS Secret", func() {
+				noop := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "noop"}}}
+				apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, []*gatewayv1alpha1.Mutator{}, noop)})
+				caSecret := "example-ca"
+				apiRule.Spec.Service.TLS = &gatewayv1alpha1.UpstreamTLS{Mode: gatewayv1alpha1.TLSModeSimple, CASecretName: &caSecret}
+
+				f := NewFactory(nil, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+				f.SourceIPs = &SourceIPs{Namespace: "ingress"}
+
+				desiredState := f.CalculateRequiredState(apiRule)
+
+				//verify DR
+				dr := desiredState.destinationRule
+				Expect(dr.Spec.TrafficPolicy.PortLevelSettings[0].Tls.Mode).To(Equal(v1beta1.ClientTLSSettings_SIMPLE))
+				Expect(dr.Spec.TrafficPolicy.PortLevelSettings[0].Tls.CredentialName).To(Equal(caSecret))
+				Expect(dr.Spec.ExportTo).To(Equal([]string{".", "ingress"}))
+
+				//Verify AR
+				Expect(desiredState.accessRules).To(HaveLen(1))
+				for _, ar := range desiredState.accessRules {
+					Expect(ar.Spec.Upstream.URL).To(Equal(fmt.Sprintf("https://%s.%s.svc.cluster.local:%d", serviceName, apiNamespace, servicePort)))
+				}
+			})
+
+			It("should keep HTTP upstream in AR and leave TLS origination to the sidecar", func() {
+				noop := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "noop"}}}
+				apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, []*gatewayv1alpha1.Mutator{}, noop)})
+				apiRule.Spec.Service.TLS = &gatewayv1alpha1.UpstreamTLS{Mode: gatewayv1alpha1.TLSModeIstioMutual}
+
+				f := NewFactory(nil, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+
+				desiredState := f.CalculateRequiredState(apiRule)
+
+				//verify DR
+				Expect(desiredState.destinationRule.Spec.TrafficPolicy.PortLevelSettings[0].Tls.Mode).To(Equal(v1beta1.ClientTLSSettings_ISTIO_MUTUAL))
+				Expect(desiredState.destinationRule.Spec.ExportTo).To(BeEmpty())
+
+				//Verify AR
+				Expect(desiredState.accessRules).To(HaveLen(1))
+				for _, ar := range desiredState.accessRules {
+					Expect(ar.Spec.Upstream.URL).To(Equal(fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", serviceName, apiNamespace, servicePort)))
+				}
+			})
+
+			It("should not produce DR without upstream TLS", func() {
+				allow := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "allow"}}}
+				apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, []*gatewayv1alpha1.Mutator{}, allow)})
+
+				f := NewFactory(nil, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+
+				Expect(f.CalculateRequiredState(apiRule).destinationRule).To(BeNil())
+			})
+
+			It("should use access strategies from the handlers registry", func() {
+				public := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "test_public"}}}
+				session := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "test_session"}}}
+
+				rules := []gatewayv1alpha1.Rule{
+					getRuleFor(apiPath, apiMethods, []*gatewayv1alpha1.Mutator{}, public),
+					getRuleFor(headersAPIPath, apiMethods, []*gatewayv1alpha1.Mutator{}, session),
+				}
+				apiRule := getAPIRuleFor(rules)
+
+				f := NewFactory(nil, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+
+				desiredState := f.CalculateRequiredState(apiRule)
+				vs := desiredState.virtualService
+				accessRules := desiredState.accessRules
+
+				Expect(vs.Spec.Http[0].Route[0].Destination.Host).To(Equal(serviceName + "." + apiNamespace + ".svc.cluster.local"))
+				Expect(vs.Spec.Http[1].Route[0].Destination.Host).To(Equal(oathkeeperSvc))
+
+				Expect(len(accessRules)).To(Equal(1))
+				sessionAccessRule := accessRules[fmt.Sprintf("<http|https>://%s<%s>", serviceHost, headersAPIPath)]
+				Expect(sessionAccessRule).NotTo(BeNil())
+				Expect(sessionAccessRule.Spec.Authenticators[0].Handler.Name).To(Equal("cookie_session"))
+			})
+
 			It("should produce VS and ARs for given paths", func() {
 				noop := []*gatewayv1alpha1.Authenticator{
 					{
@@ -205,8 +350,8 @@ var _ = Describe("Factory", func() {
 				Expect(vs.Spec.Http[1].CorsPolicy.AllowMethods).To(Equal(testCors.AllowMethods))
 				Expect(vs.Spec.Http[1].CorsPolicy.AllowHeaders).To(Equal(testCors.AllowHeaders))
 
-				Expect(vs.ObjectMeta.Name).To(BeEmpty())
-				Expect(vs.ObjectMeta.GenerateName).To(Equal(apiName + "-"))
+				Expect(vs.ObjectMeta.Name).To(Equal(childName(apiRule)))
+				Expect(vs.ObjectMeta.GenerateName).To(BeEmpty())
 				Expect(vs.ObjectMeta.Namespace).To(Equal(apiNamespace))
 				Expect(vs.ObjectMeta.Labels[testLabelKey]).To(Equal(testLabelValue))
 
@@ -235,8 +380,8 @@ var _ = Describe("Factory", func() {
 
 				Expect(noopAccessRule.Spec.Upstream.URL).To(Equal(expectedRuleUpstreamURL))
 
-				Expect(noopAccessRule.ObjectMeta.Name).To(BeEmpty())
-				Expect(noopAccessRule.ObjectMeta.GenerateName).To(Equal(apiName + "-"))
+				Expect(noopAccessRule.ObjectMeta.GenerateName).To(BeEmpty())
+				Expect(noopAccessRule.ObjectMeta.Name).To(Equal(childName(apiRule, noopAccessRule.Spec.Match.URL)))
 				Expect(noopAccessRule.ObjectMeta.Namespace).To(Equal(apiNamespace))
 				Expect(noopAccessRule.ObjectMeta.Labels[testLabelKey]).To(Equal(testLabelValue))
 
@@ -267,8 +412,8 @@ var _ = Describe("Factory", func() {
 				Expect(jwtAccessRule.Spec.Mutators[0].Handler.Name).To(Equal(testMutators[0].Name))
 				Expect(jwtAccessRule.Spec.Mutators[1].Handler.Name).To(Equal(testMutators[1].Name))
 
-				Expect(jwtAccessRule.ObjectMeta.Name).To(BeEmpty())
-				Expect(jwtAccessRule.ObjectMeta.GenerateName).To(Equal(apiName + "-"))
+				Expect(jwtAccessRule.ObjectMeta.GenerateName).To(BeEmpty())
+				Expect(jwtAccessRule.ObjectMeta.Name).To(Equal(childName(apiRule, jwtAccessRule.Spec.Match.URL)))
 				Expect(jwtAccessRule.ObjectMeta.Namespace).To(Equal(apiNamespace))
 				Expect(jwtAccessRule.ObjectMeta.Labels[testLabelKey]).To(Equal(testLabelValue))
 
@@ -339,8 +484,8 @@ var _ = Describe("Factory", func() {
 				Expect(vs.Spec.Http[0].CorsPolicy.AllowMethods).To(Equal(testCors.AllowMethods))
 				Expect(vs.Spec.Http[0].CorsPolicy.AllowHeaders).To(Equal(testCors.AllowHeaders))
 
-				Expect(vs.ObjectMeta.Name).To(BeEmpty())
-				Expect(vs.ObjectMeta.GenerateName).To(Equal(apiName + "-"))
+				Expect(vs.ObjectMeta.Name).To(Equal(childName(apiRule)))
+				Expect(vs.ObjectMeta.GenerateName).To(BeEmpty())
 				Expect(vs.ObjectMeta.Namespace).To(Equal(apiNamespace))
 				Expect(vs.ObjectMeta.Labels[testLabelKey]).To(Equal(testLabelValue))
 
@@ -372,8 +517,8 @@ var _ = Describe("Factory", func() {
 
 				Expect(rule.Spec.Upstream.URL).To(Equal(expectedRuleUpstreamURL))
 
-				Expect(rule.ObjectMeta.Name).To(BeEmpty())
-				Expect(rule.ObjectMeta.GenerateName).To(Equal(apiName + "-"))
+				Expect(rule.ObjectMeta.GenerateName).To(BeEmpty())
+				Expect(rule.ObjectMeta.Name).To(Equal(childName(apiRule, rule.Spec.Match.URL)))
 				Expect(rule.ObjectMeta.Namespace).To(Equal(apiNamespace))
 				Expect(rule.ObjectMeta.Labels[testLabelKey]).To(Equal(testLabelValue))
 
@@ -457,6 +602,63 @@ var _ = Describe("Factory", func() {
 		})
 	})
 
+	Describe("CalculateRequiredStateForHost", func() {
+		It("should produce a single VS owned by the holder with routes of all exposed APIRules", func() {
+			allow := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "allow"}}}
+			noop := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "noop"}}}
+
+			holder := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor("/orders/.*", apiMethods, nil, allow)})
+			memberService := "cart-service"
+			member := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor("/cart/.*", apiMethods, nil, noop)})
+			member.Name = "cart"
+			member.UID = "f3a1c4b2-c417-11e9-bf11-4ac644044351"
+			member.Spec.Service = &gatewayv1alpha1.Service{Name: &memberService, Host: &serviceHost, Port: &servicePort}
+
+			f := NewFactory(nil, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+
+			desiredState := f.CalculateRequiredStateForHost(member, holder, []gatewayv1alpha1.APIRule{*holder, *member})
+			vs := desiredState.virtualService
+
+			Expect(vs.Spec.Hosts).To(Equal([]string{serviceHost}))
+			Expect(vs.Spec.Http).To(HaveLen(2))
+			Expect(vs.Spec.Http[0].Match[0].Uri.GetRegex()).To(Equal("/orders/.*"))
+			Expect(vs.Spec.Http[0].Route[0].Destination.Host).To(Equal(serviceName + "." + apiNamespace + ".svc.cluster.local"))
+			Expect(vs.Spec.Http[1].Match[0].Uri.GetRegex()).To(Equal("/cart/.*"))
+			Expect(vs.Spec.Http[1].Route[0].Destination.Host).To(Equal(oathkeeperSvc))
+
+			Expect(vs.ObjectMeta.Name).To(Equal(childName(holder)))
+			Expect(vs.ObjectMeta.Labels[OwnerLabel]).To(Equal(fmt.Sprintf("%s.%s", apiName, apiNamespace)))
+			Expect(vs.ObjectMeta.OwnerReferences).To(HaveLen(1))
+			Expect(vs.ObjectMeta.OwnerReferences[0].UID).To(Equal(apiUID))
+
+			//Access rules are generated only for the processed APIRule
+			Expect(desiredState.accessRules).To(HaveLen(1))
+			for _, ar := range desiredState.accessRules {
+				Expect(ar.ObjectMeta.OwnerReferences[0].UID).To(Equal(member.UID))
+			}
+		})
+
+		It("should produce patch deleting VS replaced by the VS of the holder", func() {
+			allow := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "allow"}}}
+			holder := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor("/orders/.*", apiMethods, nil, allow)})
+			member := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor("/cart/.*", apiMethods, nil, allow)})
+			member.Name = "cart"
+
+			f := NewFactory(nil, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+
+			desiredState := f.CalculateRequiredStateForHost(member, holder, []gatewayv1alpha1.APIRule{*holder, *member})
+			ownVS := &networkingv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{Name: "cart-abcde", Namespace: apiNamespace}}
+			actualState := &State{obsoleteVirtualServices: []*networkingv1beta1.VirtualService{ownVS}}
+
+			patch := f.CalculateDiff(desiredState, actualState)
+
+			Expect(patch.virtualService.action).To(Equal("create"))
+			Expect(patch.obsoleteVirtualServices).To(HaveLen(1))
+			Expect(patch.obsoleteVirtualServices[0].action).To(Equal("delete"))
+			Expect(patch.obsoleteVirtualServices[0].obj).To(Equal(ownVS))
+		})
+	})
+
 	Describe("CalculateDiff", func() {
 		Context("between desired state & actual state", func() {
 			It("should produce patch containing VS to create & AR to create", func() {
@@ -493,6 +695,35 @@ var _ = Describe("Factory", func() {
 
 			})
 
+			It("should produce patch deleting DR once upstream TLS is removed", func() {
+				allow := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "allow"}}}
+				apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, []*gatewayv1alpha1.Mutator{}, allow)})
+				apiRule.Spec.Service.TLS = &gatewayv1alpha1.UpstreamTLS{Mode: gatewayv1alpha1.TLSModeIstioMutual}
+
+				f := NewFactory(nil, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+				existing := f.CalculateRequiredState(apiRule)
+
+				//when
+				patch := f.CalculateDiff(f.CalculateRequiredState(apiRule), existing)
+
+				//then
+				Expect(patch.destinationRule.action).To(Equal("update"))
+
+				//when
+				apiRule.Spec.Service.TLS = nil
+				patch = f.CalculateDiff(f.CalculateRequiredState(apiRule), existing)
+
+				//then
+				Expect(patch.destinationRule.action).To(Equal("delete"))
+				Expect(patch.destinationRule.obj).To(Equal(existing.destinationRule))
+
+				//when
+				patch = f.CalculateDiff(f.CalculateRequiredState(apiRule), &State{})
+
+				//then
+				Expect(patch.destinationRule).To(BeNil())
+			})
+
 			It("should produce patch containing VS to update, AR to create, AR to update & AR to delete", func() {
 				oauthConfigJSON := fmt.Sprintf(`{"required_scope": [%s]}`, toCSVList(apiScopes))
 				oauth := &gatewayv1alpha1.Authenticator{
@@ -574,7 +805,10 @@ var _ = Describe("Factory", func() {
 				//Verify patch
 				Expect(patch.virtualService).NotTo(BeNil())
 				Expect(patch.virtualService.action).To(Equal("update"))
-				Expect(vsPatch.ObjectMeta.Labels).To(Equal(actualState.virtualService.ObjectMeta.Labels))
+				//Labels set by others are kept by server-side apply, as only the labels set by the controller are managed by it
+				Expect(vsPatch.ObjectMeta.Labels).NotTo(HaveKey("myLabel"))
+				Expect(vsPatch.ObjectMeta.Labels).To(HaveKeyWithValue(testLabelKey, testLabelValue))
+				Expect(vsPatch.Spec).To(Equal(desiredState.virtualService.Spec))
 
 				//TODO verify vs spec
 
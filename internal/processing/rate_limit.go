@@ -0,0 +1,305 @@
+package processing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/builders"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+//RateLimit is an internal representation of the rate limiting settings
+type RateLimit struct {
+	Namespace string
+	Selector  map[string]string
+	//Global rate limit service counting the requests of every client, nil if rate limits with a key can't be applied
+	Global *GlobalRateLimit
+}
+
+//GlobalRateLimit is an internal representation of the settings of the descriptors configuration of the global rate limit service
+type GlobalRateLimit struct {
+	Domain             string
+	ConfigMapNamespace string
+	ConfigMapName      string
+}
+
+const (
+	localRateLimitFilter = "envoy.filters.http.local_ratelimit"
+	jwtAuthnFilter       = "envoy.filters.http.jwt_authn"
+)
+
+var rateLimitFillIntervals = map[string]time.Duration{
+	gatewayv1alpha1.RateLimitUnitSecond: time.Second,
+	gatewayv1alpha1.RateLimitUnitMinute: time.Minute,
+	gatewayv1alpha1.RateLimitUnitHour:   time.Hour,
+}
+
+//rateLimitDescriptor is a descriptor in the configuration of the global rate limit service
+type rateLimitDescriptor struct {
+	Key         string                `json:"key"`
+	Value       string                `json:"value,omitempty"`
+	RateLimit   *rateLimitPolicy      `json:"rate_limit,omitempty"`
+	Descriptors []rateLimitDescriptor `json:"descriptors,omitempty"`
+}
+
+type rateLimitPolicy struct {
+	Unit            string `json:"unit"`
+	RequestsPerUnit uint32 `json:"requests_per_unit"`
+}
+
+type rateLimitServiceConfig struct {
+	Domain      string                `json:"domain"`
+	Descriptors []rateLimitDescriptor `json:"descriptors,omitempty"`
+}
+
+//rateLimitDescriptors holds the descriptors of the rate limits of an api with a key. They are kept in a key of the shared ConfigMap
+//of the global rate limit service owned by the api alone
+type rateLimitDescriptors struct {
+	//name identifying the descriptors of the api, used for their key in the ConfigMap and for the field manager applying them
+	name        string
+	descriptors []rateLimitDescriptor
+}
+
+//routeName returns the name of the route of the rule in the Virtual Service, which is matched by the EnvoyFilter applying the rate limit.
+//The name starts with the name of the objects generated for the api, so the descriptors of the api can be found by the prefix
+func routeName(api *gatewayv1alpha1.APIRule, rule gatewayv1alpha1.Rule) string {
+	name := childName(api, rule.MatchPath())
+	return childName(api) + "/" + name[len(name)-childNameHashLength:]
+}
+
+//generateEnvoyFilter returns the EnvoyFilter patching the gateway routes of the rules of the api with their rate limits and, for WebSocket
+//services, with the WebSocket upgrade, followed by the descriptors and the status of the rate limits returned by generateRateLimits.
+//The EnvoyFilter is nil if no route needs a patch, or if the route configuration of a rate limit can't be built.
+func (f *Factory) generateEnvoyFilter(api *gatewayv1alpha1.APIRule) (*networkingv1alpha3.EnvoyFilter, *rateLimitDescriptors, *gatewayv1alpha1.APIRuleResourceStatus) {
+	namespace, selector := defaultGatewayNamespace, defaultGatewaySelector
+	if f.RateLimit != nil {
+		namespace = f.RateLimit.Namespace
+		if len(f.RateLimit.Selector) > 0 {
+			selector = f.RateLimit.Selector
+		}
+	}
+	specBuilder := builders.EnvoyFilterSpec().WorkloadSelector(selector)
+
+	descriptors, status := f.generateRateLimits(api, specBuilder.GatewayRoutePatch)
+	if status != nil && status.Code == gatewayv1alpha1.StatusError {
+		return nil, nil, status
+	}
+	if api.ServiceProtocol() == gatewayv1alpha1.ProtocolWebSocket {
+		for _, rule := range api.Spec.Rules {
+			specBuilder.GatewayRouteWebSocketUpgrade(routeName(api, rule))
+		}
+	}
+	if len(specBuilder.Get().ConfigPatches) == 0 {
+		return nil, descriptors, status
+	}
+
+	efBuilder := builders.EnvoyFilter().
+		Name(childName(api)).
+		Namespace(namespace).
+		Label(OwnerLabel, fmt.Sprintf("%s.%s", api.ObjectMeta.Name, api.ObjectMeta.Namespace)).
+		Spec(specBuilder)
+
+	for k, v := range f.additionalLabels {
+		efBuilder.Label(k, v)
+	}
+
+	return efBuilder.Get(), descriptors, status
+}
+
+//generateRateLimits adds the route patches applying the rate limits of the rules of the api with routePatch, and returns
+//the descriptors of the rate limits with a key and the status telling if the limits are active. The descriptors are nil if the global
+//rate limit service isn't configured. Both are nil if the route configuration of a rate limit can't be built, with the error reported
+//in the status.
+func (f *Factory) generateRateLimits(api *gatewayv1alpha1.APIRule, routePatch func(routeName string, value map[string]interface{}) error) (*rateLimitDescriptors, *gatewayv1alpha1.APIRuleResourceStatus) {
+	var limited, inactive []string
+	for i, rule := range api.Spec.Rules {
+		if rule.RateLimit == nil {
+			continue
+		}
+		path := fmt.Sprintf("spec.rules[%d]", i)
+		limited = append(limited, path)
+		if f.RateLimit == nil || rule.RateLimit.Key != nil && f.RateLimit.Global == nil {
+			inactive = append(inactive, path)
+		}
+	}
+
+	var descriptors *rateLimitDescriptors
+	if f.RateLimit != nil && f.RateLimit.Global != nil {
+		//The descriptors of the api are replaced even if there are none, so the ones of removed rate limits are deleted
+		descriptors = &rateLimitDescriptors{name: childName(api)}
+	}
+
+	if len(limited) == 0 {
+		return descriptors, nil
+	}
+	if f.RateLimit == nil {
+		return nil, &gatewayv1alpha1.APIRuleResourceStatus{Code: gatewayv1alpha1.StatusSkipped, Description: "Rate limiting is not enabled in the controller configuration"}
+	}
+
+	for i, rule := range api.Spec.Rules {
+		var err error
+		switch {
+		case rule.RateLimit == nil:
+		case rule.RateLimit.Key == nil:
+			err = routePatch(routeName(api, rule), localRateLimit(rule.RateLimit))
+		case descriptors != nil:
+			name := routeName(api, rule)
+			err = routePatch(name, globalRateLimitActions(name, rule))
+			descriptors.descriptors = append(descriptors.descriptors, rateLimitDescriptor{
+				Key:   "generic_key",
+				Value: name,
+				Descriptors: []rateLimitDescriptor{{
+					Key:       rateLimitDescriptorKey(rule.RateLimit.Key),
+					RateLimit: &rateLimitPolicy{Unit: strings.ToUpper(rule.RateLimit.Unit), RequestsPerUnit: rule.RateLimit.Requests},
+				}},
+			})
+		}
+		if err != nil {
+			return nil, &gatewayv1alpha1.APIRuleResourceStatus{
+				Code:        gatewayv1alpha1.StatusError,
+				Description: fmt.Sprintf("Rate limit of spec.rules[%d] can't be applied: %v", i, err),
+			}
+		}
+	}
+
+	if len(inactive) > 0 {
+		return descriptors, &gatewayv1alpha1.APIRuleResourceStatus{
+			Code:        gatewayv1alpha1.StatusSkipped,
+			Description: fmt.Sprintf("Rate limits with a key of %s need the global rate limit service, which is not configured", strings.Join(inactive, ", ")),
+		}
+	}
+	return descriptors, &gatewayv1alpha1.APIRuleResourceStatus{Code: gatewayv1alpha1.StatusOK, Description: fmt.Sprintf("Rate limits active for %s", strings.Join(limited, ", "))}
+}
+
+//localRateLimit returns the route configuration limiting the requests with a token bucket of the gateway. Every replica of the gateway
+//counts the requests on its own
+func localRateLimit(limit *gatewayv1alpha1.RateLimit) map[string]interface{} {
+	enabled := map[string]interface{}{
+		"numerator":   100,
+		"denominator": "HUNDRED",
+	}
+	return map[string]interface{}{
+		"typed_per_filter_config": map[string]interface{}{
+			localRateLimitFilter: map[string]interface{}{
+				"@type":    "type.googleapis.com/udpa.type.v1.TypedStruct",
+				"type_url": "type.googleapis.com/envoy.extensions.filters.http.local_ratelimit.v3.LocalRateLimit",
+				"value": map[string]interface{}{
+					"stat_prefix": "http_local_rate_limiter",
+					"token_bucket": map[string]interface{}{
+						"max_tokens":      limit.Requests,
+						"tokens_per_fill": limit.Requests,
+						"fill_interval":   fmt.Sprintf("%ds", int(rateLimitFillIntervals[limit.Unit].Seconds())),
+					},
+					"filter_enabled":  map[string]interface{}{"runtime_key": "local_rate_limit_enabled", "default_value": enabled},
+					"filter_enforced": map[string]interface{}{"runtime_key": "local_rate_limit_enforced", "default_value": enabled},
+				},
+			},
+		},
+	}
+}
+
+//globalRateLimitActions returns the route configuration sending the descriptors of the requests to the global rate limit service.
+//The JWT subject is read from the metadata of the JWT authentication of the gateway, which keeps the payload of a verified token by its issuer
+func globalRateLimitActions(name string, rule gatewayv1alpha1.Rule) map[string]interface{} {
+	route := map[string]interface{}{"generic_key": map[string]interface{}{"descriptor_value": name}}
+	var rateLimits []interface{}
+	switch rule.RateLimit.Key.Type {
+	case gatewayv1alpha1.RateLimitKeyClientIP:
+		rateLimits = append(rateLimits, map[string]interface{}{"actions": []interface{}{route, map[string]interface{}{"remote_address": map[string]interface{}{}}}})
+	case gatewayv1alpha1.RateLimitKeyHeader:
+		header := map[string]interface{}{"header_name": *rule.RateLimit.Key.Header, "descriptor_key": rateLimitDescriptorKey(rule.RateLimit.Key)}
+		rateLimits = append(rateLimits, map[string]interface{}{"actions": []interface{}{route, map[string]interface{}{"request_headers": header}}})
+	case gatewayv1alpha1.RateLimitKeyJWTSubject:
+		for _, config := range jwtConfigs(rule) {
+			for _, issuer := range config.TrustedIssuer {
+				metadata := map[string]interface{}{
+					"descriptor_key": rateLimitDescriptorKey(rule.RateLimit.Key),
+					"metadata_key": map[string]interface{}{
+						"key":  jwtAuthnFilter,
+						"path": []interface{}{map[string]interface{}{"key": issuer}, map[string]interface{}{"key": "sub"}},
+					},
+				}
+				rateLimits = append(rateLimits, map[string]interface{}{"actions": []interface{}{route, map[string]interface{}{"metadata": metadata}}})
+			}
+		}
+	}
+	return map[string]interface{}{"route": map[string]interface{}{"rate_limits": rateLimits}}
+}
+
+func rateLimitDescriptorKey(key *gatewayv1alpha1.RateLimitKey) string {
+	if key.Type == gatewayv1alpha1.RateLimitKeyClientIP {
+		return "remote_address"
+	}
+	return key.Type
+}
+
+//applyRateLimitDescriptors applies the descriptors of the api to their own key of the ConfigMap of the global rate limit service, with
+//a field manager of the api. APIRules don't overwrite the descriptors of each other this way, and keys added by hand are kept. Every key
+//holds a configuration of the same domain, which the service merges. The key is removed once the api has no descriptors.
+func (f *Factory) applyRateLimitDescriptors(ctx context.Context, descriptors *rateLimitDescriptors) error {
+	global := f.RateLimit.Global
+	key := descriptors.name + ".yaml"
+
+	cm := &corev1.ConfigMap{}
+	err := f.client.Get(ctx, client.ObjectKey{Namespace: global.ConfigMapNamespace, Name: global.ConfigMapName}, cm)
+	if err != nil && !apierrs.IsNotFound(err) {
+		return err
+	}
+	current, exists := cm.Data[key]
+
+	if len(descriptors.descriptors) == 0 {
+		if !exists {
+			return nil
+		}
+		//A merge patch removes the key without touching the other keys, also if it was applied by another field manager
+		patch, err := json.Marshal(map[string]interface{}{"data": map[string]interface{}{key: nil}})
+		if err != nil {
+			return err
+		}
+		return client.IgnoreNotFound(f.client.Patch(ctx, cm, client.RawPatch(types.MergePatchType, patch)))
+	}
+
+	data, err := yaml.Marshal(rateLimitServiceConfig{Domain: global.Domain, Descriptors: descriptors.descriptors})
+	if err != nil {
+		return err
+	}
+	if exists && current == string(data) {
+		return nil
+	}
+
+	applied := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: global.ConfigMapNamespace, Name: global.ConfigMapName},
+		Data:       map[string]string{key: string(data)},
+	}
+	applied.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+	//The key belongs to the api only, so its ownership is forced
+	return f.client.Patch(ctx, applied, client.Apply, client.FieldOwner(FieldManager+"-"+descriptors.name), client.ForceOwnership)
+}
+
+//listEnvoyFilters lists the EnvoyFilters generated for the api
+func (f *Factory) listEnvoyFilters(ctx context.Context, api *gatewayv1alpha1.APIRule) ([]client.Object, error) {
+	var efList networkingv1alpha3.EnvoyFilterList
+	if err := f.client.List(ctx, &efList, ownedBy(api)...); err != nil {
+		return nil, err
+	}
+	res := make([]client.Object, 0, len(efList.Items))
+	for i := range efList.Items {
+		res = append(res, &efList.Items[i])
+	}
+	return res, nil
+}
+
+//RateLimitStatus tells if the rate limits of the rules are active. Returns nil if no rule is rate limited
+func (p *Patch) RateLimitStatus() *gatewayv1alpha1.APIRuleResourceStatus {
+	return p.rateLimitStatus
+}
@@ -0,0 +1,307 @@
+package processing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+var _ = Describe("Rate limits", func() {
+
+	noop := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "noop"}}}
+
+	global := &GlobalRateLimit{Domain: "api-gateway", ConfigMapNamespace: "istio-system", ConfigMapName: "ratelimit-config"}
+
+	newFactory := func(c client.Client, rateLimit *RateLimit) *Factory {
+		f := NewFactory(c, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+		f.RateLimit = rateLimit
+		return f
+	}
+
+	newClient := func(objs ...runtime.Object) *applyClient {
+		s := runtime.NewScheme()
+		Expect(networkingv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(securityv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(networkingv1alpha3.AddToScheme(s)).To(Succeed())
+		Expect(rulev1alpha1.AddToScheme(s)).To(Succeed())
+		Expect(corev1.AddToScheme(s)).To(Succeed())
+		return &applyClient{Client: fake.NewFakeClientWithScheme(s, objs...)}
+	}
+
+	limitedRule := func(path string, key *gatewayv1alpha1.RateLimitKey) gatewayv1alpha1.Rule {
+		rule := getRuleFor(path, apiMethods, nil, noop)
+		rule.RateLimit = &gatewayv1alpha1.RateLimit{Requests: 10, Unit: gatewayv1alpha1.RateLimitUnitMinute, Key: key}
+		return rule
+	}
+
+	readConfigMap := func(c client.Client) *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{}
+		Expect(c.Get(context.Background(), client.ObjectKey{Namespace: "istio-system", Name: "ratelimit-config"}, cm)).To(Succeed())
+		return cm
+	}
+
+	readDescriptors := func(c client.Client, api *gatewayv1alpha1.APIRule) rateLimitServiceConfig {
+		var config rateLimitServiceConfig
+		Expect(yaml.Unmarshal([]byte(readConfigMap(c).Data[childName(api)+".yaml"]), &config)).To(Succeed())
+		return config
+	}
+
+	It("should limit the route of the rule with the local rate limit of the gateway", func() {
+		//given
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{limitedRule(apiPath, nil), getRuleFor(headersAPIPath, apiMethods, nil, noop)})
+		f := newFactory(nil, &RateLimit{Namespace: "istio-system"})
+
+		//when
+		desiredState := f.CalculateRequiredState(apiRule)
+
+		//then
+		name := routeName(apiRule, apiRule.Spec.Rules[0])
+		Expect(desiredState.virtualService.Spec.Http[0].Name).To(Equal(name))
+		Expect(desiredState.virtualService.Spec.Http[1].Name).To(BeEmpty())
+
+		ef := desiredState.envoyFilter
+		Expect(ef).NotTo(BeNil())
+		Expect(ef.Name).To(Equal(childName(apiRule)))
+		Expect(ef.Namespace).To(Equal("istio-system"))
+		Expect(ef.OwnerReferences).To(BeEmpty())
+		Expect(ef.Labels).To(HaveKeyWithValue(OwnerLabel, apiName+"."+apiNamespace))
+		Expect(ef.Labels).To(HaveKeyWithValue(testLabelKey, testLabelValue))
+		Expect(ef.Spec.WorkloadSelector.Labels).To(Equal(map[string]string{"istio": "ingressgateway"}))
+		Expect(ef.Spec.ConfigPatches).To(HaveLen(1))
+		Expect(ef.Spec.ConfigPatches[0].Match.GetRouteConfiguration().GetVhost().GetRoute().GetName()).To(Equal(name))
+
+		spec, err := json.Marshal(&ef.Spec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(spec)).To(ContainSubstring(localRateLimitFilter))
+		Expect(string(spec)).To(ContainSubstring(`"fill_interval":"60s"`))
+
+		Expect(desiredState.rateLimitDescriptors).To(BeNil())
+		Expect(desiredState.rateLimitStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+		Expect(desiredState.rateLimitStatus.Description).To(Equal("Rate limits active for spec.rules[0]"))
+	})
+
+	It("should skip rate limits if rate limiting is not enabled", func() {
+		//given
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{limitedRule(apiPath, nil)})
+		f := newFactory(nil, nil)
+
+		//when
+		desiredState := f.CalculateRequiredState(apiRule)
+
+		//then
+		Expect(desiredState.envoyFilter).To(BeNil())
+		Expect(desiredState.rateLimitStatus.Code).To(Equal(gatewayv1alpha1.StatusSkipped))
+	})
+
+	It("should enable the WebSocket upgrade on the routes of WebSocket Services", func() {
+		//given
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor("/ws", apiMethods, nil, noop), getRuleFor(headersAPIPath, apiMethods, nil, noop)})
+		protocol := gatewayv1alpha1.ProtocolWebSocket
+		apiRule.Spec.Service.Protocol = &protocol
+		f := newFactory(nil, nil)
+
+		//when
+		desiredState := f.CalculateRequiredState(apiRule)
+
+		//then
+		Expect(desiredState.virtualService.Spec.Http[0].Name).To(Equal(routeName(apiRule, apiRule.Spec.Rules[0])))
+		Expect(desiredState.virtualService.Spec.Http[1].Name).To(Equal(routeName(apiRule, apiRule.Spec.Rules[1])))
+
+		ef := desiredState.envoyFilter
+		Expect(ef).NotTo(BeNil())
+		Expect(ef.Namespace).To(Equal("istio-system"))
+		Expect(ef.Spec.WorkloadSelector.Labels).To(Equal(map[string]string{"istio": "ingressgateway"}))
+		Expect(ef.Spec.ConfigPatches).To(HaveLen(2))
+		for i, patch := range ef.Spec.ConfigPatches {
+			Expect(patch.Match.GetRouteConfiguration().GetVhost().GetRoute().GetName()).To(Equal(routeName(apiRule, apiRule.Spec.Rules[i])))
+			Expect(patch.Patch.Value.Fields["route"].GetStructValue().Fields).To(HaveKey("upgrade_configs"))
+		}
+		Expect(desiredState.rateLimitStatus).To(BeNil())
+	})
+
+	It("should add the WebSocket upgrade to the EnvoyFilter applying the rate limits", func() {
+		//given
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{limitedRule("/ws", nil)})
+		protocol := gatewayv1alpha1.ProtocolWebSocket
+		apiRule.Spec.Service.Protocol = &protocol
+		f := newFactory(nil, &RateLimit{Namespace: "ingress", Selector: map[string]string{"app": "gateway"}})
+
+		//when
+		desiredState := f.CalculateRequiredState(apiRule)
+
+		//then
+		ef := desiredState.envoyFilter
+		Expect(ef.Namespace).To(Equal("ingress"))
+		Expect(ef.Spec.WorkloadSelector.Labels).To(Equal(map[string]string{"app": "gateway"}))
+		Expect(ef.Spec.ConfigPatches).To(HaveLen(2))
+		Expect(ef.Spec.ConfigPatches[0].Patch.Value.Fields["typed_per_filter_config"]).NotTo(BeNil())
+		Expect(ef.Spec.ConfigPatches[1].Patch.Value.Fields["route"].GetStructValue().Fields).To(HaveKey("upgrade_configs"))
+		Expect(desiredState.rateLimitStatus.Code).To(Equal(gatewayv1alpha1.StatusOK))
+	})
+
+	It("should skip rate limits with a key if the global rate limit service is not configured", func() {
+		//given
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{
+			limitedRule(apiPath, nil),
+			limitedRule(headersAPIPath, &gatewayv1alpha1.RateLimitKey{Type: gatewayv1alpha1.RateLimitKeyClientIP}),
+		})
+		f := newFactory(nil, &RateLimit{Namespace: "istio-system"})
+
+		//when
+		desiredState := f.CalculateRequiredState(apiRule)
+
+		//then
+		Expect(desiredState.envoyFilter.Spec.ConfigPatches).To(HaveLen(1))
+		Expect(desiredState.rateLimitDescriptors).To(BeNil())
+		Expect(desiredState.rateLimitStatus.Code).To(Equal(gatewayv1alpha1.StatusSkipped))
+		Expect(desiredState.rateLimitStatus.Description).To(ContainSubstring("spec.rules[1]"))
+	})
+
+	It("should apply the descriptors of rate limits with a key to their own key of the configuration of the global rate limit service", func() {
+		//given
+		header := "X-Api-Key"
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{
+			limitedRule(apiPath, &gatewayv1alpha1.RateLimitKey{Type: gatewayv1alpha1.RateLimitKeyClientIP}),
+			limitedRule(headersAPIPath, &gatewayv1alpha1.RateLimitKey{Type: gatewayv1alpha1.RateLimitKeyHeader, Header: &header}),
+		})
+		existing := "domain: api-gateway\ndescriptors:\n- key: generic_key\n  value: by-hand\n"
+		c := newClient(&corev1.ConfigMap{
+			ObjectMeta: ctrl.ObjectMeta{Namespace: "istio-system", Name: "ratelimit-config"},
+			Data:       map[string]string{"config.yaml": existing},
+		})
+		f := newFactory(c, &RateLimit{Namespace: "istio-system", Global: global})
+
+		//when
+		patch := f.CalculateDiff(f.CalculateRequiredState(apiRule), &State{})
+		Expect(f.ApplyDiff(context.Background(), patch)).To(Succeed())
+
+		//then
+		Expect(patch.RateLimitStatus().Code).To(Equal(gatewayv1alpha1.StatusOK))
+		Expect(c.fieldOwners).To(ContainElement(FieldManager + "-" + childName(apiRule)))
+		Expect(readConfigMap(c).Data).To(HaveKeyWithValue("config.yaml", existing))
+		config := readDescriptors(c, apiRule)
+		Expect(config.Domain).To(Equal("api-gateway"))
+		Expect(config.Descriptors).To(HaveLen(2))
+		Expect(config.Descriptors).To(ContainElement(rateLimitDescriptor{
+			Key:   "generic_key",
+			Value: routeName(apiRule, apiRule.Spec.Rules[1]),
+			Descriptors: []rateLimitDescriptor{{
+				Key:       gatewayv1alpha1.RateLimitKeyHeader,
+				RateLimit: &rateLimitPolicy{Unit: "MINUTE", RequestsPerUnit: 10},
+			}},
+		}))
+
+		var efList networkingv1alpha3.EnvoyFilterList
+		Expect(c.List(context.Background(), &efList)).To(Succeed())
+		Expect(efList.Items).To(HaveLen(1))
+		Expect(efList.Items[0].Spec.ConfigPatches).To(HaveLen(2))
+
+		//when
+		remaining, err := f.DeleteGenerated(context.Background(), apiRule)
+
+		//then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remaining).To(Equal(4))
+		Expect(readConfigMap(c).Data).To(Equal(map[string]string{"config.yaml": existing}))
+		Expect(c.List(context.Background(), &efList)).To(Succeed())
+		Expect(efList.Items).To(BeEmpty())
+	})
+
+	It("should count the requests by the JWT subject verified by the gateway for every trusted issuer", func() {
+		//given
+		config := fmt.Sprintf(`{"trusted_issuers": ["%s", "https://other.example.com/"]}`, jwtIssuer)
+		rule := limitedRule(apiPath, &gatewayv1alpha1.RateLimitKey{Type: gatewayv1alpha1.RateLimitKeyJWTSubject})
+		rule.AccessStrategies = []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "jwt", Config: &runtime.RawExtension{Raw: []byte(config)}}}}
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{rule})
+		f := newFactory(nil, &RateLimit{Namespace: "istio-system", Global: global})
+
+		//when
+		actions := globalRateLimitActions("route", rule)
+		desiredState := f.CalculateRequiredState(apiRule)
+
+		//then
+		rateLimits := actions["route"].(map[string]interface{})["rate_limits"].([]interface{})
+		Expect(rateLimits).To(HaveLen(2))
+		data, err := json.Marshal(rateLimits)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(fmt.Sprintf(`"path":[{"key":"%s"},{"key":"sub"}]`, jwtIssuer)))
+		Expect(string(data)).To(ContainSubstring(`"path":[{"key":"https://other.example.com/"},{"key":"sub"}]`))
+
+		Expect(desiredState.envoyFilter.Spec.ConfigPatches).To(HaveLen(1))
+		ra := desiredState.requestAuthentication
+		Expect(ra).NotTo(BeNil())
+		Expect(ra.Spec.JwtRules).To(HaveLen(2))
+		Expect(ra.Spec.JwtRules[0].Issuer).To(Equal(jwtIssuer))
+		Expect(ra.Spec.JwtRules[0].ForwardOriginalToken).To(BeTrue())
+		Expect(desiredState.authorizationPolicy).To(BeNil())
+		Expect(desiredState.accessRules).To(HaveLen(1))
+	})
+
+	It("should not verify JWTs on the gateway if the global rate limit service is not configured", func() {
+		//given
+		rule := limitedRule(apiPath, &gatewayv1alpha1.RateLimitKey{Type: gatewayv1alpha1.RateLimitKeyJWTSubject})
+		rule.AccessStrategies = []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "jwt", Config: &runtime.RawExtension{Raw: []byte(`{"trusted_issuers": ["https://dex.example.com"]}`)}}}}
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{rule})
+		f := newFactory(nil, &RateLimit{Namespace: "istio-system"})
+
+		//when
+		desiredState := f.CalculateRequiredState(apiRule)
+
+		//then
+		Expect(desiredState.requestAuthentication).To(BeNil())
+	})
+
+	It("should keep the descriptors of every APIRule in a key of its own", func() {
+		//given
+		orders := getAPIRuleFor([]gatewayv1alpha1.Rule{limitedRule(apiPath, &gatewayv1alpha1.RateLimitKey{Type: gatewayv1alpha1.RateLimitKeyClientIP})})
+		cart := getAPIRuleFor([]gatewayv1alpha1.Rule{limitedRule(apiPath, &gatewayv1alpha1.RateLimitKey{Type: gatewayv1alpha1.RateLimitKeyClientIP})})
+		cart.Name = "cart"
+		c := newClient()
+		f := newFactory(c, &RateLimit{Namespace: "istio-system", Global: global})
+
+		//when
+		Expect(f.applyRateLimitDescriptors(context.Background(), f.CalculateRequiredState(orders).rateLimitDescriptors)).To(Succeed())
+		Expect(f.applyRateLimitDescriptors(context.Background(), f.CalculateRequiredState(cart).rateLimitDescriptors)).To(Succeed())
+
+		//then
+		Expect(readConfigMap(c).Data).To(HaveLen(2))
+		Expect(readDescriptors(c, orders).Descriptors[0].Value).To(Equal(routeName(orders, orders.Spec.Rules[0])))
+		Expect(readDescriptors(c, cart).Descriptors[0].Value).To(Equal(routeName(cart, cart.Spec.Rules[0])))
+		Expect(c.fieldOwners).To(Equal([]string{FieldManager + "-" + childName(orders), FieldManager + "-" + childName(cart)}))
+	})
+
+	It("should remove the descriptors of rate limits removed from the rules", func() {
+		//given
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{limitedRule(apiPath, &gatewayv1alpha1.RateLimitKey{Type: gatewayv1alpha1.RateLimitKeyClientIP})})
+		c := newClient()
+		f := newFactory(c, &RateLimit{Namespace: "istio-system", Global: global})
+		Expect(f.ApplyDiff(context.Background(), f.CalculateDiff(f.CalculateRequiredState(apiRule), &State{}))).To(Succeed())
+		Expect(readDescriptors(c, apiRule).Descriptors).To(HaveLen(1))
+
+		//when
+		apiRule.Spec.Rules[0].RateLimit = nil
+		actualState, err := f.GetActualState(context.Background(), apiRule)
+		Expect(err).NotTo(HaveOccurred())
+		patch := f.CalculateDiff(f.CalculateRequiredState(apiRule), actualState)
+		Expect(f.ApplyDiff(context.Background(), patch)).To(Succeed())
+
+		//then
+		Expect(patch.envoyFilter.action).To(Equal("delete"))
+		Expect(patch.RateLimitStatus()).To(BeNil())
+		Expect(readConfigMap(c).Data).NotTo(HaveKey(childName(apiRule) + ".yaml"))
+	})
+})
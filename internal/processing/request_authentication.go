@@ -0,0 +1,108 @@
+package processing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/builders"
+	"github.com/kyma-incubator/api-gateway/internal/types/ory"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//scopeClaim is the claim of the JWT holding the scopes required by the jwt access strategy
+const scopeClaim = "scp"
+
+//securedOnGateway tells if the requests matching the rule of the api are secured by Istio on the ingress gateway instead of Oathkeeper.
+//The validation allows only the jwt access strategy on these rules
+func securedOnGateway(api *gatewayv1alpha1.APIRule, rule gatewayv1alpha1.Rule) bool {
+	return isSecured(rule) && !routedThroughOathkeeper(api, rule)
+}
+
+//SecuredOnGateway tells if any rule of the api is secured by Istio on the ingress gateway
+func SecuredOnGateway(api *gatewayv1alpha1.APIRule) bool {
+	for _, rule := range api.Spec.Rules {
+		if securedOnGateway(api, rule) {
+			return true
+		}
+	}
+	return false
+}
+
+//countsByJWTSubject tells if the global rate limit service counts the requests of the rule by the subject of their JWT, which the
+//gateway has to verify to keep it in the metadata read by the rate limit actions
+func (f *Factory) countsByJWTSubject(rule gatewayv1alpha1.Rule) bool {
+	return rule.RateLimit != nil && rule.RateLimit.Key != nil && rule.RateLimit.Key.Type == gatewayv1alpha1.RateLimitKeyJWTSubject &&
+		f.RateLimit != nil && f.RateLimit.Global != nil
+}
+
+//jwtConfigs returns the configs of the jwt access strategies of the rule. Configs that can't be read are rejected by the validation
+func jwtConfigs(rule gatewayv1alpha1.Rule) []ory.JwtConfig {
+	var res []ory.JwtConfig
+	for _, strategy := range rule.AccessStrategies {
+		if strategy == nil || strategy.Handler == nil || strategy.Name != "jwt" || strategy.Config == nil {
+			continue
+		}
+		var config ory.JwtConfig
+		if err := json.Unmarshal(strategy.Config.Raw, &config); err != nil {
+			continue
+		}
+		res = append(res, config)
+	}
+	return res
+}
+
+//generateRequestAuthentication returns the RequestAuthentication validating on the ingress gateway the JWTs of the trusted issuers of
+//the rules secured on the gateway or rate limited by the JWT subject. The keys of an issuer are read from the first jwks_urls of the access
+//strategy, or from the JWKS URI of the controller if it sets none. Returns nil if no rule needs it.
+func (f *Factory) generateRequestAuthentication(api *gatewayv1alpha1.APIRule) *securityv1beta1.RequestAuthentication {
+	namespace, selector := f.gatewayPolicyWorkload()
+
+	specBuilder := builders.RequestAuthenticationSpec().Selector(selector)
+	secured := false
+	for _, rule := range api.Spec.Rules {
+		if !securedOnGateway(api, rule) && !f.countsByJWTSubject(rule) {
+			continue
+		}
+		for _, config := range jwtConfigs(rule) {
+			jwksURI := f.JWKSURI
+			if len(config.JwksURLs) > 0 {
+				jwksURI = config.JwksURLs[0]
+			}
+			for _, issuer := range config.TrustedIssuer {
+				specBuilder.JWTRule(issuer, jwksURI)
+				secured = true
+			}
+		}
+	}
+	if !secured {
+		return nil
+	}
+
+	raBuilder := builders.RequestAuthentication().
+		Name(childName(api)).
+		Namespace(namespace).
+		Label(OwnerLabel, fmt.Sprintf("%s.%s", api.ObjectMeta.Name, api.ObjectMeta.Namespace)).
+		Spec(specBuilder)
+
+	for k, v := range f.additionalLabels {
+		raBuilder.Label(k, v)
+	}
+
+	return raBuilder.Get()
+}
+
+//listRequestAuthentications lists the RequestAuthentications generated for the api
+func (f *Factory) listRequestAuthentications(ctx context.Context, api *gatewayv1alpha1.APIRule) ([]client.Object, error) {
+	var raList securityv1beta1.RequestAuthenticationList
+	if err := f.client.List(ctx, &raList, ownedBy(api)...); err != nil {
+		return nil, err
+	}
+	res := make([]client.Object, 0, len(raList.Items))
+	for i := range raList.Items {
+		res = append(res, &raList.Items[i])
+	}
+	return res, nil
+}
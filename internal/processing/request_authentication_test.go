@@ -0,0 +1,129 @@
+package processing
+
+import (
+	"context"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Rules secured on the gateway", func() {
+
+	jwksURI := "https://example.com/.well-known/jwks.json"
+
+	newFactory := func(c client.Client) *Factory {
+		return NewFactory(c, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, jwksURI, testCors, testAdditionalLabels, defaultDomain)
+	}
+
+	newClient := func() *applyClient {
+		s := runtime.NewScheme()
+		Expect(networkingv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(networkingv1alpha3.AddToScheme(s)).To(Succeed())
+		Expect(securityv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(rulev1alpha1.AddToScheme(s)).To(Succeed())
+		return &applyClient{Client: fake.NewFakeClientWithScheme(s)}
+	}
+
+	grpcAPIRuleFor := func(config string) *gatewayv1alpha1.APIRule {
+		jwt := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "jwt", Config: &runtime.RawExtension{Raw: []byte(config)}}}}
+		rule := getRuleFor("", []string{"POST"}, nil, jwt)
+		rule.GRPC = &gatewayv1alpha1.GRPCMatch{Service: "helloworld.Greeter"}
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{rule})
+		protocol := gatewayv1alpha1.ProtocolGRPC
+		apiRule.Spec.Service.Protocol = &protocol
+		return apiRule
+	}
+
+	It("should validate the JWTs of gRPC rules on the ingress gateway instead of Oathkeeper", func() {
+		//given
+		apiRule := grpcAPIRuleFor(`{"trusted_issuers": ["` + jwtIssuer + `"], "required_scope": ["read", "write"]}`)
+		f := newFactory(nil)
+
+		//when
+		desiredState := f.CalculateRequiredState(apiRule)
+
+		//then
+		Expect(desiredState.accessRules).To(BeEmpty())
+		Expect(desiredState.virtualService.Spec.Http[0].Route[0].Destination.Host).To(Equal(serviceName + "." + apiNamespace + ".svc.cluster.local"))
+
+		ra := desiredState.requestAuthentication
+		Expect(ra).NotTo(BeNil())
+		Expect(ra.Name).To(Equal(childName(apiRule)))
+		Expect(ra.Namespace).To(Equal("istio-system"))
+		Expect(ra.Labels).To(HaveKeyWithValue(OwnerLabel, apiName+"."+apiNamespace))
+		Expect(ra.Labels).To(HaveKeyWithValue(testLabelKey, testLabelValue))
+		Expect(ra.Spec.Selector.MatchLabels).To(Equal(map[string]string{"istio": "ingressgateway"}))
+		Expect(ra.Spec.JwtRules).To(HaveLen(1))
+		Expect(ra.Spec.JwtRules[0].Issuer).To(Equal(jwtIssuer))
+		Expect(ra.Spec.JwtRules[0].JwksUri).To(Equal(jwksURI))
+
+		ap := desiredState.authorizationPolicy
+		Expect(ap).NotTo(BeNil())
+		Expect(ap.Namespace).To(Equal("istio-system"))
+		Expect(ap.Spec.Rules).To(HaveLen(3))
+		Expect(ap.Spec.Rules[0].From[0].Source.NotRequestPrincipals).To(ConsistOf("*"))
+		Expect(ap.Spec.Rules[0].To[0].Operation.Hosts).To(ConsistOf(serviceHost, serviceHost+":*"))
+		Expect(ap.Spec.Rules[0].To[0].Operation.Paths).To(ConsistOf("/helloworld.Greeter/*"))
+		Expect(ap.Spec.Rules[0].To[0].Operation.Methods).To(ConsistOf("POST"))
+		Expect(ap.Spec.Rules[1].When[0].Key).To(Equal("request.auth.claims[scp]"))
+		Expect(ap.Spec.Rules[1].When[0].NotValues).To(ConsistOf("read"))
+		Expect(ap.Spec.Rules[2].When[0].NotValues).To(ConsistOf("write"))
+	})
+
+	It("should read the keys from the JWKS URL of the access strategy", func() {
+		//given
+		apiRule := grpcAPIRuleFor(`{"trusted_issuers": ["` + jwtIssuer + `"], "jwks_urls": ["https://oauth2.example.com/keys"]}`)
+
+		//when
+		ra := newFactory(nil).CalculateRequiredState(apiRule).requestAuthentication
+
+		//then
+		Expect(ra.Spec.JwtRules).To(HaveLen(1))
+		Expect(ra.Spec.JwtRules[0].JwksUri).To(Equal("https://oauth2.example.com/keys"))
+	})
+
+	It("should keep routing secured rules of HTTP Services through Oathkeeper", func() {
+		//given
+		apiRule := grpcAPIRuleFor(`{"trusted_issuers": ["` + jwtIssuer + `"]}`)
+		apiRule.Spec.Service.Protocol = nil
+		apiRule.Spec.Rules[0].GRPC = nil
+		apiRule.Spec.Rules[0].Path = apiPath
+
+		//when
+		desiredState := newFactory(nil).CalculateRequiredState(apiRule)
+
+		//then
+		Expect(desiredState.requestAuthentication).To(BeNil())
+		Expect(desiredState.authorizationPolicy).To(BeNil())
+		Expect(desiredState.accessRules).To(HaveLen(1))
+	})
+
+	It("should delete the RequestAuthentication with the APIRule", func() {
+		//given
+		apiRule := grpcAPIRuleFor(`{"trusted_issuers": ["` + jwtIssuer + `"]}`)
+		c := newClient()
+		f := newFactory(c)
+		Expect(f.ApplyDiff(context.Background(), f.CalculateDiff(f.CalculateRequiredState(apiRule), &State{}))).To(Succeed())
+		actualState, err := f.GetActualState(context.Background(), apiRule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actualState.requestAuthentication).NotTo(BeNil())
+
+		//when
+		_, err = f.DeleteGenerated(context.Background(), apiRule)
+
+		//then
+		Expect(err).NotTo(HaveOccurred())
+		var raList securityv1beta1.RequestAuthenticationList
+		Expect(c.List(context.Background(), &raList)).To(Succeed())
+		Expect(raList.Items).To(BeEmpty())
+	})
+})
@@ -0,0 +1,94 @@
+package processing
+
+import (
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/secretrefs"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+)
+
+//withSecretValues returns the rule with the valueFrom.secretKeyRef placeholders in the configs of the access strategies and the mutators
+//replaced by the values returned by lookup, and tells if the configs hold any placeholder. The handlers of the api are not modified.
+func withSecretValues(rule gatewayv1alpha1.Rule, lookup func(secretrefs.Ref) (string, bool)) (gatewayv1alpha1.Rule, bool) {
+	found := false
+	resolve := func(handler *gatewayv1alpha1.Handler) *gatewayv1alpha1.Handler {
+		if handler == nil {
+			return nil
+		}
+		config := secretrefs.Resolve(handler.Config, lookup)
+		if config == handler.Config {
+			return handler
+		}
+		found = true
+		return &gatewayv1alpha1.Handler{Name: handler.Name, Config: config}
+	}
+
+	if rule.AccessStrategies != nil {
+		accessStrategies := make([]*gatewayv1alpha1.Authenticator, len(rule.AccessStrategies))
+		for i, strategy := range rule.AccessStrategies {
+			accessStrategies[i] = &gatewayv1alpha1.Authenticator{Handler: resolve(strategy.Handler)}
+		}
+		rule.AccessStrategies = accessStrategies
+	}
+	if rule.Mutators != nil {
+		mutators := make([]*gatewayv1alpha1.Mutator, len(rule.Mutators))
+		for i, mutator := range rule.Mutators {
+			mutators[i] = &gatewayv1alpha1.Mutator{Handler: resolve(mutator.Handler)}
+		}
+		rule.Mutators = mutators
+	}
+	return rule, found
+}
+
+//lookupSecretValue returns the value of the key of the Secret in the namespace of the api
+func (f *Factory) lookupSecretValue(ref secretrefs.Ref) (string, bool) {
+	secret := f.Secrets[ref.Name]
+	if secret == nil {
+		return "", false
+	}
+	value, ok := secret.Data[ref.Key]
+	return string(value), ok
+}
+
+//noSecretValues replaces the placeholders with their references
+func noSecretValues(secretrefs.Ref) (string, bool) {
+	return "", false
+}
+
+//redactAccessRule returns a copy of the current access rule with the values of Secrets hidden in the configs of its handlers. The resolved
+//and the redacted access rules are the required one with the placeholders replaced by the values of the keys and by their references.
+//Configs of handlers the required access rule doesn't have are redacted as a whole.
+func redactAccessRule(current, resolved, redacted *rulev1alpha1.Rule) *rulev1alpha1.Rule {
+	res := current.DeepCopy()
+	redactHandler := func(handler, resolvedHandler, redactedHandler *rulev1alpha1.Handler) {
+		if handler == nil {
+			return
+		}
+		if resolvedHandler == nil || redactedHandler == nil {
+			handler.Config = secretrefs.Redact(handler.Config, nil, nil)
+			return
+		}
+		handler.Config = secretrefs.Redact(handler.Config, resolvedHandler.Config, redactedHandler.Config)
+	}
+
+	for i, authenticator := range res.Spec.Authenticators {
+		if authenticator == nil {
+			continue
+		}
+		var resolvedHandler, redactedHandler *rulev1alpha1.Handler
+		if i < len(resolved.Spec.Authenticators) && i < len(redacted.Spec.Authenticators) && resolved.Spec.Authenticators[i] != nil && redacted.Spec.Authenticators[i] != nil {
+			resolvedHandler, redactedHandler = resolved.Spec.Authenticators[i].Handler, redacted.Spec.Authenticators[i].Handler
+		}
+		redactHandler(authenticator.Handler, resolvedHandler, redactedHandler)
+	}
+	for i, mutator := range res.Spec.Mutators {
+		if mutator == nil {
+			continue
+		}
+		var resolvedHandler, redactedHandler *rulev1alpha1.Handler
+		if i < len(resolved.Spec.Mutators) && i < len(redacted.Spec.Mutators) && resolved.Spec.Mutators[i] != nil && redacted.Spec.Mutators[i] != nil {
+			resolvedHandler, redactedHandler = resolved.Spec.Mutators[i].Handler, redacted.Spec.Mutators[i].Handler
+		}
+		redactHandler(mutator.Handler, resolvedHandler, redactedHandler)
+	}
+	return res
+}
@@ -0,0 +1,101 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/builders"
+	"github.com/kyma-incubator/api-gateway/internal/helpers"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//SourceIPs is an internal representation of the settings of the ingress gateway applying the source IP restrictions
+type SourceIPs struct {
+	Namespace string
+	Selector  map[string]string
+}
+
+//gatewayPolicyWorkload returns the namespace and the selector of the ingress gateway on which AuthorizationPolicies and
+//RequestAuthentications are applied, and to which Destination Rules with gateway-only TLS settings are exported
+func (f *Factory) gatewayPolicyWorkload() (string, map[string]string) {
+	namespace, selector := defaultGatewayNamespace, defaultGatewaySelector
+	if f.SourceIPs != nil && f.SourceIPs.Namespace != "" {
+		namespace = f.SourceIPs.Namespace
+	}
+	if f.SourceIPs != nil && len(f.SourceIPs.Selector) > 0 {
+		selector = f.SourceIPs.Selector
+	}
+	return namespace, selector
+}
+
+//generateAuthorizationPolicy returns the AuthorizationPolicy denying the requests to the rules of the api from addresses outside
+//the allowed ones or from the denied ones, and the requests to the rules secured on the gateway without a valid JWT holding the required
+//scopes. It is applied on the ingress gateway, so it restricts the rules whatever their access strategies.
+//Returns nil if no rule restricts the source IPs or is secured on the gateway.
+func (f *Factory) generateAuthorizationPolicy(api *gatewayv1alpha1.APIRule) *securityv1beta1.AuthorizationPolicy {
+	namespace, selector := f.gatewayPolicyWorkload()
+
+	host := helpers.GetHostWithDomain(*api.Spec.Service.Host, f.defaultDomainName)
+	//The Host header can carry the port
+	hosts := []string{host, host + ":*"}
+
+	specBuilder := builders.AuthorizationPolicySpec().Selector(selector).Deny()
+	restricted := false
+	for _, rule := range api.Spec.Rules {
+		if rule.SourceIPs == nil && !securedOnGateway(api, rule) {
+			continue
+		}
+		//Paths that can't be converted are rejected by the validation
+		path, ok := helpers.RulePathPattern(rule)
+		if !ok {
+			continue
+		}
+		if rule.SourceIPs != nil && len(rule.SourceIPs.Allow) > 0 {
+			specBuilder.DenyRemoteIPsExcept(hosts, []string{path}, rule.Methods, rule.SourceIPs.Allow)
+			restricted = true
+		}
+		if rule.SourceIPs != nil && len(rule.SourceIPs.Deny) > 0 {
+			specBuilder.DenyRemoteIPs(hosts, []string{path}, rule.Methods, rule.SourceIPs.Deny)
+			restricted = true
+		}
+		if securedOnGateway(api, rule) {
+			specBuilder.DenyUnauthenticated(hosts, []string{path}, rule.Methods)
+			for _, config := range jwtConfigs(rule) {
+				for _, scope := range config.RequiredScope {
+					specBuilder.DenyWithoutClaimValue(hosts, []string{path}, rule.Methods, scopeClaim, scope)
+				}
+			}
+			restricted = true
+		}
+	}
+	if !restricted {
+		return nil
+	}
+
+	apBuilder := builders.AuthorizationPolicy().
+		Name(childName(api)).
+		Namespace(namespace).
+		Label(OwnerLabel, fmt.Sprintf("%s.%s", api.ObjectMeta.Name, api.ObjectMeta.Namespace)).
+		Spec(specBuilder)
+
+	for k, v := range f.additionalLabels {
+		apBuilder.Label(k, v)
+	}
+
+	return apBuilder.Get()
+}
+
+//listAuthorizationPolicies lists the AuthorizationPolicies generated for the api
+func (f *Factory) listAuthorizationPolicies(ctx context.Context, api *gatewayv1alpha1.APIRule) ([]client.Object, error) {
+	var apList securityv1beta1.AuthorizationPolicyList
+	if err := f.client.List(ctx, &apList, ownedBy(api)...); err != nil {
+		return nil, err
+	}
+	res := make([]client.Object, 0, len(apList.Items))
+	for i := range apList.Items {
+		res = append(res, &apList.Items[i])
+	}
+	return res, nil
+}
@@ -0,0 +1,149 @@
+package processing
+
+import (
+	"context"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	"istio.io/api/security/v1beta1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Source IPs", func() {
+
+	noop := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "noop"}}}
+	allow := []*gatewayv1alpha1.Authenticator{{Handler: &gatewayv1alpha1.Handler{Name: "allow"}}}
+
+	newFactory := func(c client.Client) *Factory {
+		return NewFactory(c, ctrl.Log.WithName("test"), oathkeeperSvc, oathkeeperSvcPort, "https://example.com/.well-known/jwks.json", testCors, testAdditionalLabels, defaultDomain)
+	}
+
+	newClient := func() *applyClient {
+		s := runtime.NewScheme()
+		Expect(networkingv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(networkingv1alpha3.AddToScheme(s)).To(Succeed())
+		Expect(securityv1beta1.AddToScheme(s)).To(Succeed())
+		Expect(rulev1alpha1.AddToScheme(s)).To(Succeed())
+		return &applyClient{Client: fake.NewFakeClientWithScheme(s)}
+	}
+
+	It("should deny requests from outside the allowed and from the denied addresses on the ingress gateway", func() {
+		//given
+		secured := getRuleFor("/admin/.*", apiMethods, nil, noop)
+		secured.SourceIPs = &gatewayv1alpha1.SourceIPs{Allow: []string{"10.0.0.0/8"}}
+		unsecured := getRuleFor(headersAPIPath, apiMethods, nil, allow)
+		unsecured.SourceIPs = &gatewayv1alpha1.SourceIPs{Deny: []string{"192.168.1.10"}}
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{secured, unsecured, getRuleFor(apiPath, apiMethods, nil, allow)})
+		f := newFactory(nil)
+
+		//when
+		desiredState := f.CalculateRequiredState(apiRule)
+
+		//then
+		ap := desiredState.authorizationPolicy
+		Expect(ap).NotTo(BeNil())
+		Expect(ap.Name).To(Equal(childName(apiRule)))
+		Expect(ap.Namespace).To(Equal("istio-system"))
+		Expect(ap.OwnerReferences).To(BeEmpty())
+		Expect(ap.Labels).To(HaveKeyWithValue(OwnerLabel, apiName+"."+apiNamespace))
+		Expect(ap.Labels).To(HaveKeyWithValue(testLabelKey, testLabelValue))
+		Expect(ap.Spec.Selector.MatchLabels).To(Equal(map[string]string{"istio": "ingressgateway"}))
+		Expect(ap.Spec.Action).To(Equal(v1beta1.AuthorizationPolicy_DENY))
+		Expect(ap.Spec.Rules).To(HaveLen(2))
+
+		Expect(ap.Spec.Rules[0].From[0].Source.NotRemoteIpBlocks).To(ConsistOf("10.0.0.0/8"))
+		Expect(ap.Spec.Rules[0].To[0].Operation.Hosts).To(ConsistOf(serviceHost, serviceHost+":*"))
+		Expect(ap.Spec.Rules[0].To[0].Operation.Paths).To(ConsistOf("/admin/*"))
+		Expect(ap.Spec.Rules[0].To[0].Operation.Methods).To(Equal(apiMethods))
+
+		Expect(ap.Spec.Rules[1].From[0].Source.RemoteIpBlocks).To(ConsistOf("192.168.1.10"))
+		Expect(ap.Spec.Rules[1].To[0].Operation.Paths).To(ConsistOf(headersAPIPath))
+	})
+
+	It("should restrict only the methods of the rule", func() {
+		//given
+		restricted := getRuleFor(headersAPIPath, []string{"POST"}, nil, noop)
+		restricted.SourceIPs = &gatewayv1alpha1.SourceIPs{Allow: []string{"10.0.0.0/8"}}
+		open := getRuleFor(headersAPIPath, []string{"GET"}, nil, allow)
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{restricted, open})
+		f := newFactory(nil)
+
+		//when
+		ap := f.CalculateRequiredState(apiRule).authorizationPolicy
+
+		//then
+		Expect(ap.Spec.Rules).To(HaveLen(1))
+		Expect(ap.Spec.Rules[0].To[0].Operation.Paths).To(ConsistOf(headersAPIPath))
+		Expect(ap.Spec.Rules[0].To[0].Operation.Methods).To(ConsistOf("POST"))
+	})
+
+	It("should restrict all methods of a gRPC service by the prefix of the service", func() {
+		//given
+		rule := getRuleFor("", []string{"POST"}, nil, allow)
+		rule.GRPC = &gatewayv1alpha1.GRPCMatch{Service: "helloworld.Greeter"}
+		rule.SourceIPs = &gatewayv1alpha1.SourceIPs{Allow: []string{"10.0.0.0/8"}}
+		f := newFactory(nil)
+
+		//when
+		ap := f.CalculateRequiredState(getAPIRuleFor([]gatewayv1alpha1.Rule{rule})).authorizationPolicy
+
+		//then
+		Expect(ap.Spec.Rules).To(HaveLen(1))
+		Expect(ap.Spec.Rules[0].To[0].Operation.Paths).To(ConsistOf("/helloworld.Greeter/*"))
+	})
+
+	It("should use the configured ingress gateway", func() {
+		//given
+		rule := getRuleFor(apiPath, apiMethods, nil, noop)
+		rule.SourceIPs = &gatewayv1alpha1.SourceIPs{Allow: []string{"10.0.0.0/8"}}
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{rule})
+		f := newFactory(nil)
+		f.SourceIPs = &SourceIPs{Namespace: "ingress", Selector: map[string]string{"app": "gateway"}}
+
+		//when
+		ap := f.CalculateRequiredState(apiRule).authorizationPolicy
+
+		//then
+		Expect(ap.Namespace).To(Equal("ingress"))
+		Expect(ap.Spec.Selector.MatchLabels).To(Equal(map[string]string{"app": "gateway"}))
+		Expect(ap.Spec.Rules[0].To[0].Operation.Paths).To(ConsistOf("/*"))
+	})
+
+	It("should delete the AuthorizationPolicy when no rule restricts the source IPs", func() {
+		//given
+		rule := getRuleFor(apiPath, apiMethods, nil, noop)
+		rule.SourceIPs = &gatewayv1alpha1.SourceIPs{Allow: []string{"10.0.0.0/8"}}
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{rule})
+		c := newClient()
+		f := newFactory(c)
+		Expect(f.ApplyDiff(context.Background(), f.CalculateDiff(f.CalculateRequiredState(apiRule), &State{}))).To(Succeed())
+
+		//when
+		apiRule.Spec.Rules[0].SourceIPs = nil
+		actualState, err := f.GetActualState(context.Background(), apiRule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actualState.authorizationPolicy).NotTo(BeNil())
+		patch := f.CalculateDiff(f.CalculateRequiredState(apiRule), actualState)
+		Expect(f.ApplyDiff(context.Background(), patch)).To(Succeed())
+
+		//then
+		Expect(patch.authorizationPolicy.action).To(Equal("delete"))
+		var apList securityv1beta1.AuthorizationPolicyList
+		Expect(c.List(context.Background(), &apList)).To(Succeed())
+		Expect(apList.Items).To(BeEmpty())
+	})
+
+	It("should not generate an AuthorizationPolicy for rules without source IPs", func() {
+		apiRule := getAPIRuleFor([]gatewayv1alpha1.Rule{getRuleFor(apiPath, apiMethods, nil, noop)})
+
+		Expect(newFactory(nil).CalculateRequiredState(apiRule).authorizationPolicy).To(BeNil())
+	})
+})
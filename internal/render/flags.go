@@ -0,0 +1,136 @@
+package render
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/config"
+	"github.com/kyma-incubator/api-gateway/internal/handlers"
+	"github.com/kyma-incubator/api-gateway/internal/manifests"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//Flags holds the settings of the controller given to commands working without a cluster
+type Flags struct {
+	oathkeeperSvcAddr, jwksURI, blockListedServices, allowListedDomains, domainName string
+	oathkeeperSvcPort                                                              uint
+	corsAllowOrigins, corsAllowMethods, corsAllowHeaders                           string
+	generatedObjectsLabels, accessStrategiesConfig, configFile, virtualServices    string
+}
+
+//AddFlags registers the flags describing the settings of the controller. The flags are named after the flags of the controller
+func AddFlags(flags *flag.FlagSet) *Flags {
+	f := &Flags{}
+	flags.StringVar(&f.oathkeeperSvcAddr, "oathkeeper-svc-address", "ory-oathkeeper-proxy.kyma-system.svc.cluster.local", "Oathkeeper proxy service")
+	flags.UintVar(&f.oathkeeperSvcPort, "oathkeeper-svc-port", 4455, "Oathkeeper proxy service port")
+	flags.StringVar(&f.jwksURI, "jwks-uri", "https://example.com/.well-known/jwks.json", "URL of the provider's public key set to validate signature of the JWT")
+	flags.StringVar(&f.blockListedServices, "service-blocklist", "kubernetes.default,kube-dns.kube-system", "List of services to be blocklisted from exposure.")
+	flags.StringVar(&f.allowListedDomains, "domain-allowlist", "", "List of domains to be allowed.")
+	flags.StringVar(&f.domainName, "default-domain-name", "", "A default domain name for hostnames with no domain provided. Optional.")
+	flags.StringVar(&f.corsAllowOrigins, "cors-allow-origins", "regex:.*", "list of allowed origins")
+	flags.StringVar(&f.corsAllowMethods, "cors-allow-methods", "GET,POST,PUT,DELETE", "list of allowed methods")
+	flags.StringVar(&f.corsAllowHeaders, "cors-allow-headers", "Authorization,Content-Type,*", "list of allowed headers")
+	flags.StringVar(&f.generatedObjectsLabels, "generated-objects-labels", "", "Comma-separated list of key=value pairs used to label generated objects")
+	flags.StringVar(&f.configFile, "config-file", "", "Path to the configuration file of the controller. Settings from the file override the ones from flags. Optional.")
+	flags.StringVar(&f.accessStrategiesConfig, "access-strategies-config", "", "Path to a file with additional access strategies to register. Optional.")
+	flags.StringVar(&f.virtualServices, "virtual-services", "", "Comma-separated list of files with Virtual Services existing in the cluster, checked for hosts occupied by other Virtual Services. Optional.")
+	return f
+}
+
+//Config returns the settings given in the flags and in the configuration file. Additional access strategies are registered
+func (f *Flags) Config() (*config.Config, error) {
+	if f.accessStrategiesConfig != "" {
+		if err := handlers.LoadFile(f.accessStrategiesConfig); err != nil {
+			return nil, fmt.Errorf("loading access strategies failed: %w", err)
+		}
+	}
+
+	additionalLabels, err := config.ParseLabels(f.generatedObjectsLabels)
+	if err != nil {
+		return nil, fmt.Errorf("parsing labels failed: %w", err)
+	}
+
+	cfg := &config.Config{
+		OathkeeperSvcAddress: f.oathkeeperSvcAddr,
+		OathkeeperSvcPort:    uint32(f.oathkeeperSvcPort),
+		JWKSURI:              f.jwksURI,
+		ServiceBlockList:     config.ParseList(f.blockListedServices),
+		DomainAllowList:      config.ParseList(f.allowListedDomains),
+		DefaultDomainName:    f.domainName,
+		Cors: config.Cors{
+			AllowOrigins: config.ParseList(f.corsAllowOrigins),
+			AllowMethods: config.ParseList(f.corsAllowMethods),
+			AllowHeaders: config.ParseList(f.corsAllowHeaders),
+		},
+		GeneratedObjectsLabels: additionalLabels,
+	}
+	if f.configFile != "" {
+		data, err := ioutil.ReadFile(f.configFile)
+		if err != nil {
+			return nil, err
+		}
+		if cfg, err = config.Load(data, cfg); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.configFile, err)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+//Renderer creates the Renderer with the settings given in the flags and the Virtual Services read from the files given in the flags
+func (f *Flags) Renderer() (*Renderer, error) {
+	cfg, err := f.Config()
+	if err != nil {
+		return nil, err
+	}
+	r := New(cfg)
+	if f.virtualServices != "" {
+		if r.VirtualServices, err = ReadVirtualServices(config.ParseList(f.virtualServices)...); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+//ReadAPIRules reads APIRules from the manifest files. Objects of other kinds are rejected
+func ReadAPIRules(paths ...string) ([]gatewayv1alpha1.APIRule, error) {
+	objs, err := manifests.ReadFiles(paths...)
+	if err != nil {
+		return nil, err
+	}
+	var apis []gatewayv1alpha1.APIRule
+	for _, obj := range objs {
+		api, ok := obj.(*gatewayv1alpha1.APIRule)
+		if !ok {
+			return nil, unexpectedKind(obj, "APIRule")
+		}
+		apis = append(apis, *api)
+	}
+	return apis, nil
+}
+
+//ReadVirtualServices reads Virtual Services from the manifest files. Objects of other kinds are rejected
+func ReadVirtualServices(paths ...string) (networkingv1beta1.VirtualServiceList, error) {
+	var vsList networkingv1beta1.VirtualServiceList
+	objs, err := manifests.ReadFiles(paths...)
+	if err != nil {
+		return vsList, err
+	}
+	for _, obj := range objs {
+		vs, ok := obj.(*networkingv1beta1.VirtualService)
+		if !ok {
+			return vsList, unexpectedKind(obj, "VirtualService")
+		}
+		vsList.Items = append(vsList.Items, *vs)
+	}
+	return vsList, nil
+}
+
+func unexpectedKind(obj client.Object, expected string) error {
+	return fmt.Errorf("%s %s: expected %s", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), expected)
+}
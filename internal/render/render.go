@@ -0,0 +1,154 @@
+package render
+
+import (
+	"fmt"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/claims"
+	"github.com/kyma-incubator/api-gateway/internal/config"
+	"github.com/kyma-incubator/api-gateway/internal/processing"
+	"github.com/kyma-incubator/api-gateway/internal/validation"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const defaultNamespace = "default"
+
+// Renderer generates the objects for APIRules the way the controller does, without a cluster
+type Renderer struct {
+	cfg       *config.Config
+	validator *validation.APIRule
+	factory   *processing.Factory
+	//VirtualServices existing in the cluster, checked for hosts occupied by other Virtual Services
+	VirtualServices networkingv1beta1.VirtualServiceList
+}
+
+// New creates the Renderer with the settings of the controller
+func New(cfg *config.Config) *Renderer {
+	labels := cfg.GeneratedObjectsLabels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	cors := &processing.CorsConfig{
+		AllowOrigins: cfg.CorsAllowOrigins(),
+		AllowMethods: cfg.Cors.AllowMethods,
+		AllowHeaders: cfg.Cors.AllowHeaders,
+	}
+	factory := processing.NewFactory(nil, ctrl.Log.WithName("render"), cfg.OathkeeperSvcAddress, cfg.OathkeeperSvcPort, cfg.JWKSURI, cors, labels, cfg.DefaultDomainName)
+	if cfg.GatewayProvisioning != nil {
+		provisioning := processing.GatewayProvisioning(*cfg.GatewayProvisioning)
+		factory.GatewayProvisioning = &provisioning
+	}
+	if cfg.RateLimit != nil {
+		factory.RateLimit = &processing.RateLimit{Namespace: cfg.RateLimit.Namespace, Selector: cfg.RateLimit.Selector}
+		if cfg.RateLimit.Global != nil {
+			global := processing.GlobalRateLimit(*cfg.RateLimit.Global)
+			factory.RateLimit.Global = &global
+		}
+	}
+	if cfg.SourceIPs != nil {
+		sourceIPs := processing.SourceIPs(*cfg.SourceIPs)
+		factory.SourceIPs = &sourceIPs
+	}
+	if cfg.APIKeys != nil {
+		factory.APIKeys = &processing.APIKeys{CheckURL: cfg.APIKeys.CheckURL}
+	}
+	return &Renderer{
+		cfg: cfg,
+		validator: &validation.APIRule{
+			ServiceBlockList:  cfg.ServiceBlockListMap(),
+			DomainAllowList:   cfg.DomainAllowList,
+			DefaultDomainName: cfg.DefaultDomainName,
+			APIKeys:           cfg.APIKeys != nil,
+		},
+		factory: factory,
+	}
+}
+
+// Config returns the settings of the controller used to generate the objects
+func (r *Renderer) Config() *config.Config {
+	return r.cfg
+}
+
+// Render validates the APIRules and returns the objects generated for the valid ones with the validation failures of the others.
+// Hosts are arbitrated between the rendered APIRules, so APIRules in the same namespace can share a host.
+func (r *Renderer) Render(apis []gatewayv1alpha1.APIRule) ([]client.Object, []string) {
+	for i := range apis {
+		if apis[i].Namespace == "" {
+			apis[i].Namespace = defaultNamespace
+		}
+	}
+
+	var failures []string
+	valid := make(map[types.NamespacedName]bool)
+	for i := range apis {
+		api := &apis[i]
+		name := types.NamespacedName{Namespace: api.Namespace, Name: api.Name}
+		problems := r.validate(api, apis)
+		for _, problem := range problems {
+			failures = append(failures, fmt.Sprintf("APIRule %s: %s", name, problem.String()))
+		}
+		valid[name] = len(problems) == 0
+	}
+
+	var objs []client.Object
+	seen := make(map[string]bool)
+	for i := range apis {
+		api := &apis[i]
+		if !valid[types.NamespacedName{Namespace: api.Namespace, Name: api.Name}] {
+			continue
+		}
+		claim := claims.Arbitrate(api, apis, r.validator.DefaultDomainName, r.claimOptions(api))
+		var exposed []gatewayv1alpha1.APIRule
+		for _, member := range claim.Group {
+			if valid[types.NamespacedName{Namespace: member.Namespace, Name: member.Name}] {
+				exposed = append(exposed, member)
+			}
+		}
+		//APIRules sharing a host generate the same Virtual Service, which is rendered once
+		for _, obj := range r.factory.CalculateRequiredStateForHost(api, claim.Holder(), exposed).Objects() {
+			key := fmt.Sprintf("%T/%s/%s", obj, obj.GetNamespace(), obj.GetName())
+			if !seen[key] {
+				seen[key] = true
+				objs = append(objs, obj)
+			}
+		}
+	}
+	return objs, failures
+}
+
+func (r *Renderer) validate(api *gatewayv1alpha1.APIRule, apis []gatewayv1alpha1.APIRule) []validation.Failure {
+	if problems := validateRequired(api); len(problems) > 0 {
+		return problems
+	}
+	validator := *r.validator
+	validator.HostClaim = claims.Arbitrate(api, apis, r.validator.DefaultDomainName, r.claimOptions(api))
+	return validator.Validate(api, processing.WithoutGeneratedVirtualServices(r.VirtualServices))
+}
+
+//claimOptions tells the holder of the host from the generated Virtual Services passed to the renderer
+func (r *Renderer) claimOptions(api *gatewayv1alpha1.APIRule) claims.Options {
+	return claims.Options{Holder: processing.HostHolder(r.VirtualServices, claims.Host(api, r.validator.DefaultDomainName))}
+}
+
+// validateRequired verifies the fields required by the schema of the APIRule, which is enforced by the cluster when the APIRule is created
+func validateRequired(api *gatewayv1alpha1.APIRule) []validation.Failure {
+	var problems []validation.Failure
+	required := func(attributePath string, missing bool) {
+		if missing {
+			problems = append(problems, validation.Failure{AttributePath: attributePath, Message: "Required value"})
+		}
+	}
+
+	required(".metadata.name", api.Name == "")
+	required(".spec.gateway", api.Spec.Gateway == nil)
+	required(".spec.service", api.Spec.Service == nil)
+	if api.Spec.Service != nil {
+		required(".spec.service.name", api.Spec.Service.Name == nil)
+		required(".spec.service.port", api.Spec.Service.Port == nil)
+		required(".spec.service.host", api.Spec.Service.Host == nil)
+	}
+	return problems
+}
@@ -0,0 +1,253 @@
+package secretrefs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+//redacted replaces the values of placeholders that can't be matched with the current values of the keys
+const redacted = "<redacted>"
+
+//Ref is a reference to a key of a Secret in the namespace of the APIRule
+type Ref struct {
+	Name string
+	Key  string
+}
+
+//Reference returns the text standing for the value of the key in plans and in objects rendered without access to the Secrets
+func (r Ref) Reference() string {
+	return fmt.Sprintf("<secret %s/%s>", r.Name, r.Key)
+}
+
+//Placeholder is a valueFrom.secretKeyRef placeholder found in a handler config
+type Placeholder struct {
+	//Path of the placeholder in the config, such as .introspection_request_headers.authorization
+	Path string
+	//Ref is nil if the placeholder doesn't set both the name and the key
+	Ref *Ref
+}
+
+//Find returns the placeholders of the config ordered by path. A placeholder is an object with the valueFrom.secretKeyRef field only,
+//which is replaced by the value of the key of the Secret as a string
+func Find(config *runtime.RawExtension) ([]Placeholder, error) {
+	if config == nil || len(config.Raw) == 0 {
+		return nil, nil
+	}
+	value, err := decode(config.Raw)
+	if err != nil {
+		return nil, err
+	}
+	var res []Placeholder
+	walk(value, "", func(path string, ref *Ref) interface{} {
+		res = append(res, Placeholder{Path: path, Ref: ref})
+		return nil
+	})
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res, nil
+}
+
+//Resolve returns the config with the placeholders replaced by the values returned by lookup. Placeholders of keys that can't be looked up
+//are replaced by their references. The config is returned unchanged if it holds no placeholder or can't be read
+func Resolve(config *runtime.RawExtension, lookup func(Ref) (string, bool)) *runtime.RawExtension {
+	if config == nil || len(config.Raw) == 0 {
+		return config
+	}
+	value, err := decode(config.Raw)
+	if err != nil {
+		return config
+	}
+	found := false
+	value = walk(value, "", func(path string, ref *Ref) interface{} {
+		found = true
+		if ref == nil {
+			return redacted
+		}
+		if v, ok := lookup(*ref); ok {
+			return v
+		}
+		return ref.Reference()
+	})
+	if !found {
+		return config
+	}
+	raw, err := encode(value)
+	if err != nil {
+		return config
+	}
+	return &runtime.RawExtension{Raw: raw}
+}
+
+//Redact hides the values of Secrets in the current config of a handler. The resolved and the redacted configs are the required config with
+//the placeholders replaced by the values of the keys and by their references. The current config may hold values of Secrets the required
+//one doesn't reference, so only the strings equal to the resolved value in the same place are kept, shown as in the redacted config, and
+//the other strings are replaced by <redacted>. The whole config is redacted if there is no required config.
+func Redact(current, resolved, redactedConfig *runtime.RawExtension) *runtime.RawExtension {
+	if current == nil || len(current.Raw) == 0 {
+		return current
+	}
+	currentValue, err := decode(current.Raw)
+	if err != nil {
+		return &runtime.RawExtension{Raw: []byte(`"` + redacted + `"`)}
+	}
+	var resolvedValue, redactedValue interface{}
+	if resolved != nil && redactedConfig != nil {
+		var errResolved, errRedacted error
+		resolvedValue, errResolved = decode(resolved.Raw)
+		redactedValue, errRedacted = decode(redactedConfig.Raw)
+		if errResolved != nil || errRedacted != nil {
+			resolvedValue, redactedValue = nil, nil
+		}
+	}
+	raw, err := encode(redactLike(currentValue, resolvedValue, redactedValue))
+	if err != nil {
+		return &runtime.RawExtension{Raw: []byte(`"` + redacted + `"`)}
+	}
+	return &runtime.RawExtension{Raw: raw}
+}
+
+//redactLike walks the current value along the resolved and the redacted ones, which are nil where the required config has nothing
+func redactLike(current, resolved, redactedValue interface{}) interface{} {
+	switch c := current.(type) {
+	case string:
+		if s, ok := resolved.(string); ok && s == c {
+			if r, ok := redactedValue.(string); ok {
+				return r
+			}
+		}
+		return redacted
+	case map[string]interface{}:
+		s, _ := resolved.(map[string]interface{})
+		r, _ := redactedValue.(map[string]interface{})
+		res := make(map[string]interface{}, len(c))
+		for k, v := range c {
+			res[k] = redactLike(v, s[k], r[k])
+		}
+		return res
+	case []interface{}:
+		s, _ := resolved.([]interface{})
+		r, _ := redactedValue.([]interface{})
+		res := make([]interface{}, len(c))
+		for i, v := range c {
+			var resolvedItem, redactedItem interface{}
+			if i < len(s) && i < len(r) {
+				resolvedItem, redactedItem = s[i], r[i]
+			}
+			res[i] = redactLike(v, resolvedItem, redactedItem)
+		}
+		return res
+	}
+	return current
+}
+
+//SecretNames returns the sorted names of the Secrets referenced by the placeholders in the configs of the access strategies and mutators
+//of the APIRule
+func SecretNames(api *gatewayv1alpha1.APIRule) []string {
+	seen := make(map[string]bool)
+	var res []string
+	add := func(handler *gatewayv1alpha1.Handler) {
+		if handler == nil {
+			return
+		}
+		placeholders, _ := Find(handler.Config)
+		for _, p := range placeholders {
+			if p.Ref != nil && !seen[p.Ref.Name] {
+				seen[p.Ref.Name] = true
+				res = append(res, p.Ref.Name)
+			}
+		}
+	}
+	for _, rule := range api.Spec.Rules {
+		for _, strategy := range rule.AccessStrategies {
+			if strategy != nil {
+				add(strategy.Handler)
+			}
+		}
+		for _, mutator := range rule.Mutators {
+			if mutator != nil {
+				add(mutator.Handler)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+//IsPlaceholder tells if the JSON value is a placeholder, even if it doesn't set both the name and the key
+func IsPlaceholder(raw json.RawMessage) bool {
+	value, err := decode(raw)
+	if err != nil {
+		return false
+	}
+	_, ok := placeholderRef(value)
+	return ok
+}
+
+func decode(raw []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+//encode marshals the value without escaping the angle brackets of references
+func encode(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+//walk replaces the placeholders found in the value with the results of replace, called with the paths of the placeholders
+func walk(value interface{}, path string, replace func(path string, ref *Ref) interface{}) interface{} {
+	if ref, ok := placeholderRef(value); ok {
+		return replace(path, ref)
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = walk(item, path+"."+k, replace)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = walk(item, fmt.Sprintf("%s[%d]", path, i), replace)
+		}
+	}
+	return value
+}
+
+//placeholderRef tells if the value is a placeholder and returns its reference, which is nil if the name or the key is missing
+func placeholderRef(value interface{}) (*Ref, bool) {
+	obj, ok := value.(map[string]interface{})
+	if !ok || len(obj) != 1 {
+		return nil, false
+	}
+	valueFrom, ok := obj["valueFrom"]
+	if !ok {
+		return nil, false
+	}
+	source, ok := valueFrom.(map[string]interface{})
+	if !ok || len(source) != 1 {
+		return nil, true
+	}
+	secretKeyRef, ok := source["secretKeyRef"].(map[string]interface{})
+	if !ok {
+		return nil, true
+	}
+	name, _ := secretKeyRef["name"].(string)
+	key, _ := secretKeyRef["key"].(string)
+	if name == "" || key == "" || len(secretKeyRef) != 2 {
+		return nil, true
+	}
+	return &Ref{Name: name, Key: key}, true
+}
@@ -0,0 +1,356 @@
+package simulation
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	"istio.io/api/networking/v1beta1"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//Request is a sample request sent to the gateway
+type Request struct {
+	Method string `json:"method"`
+	//Scheme of the request, https if not set
+	Scheme string `json:"scheme,omitempty"`
+	//Host, which can include a port
+	Host string `json:"host"`
+	//Path, which can include a query
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+//Result tells how the request is handled by the Virtual Services and the Oathkeeper access rules
+type Result struct {
+	//Virtual Service exposing the host
+	VirtualService string `json:"virtualService,omitempty"`
+	//Index of the HTTP route of the Virtual Service matching the request, or -1 if no route matches
+	Route int `json:"route"`
+	//Match condition of the route matching the request
+	Match string `json:"match,omitempty"`
+	//Destination the route forwards the request to, in the host:port format
+	Destination string `json:"destination,omitempty"`
+	//ThroughOathkeeper tells if the request is forwarded to Oathkeeper instead of straight to the service
+	ThroughOathkeeper bool `json:"throughOathkeeper"`
+	//Access rule matching the request and its URL pattern
+	AccessRule    string `json:"accessRule,omitempty"`
+	AccessRuleURL string `json:"accessRuleURL,omitempty"`
+	//Authenticators tried in order, the authorizer and the mutators applied by Oathkeeper
+	Authenticators []string `json:"authenticators,omitempty"`
+	Authorizer     string   `json:"authorizer,omitempty"`
+	Mutators       []string `json:"mutators,omitempty"`
+	//Upstream Oathkeeper forwards the request to
+	Upstream string `json:"upstream,omitempty"`
+	//StatusCode of the response returned by the gateway or Oathkeeper before authentication, if the request is rejected
+	StatusCode int `json:"statusCode,omitempty"`
+	//Reason of the rejection
+	Reason string `json:"reason,omitempty"`
+}
+
+//Simulator replays how Istio Virtual Services and Oathkeeper access rules handle a request
+type Simulator struct {
+	virtualServices []*networkingv1beta1.VirtualService
+	accessRules     []*rulev1alpha1.Rule
+	oathkeeperSvc   string
+}
+
+//New creates the Simulator for the Virtual Services and access rules among the objects. Requests routed to the oathkeeperSvc host
+//are matched against the access rules. Virtual Services exposing the same host are tried in the given order.
+func New(objs []client.Object, oathkeeperSvc string) *Simulator {
+	s := &Simulator{oathkeeperSvc: oathkeeperSvc}
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *networkingv1beta1.VirtualService:
+			s.virtualServices = append(s.virtualServices, o)
+		case *rulev1alpha1.Rule:
+			s.accessRules = append(s.accessRules, o)
+		}
+	}
+	return s
+}
+
+//Simulate finds the route and the access rule handling the request
+func (s *Simulator) Simulate(req Request) (*Result, error) {
+	res := &Result{Route: -1}
+	host := hostWithoutPort(req.Host)
+	path := req.Path
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	if path == "" {
+		path = "/"
+	}
+	scheme := req.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+
+	vs := s.virtualServiceFor(host)
+	if vs == nil {
+		return rejected(res, http.StatusNotFound, fmt.Sprintf("No Virtual Service exposes host %s", host)), nil
+	}
+	res.VirtualService = types.NamespacedName{Namespace: vs.Namespace, Name: vs.Name}.String()
+
+	for i, route := range vs.Spec.Http {
+		match, ok, err := matchRoute(route, req, scheme, path)
+		if err != nil {
+			return nil, fmt.Errorf("Virtual Service %s, route %d: %w", res.VirtualService, i, err)
+		}
+		if !ok {
+			continue
+		}
+		res.Route = i
+		res.Match = match
+		if len(route.Route) > 0 && route.Route[0].Destination != nil {
+			destination := route.Route[0].Destination
+			res.Destination = destination.Host
+			if destination.Port != nil {
+				res.Destination = fmt.Sprintf("%s:%d", destination.Host, destination.Port.Number)
+			}
+			res.ThroughOathkeeper = destination.Host == s.oathkeeperSvc
+		}
+		break
+	}
+	if res.Route < 0 {
+		return rejected(res, http.StatusNotFound, fmt.Sprintf("No route of Virtual Service %s matches %s %s", res.VirtualService, req.Method, path)), nil
+	}
+	if !res.ThroughOathkeeper {
+		return res, nil
+	}
+
+	return s.matchAccessRule(res, req.Method, fmt.Sprintf("%s://%s%s", scheme, host, path))
+}
+
+func (s *Simulator) virtualServiceFor(host string) *networkingv1beta1.VirtualService {
+	for _, vs := range s.virtualServices {
+		for _, h := range vs.Spec.Hosts {
+			if hostMatches(h, host) {
+				return vs
+			}
+		}
+	}
+	return nil
+}
+
+//matchAccessRule finds the access rule matching the request URL the way Oathkeeper does. Exactly one rule has to match
+func (s *Simulator) matchAccessRule(res *Result, method, url string) (*Result, error) {
+	var matching []*rulev1alpha1.Rule
+	for _, rule := range s.accessRules {
+		if rule.Spec.Match == nil || !contains(rule.Spec.Match.Methods, method) {
+			continue
+		}
+		re, err := compileAccessRuleURL(rule.Spec.Match.URL)
+		if err != nil {
+			return nil, fmt.Errorf("access rule %s/%s: %w", rule.Namespace, rule.Name, err)
+		}
+		if re.MatchString(url) {
+			matching = append(matching, rule)
+		}
+	}
+
+	switch len(matching) {
+	case 0:
+		return rejected(res, http.StatusNotFound, fmt.Sprintf("No access rule matches %s %s", method, url)), nil
+	case 1:
+	default:
+		var names []string
+		for _, rule := range matching {
+			names = append(names, types.NamespacedName{Namespace: rule.Namespace, Name: rule.Name}.String())
+		}
+		sort.Strings(names)
+		return rejected(res, http.StatusInternalServerError, fmt.Sprintf("Multiple access rules match %s %s: %s", method, url, strings.Join(names, ", "))), nil
+	}
+
+	rule := matching[0]
+	res.AccessRule = types.NamespacedName{Namespace: rule.Namespace, Name: rule.Name}.String()
+	res.AccessRuleURL = rule.Spec.Match.URL
+	for _, authenticator := range rule.Spec.Authenticators {
+		if authenticator.Handler != nil {
+			res.Authenticators = append(res.Authenticators, authenticator.Name)
+		}
+	}
+	if rule.Spec.Authorizer != nil && rule.Spec.Authorizer.Handler != nil {
+		res.Authorizer = rule.Spec.Authorizer.Name
+	}
+	for _, mutator := range rule.Spec.Mutators {
+		if mutator.Handler != nil {
+			res.Mutators = append(res.Mutators, mutator.Name)
+		}
+	}
+	if rule.Spec.Upstream != nil {
+		res.Upstream = rule.Spec.Upstream.URL
+	}
+	return res, nil
+}
+
+//matchRoute tells if any match condition of the route matches the request. A route with no conditions matches all requests
+func matchRoute(route *v1beta1.HTTPRoute, req Request, scheme, path string) (string, bool, error) {
+	if len(route.Match) == 0 {
+		return "any request", true, nil
+	}
+	for _, m := range route.Match {
+		ok, err := matchRequest(m, req, scheme, path)
+		if err != nil || ok {
+			return describeMatch(m), ok, err
+		}
+	}
+	return "", false, nil
+}
+
+func matchRequest(m *v1beta1.HTTPMatchRequest, req Request, scheme, path string) (bool, error) {
+	conditions := []struct {
+		match *v1beta1.StringMatch
+		value string
+	}{
+		{m.Uri, path},
+		{m.Method, req.Method},
+		{m.Scheme, scheme},
+		{m.Authority, req.Host},
+	}
+	for name, match := range m.Headers {
+		conditions = append(conditions, struct {
+			match *v1beta1.StringMatch
+			value string
+		}{match, header(req.Headers, name)})
+	}
+	for _, c := range conditions {
+		if c.match == nil {
+			continue
+		}
+		ok, err := matchString(c.match, c.value)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchString(m *v1beta1.StringMatch, value string) (bool, error) {
+	switch t := m.MatchType.(type) {
+	case *v1beta1.StringMatch_Exact:
+		return value == t.Exact, nil
+	case *v1beta1.StringMatch_Prefix:
+		return strings.HasPrefix(value, t.Prefix), nil
+	case *v1beta1.StringMatch_Regex:
+		//Envoy matches the whole value with RE2 regular expressions
+		re, err := regexp.Compile("^(?:" + t.Regex + ")$")
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(value), nil
+	}
+	return true, nil
+}
+
+func describeMatch(m *v1beta1.HTTPMatchRequest) string {
+	var parts []string
+	describe := func(name string, match *v1beta1.StringMatch) {
+		if match == nil {
+			return
+		}
+		switch t := match.MatchType.(type) {
+		case *v1beta1.StringMatch_Exact:
+			parts = append(parts, fmt.Sprintf("%s exact %s", name, t.Exact))
+		case *v1beta1.StringMatch_Prefix:
+			parts = append(parts, fmt.Sprintf("%s prefix %s", name, t.Prefix))
+		case *v1beta1.StringMatch_Regex:
+			parts = append(parts, fmt.Sprintf("%s regex %s", name, t.Regex))
+		}
+	}
+	describe("uri", m.Uri)
+	describe("method", m.Method)
+	describe("scheme", m.Scheme)
+	describe("authority", m.Authority)
+	names := make([]string, 0, len(m.Headers))
+	for name := range m.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		describe("header "+name, m.Headers[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+//compileAccessRuleURL compiles the URL pattern of an access rule. Parts of the pattern enclosed in angle brackets are regular expressions,
+//the rest is matched literally, and the whole URL has to match.
+func compileAccessRuleURL(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteByte('^')
+	level, start, end := 0, 0, 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '<':
+			if level == 0 {
+				start = i
+			}
+			level++
+		case '>':
+			level--
+			if level < 0 {
+				return nil, fmt.Errorf("unbalanced angle brackets in %s", pattern)
+			}
+			if level == 0 {
+				sb.WriteString(regexp.QuoteMeta(pattern[end:start]))
+				sb.WriteString("(" + pattern[start+1:i] + ")")
+				end = i + 1
+			}
+		}
+	}
+	if level != 0 {
+		return nil, fmt.Errorf("unbalanced angle brackets in %s", pattern)
+	}
+	sb.WriteString(regexp.QuoteMeta(pattern[end:]))
+	sb.WriteByte('$')
+	return regexp.Compile(sb.String())
+}
+
+//hostMatches tells if the host of a Virtual Service, which can be a wildcard, matches the host of the request
+func hostMatches(pattern, host string) bool {
+	pattern, host = strings.ToLower(pattern), strings.ToLower(host)
+	if pattern == "*" {
+		return true
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return pattern == host
+}
+
+func hostWithoutPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+func header(headers map[string]string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func rejected(res *Result, statusCode int, reason string) *Result {
+	res.StatusCode = statusCode
+	res.Reason = reason
+	return res
+}
@@ -0,0 +1,133 @@
+package simulation
+
+import (
+	"net/http"
+	"testing"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/config"
+	"github.com/kyma-incubator/api-gateway/internal/render"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestSimulation(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Simulation Suite")
+}
+
+const oathkeeperSvc = "ory-oathkeeper-proxy.kyma-system.svc.cluster.local"
+
+var _ = Describe("Simulate", func() {
+
+	cfg := &config.Config{
+		OathkeeperSvcAddress: oathkeeperSvc,
+		OathkeeperSvcPort:    4455,
+		JWKSURI:              "https://example.com/.well-known/jwks.json",
+		DomainAllowList:      []string{"kyma.local"},
+	}
+
+	handler := func(name, config string) *gatewayv1alpha1.Handler {
+		h := &gatewayv1alpha1.Handler{Name: name}
+		if config != "" {
+			h.Config = &runtime.RawExtension{Raw: []byte(config)}
+		}
+		return h
+	}
+
+	newSimulator := func() *Simulator {
+		serviceName, host, gateway, port := "httpbin", "httpbin.kyma.local", "kyma-gateway.kyma-system.svc.cluster.local", uint32(8000)
+		api := gatewayv1alpha1.APIRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "httpbin", Namespace: "default"},
+			Spec: gatewayv1alpha1.APIRuleSpec{
+				Service: &gatewayv1alpha1.Service{Name: &serviceName, Port: &port, Host: &host},
+				Gateway: &gateway,
+				Rules: []gatewayv1alpha1.Rule{
+					{
+						Path:             "/headers",
+						Methods:          []string{"GET"},
+						AccessStrategies: []*gatewayv1alpha1.Authenticator{{Handler: handler("jwt", `{"trusted_issuers":["https://dex.kyma.local"]}`)}},
+						Mutators:         []*gatewayv1alpha1.Mutator{{Handler: handler("id_token", "")}},
+					},
+					{
+						Path:             "/status/.*",
+						Methods:          []string{"GET", "POST"},
+						AccessStrategies: []*gatewayv1alpha1.Authenticator{{Handler: handler("noop", "")}},
+					},
+					{
+						Path:             "/public/.*",
+						Methods:          []string{"GET"},
+						AccessStrategies: []*gatewayv1alpha1.Authenticator{{Handler: handler("allow", "")}},
+					},
+				},
+			},
+		}
+		objs, failures := render.New(cfg).Render([]gatewayv1alpha1.APIRule{api})
+		Expect(failures).To(BeEmpty())
+		return New(objs, oathkeeperSvc)
+	}
+
+	It("Should follow a secured route to the access rule and its handlers", func() {
+		res, err := newSimulator().Simulate(Request{Method: "GET", Host: "httpbin.kyma.local", Path: "/headers?x=1"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.StatusCode).To(BeZero())
+		Expect(res.Route).To(Equal(0))
+		Expect(res.Match).To(Equal("uri regex /headers"))
+		Expect(res.ThroughOathkeeper).To(BeTrue())
+		Expect(res.Destination).To(Equal(oathkeeperSvc + ":4455"))
+		Expect(res.AccessRuleURL).To(Equal("<http|https>://httpbin.kyma.local</headers>"))
+		Expect(res.Authenticators).To(Equal([]string{"jwt"}))
+		Expect(res.Authorizer).To(Equal("allow"))
+		Expect(res.Mutators).To(Equal([]string{"id_token"}))
+		Expect(res.Upstream).To(Equal("http://httpbin.default.svc.cluster.local:8000"))
+	})
+
+	It("Should follow an unsecured route straight to the service", func() {
+		res, err := newSimulator().Simulate(Request{Method: "GET", Host: "httpbin.kyma.local:443", Path: "/public/index.html"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Route).To(Equal(2))
+		Expect(res.ThroughOathkeeper).To(BeFalse())
+		Expect(res.Destination).To(Equal("httpbin.default.svc.cluster.local:8000"))
+		Expect(res.AccessRule).To(BeEmpty())
+	})
+
+	It("Should reject a request for an unknown host", func() {
+		res, err := newSimulator().Simulate(Request{Method: "GET", Host: "other.kyma.local", Path: "/headers"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(res.Reason).To(Equal("No Virtual Service exposes host other.kyma.local"))
+	})
+
+	It("Should reject a request matching no route", func() {
+		res, err := newSimulator().Simulate(Request{Method: "GET", Host: "httpbin.kyma.local", Path: "/headers/nested"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(res.Route).To(Equal(-1))
+	})
+
+	It("Should reject a request with a method not allowed by the access rule", func() {
+		res, err := newSimulator().Simulate(Request{Method: "POST", Host: "httpbin.kyma.local", Path: "/headers"})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.ThroughOathkeeper).To(BeTrue())
+		Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(res.Reason).To(Equal("No access rule matches POST https://httpbin.kyma.local/headers"))
+	})
+
+	It("Should compile access rule URLs the way Oathkeeper does", func() {
+		re, err := compileAccessRuleURL("<http|https>://shop.kyma.local</orders/.*>")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(re.MatchString("https://shop.kyma.local/orders/1")).To(BeTrue())
+		Expect(re.MatchString("https://shopXkyma.local/orders/1")).To(BeFalse())
+		Expect(re.MatchString("ftp://shop.kyma.local/orders/1")).To(BeFalse())
+
+		_, err = compileAccessRuleURL("<http://shop.kyma.local")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -11,4 +11,24 @@ type JwtConfig struct {
 	// Array of required scopes
 	RequiredScope []string `json:"required_scope"`
 	TrustedIssuer []string `json:"trusted_issuers"`
+	// URLs of the key sets validating the signature of the tokens
+	JwksURLs []string `json:"jwks_urls,omitempty"`
+}
+
+// BearerTokenConfig Config for Bearer Token Oathkeeper Authenticator
+type BearerTokenConfig struct {
+	// URL of the session store checking the token
+	CheckSessionURL string `json:"check_session_url"`
+	// Keep the path of CheckSessionURL instead of the path of the request
+	PreservePath bool `json:"preserve_path"`
+	// Location of the token in the request
+	TokenFrom *TokenFrom `json:"token_from,omitempty"`
+	// Headers of the request forwarded to the session store. Oathkeeper forwards only Authorization and Cookie if not set
+	ForwardHTTPHeaders []string `json:"forward_http_headers,omitempty"`
+}
+
+// TokenFrom Location of the token in the request
+type TokenFrom struct {
+	Header         string `json:"header,omitempty"`
+	QueryParameter string `json:"query_parameter,omitempty"`
 }
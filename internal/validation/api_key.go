@@ -0,0 +1,50 @@
+package validation
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
+)
+
+//queryParameterName matches the names of query parameters, which are placed in the path of the URL checking the keys
+var queryParameterName = regexp.MustCompile(`^[A-Za-z0-9_.~-]+$`)
+
+//apiKeyAccStrValidator is an accessStrategy validator for the api_key access strategy
+type apiKeyAccStrValidator struct{}
+
+func (a *apiKeyAccStrValidator) Validate(attrPath string, handler *gatewayv1alpha1.Handler) []Failure {
+	var problems []Failure
+
+	if configEmpty(handler.Config) {
+		problems = append(problems, Failure{AttributePath: attrPath + ".config", Message: "supplied config cannot be empty"})
+		return problems
+	}
+	var config gatewayv1alpha1.APIKeyAccStrConfig
+	if err := json.Unmarshal(handler.Config.Raw, &config); err != nil {
+		problems = append(problems, Failure{AttributePath: attrPath + ".config", Message: "Can't read json: " + err.Error()})
+		return problems
+	}
+
+	if config.SecretName == "" {
+		problems = append(problems, Failure{AttributePath: attrPath + ".config.secretName", Message: "value is required"})
+	} else if errs := k8svalidation.IsDNS1123Subdomain(config.SecretName); len(errs) > 0 {
+		problems = append(problems, Failure{AttributePath: attrPath + ".config.secretName", Message: strings.Join(errs, "; ")})
+	}
+
+	switch {
+	case config.Header == "" && config.QueryParameter == "":
+		problems = append(problems, Failure{AttributePath: attrPath + ".config", Message: "Either header or queryParameter has to be defined"})
+	case config.Header != "" && config.QueryParameter != "":
+		problems = append(problems, Failure{AttributePath: attrPath + ".config", Message: "Only one of header or queryParameter can be defined"})
+	case config.Header != "":
+		if errs := k8svalidation.IsHTTPHeaderName(config.Header); len(errs) > 0 {
+			problems = append(problems, Failure{AttributePath: attrPath + ".config.header", Message: strings.Join(errs, "; ")})
+		}
+	case !queryParameterName.MatchString(config.QueryParameter):
+		problems = append(problems, Failure{AttributePath: attrPath + ".config.queryParameter", Message: "Query parameter can contain only letters, digits and the characters _.~-"})
+	}
+	return problems
+}
@@ -3,6 +3,7 @@ package validation
 import (
 	"net/url"
 	"regexp"
+	"strings"
 
 	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
 )
@@ -11,7 +12,7 @@ func hasDuplicates(rules []gatewayv1alpha1.Rule) bool {
 	encountered := map[string]bool{}
 	// Create a map of all unique elements.
 	for v := range rules {
-		encountered[rules[v].Path] = true
+		encountered[rules[v].MatchPath()] = true
 	}
 	return len(encountered) != len(rules)
 }
@@ -33,6 +34,11 @@ func ValidateDomainName(domain string) bool {
 	return RegExp.MatchString(domain)
 }
 
+//ValidateDomainPattern verifies a domain name that can also be a wildcard, such as `*.apps.example.com`
+func ValidateDomainPattern(domain string) bool {
+	return ValidateDomainName(strings.TrimPrefix(domain, "*."))
+}
+
 //ValidateServiceName ?
 func ValidateServiceName(service string) bool {
 	regExp := regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?\.[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
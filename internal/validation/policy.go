@@ -0,0 +1,141 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/handlers"
+	"github.com/kyma-incubator/api-gateway/internal/helpers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+//PolicyAppliesTo tells if the policy applies to the namespace with given name and labels
+func PolicyAppliesTo(policy *gatewayv1alpha1.APIGatewayPolicy, namespace string, namespaceLabels map[string]string) bool {
+	if len(policy.Spec.Namespaces) == 0 && policy.Spec.NamespaceSelector == nil {
+		return true
+	}
+	for _, ns := range policy.Spec.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	if policy.Spec.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(policy.Spec.NamespaceSelector)
+		if err != nil {
+			return false
+		}
+		return selector.Matches(labels.Set(namespaceLabels))
+	}
+	return false
+}
+
+func (v *APIRule) validatePolicies(api *gatewayv1alpha1.APIRule) []Failure {
+	var problems []Failure
+
+	policies := make([]*gatewayv1alpha1.APIGatewayPolicy, 0, len(v.Policies))
+	for i := range v.Policies {
+		if PolicyAppliesTo(&v.Policies[i], api.Namespace, v.NamespaceLabels) {
+			policies = append(policies, &v.Policies[i])
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+
+	for _, policy := range policies {
+		problems = append(problems, v.validatePolicy(policy, api)...)
+	}
+	return problems
+}
+
+func (v *APIRule) validatePolicy(policy *gatewayv1alpha1.APIGatewayPolicy, api *gatewayv1alpha1.APIRule) []Failure {
+	var problems []Failure
+	spec := policy.Spec
+
+	violation := func(attributePath, message string) {
+		problems = append(problems, Failure{AttributePath: attributePath, Message: message, Policy: policy.Name})
+	}
+
+	if len(spec.AllowedDomains) > 0 && api.Spec.Service != nil && api.Spec.Service.Host != nil {
+		host := helpers.GetHostWithDomain(*api.Spec.Service.Host, v.DefaultDomainName)
+		if !hostInDomains(host, spec.AllowedDomains) {
+			violation(".spec.service.host", "Host is not allowed by the policy")
+		}
+	}
+
+	if len(spec.AllowedGateways) > 0 && api.Spec.Gateway != nil && !contains(spec.AllowedGateways, *api.Spec.Gateway) {
+		violation(".spec.gateway", fmt.Sprintf("Gateway %s is not allowed by the policy", *api.Spec.Gateway))
+	}
+
+	if api.Spec.Service != nil && api.Spec.Service.Name != nil && contains(spec.BlockedServices, *api.Spec.Service.Name) {
+		violation(".spec.service.name", fmt.Sprintf("Service %s is blocked by the policy", *api.Spec.Service.Name))
+	}
+
+	if spec.MaxRules != nil && len(api.Spec.Rules) > *spec.MaxRules {
+		violation(".spec.rules", fmt.Sprintf("Number of rules exceeds the limit of %d", *spec.MaxRules))
+	}
+
+	for i, rule := range api.Spec.Rules {
+		ruleAttrPath := fmt.Sprintf(".spec.rules[%d]", i)
+		for j, strategy := range rule.AccessStrategies {
+			if strategy == nil || strategy.Handler == nil {
+				continue
+			}
+			name := strategy.Handler.Name
+			if contains(spec.DeniedAccessStrategies, name) || (len(spec.AllowedAccessStrategies) > 0 && !contains(spec.AllowedAccessStrategies, name)) {
+				violation(fmt.Sprintf("%s.accessStrategies[%d].handler", ruleAttrPath, j), fmt.Sprintf("Access strategy %s is not allowed by the policy", name))
+			}
+		}
+		if !isSecured(rule) || http2Protocol(api.ServiceProtocol()) {
+			//Mutators are applied by Oathkeeper, so they can't be required on rules not routed through it, such as the rules of gRPC Services
+			continue
+		}
+		for _, required := range spec.RequiredMutators {
+			if !hasMutator(rule.Mutators, required) {
+				violation(ruleAttrPath+".mutators", fmt.Sprintf("Mutator %s is required by the policy", required))
+			}
+		}
+	}
+
+	return problems
+}
+
+func hostInDomains(host string, domains []string) bool {
+	for _, domain := range domains {
+		if helpers.HostMatchesDomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+//isSecured tells if the requests matching the rule are routed through Oathkeeper
+func isSecured(rule gatewayv1alpha1.Rule) bool {
+	if len(rule.Mutators) > 0 {
+		return true
+	}
+	for _, strategy := range rule.AccessStrategies {
+		if strategy != nil && strategy.Handler != nil && handlers.IsSecured(strategy.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMutator(mutators []*gatewayv1alpha1.Mutator, name string) bool {
+	for _, m := range mutators {
+		if m != nil && m.Handler != nil && m.Handler.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
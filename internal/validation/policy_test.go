@@ -0,0 +1,153 @@
+package validation
+
+import (
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Validate function with policies", func() {
+
+	gateway := "kyma-gateway.kyma-system.svc.cluster.local"
+
+	getInput := func(namespace string, rules ...gatewayv1alpha1.Rule) *gatewayv1alpha1.APIRule {
+		return &gatewayv1alpha1.APIRule{
+			ObjectMeta: v1.ObjectMeta{Namespace: namespace},
+			Spec: gatewayv1alpha1.APIRuleSpec{
+				Service: getService(sampleServiceName, uint32(8080), sampleValidHost),
+				Gateway: &gateway,
+				Rules:   rules,
+			},
+		}
+	}
+
+	noopRule := gatewayv1alpha1.Rule{
+		Path:             "/abc",
+		AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("noop", emptyConfig())},
+	}
+	jwtRule := gatewayv1alpha1.Rule{
+		Path:             "/def",
+		AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("jwt", simpleJWTConfig())},
+		Mutators:         []*gatewayv1alpha1.Mutator{{Handler: &gatewayv1alpha1.Handler{Name: "id_token"}}},
+	}
+
+	It("Should report violated policies", func() {
+		//given
+		maxRules := 1
+		policies := []gatewayv1alpha1.APIGatewayPolicy{
+			{
+				ObjectMeta: v1.ObjectMeta{Name: "prod"},
+				Spec: gatewayv1alpha1.APIGatewayPolicySpec{
+					NamespaceSelector:      &v1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+					DeniedAccessStrategies: []string{"allow", "noop"},
+					RequiredMutators:       []string{"id_token"},
+				},
+			},
+			{
+				ObjectMeta: v1.ObjectMeta{Name: "limits"},
+				Spec: gatewayv1alpha1.APIGatewayPolicySpec{
+					Namespaces:      []string{"team-a"},
+					AllowedDomains:  []string{"team-a.foo.bar"},
+					AllowedGateways: []string{"team-a-gateway.team-a.svc.cluster.local"},
+					BlockedServices: []string{sampleServiceName},
+					MaxRules:        &maxRules,
+				},
+			},
+		}
+
+		//when
+		problems := (&APIRule{
+			DomainAllowList: testDomainAllowlist,
+			Policies:        policies,
+			NamespaceLabels: map[string]string{"env": "prod"},
+		}).Validate(getInput("team-a", noopRule, jwtRule), networkingv1beta1.VirtualServiceList{})
+
+		//then
+		Expect(problems).To(HaveLen(6))
+		Expect(problems[0]).To(Equal(Failure{AttributePath: ".spec.service.host", Message: "Host is not allowed by the policy", Policy: "limits"}))
+		Expect(problems[1]).To(Equal(Failure{AttributePath: ".spec.gateway", Message: "Gateway " + gateway + " is not allowed by the policy", Policy: "limits"}))
+		Expect(problems[2]).To(Equal(Failure{AttributePath: ".spec.service.name", Message: "Service some-service is blocked by the policy", Policy: "limits"}))
+		Expect(problems[3]).To(Equal(Failure{AttributePath: ".spec.rules", Message: "Number of rules exceeds the limit of 1", Policy: "limits"}))
+		Expect(problems[4]).To(Equal(Failure{AttributePath: ".spec.rules[0].accessStrategies[0].handler", Message: "Access strategy noop is not allowed by the policy", Policy: "prod"}))
+		Expect(problems[5]).To(Equal(Failure{AttributePath: ".spec.rules[0].mutators", Message: "Mutator id_token is required by the policy", Policy: "prod"}))
+	})
+
+	It("Should require mutators only on secured rules", func() {
+		//given
+		allowRule := gatewayv1alpha1.Rule{
+			Path:             "/ghi",
+			AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("allow", emptyConfig())},
+		}
+		policies := []gatewayv1alpha1.APIGatewayPolicy{
+			{
+				ObjectMeta: v1.ObjectMeta{Name: "mutators"},
+				Spec:       gatewayv1alpha1.APIGatewayPolicySpec{RequiredMutators: []string{"id_token"}},
+			},
+		}
+
+		//when
+		problems := (&APIRule{
+			DomainAllowList: testDomainAllowlist,
+			Policies:        policies,
+		}).Validate(getInput("team-a", allowRule, noopRule), networkingv1beta1.VirtualServiceList{})
+
+		//then
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0]).To(Equal(Failure{AttributePath: ".spec.rules[1].mutators", Message: "Mutator id_token is required by the policy", Policy: "mutators"}))
+	})
+
+	It("Should ignore policies for other namespaces", func() {
+		//given
+		policies := []gatewayv1alpha1.APIGatewayPolicy{
+			{
+				ObjectMeta: v1.ObjectMeta{Name: "prod"},
+				Spec: gatewayv1alpha1.APIGatewayPolicySpec{
+					NamespaceSelector:      &v1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+					DeniedAccessStrategies: []string{"noop"},
+				},
+			},
+			{
+				ObjectMeta: v1.ObjectMeta{Name: "team-b"},
+				Spec: gatewayv1alpha1.APIGatewayPolicySpec{
+					Namespaces:     []string{"team-b"},
+					AllowedDomains: []string{"team-b.foo.bar"},
+				},
+			},
+		}
+
+		//when
+		problems := (&APIRule{
+			DomainAllowList: testDomainAllowlist,
+			Policies:        policies,
+			NamespaceLabels: map[string]string{"env": "dev"},
+		}).Validate(getInput("team-a", noopRule), networkingv1beta1.VirtualServiceList{})
+
+		//then
+		Expect(problems).To(HaveLen(0))
+	})
+
+	It("Should apply policy with no namespaces to all namespaces", func() {
+		//given
+		policies := []gatewayv1alpha1.APIGatewayPolicy{
+			{
+				ObjectMeta: v1.ObjectMeta{Name: "global"},
+				Spec: gatewayv1alpha1.APIGatewayPolicySpec{
+					AllowedAccessStrategies: []string{"jwt"},
+					AllowedDomains:          []string{allowlistedDomain},
+				},
+			},
+		}
+
+		//when
+		problems := (&APIRule{
+			DomainAllowList: testDomainAllowlist,
+			Policies:        policies,
+		}).Validate(getInput("any", noopRule, jwtRule), networkingv1beta1.VirtualServiceList{})
+
+		//then
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0]).To(Equal(Failure{AttributePath: ".spec.rules[0].accessStrategies[0].handler", Message: "Access strategy noop is not allowed by the policy", Policy: "global"}))
+	})
+})
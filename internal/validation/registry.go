@@ -0,0 +1,55 @@
+package validation
+
+import (
+	"fmt"
+	"sync"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/handlers"
+)
+
+//AccessStrategyValidator validates the configuration of a single access strategy
+type AccessStrategyValidator interface {
+	Validate(attrPath string, handler *gatewayv1alpha1.Handler) []Failure
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]AccessStrategyValidator{}
+)
+
+func init() {
+	RegisterAccessStrategyValidator("jwt", vldJWT)
+	RegisterAccessStrategyValidator("api_key", vldAPIKey)
+}
+
+//RegisterAccessStrategyValidator registers a dedicated validator for the access strategy with the given name.
+//Access strategies without a dedicated validator are validated against the config schema declared in the handlers registry.
+func RegisterAccessStrategyValidator(name string, vld AccessStrategyValidator) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[name] = vld
+}
+
+func lookupAccessStrategyValidator(name string) (AccessStrategyValidator, bool) {
+	strategy, ok := handlers.Lookup(name)
+	if !ok {
+		return nil, false
+	}
+
+	validatorsMu.RLock()
+	vld, ok := validators[name]
+	validatorsMu.RUnlock()
+	if ok {
+		return vld, true
+	}
+
+	if strategy.Config == nil {
+		return vldNoConfig, true
+	}
+	return &schemaAccStrValidator{schema: strategy.Config}, true
+}
+
+func unsupportedAccessStrategy(attributePath, name string) Failure {
+	return Failure{AttributePath: attributePath + ".handler", Message: fmt.Sprintf("Unsupported accessStrategy: %s", name)}
+}
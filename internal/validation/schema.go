@@ -0,0 +1,87 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/handlers"
+	"github.com/kyma-incubator/api-gateway/internal/secretrefs"
+)
+
+//schemaAccStrValidator is an accessStrategy validator that checks the config against the schema declared in the handlers registry
+type schemaAccStrValidator struct {
+	schema *handlers.ConfigSchema
+}
+
+func (s *schemaAccStrValidator) Validate(attrPath string, handler *gatewayv1alpha1.Handler) []Failure {
+	var problems []Failure
+
+	if configEmpty(handler.Config) {
+		if s.schema.Required {
+			problems = append(problems, Failure{AttributePath: attrPath + ".config", Message: "supplied config cannot be empty"})
+		}
+		return problems
+	}
+
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(handler.Config.Raw, &config); err != nil {
+		problems = append(problems, Failure{AttributePath: attrPath + ".config", Message: "Can't read json: " + err.Error()})
+		return problems
+	}
+
+	for _, key := range s.schema.RequiredProperties {
+		if _, ok := config[key]; !ok {
+			problems = append(problems, Failure{AttributePath: attrPath + ".config." + key, Message: "value is required"})
+		}
+	}
+
+	if len(s.schema.Properties) == 0 {
+		return problems
+	}
+
+	var keys []string
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		kind, ok := s.schema.Properties[key]
+		if !ok {
+			problems = append(problems, Failure{AttributePath: attrPath + ".config." + key, Message: "strategy: " + handler.Name + " does not support this property"})
+			continue
+		}
+		if actual := jsonType(config[key]); actual != kind {
+			problems = append(problems, Failure{AttributePath: attrPath + ".config." + key, Message: fmt.Sprintf("value should be of type %s, got %s", kind, actual)})
+		}
+	}
+
+	return problems
+}
+
+//jsonType returns the JSON type of the value. Placeholders of Secret values are strings
+func jsonType(raw json.RawMessage) string {
+	if secretrefs.IsPlaceholder(raw) {
+		return "string"
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "invalid"
+	}
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "null"
+	}
+}
@@ -0,0 +1,118 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+//ServiceLookup is the outcome of looking up the Service referenced by the validated APIRule
+type ServiceLookup struct {
+	// Service found, nil if it doesn't exist
+	Service *corev1.Service
+	// ReferenceGrants in the namespace of the Service, needed if it is in another namespace than the APIRule
+	Grants []gatewayv1alpha1.ReferenceGrant
+}
+
+//Granted tells if the APIRule can expose the Service. Services in the namespace of the APIRule can always be exposed,
+//Services in other namespaces only if a ReferenceGrant in their namespace allows it.
+func (l *ServiceLookup) Granted(api *gatewayv1alpha1.APIRule) bool {
+	namespace := api.ServiceNamespace()
+	if namespace == api.Namespace {
+		return true
+	}
+	for i := range l.Grants {
+		if l.Grants[i].Namespace == namespace && l.Grants[i].Grants(api.Namespace, *api.Spec.Service.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+//nonHTTPProtocols are the protocols which can't be routed by Virtual Services generated for APIRules.
+//They are recognized in the appProtocol of the port or in the prefix of its name, following the Istio convention.
+var nonHTTPProtocols = map[string]bool{
+	"tcp":   true,
+	"tls":   true,
+	"udp":   true,
+	"mongo": true,
+	"mysql": true,
+	"redis": true,
+}
+
+func (v *APIRule) validateServiceReference(attributePath string, api *gatewayv1alpha1.APIRule) []Failure {
+	if v.ServiceLookup == nil || api.Spec.Service == nil || api.Spec.Service.Name == nil {
+		return nil
+	}
+	if !v.ServiceLookup.Granted(api) {
+		return []Failure{{
+			AttributePath: attributePath + ".namespace",
+			Message:       fmt.Sprintf("Exposing Service %s in namespace %s is not allowed by any ReferenceGrant in that namespace", *api.Spec.Service.Name, api.ServiceNamespace()),
+		}}
+	}
+	svc := v.ServiceLookup.Service
+	if svc == nil {
+		return []Failure{{
+			AttributePath: attributePath + ".name",
+			Message:       fmt.Sprintf("Service %s does not exist in namespace %s", *api.Spec.Service.Name, api.ServiceNamespace()),
+		}}
+	}
+	//External name Services don't need to declare ports
+	if svc.Spec.Type == corev1.ServiceTypeExternalName && len(svc.Spec.Ports) == 0 {
+		return nil
+	}
+	if api.Spec.Service.Port == nil {
+		return nil
+	}
+
+	port := findServicePort(svc, *api.Spec.Service.Port)
+	if port == nil {
+		return []Failure{{
+			AttributePath: attributePath + ".port",
+			Message:       fmt.Sprintf("Port %d is not exposed by Service %s", *api.Spec.Service.Port, svc.Name),
+		}}
+	}
+	if protocol := portProtocol(port); protocol != "" {
+		return []Failure{{
+			AttributePath: attributePath + ".port",
+			Message:       fmt.Sprintf("Port %d of Service %s uses the %s protocol, which can't be exposed over HTTP", port.Port, svc.Name, protocol),
+		}}
+	}
+	return nil
+}
+
+func findServicePort(svc *corev1.Service, number uint32) *corev1.ServicePort {
+	for i := range svc.Spec.Ports {
+		if uint32(svc.Spec.Ports[i].Port) == number {
+			return &svc.Spec.Ports[i]
+		}
+	}
+	return nil
+}
+
+//http2Protocol tells if the protocol of the Service is carried over HTTP/2. The generated Destination Rule upgrades the connections to
+//such Services to HTTP/2, so their ports don't have to declare it
+func http2Protocol(protocol string) bool {
+	return protocol == gatewayv1alpha1.ProtocolGRPC || protocol == gatewayv1alpha1.ProtocolGRPCWeb
+}
+
+//portProtocol returns the protocol of the port if it isn't HTTP-based, or an empty string otherwise.
+//The appProtocol takes precedence over the name of the port.
+func portProtocol(port *corev1.ServicePort) string {
+	if port.Protocol != "" && port.Protocol != corev1.ProtocolTCP {
+		return string(port.Protocol)
+	}
+	if port.AppProtocol != nil && *port.AppProtocol != "" {
+		if protocol := strings.ToLower(*port.AppProtocol); nonHTTPProtocols[protocol] {
+			return protocol
+		}
+		return ""
+	}
+	protocol := strings.ToLower(strings.SplitN(port.Name, "-", 2)[0])
+	if nonHTTPProtocols[protocol] {
+		return protocol
+	}
+	return ""
+}
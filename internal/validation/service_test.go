@@ -0,0 +1,147 @@
+package validation
+
+import (
+	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Service reference validation", func() {
+
+	newAPIRule := func(port uint32) *gatewayv1alpha1.APIRule {
+		return &gatewayv1alpha1.APIRule{
+			ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "api"},
+			Spec: gatewayv1alpha1.APIRuleSpec{
+				Service: getService(sampleServiceName, port, sampleValidHost),
+			},
+		}
+	}
+
+	newService := func(ports ...corev1.ServicePort) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: sampleServiceName},
+			Spec:       corev1.ServiceSpec{Ports: ports},
+		}
+	}
+
+	appProtocol := func(protocol string) *string {
+		return &protocol
+	}
+
+	It("Should fail for a missing Service", func() {
+		//when
+		problems := (&APIRule{ServiceLookup: &ServiceLookup{}}).validateServiceReference(".spec.service", newAPIRule(8080))
+
+		//then
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].AttributePath).To(Equal(".spec.service.name"))
+		Expect(problems[0].Message).To(Equal("Service some-service does not exist in namespace default"))
+	})
+
+	It("Should fail for a port not exposed by the Service", func() {
+		//when
+		problems := (&APIRule{ServiceLookup: &ServiceLookup{Service: newService(corev1.ServicePort{Name: "http", Port: 80})}}).
+			validateServiceReference(".spec.service", newAPIRule(8080))
+
+		//then
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].AttributePath).To(Equal(".spec.service.port"))
+		Expect(problems[0].Message).To(Equal("Port 8080 is not exposed by Service some-service"))
+	})
+
+	It("Should fail for a Service in another namespace without a ReferenceGrant", func() {
+		//given
+		api := newAPIRule(8080)
+		namespace := "backend"
+		api.Spec.Service.Namespace = &namespace
+		grants := []gatewayv1alpha1.ReferenceGrant{
+			{
+				ObjectMeta: v1.ObjectMeta{Namespace: "backend", Name: "other-namespace"},
+				Spec:       gatewayv1alpha1.ReferenceGrantSpec{From: []gatewayv1alpha1.ReferenceGrantFrom{{Namespace: "other"}}},
+			},
+			{
+				ObjectMeta: v1.ObjectMeta{Namespace: "backend", Name: "other-service"},
+				Spec: gatewayv1alpha1.ReferenceGrantSpec{
+					From: []gatewayv1alpha1.ReferenceGrantFrom{{Namespace: "default"}},
+					To:   []gatewayv1alpha1.ReferenceGrantTo{{Name: "other"}},
+				},
+			},
+		}
+
+		//when
+		problems := (&APIRule{ServiceLookup: &ServiceLookup{Grants: grants}}).validateServiceReference(".spec.service", api)
+
+		//then
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].AttributePath).To(Equal(".spec.service.namespace"))
+		Expect(problems[0].Message).To(Equal("Exposing Service some-service in namespace backend is not allowed by any ReferenceGrant in that namespace"))
+	})
+
+	It("Should allow a Service in another namespace granted by a ReferenceGrant", func() {
+		//given
+		api := newAPIRule(8080)
+		namespace := "backend"
+		api.Spec.Service.Namespace = &namespace
+		svc := newService(corev1.ServicePort{Name: "http", Port: 8080})
+		svc.Namespace = namespace
+		grant := gatewayv1alpha1.ReferenceGrant{
+			ObjectMeta: v1.ObjectMeta{Namespace: "backend", Name: "frontend"},
+			Spec: gatewayv1alpha1.ReferenceGrantSpec{
+				From: []gatewayv1alpha1.ReferenceGrantFrom{{Namespace: "default"}},
+				To:   []gatewayv1alpha1.ReferenceGrantTo{{Name: sampleServiceName}},
+			},
+		}
+
+		//when
+		problems := (&APIRule{ServiceLookup: &ServiceLookup{Service: svc, Grants: []gatewayv1alpha1.ReferenceGrant{grant}}}).
+			validateServiceReference(".spec.service", api)
+
+		//then
+		Expect(problems).To(BeEmpty())
+	})
+
+	It("Should not check the Service if it wasn't looked up", func() {
+		Expect((&APIRule{}).validateServiceReference(".spec.service", newAPIRule(8080))).To(BeEmpty())
+	})
+
+	table.DescribeTable("Should check the protocol of the port",
+		func(port corev1.ServicePort, expected string) {
+			port.Port = 8080
+			problems := (&APIRule{ServiceLookup: &ServiceLookup{Service: newService(port)}}).validateServiceReference(".spec.service", newAPIRule(8080))
+
+			if expected == "" {
+				Expect(problems).To(BeEmpty())
+				return
+			}
+			Expect(problems).To(HaveLen(1))
+			Expect(problems[0].Message).To(Equal("Port 8080 of Service some-service uses the " + expected + " protocol, which can't be exposed over HTTP"))
+		},
+		table.Entry("unnamed port", corev1.ServicePort{}, ""),
+		table.Entry("HTTP port name", corev1.ServicePort{Name: "http-web"}, ""),
+		table.Entry("gRPC port name", corev1.ServicePort{Name: "grpc"}, ""),
+		table.Entry("TCP port name", corev1.ServicePort{Name: "tcp-db"}, "tcp"),
+		table.Entry("Redis port name", corev1.ServicePort{Name: "redis"}, "redis"),
+		table.Entry("HTTP appProtocol overriding the name", corev1.ServicePort{Name: "tcp", AppProtocol: appProtocol("http")}, ""),
+		table.Entry("MySQL appProtocol", corev1.ServicePort{Name: "http", AppProtocol: appProtocol("mysql")}, "mysql"),
+		table.Entry("UDP port", corev1.ServicePort{Name: "dns", Protocol: corev1.ProtocolUDP}, "UDP"),
+	)
+
+	table.DescribeTable("Should accept HTTP ports for gRPC, as the connections are upgraded to HTTP/2",
+		func(protocol string, port corev1.ServicePort) {
+			port.Port = 8080
+			api := newAPIRule(8080)
+			api.Spec.Service.Protocol = &protocol
+			problems := (&APIRule{ServiceLookup: &ServiceLookup{Service: newService(port)}}).validateServiceReference(".spec.service", api)
+
+			Expect(problems).To(BeEmpty())
+		},
+		table.Entry("gRPC port name", "grpc", corev1.ServicePort{Name: "grpc-api"}),
+		table.Entry("HTTP/2 appProtocol", "grpc-web", corev1.ServicePort{Name: "web", AppProtocol: appProtocol("http2")}),
+		table.Entry("HTTP port name", "grpc", corev1.ServicePort{Name: "http"}),
+		table.Entry("HTTP appProtocol overriding the name", "grpc", corev1.ServicePort{Name: "grpc", AppProtocol: appProtocol("http")}),
+		table.Entry("HTTP port for WebSocket", "websocket", corev1.ServicePort{Name: "http"}),
+	)
+})
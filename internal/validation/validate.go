@@ -2,24 +2,27 @@ package validation
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"net"
 	"strings"
 
+	"github.com/kyma-incubator/api-gateway/internal/claims"
 	"github.com/kyma-incubator/api-gateway/internal/helpers"
+	"github.com/kyma-incubator/api-gateway/internal/secretrefs"
+	"github.com/kyma-incubator/api-gateway/internal/types/ory"
 	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 
 	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
 	"k8s.io/apimachinery/pkg/runtime"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 )
 
 //Validators for AccessStrategies
 var vldNoConfig = &noConfigAccStrValidator{}
 var vldJWT = &jwtAccStrValidator{}
-var vldDummy = &dummyAccStrValidator{}
-
-type accessStrategyValidator interface {
-	Validate(attrPath string, Handler *gatewayv1alpha1.Handler) []Failure
-}
+var vldAPIKey = &apiKeyAccStrValidator{}
 
 //configNotEmpty Verify if the config object is not empty
 func configEmpty(config *runtime.RawExtension) bool {
@@ -40,6 +43,25 @@ type APIRule struct {
 	ServiceBlockList  map[string][]string
 	DomainAllowList   []string
 	DefaultDomainName string
+	// Policies restricting APIRules. Only the ones applying to the namespace of the validated APIRule are evaluated
+	Policies []gatewayv1alpha1.APIGatewayPolicy
+	// Labels of the namespace of the validated APIRule, used to match policy namespace selectors
+	NamespaceLabels map[string]string
+	// Domains delegated to namespaces. Hosts in a delegated domain can be exposed only by APIRules in these namespaces
+	DomainDelegations map[string][]string
+	// Outcome of the arbitration of the host between APIRules exposing it
+	HostClaim *claims.Claim
+	// Outcome of looking up the Service referenced by the APIRule. The Service and its port are not checked if nil
+	ServiceLookup *ServiceLookup
+	// Tells if the api_key access strategy can be used, which requires the URL checking the keys to be configured
+	APIKeys bool
+	// Secrets referenced by the APIRule, by their names in its namespace. Secrets that don't exist are missing. Secrets are not checked if nil.
+	// Failures name the Secrets and their keys, never their values
+	Secrets map[string]*corev1.Secret
+	// Namespace of Oathkeeper, which connects to the Service over TLS on its own in the SIMPLE and MUTUAL upstream TLS modes
+	OathkeeperNamespace string
+	// Secret of the upstream TLS mode in the namespace of Oathkeeper, by its name. Missing if it doesn't exist. Not checked if nil
+	OathkeeperSecrets map[string]*corev1.Secret
 }
 
 //Validate performs APIRule validation
@@ -48,10 +70,14 @@ func (v *APIRule) Validate(api *gatewayv1alpha1.APIRule, vsList networkingv1beta
 	res := []Failure{}
 	//Validate service
 	res = append(res, v.validateService(".spec.service", vsList, api)...)
+	res = append(res, v.validateServiceReference(".spec.service", api)...)
+	res = append(res, v.validateUpstreamTLS(".spec.service.tls", api.ServiceProtocol(), api.Spec.Service.TLS, api.Spec.Rules)...)
 	//Validate Gateway
 	res = append(res, v.validateGateway(".spec.gateway", api.Spec.Gateway)...)
 	//Validate Rules
-	res = append(res, v.validateRules(".spec.rules", api.Spec.Rules)...)
+	res = append(res, v.validateRules(".spec.rules", api.ServiceProtocol(), api.Spec.Rules)...)
+	//Validate Policies
+	res = append(res, v.validatePolicies(api)...)
 
 	return res
 }
@@ -60,6 +86,17 @@ func (v *APIRule) Validate(api *gatewayv1alpha1.APIRule, vsList networkingv1beta
 type Failure struct {
 	AttributePath string
 	Message       string
+	// Name of the APIGatewayPolicy violated, if any
+	Policy string
+}
+
+//String describes the failure with the attribute path and the violated policy, as reported in the status of the APIRule
+func (f Failure) String() string {
+	description := fmt.Sprintf("Attribute \"%s\": %s", f.AttributePath, f.Message)
+	if f.Policy != "" {
+		description += fmt.Sprintf(" (policy: %s)", f.Policy)
+	}
+	return description
 }
 
 func (v *APIRule) validateService(attributePath string, vsList networkingv1beta1.VirtualServiceList, api *gatewayv1alpha1.APIRule) []Failure {
@@ -78,6 +115,12 @@ func (v *APIRule) validateService(attributePath string, vsList networkingv1beta1
 		// if the default domain name is used, then there is no need to check if it is allowlisted
 		domainFound := false
 		for _, domain := range v.DomainAllowList {
+			if helpers.IsWildcardDomain(domain) {
+				if helpers.HostMatchesDomain(host, domain) {
+					domainFound = true
+				}
+				continue
+			}
 			// service host containing duplicated allowlisted domain should be rejected.
 			// for example `my-lambda.kyma.local.kyma.local`
 			// service host containing allowlisted domain but only as a part of bigger domain should also be rejected
@@ -94,6 +137,15 @@ func (v *APIRule) validateService(attributePath string, vsList networkingv1beta1
 		}
 	}
 
+	problems = append(problems, v.validateDelegation(attributePath+".host", host, api.Namespace)...)
+
+	if v.HostClaim != nil && !v.HostClaim.Granted {
+		problems = append(problems, Failure{
+			AttributePath: attributePath + ".host",
+			Message:       v.HostClaim.Describe(),
+		})
+	}
+
 	for _, vs := range vsList.Items {
 		if occupiesHost(vs, host) && !ownedBy(vs, api) {
 			problems = append(problems, Failure{
@@ -105,7 +157,7 @@ func (v *APIRule) validateService(attributePath string, vsList networkingv1beta1
 
 	for namespace, services := range v.ServiceBlockList {
 		for _, svc := range services {
-			if svc == *api.Spec.Service.Name && namespace == api.ObjectMeta.Namespace {
+			if svc == *api.Spec.Service.Name && namespace == api.ServiceNamespace() {
 				problems = append(problems, Failure{
 					AttributePath: attributePath + ".name",
 					Message:       fmt.Sprintf("Service %s in namespace %s is blocklisted", svc, namespace),
@@ -116,11 +168,88 @@ func (v *APIRule) validateService(attributePath string, vsList networkingv1beta1
 	return problems
 }
 
+//validateUpstreamTLS verifies that the Secrets required by the TLS mode are set, and only these. Only gateways can read the Secrets of the
+//SIMPLE and MUTUAL modes, so Oathkeeper connects over TLS on its own and needs the Secret in its namespace if it proxies secured rules
+func (v *APIRule) validateUpstreamTLS(attributePath, protocol string, tls *gatewayv1alpha1.UpstreamTLS, rules []gatewayv1alpha1.Rule) []Failure {
+	if tls == nil {
+		return nil
+	}
+	var problems []Failure
+
+	switch {
+	case tls.Mode == gatewayv1alpha1.TLSModeSimple && tls.CASecretName == nil:
+		problems = append(problems, Failure{AttributePath: attributePath + ".caSecretName", Message: "CA Secret is required in SIMPLE mode"})
+	case tls.Mode != gatewayv1alpha1.TLSModeSimple && tls.CASecretName != nil:
+		problems = append(problems, Failure{AttributePath: attributePath + ".caSecretName", Message: "CA Secret can be set only in SIMPLE mode"})
+	}
+	switch {
+	case tls.Mode == gatewayv1alpha1.TLSModeMutual && tls.ClientSecretName == nil:
+		problems = append(problems, Failure{AttributePath: attributePath + ".clientSecretName", Message: "Client Secret is required in MUTUAL mode"})
+	case tls.Mode != gatewayv1alpha1.TLSModeMutual && tls.ClientSecretName != nil:
+		problems = append(problems, Failure{AttributePath: attributePath + ".clientSecretName", Message: "Client Secret can be set only in MUTUAL mode"})
+	}
+	if v.OathkeeperSecrets == nil || http2Protocol(protocol) {
+		return problems
+	}
+	secretPath, secretName := attributePath+".caSecretName", tls.CASecretName
+	if tls.Mode == gatewayv1alpha1.TLSModeMutual {
+		secretPath, secretName = attributePath+".clientSecretName", tls.ClientSecretName
+	}
+	if (tls.Mode != gatewayv1alpha1.TLSModeSimple && tls.Mode != gatewayv1alpha1.TLSModeMutual) || secretName == nil {
+		return problems
+	}
+	for _, rule := range rules {
+		if isSecured(rule) {
+			if _, ok := v.OathkeeperSecrets[*secretName]; !ok {
+				problems = append(problems, Failure{AttributePath: secretPath,
+					Message: fmt.Sprintf("Secret %s has to exist in the %s namespace too, as Oathkeeper connects to the Service over TLS for secured rules", *secretName, v.OathkeeperNamespace)})
+			}
+			break
+		}
+	}
+	return problems
+}
+
+//validateDelegation verifies if the host can be exposed from the namespace. The most specific domain delegation matching the host is used.
+//Hosts in domains that are not delegated can be exposed from any namespace.
+func (v *APIRule) validateDelegation(attributePath, host, namespace string) []Failure {
+	var delegated string
+	for domain := range v.DomainDelegations {
+		if !helpers.HostMatchesDomain(host, domain) {
+			continue
+		}
+		if delegated == "" || moreSpecificDomain(domain, delegated) {
+			delegated = domain
+		}
+	}
+	if delegated == "" {
+		return nil
+	}
+
+	for _, ns := range v.DomainDelegations[delegated] {
+		if ns == namespace {
+			return nil
+		}
+	}
+	return []Failure{{
+		AttributePath: attributePath,
+		Message:       fmt.Sprintf("Domain %s is not delegated to namespace %s", delegated, namespace),
+	}}
+}
+
+func moreSpecificDomain(a, b string) bool {
+	lenA, lenB := len(strings.TrimPrefix(a, "*")), len(strings.TrimPrefix(b, "*"))
+	if lenA != lenB {
+		return lenA > lenB
+	}
+	return a < b
+}
+
 func (v *APIRule) validateGateway(attributePath string, gateway *string) []Failure {
 	return nil
 }
 
-func (v *APIRule) validateRules(attributePath string, rules []gatewayv1alpha1.Rule) []Failure {
+func (v *APIRule) validateRules(attributePath, protocol string, rules []gatewayv1alpha1.Rule) []Failure {
 	var problems []Failure
 
 	if len(rules) == 0 {
@@ -134,13 +263,261 @@ func (v *APIRule) validateRules(attributePath string, rules []gatewayv1alpha1.Ru
 
 	for i, r := range rules {
 		attrPath := fmt.Sprintf("%s[%d]", attributePath, i)
+		problems = append(problems, v.validateMatch(attrPath, protocol, r)...)
+		problems = append(problems, v.validateGatewaySecurity(attrPath, protocol, r)...)
 		problems = append(problems, v.validateMethods(attrPath+".methods", r.Methods)...)
 		problems = append(problems, v.validateAccessStrategies(attrPath+".accessStrategies", r.AccessStrategies)...)
+		problems = append(problems, v.validateAPIKeys(attrPath+".accessStrategies", r.AccessStrategies)...)
+		problems = append(problems, v.validateSecretRefs(attrPath, r)...)
+		problems = append(problems, v.validateRateLimit(attrPath+".rateLimit", r)...)
+		problems = append(problems, v.validateSourceIPs(attrPath+".sourceIPs", r)...)
+	}
+
+	return problems
+}
+
+//validateMatch verifies that the rule matches either a path or a gRPC service, and that its methods allow the requests of the protocol
+func (v *APIRule) validateMatch(attributePath, protocol string, rule gatewayv1alpha1.Rule) []Failure {
+	var problems []Failure
+
+	switch {
+	case rule.Path == "" && rule.GRPC == nil:
+		problems = append(problems, Failure{AttributePath: attributePath + ".path", Message: "Either path or grpc has to be defined"})
+	case rule.Path != "" && rule.GRPC != nil:
+		problems = append(problems, Failure{AttributePath: attributePath + ".grpc", Message: "Only one of path or grpc can be defined"})
+	case rule.GRPC != nil && protocol != gatewayv1alpha1.ProtocolGRPC && protocol != gatewayv1alpha1.ProtocolGRPCWeb:
+		problems = append(problems, Failure{AttributePath: attributePath + ".grpc", Message: fmt.Sprintf("gRPC matching can't be used with the %s protocol", protocol)})
+	}
+
+	//gRPC calls are POST requests, WebSocket connections are opened with GET requests
+	switch protocol {
+	case gatewayv1alpha1.ProtocolGRPC, gatewayv1alpha1.ProtocolGRPCWeb:
+		if !containsMethod(rule.Methods, "POST") {
+			problems = append(problems, Failure{AttributePath: attributePath + ".methods", Message: "gRPC calls require the POST method"})
+		}
+	case gatewayv1alpha1.ProtocolWebSocket:
+		if !containsMethod(rule.Methods, "GET") {
+			problems = append(problems, Failure{AttributePath: attributePath + ".methods", Message: "WebSocket connections require the GET method"})
+		}
+	}
+	return problems
+}
+
+//validateGatewaySecurity verifies that the secured rules of gRPC Services can be secured by Istio on the ingress gateway, as Oathkeeper
+//proxies requests over HTTP/1.1 only. Istio validates the JWTs of a single jwt access strategy with trusted issuers and applies no mutators
+func (v *APIRule) validateGatewaySecurity(attributePath, protocol string, rule gatewayv1alpha1.Rule) []Failure {
+	if !http2Protocol(protocol) || !isSecured(rule) {
+		return nil
+	}
+	var problems []Failure
+
+	if len(rule.Mutators) > 0 {
+		problems = append(problems, Failure{AttributePath: attributePath + ".mutators", Message: fmt.Sprintf("Rules of %s Services are secured on the gateway, which can't apply mutators", protocol)})
+	}
+	if _, ok := helpers.RulePathPattern(rule); !ok {
+		problems = append(problems, Failure{AttributePath: attributePath + ".path", Message: "Rules secured on the gateway need a gRPC match, a literal path or a literal prefix followed by .*"})
+	}
+	strategies := rule.AccessStrategies
+	if len(strategies) != 1 || strategies[0] == nil || strategies[0].Handler == nil || strategies[0].Name != "jwt" {
+		return append(problems, Failure{AttributePath: attributePath + ".accessStrategies", Message: fmt.Sprintf("Rules of %s Services can be secured only by a single jwt access strategy", protocol)})
 	}
 
+	//Configs that can't be read are reported by the validator of the jwt access strategy
+	var config ory.JwtConfig
+	if strategies[0].Config == nil || json.Unmarshal(strategies[0].Config.Raw, &config) != nil {
+		return problems
+	}
+	configPath := attributePath + ".accessStrategies[0].config"
+	if len(config.TrustedIssuer) == 0 {
+		problems = append(problems, Failure{AttributePath: configPath + ".trusted_issuers", Message: fmt.Sprintf("Trusted issuers are required to secure rules of %s Services", protocol)})
+	}
+	if len(config.JwksURLs) > 1 {
+		problems = append(problems, Failure{AttributePath: configPath + ".jwks_urls", Message: fmt.Sprintf("Rules of %s Services can be secured with a single JWKS URL", protocol)})
+	}
 	return problems
 }
 
+//validateRateLimit verifies the unit and the key of the rate limit. Requests can be counted by the JWT subject only if the rule trusts
+//issuers of tokens, which the gateway verifies with a single JWKS URL each
+func (v *APIRule) validateRateLimit(attributePath string, rule gatewayv1alpha1.Rule) []Failure {
+	limit := rule.RateLimit
+	if limit == nil {
+		return nil
+	}
+	var problems []Failure
+
+	if limit.Requests == 0 {
+		problems = append(problems, Failure{AttributePath: attributePath + ".requests", Message: "At least one request has to be allowed"})
+	}
+	switch limit.Unit {
+	case gatewayv1alpha1.RateLimitUnitSecond, gatewayv1alpha1.RateLimitUnitMinute, gatewayv1alpha1.RateLimitUnitHour:
+	default:
+		problems = append(problems, Failure{AttributePath: attributePath + ".unit", Message: fmt.Sprintf("Unsupported unit %q", limit.Unit)})
+	}
+
+	key := limit.Key
+	if key == nil {
+		return problems
+	}
+	switch key.Type {
+	case gatewayv1alpha1.RateLimitKeyClientIP:
+	case gatewayv1alpha1.RateLimitKeyJWTSubject:
+		problems = append(problems, validateJWTSubjectKey(attributePath+".key.type", rule)...)
+	case gatewayv1alpha1.RateLimitKeyHeader:
+		if key.Header == nil {
+			problems = append(problems, Failure{AttributePath: attributePath + ".key.header", Message: "Header is required for the header key"})
+		} else if errs := k8svalidation.IsHTTPHeaderName(*key.Header); len(errs) > 0 {
+			problems = append(problems, Failure{AttributePath: attributePath + ".key.header", Message: strings.Join(errs, "; ")})
+		}
+	default:
+		problems = append(problems, Failure{AttributePath: attributePath + ".key.type", Message: fmt.Sprintf("Unsupported key type %q", key.Type)})
+	}
+	if key.Type != gatewayv1alpha1.RateLimitKeyHeader && key.Header != nil {
+		problems = append(problems, Failure{AttributePath: attributePath + ".key.header", Message: "Header can be set only for the header key"})
+	}
+	return problems
+}
+
+func validateJWTSubjectKey(attributePath string, rule gatewayv1alpha1.Rule) []Failure {
+	var issuers int
+	for _, strategy := range rule.AccessStrategies {
+		if strategy == nil || strategy.Handler == nil || strategy.Name != "jwt" || strategy.Config == nil {
+			continue
+		}
+		//Configs that can't be read are reported by the validator of the jwt access strategy
+		var config ory.JwtConfig
+		if json.Unmarshal(strategy.Config.Raw, &config) != nil {
+			continue
+		}
+		if len(config.JwksURLs) > 1 {
+			return []Failure{{AttributePath: attributePath, Message: "Counting requests by JWT subject requires jwt access strategies with a single JWKS URL"}}
+		}
+		issuers += len(config.TrustedIssuer)
+	}
+	if issuers == 0 {
+		return []Failure{{AttributePath: attributePath, Message: "Counting requests by JWT subject requires a jwt access strategy with trusted issuers"}}
+	}
+	return nil
+}
+
+//validateSourceIPs verifies the IP addresses and CIDR blocks, and that the path of the rule can be matched by an AuthorizationPolicy
+func (v *APIRule) validateSourceIPs(attributePath string, rule gatewayv1alpha1.Rule) []Failure {
+	sourceIPs := rule.SourceIPs
+	if sourceIPs == nil {
+		return nil
+	}
+	var problems []Failure
+
+	if len(sourceIPs.Allow) == 0 && len(sourceIPs.Deny) == 0 {
+		problems = append(problems, Failure{AttributePath: attributePath, Message: "Either allow or deny has to be defined"})
+	}
+	for i, block := range sourceIPs.Allow {
+		if !isIPBlock(block) {
+			problems = append(problems, Failure{AttributePath: fmt.Sprintf("%s.allow[%d]", attributePath, i), Message: fmt.Sprintf("Invalid IP address or CIDR block %q", block)})
+		}
+	}
+	for i, block := range sourceIPs.Deny {
+		if !isIPBlock(block) {
+			problems = append(problems, Failure{AttributePath: fmt.Sprintf("%s.deny[%d]", attributePath, i), Message: fmt.Sprintf("Invalid IP address or CIDR block %q", block)})
+		}
+	}
+	if _, ok := helpers.RulePathPattern(rule); !ok {
+		problems = append(problems, Failure{AttributePath: attributePath, Message: "Source IPs can be restricted only for a literal path or a literal prefix followed by .*"})
+	}
+	return problems
+}
+
+//validateAPIKeys verifies that the api_key access strategy is enabled and that the Secrets holding the keys exist and are not empty
+func (v *APIRule) validateAPIKeys(attributePath string, accessStrategies []*gatewayv1alpha1.Authenticator) []Failure {
+	var problems []Failure
+
+	for i, strategy := range accessStrategies {
+		if strategy.Handler == nil || strategy.Name != "api_key" || configEmpty(strategy.Config) {
+			continue
+		}
+		strategyAttrPath := fmt.Sprintf("%s[%d]", attributePath, i)
+		if !v.APIKeys {
+			problems = append(problems, Failure{AttributePath: strategyAttrPath + ".handler", Message: "The api_key access strategy is not enabled in the controller configuration"})
+			continue
+		}
+		var config gatewayv1alpha1.APIKeyAccStrConfig
+		if err := json.Unmarshal(strategy.Config.Raw, &config); err != nil || config.SecretName == "" || v.Secrets == nil {
+			continue
+		}
+		secret, ok := v.Secrets[config.SecretName]
+		switch {
+		case !ok || secret == nil:
+			problems = append(problems, Failure{AttributePath: strategyAttrPath + ".config.secretName", Message: fmt.Sprintf("Secret %s does not exist", config.SecretName)})
+		case len(secret.Data) == 0:
+			problems = append(problems, Failure{AttributePath: strategyAttrPath + ".config.secretName", Message: fmt.Sprintf("Secret %s does not hold any key", config.SecretName)})
+		}
+	}
+	return problems
+}
+
+//validateSecretRefs verifies the valueFrom.secretKeyRef placeholders in the configs of the access strategies and mutators of the rule,
+//and that the referenced keys exist
+func (v *APIRule) validateSecretRefs(attributePath string, rule gatewayv1alpha1.Rule) []Failure {
+	var problems []Failure
+
+	validate := func(handlerAttrPath string, handler *gatewayv1alpha1.Handler) {
+		if handler == nil {
+			return
+		}
+		placeholders, err := secretrefs.Find(handler.Config)
+		if err != nil {
+			//Configs that can't be read are reported by the validators of the handlers
+			return
+		}
+		for _, p := range placeholders {
+			attrPath := handlerAttrPath + ".config" + p.Path
+			if p.Ref == nil {
+				problems = append(problems, Failure{AttributePath: attrPath, Message: "valueFrom has to set only secretKeyRef with the name and the key"})
+				continue
+			}
+			if v.Secrets == nil {
+				continue
+			}
+			secret, ok := v.Secrets[p.Ref.Name]
+			switch {
+			case !ok || secret == nil:
+				problems = append(problems, Failure{AttributePath: attrPath, Message: fmt.Sprintf("Secret %s does not exist", p.Ref.Name)})
+			case secret.Data[p.Ref.Key] == nil:
+				problems = append(problems, Failure{AttributePath: attrPath, Message: fmt.Sprintf("Secret %s does not hold the key %s", p.Ref.Name, p.Ref.Key)})
+			}
+		}
+	}
+
+	for i, strategy := range rule.AccessStrategies {
+		if strategy != nil {
+			validate(fmt.Sprintf("%s.accessStrategies[%d]", attributePath, i), strategy.Handler)
+		}
+	}
+	for i, mutator := range rule.Mutators {
+		if mutator != nil {
+			validate(fmt.Sprintf("%s.mutators[%d]", attributePath, i), mutator.Handler)
+		}
+	}
+	return problems
+}
+
+func isIPBlock(block string) bool {
+	if strings.Contains(block, "/") {
+		_, _, err := net.ParseCIDR(block)
+		return err == nil
+	}
+	return net.ParseIP(block) != nil
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
 func (v *APIRule) validateMethods(attributePath string, methods []string) []Failure {
 	return nil
 }
@@ -162,30 +539,9 @@ func (v *APIRule) validateAccessStrategies(attributePath string, accessStrategie
 }
 
 func (v *APIRule) validateAccessStrategy(attributePath string, accessStrategy *gatewayv1alpha1.Authenticator) []Failure {
-	var problems []Failure
-
-	var vld accessStrategyValidator
-
-	switch accessStrategy.Handler.Name {
-	case "allow": //our internal constant, does not exist in ORY
-		vld = vldNoConfig
-	case "noop":
-		vld = vldNoConfig
-	case "unauthorized":
-		vld = vldNoConfig
-	case "anonymous":
-		vld = vldNoConfig
-	case "cookie_session":
-		vld = vldNoConfig
-	case "oauth2_client_credentials":
-		vld = vldDummy
-	case "oauth2_introspection":
-		vld = vldDummy
-	case "jwt":
-		vld = vldJWT
-	default:
-		problems = append(problems, Failure{AttributePath: attributePath + ".handler", Message: fmt.Sprintf("Unsupported accessStrategy: %s", accessStrategy.Handler.Name)})
-		return problems
+	vld, ok := lookupAccessStrategyValidator(accessStrategy.Handler.Name)
+	if !ok {
+		return []Failure{unsupportedAccessStrategy(attributePath, accessStrategy.Handler.Name)}
 	}
 
 	return vld.Validate(attributePath, accessStrategy.Handler)
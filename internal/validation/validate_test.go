@@ -10,8 +10,12 @@ import (
 	"testing"
 
 	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
+	"github.com/kyma-incubator/api-gateway/internal/claims"
+	"github.com/kyma-incubator/api-gateway/internal/handlers"
 	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -251,6 +255,116 @@ var _ = Describe("Validate function", func() {
 		Expect(problems[0].Message).To(Equal("Host is not allowlisted"))
 	})
 
+	It("Should allow host matching wildcard domain", func() {
+		//given
+		input := &gatewayv1alpha1.APIRule{
+			Spec: gatewayv1alpha1.APIRuleSpec{
+				Service: getService(sampleServiceName, uint32(8080), "my-app.apps.example.com"),
+				Rules: []gatewayv1alpha1.Rule{
+					{
+						Path:             "/abc",
+						AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("noop", emptyConfig())},
+					},
+				},
+			},
+		}
+
+		//when
+		problems := (&APIRule{
+			DomainAllowList: []string{"*.apps.example.com"},
+		}).Validate(input, networkingv1beta1.VirtualServiceList{})
+
+		//then
+		Expect(problems).To(HaveLen(0))
+	})
+
+	It("Should fail for host matching wildcard domain with more than one label", func() {
+		//given
+		input := &gatewayv1alpha1.APIRule{
+			Spec: gatewayv1alpha1.APIRuleSpec{
+				Service: getService(sampleServiceName, uint32(8080), "my.app.apps.example.com"),
+				Rules: []gatewayv1alpha1.Rule{
+					{
+						Path:             "/abc",
+						AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("noop", emptyConfig())},
+					},
+				},
+			},
+		}
+
+		//when
+		problems := (&APIRule{
+			DomainAllowList: []string{"*.apps.example.com"},
+		}).Validate(input, networkingv1beta1.VirtualServiceList{})
+
+		//then
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].AttributePath).To(Equal(".spec.service.host"))
+		Expect(problems[0].Message).To(Equal("Host is not allowlisted"))
+	})
+
+	It("Should fail for host in domain delegated to another namespace", func() {
+		//given
+		input := &gatewayv1alpha1.APIRule{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "team-a",
+			},
+			Spec: gatewayv1alpha1.APIRuleSpec{
+				Service: getService(sampleServiceName, uint32(8080), "billing."+allowlistedDomain),
+				Rules: []gatewayv1alpha1.Rule{
+					{
+						Path:             "/abc",
+						AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("noop", emptyConfig())},
+					},
+				},
+			},
+		}
+
+		//when
+		problems := (&APIRule{
+			DomainAllowList: testDomainAllowlist,
+			DomainDelegations: map[string][]string{
+				allowlistedDomain:              {"team-a"},
+				"billing." + allowlistedDomain: {"team-b"},
+			},
+		}).Validate(input, networkingv1beta1.VirtualServiceList{})
+
+		//then
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].AttributePath).To(Equal(".spec.service.host"))
+		Expect(problems[0].Message).To(Equal("Domain billing.foo.bar is not delegated to namespace team-a"))
+	})
+
+	It("Should NOT fail for host in domain delegated with wildcard to this namespace", func() {
+		//given
+		input := &gatewayv1alpha1.APIRule{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace: "team-b",
+			},
+			Spec: gatewayv1alpha1.APIRuleSpec{
+				Service: getService(sampleServiceName, uint32(8080), "api.billing."+allowlistedDomain),
+				Rules: []gatewayv1alpha1.Rule{
+					{
+						Path:             "/abc",
+						AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("noop", emptyConfig())},
+					},
+				},
+			},
+		}
+
+		//when
+		problems := (&APIRule{
+			DomainAllowList: testDomainAllowlist,
+			DomainDelegations: map[string][]string{
+				allowlistedDomain:                {"team-a"},
+				"*.billing." + allowlistedDomain: {"team-b"},
+			},
+		}).Validate(input, networkingv1beta1.VirtualServiceList{})
+
+		//then
+		Expect(problems).To(HaveLen(0))
+	})
+
 	It("Should fail for a host that is occupied by a VS exposed by another resource", func() {
 		//given
 		occupiedHost := "occupied-host" + allowlistedDomain
@@ -286,6 +400,42 @@ var _ = Describe("Validate function", func() {
 		Expect(problems[0].Message).To(Equal("This host is occupied by another Virtual Service"))
 	})
 
+	It("Should fail for a host claimed by another APIRule", func() {
+		//given
+		claimedHost := "claimed-host." + allowlistedDomain
+		holder := &gatewayv1alpha1.APIRule{
+			ObjectMeta: v1.ObjectMeta{Namespace: "other", Name: "holder", CreationTimestamp: v1.Unix(0, 0)},
+			Spec: gatewayv1alpha1.APIRuleSpec{
+				Service: getService(sampleServiceName, uint32(8080), claimedHost),
+			},
+		}
+		input := &gatewayv1alpha1.APIRule{
+			ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "late", CreationTimestamp: v1.Unix(60, 0)},
+			Spec: gatewayv1alpha1.APIRuleSpec{
+				Service: getService(sampleServiceName, uint32(8080), claimedHost),
+				Rules: []gatewayv1alpha1.Rule{
+					{
+						Path: "/abc",
+						AccessStrategies: []*gatewayv1alpha1.Authenticator{
+							toAuthenticator("noop", emptyConfig()),
+						},
+					},
+				},
+			},
+		}
+
+		//when
+		problems := (&APIRule{
+			DomainAllowList: testDomainAllowlist,
+			HostClaim:       claims.Arbitrate(input, []gatewayv1alpha1.APIRule{*holder}, "", claims.Options{}),
+		}).Validate(input, networkingv1beta1.VirtualServiceList{})
+
+		//then
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].AttributePath).To(Equal(".spec.service.host"))
+		Expect(problems[0].Message).To(Equal("Host claimed-host.foo.bar is claimed by APIRule other/holder"))
+	})
+
 	It("Should NOT fail for a host that is occupied by a VS exposed by this resource", func() {
 		//given
 		occupiedHost := "occupied-host" + allowlistedDomain
@@ -422,6 +572,303 @@ var _ = Describe("Validate function", func() {
 		//then
 		Expect(problems).To(HaveLen(0))
 	})
+
+	table.DescribeTable("Should validate the match of the rule for the protocol",
+		func(protocol, path string, grpc *gatewayv1alpha1.GRPCMatch, methods []string, expected ...string) {
+			rule := gatewayv1alpha1.Rule{Path: path, GRPC: grpc, Methods: methods}
+			problems := (&APIRule{}).validateMatch(".spec.rules[0]", protocol, rule)
+
+			var messages []string
+			for _, problem := range problems {
+				messages = append(messages, problem.AttributePath+": "+problem.Message)
+			}
+			Expect(messages).To(ConsistOf(expected))
+		},
+		table.Entry("path over HTTP", "http", "/.*", nil, []string{"GET"}),
+		table.Entry("gRPC service", "grpc", "", &gatewayv1alpha1.GRPCMatch{Service: "helloworld.Greeter"}, []string{"POST"}),
+		table.Entry("gRPC-Web method", "grpc-web", "", &gatewayv1alpha1.GRPCMatch{Service: "helloworld.Greeter", Method: "SayHello"}, []string{"POST", "OPTIONS"}),
+		table.Entry("no path nor gRPC match", "http", "", nil, []string{"GET"},
+			".spec.rules[0].path: Either path or grpc has to be defined"),
+		table.Entry("both path and gRPC match", "grpc", "/.*", &gatewayv1alpha1.GRPCMatch{Service: "helloworld.Greeter"}, []string{"POST"},
+			".spec.rules[0].grpc: Only one of path or grpc can be defined"),
+		table.Entry("gRPC match over HTTP", "http", "", &gatewayv1alpha1.GRPCMatch{Service: "helloworld.Greeter"}, []string{"POST"},
+			".spec.rules[0].grpc: gRPC matching can't be used with the http protocol"),
+		table.Entry("gRPC without POST", "grpc", "/.*", nil, []string{"GET"},
+			".spec.rules[0].methods: gRPC calls require the POST method"),
+		table.Entry("WebSocket without GET", "websocket", "/ws", nil, []string{"POST"},
+			".spec.rules[0].methods: WebSocket connections require the GET method"),
+	)
+
+	table.DescribeTable("Should validate the rules of gRPC Services secured on the gateway",
+		func(protocol string, rule gatewayv1alpha1.Rule, expected ...string) {
+			//given
+			rule.Methods = []string{"POST"}
+			if rule.Path == "" {
+				rule.GRPC = &gatewayv1alpha1.GRPCMatch{Service: "helloworld.Greeter"}
+			}
+
+			//when
+			problems := (&APIRule{}).validateGatewaySecurity(".spec.rules[0]", protocol, rule)
+
+			//then
+			var messages []string
+			for _, problem := range problems {
+				messages = append(messages, problem.AttributePath+": "+problem.Message)
+			}
+			Expect(messages).To(ConsistOf(expected))
+		},
+		table.Entry("gRPC with allow", "grpc",
+			gatewayv1alpha1.Rule{AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("allow", nil)}}),
+		table.Entry("gRPC with jwt", "grpc",
+			gatewayv1alpha1.Rule{AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("jwt", simpleJWTConfig("https://dex.kyma.local"))}}),
+		table.Entry("HTTP with noop", "http",
+			gatewayv1alpha1.Rule{AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("noop", emptyConfig())}}),
+		table.Entry("gRPC-Web with noop", "grpc-web",
+			gatewayv1alpha1.Rule{AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("noop", emptyConfig())}},
+			".spec.rules[0].accessStrategies: Rules of grpc-web Services can be secured only by a single jwt access strategy"),
+		table.Entry("gRPC with jwt and mutators", "grpc",
+			gatewayv1alpha1.Rule{
+				AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("jwt", simpleJWTConfig("https://dex.kyma.local"))},
+				Mutators:         []*gatewayv1alpha1.Mutator{{Handler: &gatewayv1alpha1.Handler{Name: "header"}}},
+			},
+			".spec.rules[0].mutators: Rules of grpc Services are secured on the gateway, which can't apply mutators"),
+		table.Entry("gRPC with jwt without trusted issuers", "grpc",
+			gatewayv1alpha1.Rule{AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("jwt", emptyConfig())}},
+			".spec.rules[0].accessStrategies[0].config.trusted_issuers: Trusted issuers are required to secure rules of grpc Services"),
+		table.Entry("gRPC with jwt and two JWKS URLs", "grpc",
+			gatewayv1alpha1.Rule{AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("jwt",
+				rawConfig(`{"trusted_issuers": ["https://dex.kyma.local"], "jwks_urls": ["https://dex.kyma.local/keys", "https://other.kyma.local/keys"]}`))}},
+			".spec.rules[0].accessStrategies[0].config.jwks_urls: Rules of grpc Services can be secured with a single JWKS URL"),
+		table.Entry("gRPC with jwt on a path regex", "grpc",
+			gatewayv1alpha1.Rule{
+				Path:             "/helloworld.Greeter/Say[a-z]+",
+				AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("jwt", simpleJWTConfig("https://dex.kyma.local"))},
+			},
+			".spec.rules[0].path: Rules secured on the gateway need a gRPC match, a literal path or a literal prefix followed by .*"),
+	)
+
+	table.DescribeTable("Should validate the Secrets of the upstream TLS mode",
+		func(mode string, caSecretName, clientSecretName *string, expected ...string) {
+			tls := &gatewayv1alpha1.UpstreamTLS{Mode: mode, CASecretName: caSecretName, ClientSecretName: clientSecretName}
+			problems := (&APIRule{}).validateUpstreamTLS(".spec.service.tls", "http", tls, nil)
+
+			var messages []string
+			for _, problem := range problems {
+				messages = append(messages, problem.AttributePath+": "+problem.Message)
+			}
+			Expect(messages).To(ConsistOf(expected))
+		},
+		table.Entry("SIMPLE with CA", "SIMPLE", secretName("ca"), nil),
+		table.Entry("MUTUAL with client Secret", "MUTUAL", nil, secretName("client")),
+		table.Entry("ISTIO_MUTUAL", "ISTIO_MUTUAL", nil, nil),
+		table.Entry("SIMPLE without CA", "SIMPLE", nil, nil,
+			".spec.service.tls.caSecretName: CA Secret is required in SIMPLE mode"),
+		table.Entry("MUTUAL without client Secret", "MUTUAL", secretName("ca"), nil,
+			".spec.service.tls.caSecretName: CA Secret can be set only in SIMPLE mode",
+			".spec.service.tls.clientSecretName: Client Secret is required in MUTUAL mode"),
+		table.Entry("DISABLE with client Secret", "DISABLE", nil, secretName("client"),
+			".spec.service.tls.clientSecretName: Client Secret can be set only in MUTUAL mode"),
+	)
+
+	table.DescribeTable("Should require the Secret of the TLS mode in the namespace of Oathkeeper for secured rules",
+		func(mode, protocol, handler string, oathkeeperSecrets map[string]*corev1.Secret, expected ...string) {
+			//given
+			tls := &gatewayv1alpha1.UpstreamTLS{Mode: mode}
+			switch mode {
+			case "SIMPLE":
+				tls.CASecretName = secretName("ca")
+			case "MUTUAL":
+				tls.ClientSecretName = secretName("client")
+			}
+			rules := []gatewayv1alpha1.Rule{
+				{Path: "/public", Methods: []string{"GET"}, AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("allow", emptyConfig())}},
+				{Path: "/orders", Methods: []string{"GET"}, AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator(handler, emptyConfig())}},
+			}
+			validator := &APIRule{OathkeeperNamespace: "kyma-system", OathkeeperSecrets: oathkeeperSecrets}
+
+			//when
+			problems := validator.validateUpstreamTLS(".spec.service.tls", protocol, tls, rules)
+
+			//then
+			var messages []string
+			for _, problem := range problems {
+				messages = append(messages, problem.AttributePath+": "+problem.Message)
+			}
+			Expect(messages).To(ConsistOf(expected))
+		},
+		table.Entry("SIMPLE with public rules", "SIMPLE", "http", "allow", map[string]*corev1.Secret{}),
+		table.Entry("SIMPLE with a secured rule and the Secret in the namespace of Oathkeeper", "SIMPLE", "http", "jwt",
+			map[string]*corev1.Secret{"ca": {}}),
+		table.Entry("SIMPLE with a secured rule without Secrets lookup", "SIMPLE", "http", "jwt", nil),
+		table.Entry("SIMPLE with a secured rule secured on the gateway", "SIMPLE", "grpc", "jwt", map[string]*corev1.Secret{}),
+		table.Entry("SIMPLE with a secured rule", "SIMPLE", "http", "jwt", map[string]*corev1.Secret{},
+			".spec.service.tls.caSecretName: Secret ca has to exist in the kyma-system namespace too, as Oathkeeper connects to the Service over TLS for secured rules"),
+		table.Entry("MUTUAL with a secured rule", "MUTUAL", "http", "oauth2_introspection", map[string]*corev1.Secret{"ca": {}},
+			".spec.service.tls.clientSecretName: Secret client has to exist in the kyma-system namespace too, as Oathkeeper connects to the Service over TLS for secured rules"),
+		table.Entry("ISTIO_MUTUAL with a secured rule", "ISTIO_MUTUAL", "http", "jwt", map[string]*corev1.Secret{}),
+	)
+
+	table.DescribeTable("Should validate the rate limit of the rule",
+		func(limit *gatewayv1alpha1.RateLimit, strategy *gatewayv1alpha1.Authenticator, expected ...string) {
+			rule := gatewayv1alpha1.Rule{Path: "/.*", RateLimit: limit, AccessStrategies: []*gatewayv1alpha1.Authenticator{strategy}}
+			problems := (&APIRule{}).validateRateLimit(".spec.rules[0].rateLimit", rule)
+
+			var messages []string
+			for _, problem := range problems {
+				messages = append(messages, problem.AttributePath+": "+problem.Message)
+			}
+			Expect(messages).To(ConsistOf(expected))
+		},
+		table.Entry("no rate limit", nil, toAuthenticator("noop", emptyConfig())),
+		table.Entry("limit without key", &gatewayv1alpha1.RateLimit{Requests: 10, Unit: "minute"}, toAuthenticator("noop", emptyConfig())),
+		table.Entry("limit by client IP", rateLimitBy("client_ip", ""), toAuthenticator("noop", emptyConfig())),
+		table.Entry("limit by header", rateLimitBy("header", "X-Api-Key"), toAuthenticator("noop", emptyConfig())),
+		table.Entry("no requests in unsupported unit", &gatewayv1alpha1.RateLimit{Unit: "day"}, toAuthenticator("noop", emptyConfig()),
+			".spec.rules[0].rateLimit.requests: At least one request has to be allowed",
+			`.spec.rules[0].rateLimit.unit: Unsupported unit "day"`),
+		table.Entry("header key without header", rateLimitBy("header", ""), toAuthenticator("noop", emptyConfig()),
+			".spec.rules[0].rateLimit.key.header: Header is required for the header key"),
+		table.Entry("header key with invalid header", rateLimitBy("header", "X Api Key"), toAuthenticator("noop", emptyConfig()),
+			".spec.rules[0].rateLimit.key.header: a valid HTTP header must consist of alphanumeric characters or '-' (e.g. 'X-Header-Name', regex used for validation is '[-A-Za-z0-9]+')"),
+		table.Entry("header set for client IP key", rateLimitBy("client_ip", "X-Api-Key"), toAuthenticator("noop", emptyConfig()),
+			".spec.rules[0].rateLimit.key.header: Header can be set only for the header key"),
+		table.Entry("limit by JWT subject", rateLimitBy("jwt_subject", ""), toAuthenticator("jwt", simpleJWTConfig("https://issuer.example.com"))),
+		table.Entry("JWT subject without trusted issuers", rateLimitBy("jwt_subject", ""), toAuthenticator("jwt", simpleJWTConfig()),
+			".spec.rules[0].rateLimit.key.type: Counting requests by JWT subject requires a jwt access strategy with trusted issuers"),
+		table.Entry("JWT subject without jwt access strategy", rateLimitBy("jwt_subject", ""), toAuthenticator("noop", emptyConfig()),
+			".spec.rules[0].rateLimit.key.type: Counting requests by JWT subject requires a jwt access strategy with trusted issuers"),
+		table.Entry("JWT subject with two JWKS URLs", rateLimitBy("jwt_subject", ""),
+			toAuthenticator("jwt", rawConfig(`{"trusted_issuers": ["https://issuer.example.com"], "jwks_urls": ["https://issuer.example.com/keys", "https://other.example.com/keys"]}`)),
+			".spec.rules[0].rateLimit.key.type: Counting requests by JWT subject requires jwt access strategies with a single JWKS URL"),
+		table.Entry("unsupported key type", rateLimitBy("session", ""), toAuthenticator("noop", emptyConfig()),
+			`.spec.rules[0].rateLimit.key.type: Unsupported key type "session"`),
+	)
+
+	table.DescribeTable("Should validate the source IPs of the rule",
+		func(path string, sourceIPs *gatewayv1alpha1.SourceIPs, expected ...string) {
+			rule := gatewayv1alpha1.Rule{Path: path, SourceIPs: sourceIPs}
+			problems := (&APIRule{}).validateSourceIPs(".spec.rules[0].sourceIPs", rule)
+
+			var messages []string
+			for _, problem := range problems {
+				messages = append(messages, problem.AttributePath+": "+problem.Message)
+			}
+			Expect(messages).To(ConsistOf(expected))
+		},
+		table.Entry("no source IPs", "/.*", nil),
+		table.Entry("allowed CIDR blocks", "/admin/.*", &gatewayv1alpha1.SourceIPs{Allow: []string{"10.0.0.0/8", "2001:db8::/32"}}),
+		table.Entry("denied address", "/headers", &gatewayv1alpha1.SourceIPs{Deny: []string{"192.168.1.10"}}),
+		table.Entry("neither allow nor deny", "/.*", &gatewayv1alpha1.SourceIPs{},
+			".spec.rules[0].sourceIPs: Either allow or deny has to be defined"),
+		table.Entry("invalid blocks", "/.*", &gatewayv1alpha1.SourceIPs{Allow: []string{"10.0.0.0/33"}, Deny: []string{"corporate"}},
+			`.spec.rules[0].sourceIPs.allow[0]: Invalid IP address or CIDR block "10.0.0.0/33"`,
+			`.spec.rules[0].sourceIPs.deny[0]: Invalid IP address or CIDR block "corporate"`),
+		table.Entry("path not matched by prefix", "/(admin|internal)/.*", &gatewayv1alpha1.SourceIPs{Allow: []string{"10.0.0.0/8"}},
+			".spec.rules[0].sourceIPs: Source IPs can be restricted only for a literal path or a literal prefix followed by .*"),
+		table.Entry("path segment wildcard", "/orders/[^/]+", &gatewayv1alpha1.SourceIPs{Allow: []string{"10.0.0.0/8"}},
+			".spec.rules[0].sourceIPs: Source IPs can be restricted only for a literal path or a literal prefix followed by .*"),
+	)
+
+	It("Should restrict the source IPs of all methods of a gRPC service", func() {
+		//given
+		rule := gatewayv1alpha1.Rule{GRPC: &gatewayv1alpha1.GRPCMatch{Service: "helloworld.Greeter"}, SourceIPs: &gatewayv1alpha1.SourceIPs{Allow: []string{"10.0.0.0/8"}}}
+
+		//when
+		problems := (&APIRule{}).validateSourceIPs(".spec.rules[0].sourceIPs", rule)
+
+		//then
+		Expect(problems).To(BeEmpty())
+	})
+
+	table.DescribeTable("Should validate the config of the api_key access strategy",
+		func(config string, expected ...string) {
+			problems := vldAPIKey.Validate(".spec.rules[0].accessStrategies[0]", &gatewayv1alpha1.Handler{Name: "api_key", Config: rawConfig(config)})
+
+			var messages []string
+			for _, problem := range problems {
+				messages = append(messages, problem.AttributePath+": "+problem.Message)
+			}
+			Expect(messages).To(ConsistOf(expected))
+		},
+		table.Entry("key in header", `{"secretName":"partner-keys","header":"X-API-Key"}`),
+		table.Entry("key in query parameter", `{"secretName":"partner-keys","queryParameter":"api_key"}`),
+		table.Entry("no config", "",
+			".spec.rules[0].accessStrategies[0].config: supplied config cannot be empty"),
+		table.Entry("no Secret and no location", `{"header":""}`,
+			".spec.rules[0].accessStrategies[0].config.secretName: value is required",
+			".spec.rules[0].accessStrategies[0].config: Either header or queryParameter has to be defined"),
+		table.Entry("both locations", `{"secretName":"partner-keys","header":"X-API-Key","queryParameter":"api_key"}`,
+			".spec.rules[0].accessStrategies[0].config: Only one of header or queryParameter can be defined"),
+		table.Entry("invalid Secret name and header", `{"secretName":"Partner_Keys","header":"X API Key"}`,
+			".spec.rules[0].accessStrategies[0].config.secretName: a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')",
+			".spec.rules[0].accessStrategies[0].config.header: a valid HTTP header must consist of alphanumeric characters or '-' (e.g. 'X-Header-Name', regex used for validation is '[-A-Za-z0-9]+')"),
+		table.Entry("invalid query parameter", `{"secretName":"partner-keys","queryParameter":"api/key"}`,
+			".spec.rules[0].accessStrategies[0].config.queryParameter: Query parameter can contain only letters, digits and the characters _.~-"),
+	)
+
+	table.DescribeTable("Should validate the Secrets of the api_key access strategies",
+		func(enabled bool, secrets map[string]*corev1.Secret, expected ...string) {
+			strategies := []*gatewayv1alpha1.Authenticator{
+				toAuthenticator("noop", nil),
+				toAuthenticator("api_key", rawConfig(`{"secretName":"partner-keys","header":"X-API-Key"}`)),
+			}
+			problems := (&APIRule{APIKeys: enabled, Secrets: secrets}).validateAPIKeys(".spec.rules[0].accessStrategies", strategies)
+
+			var messages []string
+			for _, problem := range problems {
+				messages = append(messages, problem.AttributePath+": "+problem.Message)
+			}
+			Expect(messages).To(ConsistOf(expected))
+		},
+		table.Entry("Secret with keys", true, map[string]*corev1.Secret{"partner-keys": {Data: map[string][]byte{"acme": []byte("key")}}}),
+		table.Entry("Secrets not looked up", true, nil),
+		table.Entry("API keys not enabled", false, nil,
+			".spec.rules[0].accessStrategies[1].handler: The api_key access strategy is not enabled in the controller configuration"),
+		table.Entry("missing Secret", true, map[string]*corev1.Secret{},
+			".spec.rules[0].accessStrategies[1].config.secretName: Secret partner-keys does not exist"),
+		table.Entry("empty Secret", true, map[string]*corev1.Secret{"partner-keys": {}},
+			".spec.rules[0].accessStrategies[1].config.secretName: Secret partner-keys does not hold any key"),
+	)
+
+	table.DescribeTable("Should validate the Secret placeholders of the handler configs",
+		func(accessStrategyConfig, mutatorConfig string, expected ...string) {
+			rule := gatewayv1alpha1.Rule{
+				AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("oauth2_introspection", rawConfig(accessStrategyConfig))},
+				Mutators:         []*gatewayv1alpha1.Mutator{{Handler: &gatewayv1alpha1.Handler{Name: "hydrator", Config: rawConfig(mutatorConfig)}}},
+			}
+			secrets := map[string]*corev1.Secret{"introspection": {Data: map[string][]byte{"authorization": []byte("Basic Y2xpZW50OnNlY3JldA==")}}}
+			problems := (&APIRule{Secrets: secrets}).validateSecretRefs(".spec.rules[0]", rule)
+
+			var messages []string
+			for _, problem := range problems {
+				messages = append(messages, problem.AttributePath+": "+problem.Message)
+				Expect(problem.Message).NotTo(ContainSubstring("Y2xpZW50OnNlY3JldA=="))
+			}
+			Expect(messages).To(ConsistOf(expected))
+		},
+		table.Entry("no placeholders", `{"introspection_url":"https://auth.example.com"}`, ""),
+		table.Entry("existing key", `{"introspection_request_headers":{"authorization":{"valueFrom":{"secretKeyRef":{"name":"introspection","key":"authorization"}}}}}`, ""),
+		table.Entry("missing key", `{"introspection_request_headers":{"authorization":{"valueFrom":{"secretKeyRef":{"name":"introspection","key":"token"}}}}}`, "",
+			".spec.rules[0].accessStrategies[0].config.introspection_request_headers.authorization: Secret introspection does not hold the key token"),
+		table.Entry("missing Secret", "", `{"api":{"auth":{"basic":{"password":{"valueFrom":{"secretKeyRef":{"name":"hydrator","key":"password"}}}}}}}`,
+			".spec.rules[0].mutators[0].config.api.auth.basic.password: Secret hydrator does not exist"),
+		table.Entry("incomplete placeholder", `{"introspection_request_headers":{"authorization":{"valueFrom":{"secretKeyRef":{"name":"introspection"}}}}}`, "",
+			".spec.rules[0].accessStrategies[0].config.introspection_request_headers.authorization: valueFrom has to set only secretKeyRef with the name and the key"),
+	)
+
+	It("Should not check the Secrets of placeholders when the Secrets aren't looked up", func() {
+		//given
+		rule := gatewayv1alpha1.Rule{
+			AccessStrategies: []*gatewayv1alpha1.Authenticator{toAuthenticator("oauth2_introspection",
+				rawConfig(`{"introspection_request_headers":{"authorization":{"valueFrom":{"secretKeyRef":{"name":"introspection","key":"authorization"}}}}}`))},
+		}
+
+		//when
+		problems := (&APIRule{}).validateSecretRefs(".spec.rules[0]", rule)
+
+		//then
+		Expect(problems).To(BeEmpty())
+	})
 })
 
 var _ = Describe("Validator for", func() {
@@ -516,6 +963,93 @@ var _ = Describe("Validator for", func() {
 			Expect(problems).To(HaveLen(0))
 		})
 	})
+
+	Describe("access strategy with config schema", func() {
+
+		vld := &schemaAccStrValidator{schema: &handlers.ConfigSchema{
+			Required:           true,
+			Properties:         map[string]string{"url": "string", "preserve_path": "boolean"},
+			RequiredProperties: []string{"url"},
+		}}
+
+		It("Should fail with empty config", func() {
+			//given
+			handler := &gatewayv1alpha1.Handler{Name: "custom", Config: emptyConfig()}
+
+			//when
+			problems := vld.Validate("some.attribute", handler)
+
+			//then
+			Expect(problems).To(HaveLen(1))
+			Expect(problems[0].AttributePath).To(Equal("some.attribute.config"))
+			Expect(problems[0].Message).To(Equal("supplied config cannot be empty"))
+		})
+
+		It("Should fail for missing, unknown and mistyped properties", func() {
+			//given
+			handler := &gatewayv1alpha1.Handler{Name: "custom", Config: &runtime.RawExtension{Raw: []byte(`{"preserve_path":"yes","other":1}`)}}
+
+			//when
+			problems := vld.Validate("some.attribute", handler)
+
+			//then
+			Expect(problems).To(HaveLen(3))
+			Expect(problems[0].AttributePath).To(Equal("some.attribute.config.url"))
+			Expect(problems[0].Message).To(Equal("value is required"))
+			Expect(problems[1].AttributePath).To(Equal("some.attribute.config.other"))
+			Expect(problems[1].Message).To(Equal("strategy: custom does not support this property"))
+			Expect(problems[2].AttributePath).To(Equal("some.attribute.config.preserve_path"))
+			Expect(problems[2].Message).To(Equal("value should be of type boolean, got string"))
+		})
+
+		It("Should accept a Secret placeholder for a string property", func() {
+			//given
+			handler := &gatewayv1alpha1.Handler{Name: "custom", Config: rawConfig(`{"url":{"valueFrom":{"secretKeyRef":{"name":"remote","key":"url"}}}}`)}
+
+			//when
+			problems := vld.Validate("some.attribute", handler)
+
+			//then
+			Expect(problems).To(BeEmpty())
+		})
+
+		It("Should succeed with valid config", func() {
+			//given
+			handler := &gatewayv1alpha1.Handler{Name: "custom", Config: &runtime.RawExtension{Raw: []byte(`{"url":"http://example.com","preserve_path":true}`)}}
+
+			//when
+			problems := vld.Validate("some.attribute", handler)
+
+			//then
+			Expect(problems).To(HaveLen(0))
+		})
+	})
+
+	Describe("registered access strategy", func() {
+
+		It("Should use the validator registered for the access strategy", func() {
+			//given
+			defer overrideAccessStrategyValidator("noop", vldJWT)()
+
+			//when
+			problems := (&APIRule{}).validateAccessStrategy("some.attribute", toAuthenticator("noop", emptyConfig()))
+
+			//then
+			Expect(problems).To(HaveLen(1))
+			Expect(problems[0].AttributePath).To(Equal("some.attribute.config"))
+			Expect(problems[0].Message).To(Equal("supplied config cannot be empty"))
+		})
+
+		It("Should fail for access strategy that is not registered", func() {
+			//when
+			problems := (&APIRule{}).validateAccessStrategy("some.attribute", toAuthenticator("not_registered", emptyConfig()))
+
+			//then
+			Expect(problems).To(HaveLen(1))
+			Expect(problems[0].AttributePath).To(Equal("some.attribute.handler"))
+			Expect(problems[0].Message).To(Equal("Unsupported accessStrategy: not_registered"))
+		})
+	})
 })
 
 func emptyConfig() *runtime.RawExtension {
@@ -539,6 +1073,13 @@ func getRawConfig(config *gatewayv1alpha1.JWTAccStrConfig) *runtime.RawExtension
 	}
 }
 
+func rawConfig(config string) *runtime.RawExtension {
+	if config == "" {
+		return nil
+	}
+	return &runtime.RawExtension{Raw: []byte(config)}
+}
+
 func toAuthenticator(name string, config *runtime.RawExtension) *gatewayv1alpha1.Authenticator {
 	return &gatewayv1alpha1.Authenticator{
 		Handler: &gatewayv1alpha1.Handler{
@@ -548,6 +1089,18 @@ func toAuthenticator(name string, config *runtime.RawExtension) *gatewayv1alpha1
 	}
 }
 
+func rateLimitBy(keyType, header string) *gatewayv1alpha1.RateLimit {
+	limit := &gatewayv1alpha1.RateLimit{Requests: 10, Unit: "second", Key: &gatewayv1alpha1.RateLimitKey{Type: keyType}}
+	if header != "" {
+		limit.Key.Header = &header
+	}
+	return limit
+}
+
+func secretName(name string) *string {
+	return &name
+}
+
 func getService(serviceName string, servicePort uint32, serviceHost string) *gatewayv1alpha1.Service {
 	return &gatewayv1alpha1.Service{
 		Name: &serviceName,
@@ -555,3 +1108,30 @@ func getService(serviceName string, servicePort uint32, serviceHost string) *gat
 		Host: &serviceHost,
 	}
 }
+
+var _ = Describe("Failure", func() {
+
+	It("Should describe the attribute and the violated policy", func() {
+		Expect(Failure{AttributePath: ".spec.gateway", Message: "is not allowed"}.String()).To(Equal(`Attribute ".spec.gateway": is not allowed`))
+		Expect(Failure{AttributePath: ".spec.gateway", Message: "is not allowed", Policy: "prod"}.String()).To(Equal(`Attribute ".spec.gateway": is not allowed (policy: prod)`))
+	})
+})
+
+//overrideAccessStrategyValidator registers the validator for the access strategy and returns the function restoring the validator
+//registered before, so the override doesn't leak into the other tests
+func overrideAccessStrategyValidator(name string, vld AccessStrategyValidator) func() {
+	validatorsMu.RLock()
+	previous, ok := validators[name]
+	validatorsMu.RUnlock()
+
+	RegisterAccessStrategyValidator(name, vld)
+	return func() {
+		validatorsMu.Lock()
+		defer validatorsMu.Unlock()
+		if ok {
+			validators[name] = previous
+		} else {
+			delete(validators, name)
+		}
+	}
+}
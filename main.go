@@ -16,23 +16,27 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	gatewayv1alpha1 "github.com/kyma-incubator/api-gateway/api/v1alpha1"
-	"istio.io/api/networking/v1beta1"
 
-	"github.com/pkg/errors"
-
-	"github.com/kyma-incubator/api-gateway/internal/processing"
+	"github.com/kyma-incubator/api-gateway/internal/config"
+	"github.com/kyma-incubator/api-gateway/internal/handlers"
 
 	"github.com/kyma-incubator/api-gateway/controllers"
-	"github.com/kyma-incubator/api-gateway/internal/validation"
 	rulev1alpha1 "github.com/ory/oathkeeper-maester/api/v1alpha1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -47,7 +51,9 @@ var (
 func init() {
 	_ = clientgoscheme.AddToScheme(scheme)
 	_ = gatewayv1alpha1.AddToScheme(scheme)
+	_ = networkingv1alpha3.AddToScheme(scheme)
 	_ = networkingv1beta1.AddToScheme(scheme)
+	_ = securityv1beta1.AddToScheme(scheme)
 	_ = rulev1alpha1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
@@ -63,6 +69,14 @@ func main() {
 	var domainName string
 	var corsAllowOrigins, corsAllowMethods, corsAllowHeaders string
 	var generatedObjectsLabels string
+	var accessStrategiesConfig string
+	var configFile, configMap, configMapKey string
+	var configPollInterval time.Duration
+	var dryRun bool
+	var deletionTimeout time.Duration
+	var resyncInterval time.Duration
+	var serveCatalogue bool
+	var apiKeysAddr string
 
 	flag.StringVar(&oathkeeperSvcAddr, "oathkeeper-svc-address", "", "Oathkeeper proxy service")
 	flag.UintVar(&oathkeeperSvcPort, "oathkeeper-svc-port", 0, "Oathkeeper proxy service port")
@@ -77,33 +91,69 @@ func main() {
 	flag.StringVar(&corsAllowMethods, "cors-allow-methods", "GET,POST,PUT,DELETE", "list of allowed methods")
 	flag.StringVar(&corsAllowHeaders, "cors-allow-headers", "Authorization,Content-Type,*", "list of allowed headers")
 	flag.StringVar(&generatedObjectsLabels, "generated-objects-labels", "", "Comma-separated list of key=value pairs used to label generated objects")
+	flag.StringVar(&configFile, "config-file", "", "Path to the configuration file. Settings from the file override the ones from flags and are reloaded on change. Optional.")
+	flag.DurationVar(&configPollInterval, "config-poll-interval", 10*time.Second, "How often the configuration file is checked for changes")
+	flag.StringVar(&configMap, "config-map", "", "ConfigMap holding the configuration, in the namespace/name format. Settings from the ConfigMap override the ones from flags and are applied on change. Optional.")
+	flag.StringVar(&configMapKey, "config-map-key", "config.yaml", "Key of the configuration in the ConfigMap")
+	flag.BoolVar(&dryRun, "dry-run", false, "Compute the changes of generated objects for all APIRules and report them in the status without applying them")
+	flag.DurationVar(&deletionTimeout, "deletion-timeout", 5*time.Minute, "How long the deletion of an APIRule waits for the objects generated for it to be deleted. No limit if zero")
+	flag.DurationVar(&resyncInterval, "resync-interval", time.Hour, "How often all APIRules are processed again, even if they didn't change. No periodic processing if zero")
+	flag.BoolVar(&serveCatalogue, "serve-catalogue", false, "Serve the catalogue of the routes exposed by all APIRules at the /catalogue path of the metrics endpoint, which is not authenticated")
+	flag.StringVar(&apiKeysAddr, "api-keys-addr", "", "The address the check of the keys of api_key access strategies binds to. It should be reachable by Oathkeeper only. Not served if empty")
+	flag.StringVar(&accessStrategiesConfig, "access-strategies-config", "", "Path to a file with additional access strategies to register. Optional.")
 
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
-	if jwksURI == "" {
-		setupLog.Error(fmt.Errorf("jwks-uri required, but not supplied"), "unable to create controller", "controller", "Api")
-		os.Exit(1)
-	}
-	if oathkeeperSvcAddr == "" {
-		setupLog.Error(fmt.Errorf("oathkeeper-svc-address can't be empty"), "unable to create controller", "controller", "Api")
-		os.Exit(1)
+	if accessStrategiesConfig != "" {
+		if err := handlers.LoadFile(accessStrategiesConfig); err != nil {
+			setupLog.Error(err, "loading access strategies failed")
+			os.Exit(1)
+		}
 	}
-	if oathkeeperSvcPort == 0 {
-		setupLog.Error(fmt.Errorf("oathkeeper-svc-port can't be empty"), "unable to create controller", "controller", "Api")
+
+	additionalLabels, err := config.ParseLabels(generatedObjectsLabels)
+	if err != nil {
+		setupLog.Error(err, "parsing labels failed")
 		os.Exit(1)
 	}
-	if allowListedDomains == "" {
-		setupLog.Error(fmt.Errorf("domain-allowlist can't be empty"), "unable to create controller", "controller", "Api")
-		os.Exit(1)
-	} else {
-		for _, domain := range getList(allowListedDomains) {
-			if !validation.ValidateDomainName(domain) {
-				setupLog.Error(fmt.Errorf("invalid domain in domain-allowlist"), "unable to create controller", "controller", "Api")
-				os.Exit(1)
-			}
+
+	//Settings from flags are used as defaults for settings missing in the configuration file or ConfigMap
+	baseConfig := &config.Config{
+		OathkeeperSvcAddress: oathkeeperSvcAddr,
+		OathkeeperSvcPort:    uint32(oathkeeperSvcPort),
+		JWKSURI:              jwksURI,
+		ServiceBlockList:     config.ParseList(blockListedServices),
+		DomainAllowList:      config.ParseList(allowListedDomains),
+		DefaultDomainName:    domainName,
+		Cors: config.Cors{
+			AllowOrigins: config.ParseList(corsAllowOrigins),
+			AllowMethods: config.ParseList(corsAllowMethods),
+			AllowHeaders: config.ParseList(corsAllowHeaders),
+		},
+		GeneratedObjectsLabels: additionalLabels,
+		DryRun:                 dryRun,
+		DeletionTimeout:        &metav1.Duration{Duration: deletionTimeout},
+		ResyncInterval:         &metav1.Duration{Duration: resyncInterval},
+	}
+
+	//The configuration file and the ConfigMap are merged with the settings from flags in one place, so reloading one keeps the settings of the other
+	sources := config.NewSources(baseConfig)
+	var fileWatcher *config.FileWatcher
+	if configFile != "" {
+		fileWatcher = &config.FileWatcher{
+			Path:     configFile,
+			Sources:  sources,
+			Interval: configPollInterval,
+			Log:      ctrl.Log.WithName("config"),
+		}
+		_, data, err := fileWatcher.Read()
+		if err != nil {
+			setupLog.Error(err, "unable to load configuration file", "file", configFile)
+			os.Exit(1)
 		}
+		fileWatcher.MarkApplied(data)
 	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
@@ -116,140 +166,80 @@ func main() {
 		os.Exit(1)
 	}
 
-	additionalLabels, err := parseLabels(generatedObjectsLabels)
-	if err != nil {
-		setupLog.Error(err, "parsing labels failed")
-		os.Exit(1)
+	var configReconciler *controllers.ConfigReconciler
+	if configMap != "" {
+		namespacedName := strings.SplitN(configMap, "/", 2)
+		if len(namespacedName) != 2 {
+			setupLog.Error(fmt.Errorf("config-map should be in the namespace/name format"), "unable to create controller", "controller", "Config")
+			os.Exit(1)
+		}
+		configReconciler = &controllers.ConfigReconciler{
+			Client:    mgr.GetClient(),
+			Log:       ctrl.Log.WithName("controllers").WithName("Config"),
+			Recorder:  mgr.GetEventRecorderFor("api-gateway-controller"),
+			ConfigMap: types.NamespacedName{Namespace: namespacedName[0], Name: namespacedName[1]},
+			Key:       configMapKey,
+			Sources:   sources,
+		}
+		if _, _, err := configReconciler.Read(context.Background(), mgr.GetAPIReader()); err != nil && !apierrs.IsNotFound(err) {
+			setupLog.Error(err, "unable to load configuration", "configMap", configMap)
+			os.Exit(1)
+		}
 	}
 
-	if err = (&controllers.APIReconciler{
-		Client:            mgr.GetClient(),
-		Log:               ctrl.Log.WithName("controllers").WithName("Api"),
-		OathkeeperSvc:     oathkeeperSvcAddr,
-		OathkeeperSvcPort: uint32(oathkeeperSvcPort),
-		JWKSURI:           jwksURI,
-		ServiceBlockList:  getNamespaceServiceMap(blockListedServices),
-		DomainAllowList:   getList(allowListedDomains),
-		DefaultDomainName: domainName,
-		CorsConfig: &processing.CorsConfig{
-			AllowHeaders: getList(corsAllowHeaders),
-			AllowMethods: getList(corsAllowMethods),
-			AllowOrigins: getStringMatch(corsAllowOrigins),
-		},
-		GeneratedObjectsLabels: additionalLabels,
-	}).SetupWithManager(mgr); err != nil {
+	cfg, err := sources.Config()
+	if err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Api")
 		os.Exit(1)
 	}
-	// +kubebuilder:scaffold:builder
 
-	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
-		setupLog.Error(err, "problem running manager")
-		os.Exit(1)
+	apiReconciler := &controllers.APIReconciler{
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+		Log:       ctrl.Log.WithName("controllers").WithName("Api"),
 	}
-}
+	apiReconciler.ApplyConfig(cfg)
 
-func getList(raw string) []string {
-	var result []string
-	for _, s := range strings.Split(raw, ",") {
-		trim := strings.TrimSpace(s)
-		if trim != "" {
-			result = append(result, trim)
-		}
+	if err = apiReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Api")
+		os.Exit(1)
 	}
-	return result
-}
 
-func getStringMatch(raw string) []*v1beta1.StringMatch {
-	var result []*v1beta1.StringMatch
-	for _, s := range getList(raw) {
-		matchTypePair := strings.SplitN(s, ":", 2)
-		matchType := matchTypePair[0]
-		value := matchTypePair[1]
-		var stringMatch *v1beta1.StringMatch
-		switch {
-		case matchType == "regex":
-			stringMatch = regex(value)
-		case matchType == "prefix":
-			stringMatch = prefix(value)
-		case matchType == "exact":
-			stringMatch = exact(value)
+	//The catalogue lists the hosts, paths and issuers of the whole cluster to anyone reaching the metrics endpoint, so it's served on request only
+	if serveCatalogue {
+		if err := mgr.AddMetricsExtraHandler("/catalogue", apiReconciler.CatalogueHandler()); err != nil {
+			setupLog.Error(err, "unable to serve the catalogue of APIRules")
+			os.Exit(1)
 		}
-		result = append(result, stringMatch)
 	}
-	return result
-}
 
-func getNamespaceServiceMap(raw string) map[string][]string {
-	result := make(map[string][]string)
-	for _, s := range getList(raw) {
-		if !validation.ValidateServiceName(s) {
-			setupLog.Error(fmt.Errorf("invalid service in service-blocklist"), "unable to create controller", "controller", "Api")
+	if apiKeysAddr != "" {
+		if err := mgr.Add(&controllers.APIKeysServer{Addr: apiKeysAddr, Handler: apiReconciler.APIKeysHandler()}); err != nil {
+			setupLog.Error(err, "unable to serve the check of API keys")
 			os.Exit(1)
 		}
-		namespacedService := strings.Split(s, ".")
-		namespace := namespacedService[1]
-		service := namespacedService[0]
-		result[namespace] = append(result[namespace], service)
-	}
-	return result
-}
-
-func parseLabels(labelsString string) (map[string]string, error) {
-
-	output := make(map[string]string)
-
-	if labelsString == "" {
-		return output, nil
 	}
 
-	var err error
-
-	for _, labelString := range strings.Split(labelsString, ",") {
-		trim := strings.TrimSpace(labelString)
-		if trim != "" {
-			label := strings.Split(trim, "=")
-			if len(label) != 2 {
-				return nil, errors.New("invalid label format")
-			}
-
-			key, value := label[0], label[1]
-
-			if err = validation.VerifyLabelKey(key); err != nil {
-				return nil, errors.Wrap(err, "invalid label key")
-			}
-
-			if err = validation.VerifyLabelValue(value); err != nil {
-				return nil, errors.Wrap(err, "invalid label value")
-			}
-
-			_, exists := output[key]
-			if exists {
-				return nil, fmt.Errorf("duplicated label: %s", key)
-			}
-
-			output[key] = value
+	if fileWatcher != nil {
+		fileWatcher.Apply = func(cfg *config.Config) { apiReconciler.ApplyConfig(cfg) }
+		if err := mgr.Add(fileWatcher); err != nil {
+			setupLog.Error(err, "unable to watch configuration file", "file", configFile)
+			os.Exit(1)
 		}
 	}
 
-	return output, nil
-}
-
-func regex(val string) *v1beta1.StringMatch {
-	return &v1beta1.StringMatch{
-		MatchType: &v1beta1.StringMatch_Regex{Regex: val},
-	}
-}
-
-func prefix(val string) *v1beta1.StringMatch {
-	return &v1beta1.StringMatch{
-		MatchType: &v1beta1.StringMatch_Prefix{Prefix: val},
+	if configReconciler != nil {
+		configReconciler.Target = apiReconciler
+		if err = configReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Config")
+			os.Exit(1)
+		}
 	}
-}
+	// +kubebuilder:scaffold:builder
 
-func exact(val string) *v1beta1.StringMatch {
-	return &v1beta1.StringMatch{
-		MatchType: &v1beta1.StringMatch_Exact{Exact: val},
+	setupLog.Info("starting manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "problem running manager")
+		os.Exit(1)
 	}
 }